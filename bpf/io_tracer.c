@@ -6,19 +6,53 @@
 #include <bpf/bpf_core_read.h>
 #include <linux/blkdev.h>
 #include <linux/fs.h>
+#include <linux/sunrpc/clnt.h>
+#include <linux/sunrpc/sched.h>
+
+// NFS的RPC程序号，只统计NFS客户端发起的RPC
+#define NFS_PROGRAM 100003
+
+// 事件来源
+#define SOURCE_BLOCK    0 // 块设备I/O
+#define SOURCE_NFS      1 // NFS RPC往返
+#define SOURCE_IO_URING 2 // io_uring请求
+#define SOURCE_VFS      3 // VFS读写
+#define SOURCE_ISCSI    4 // iSCSI命令往返
+
+// 按文件系统过滤的模式，与pkg/ebpf/fsfilter.go一致
+#define FS_FILTER_OFF     0 // 不过滤
+#define FS_FILTER_INCLUDE 1 // 只跟踪映射中的文件系统
+#define FS_FILTER_EXCLUDE 2 // 跳过映射中的文件系统
+
+// fs_filter_config中的索引
+#define FS_FILTER_BY_TYPE  0 // 按超级块magic过滤
+#define FS_FILTER_BY_MOUNT 1 // 按超级块s_dev过滤
+
+// io_uring中需要统计的读写操作码，与include/uapi/linux/io_uring.h一致
+#define IORING_OP_READV       1
+#define IORING_OP_WRITEV      2
+#define IORING_OP_READ_FIXED  4
+#define IORING_OP_WRITE_FIXED 5
+#define IORING_OP_READ        22
+#define IORING_OP_WRITE       23
 
 // 定义数据结构
 struct io_event_t {
     u64 ts;          // 时间戳
     u32 pid;         // 进程ID
     u32 tid;         // 线程ID
-    u64 io_start;    // I/O开始时间
+    u64 cgroup_id;   // 发起I/O的cgroup ID，用于关联Pod
+    u64 io_start;    // I/O开始时间，块I/O为下发到设备驱动的时间
     u64 io_end;      // I/O结束时间
+    u64 queue_start; // 块I/O进入调度队列的时间，未经过调度队列时为0
     u64 bytes;       // I/O字节数
     char comm[16];   // 进程名
     char disk[32];   // 磁盘设备名
     u8 operation;    // 操作类型 (0=read, 1=write)
     u8 io_type;      // I/O类型 (0=sync, 1=async)
+    u8 source;       // 事件来源 (0=block, 1=nfs, 2=io_uring, 3=vfs, 4=iscsi)
+    u32 dev;         // 块I/O所在设备的dev_t（内核编码，major为高12位，minor为低20位），VFS读写时为文件系统超级块的s_dev
+    u64 fs_magic;    // VFS读写所在文件系统超级块的magic，其余来源为0
 };
 
 // 定义延迟信息结构
@@ -47,6 +81,40 @@ struct {
     __type(value, struct latency_info_t);
 } latency_by_pid SEC(".maps");
 
+// 进行中的NFS RPC，key为struct rpc_task指针
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 10240);
+    __type(key, struct rpc_task *);
+    __type(value, struct io_event_t);
+} rpc_tasks SEC(".maps");
+
+// 进行中的iSCSI命令，key为struct scsi_cmnd指针
+// 被target中止后未经scsi_done完成的命令不会被删除，使用LRU自动淘汰这些残留条目
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __uint(max_entries, 10240);
+    __type(key, void *);
+    __type(value, struct io_event_t);
+} iscsi_cmds SEC(".maps");
+
+// 进行中的io_uring请求，key为struct io_kiocb指针
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 10240);
+    __type(key, void *);
+    __type(value, struct io_event_t);
+} uring_reqs SEC(".maps");
+
+// 已进入调度队列但尚未下发的块I/O请求，value为入队时间
+// 被合并的请求不会再下发，使用LRU自动淘汰这些残留条目
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __uint(max_entries, 10240);
+    __type(key, struct request *);
+    __type(value, u64);
+} queued_requests SEC(".maps");
+
 // 用于事件输出的环形缓冲区
 struct {
     __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
@@ -54,7 +122,95 @@ struct {
     __uint(value_size, sizeof(int));
 } events SEC(".maps");
 
+// 按cgroup过滤的开关，索引0的值为1时只跟踪traced_cgroups中的cgroup
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, u32);
+    __type(value, u8);
+} filter_config SEC(".maps");
+
+// 启用过滤时需要跟踪的cgroup ID，由用户态按Pod维护
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 10240);
+    __type(key, u64);
+    __type(value, u8);
+} traced_cgroups SEC(".maps");
+
+// 采样率，索引0的值为N（N>1）时只记录约1/N的I/O，由用户态按N放大计数
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, u32);
+    __type(value, u32);
+} sample_config SEC(".maps");
+
+// 已进入vfs_read/vfs_write但尚未返回的读写，key为pid_tgid
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 10240);
+    __type(key, u64);
+    __type(value, struct io_event_t);
+} vfs_reqs SEC(".maps");
+
+// 按文件系统过滤的模式（FS_FILTER_*），FS_FILTER_BY_TYPE和FS_FILTER_BY_MOUNT两个维度分别设置
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, 2);
+    __type(key, u32);
+    __type(value, u8);
+} fs_filter_config SEC(".maps");
+
+// 按类型过滤时包含或排除的文件系统，key为超级块magic
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 64);
+    __type(key, u64);
+    __type(value, u8);
+} fs_type_filter SEC(".maps");
+
+// 按挂载点过滤时包含或排除的文件系统，key为挂载点所在超级块的s_dev，由用户态从mountinfo解析
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 1024);
+    __type(key, u32);
+    __type(value, u8);
+} fs_mount_filter SEC(".maps");
+
 // 辅助函数
+static __always_inline int should_trace(u64 cgroup_id) {
+    u32 key = 0;
+    u8 *enabled = bpf_map_lookup_elem(&filter_config, &key);
+    if (!enabled || *enabled == 0)
+        return 1;
+    return bpf_map_lookup_elem(&traced_cgroups, &cgroup_id) != NULL;
+}
+
+// 在I/O开始时决定是否采样，未采样的I/O不记录开始事件，完成时也就不会上报
+static __always_inline int should_sample(void) {
+    u32 key = 0;
+    u32 *rate = bpf_map_lookup_elem(&sample_config, &key);
+    if (!rate || *rate <= 1)
+        return 1;
+    return bpf_get_prandom_u32() % *rate == 0;
+}
+
+// 按fs_filter_config中index维度的模式判断是否跟踪，key是否在filter中决定包含或排除
+static __always_inline int fs_filter_match(u32 index, void *filter, void *key) {
+    u8 *mode = bpf_map_lookup_elem(&fs_filter_config, &index);
+    if (!mode || *mode == FS_FILTER_OFF)
+        return 1;
+    int found = bpf_map_lookup_elem(filter, key) != NULL;
+    return *mode == FS_FILTER_INCLUDE ? found : !found;
+}
+
+// 判断文件所在的文件系统是否需要跟踪，类型和挂载点两个维度都满足时才跟踪
+static __always_inline int should_trace_fs(u64 magic, u32 dev) {
+    return fs_filter_match(FS_FILTER_BY_TYPE, &fs_type_filter, &magic) &&
+           fs_filter_match(FS_FILTER_BY_MOUNT, &fs_mount_filter, &dev);
+}
+
 static __always_inline void update_latency_stats(u32 pid, u64 duration, u8 operation) {
     struct latency_info_t *latency, zero = {};
     
@@ -75,20 +231,58 @@ static __always_inline void update_latency_stats(u32 pid, u64 duration, u8 opera
     }
 }
 
+// 跟踪块I/O请求进入调度队列，下发时据此计算队列延迟
+SEC("tracepoint/block/block_rq_insert")
+int trace_block_rq_insert(struct trace_event_raw_block_rq *ctx) {
+    struct request *req = (struct request *)ctx->rq;
+    u64 ts;
+    
+    // 未被选中跟踪的cgroup直接跳过
+    if (!should_trace(bpf_get_current_cgroup_id()))
+        return 0;
+    
+    ts = bpf_ktime_get_ns();
+    bpf_map_update_elem(&queued_requests, &req, &ts, BPF_ANY);
+    
+    return 0;
+}
+
 // 跟踪块I/O请求开始
 SEC("tracepoint/block/block_rq_issue")
 int trace_block_rq_issue(struct trace_event_raw_block_rq_issue *ctx) {
     struct io_event_t io_event = {};
     struct request *req = (struct request *)ctx->rq;
+    u64 *queued;
     
     io_event.ts = bpf_ktime_get_ns();
     io_event.io_start = io_event.ts;
     io_event.pid = bpf_get_current_pid_tgid() >> 32;
     io_event.tid = bpf_get_current_pid_tgid() & 0xFFFFFFFF;
+    io_event.cgroup_id = bpf_get_current_cgroup_id();
+    
+    // 未被选中跟踪的cgroup直接跳过
+    if (!should_trace(io_event.cgroup_id))
+        return 0;
+    
+    // 未被采样的请求不再需要入队时间
+    if (!should_sample()) {
+        bpf_map_delete_elem(&queued_requests, &req);
+        return 0;
+    }
     
     // 获取进程名称
     bpf_get_current_comm(&io_event.comm, sizeof(io_event.comm));
     
+    io_event.dev = ctx->dev;
+    io_event.bytes = ctx->bytes;
+    
+    // 经过调度队列的请求记录入队时间，直接下发的请求没有队列延迟
+    queued = bpf_map_lookup_elem(&queued_requests, &req);
+    if (queued) {
+        io_event.queue_start = *queued;
+        bpf_map_delete_elem(&queued_requests, &req);
+    }
+    
     // 确定操作类型
     unsigned int cmd_flags = BPF_CORE_READ(req, cmd_flags);
     if (cmd_flags & REQ_OP_WRITE)
@@ -132,92 +326,249 @@ int trace_block_rq_complete(struct trace_event_raw_block_rq_complete *ctx) {
     return 0;
 }
 
-// 跟踪VFS读取操作
-SEC("kprobe/vfs_read")
-int trace_vfs_read_entry(struct pt_regs *ctx) {
+// 记录VFS读写的开始，file为vfs_read/vfs_write的第一个参数
+static __always_inline int record_vfs_entry(struct file *file, u8 operation) {
     struct io_event_t io_event = {};
     
+    io_event.cgroup_id = bpf_get_current_cgroup_id();
+    io_event.fs_magic = BPF_CORE_READ(file, f_inode, i_sb, s_magic);
+    io_event.dev = BPF_CORE_READ(file, f_inode, i_sb, s_dev);
+    
+    // 未被选中跟踪、被文件系统过滤或未被采样的I/O直接跳过
+    if (!should_trace(io_event.cgroup_id) || !should_trace_fs(io_event.fs_magic, io_event.dev) || !should_sample())
+        return 0;
+    
+    u64 id = bpf_get_current_pid_tgid();
     io_event.ts = bpf_ktime_get_ns();
     io_event.io_start = io_event.ts;
-    io_event.pid = bpf_get_current_pid_tgid() >> 32;
-    io_event.tid = bpf_get_current_pid_tgid() & 0xFFFFFFFF;
-    io_event.operation = 0; // read
-    
-    // 获取进程名称
+    io_event.pid = id >> 32;
+    io_event.tid = id & 0xFFFFFFFF;
+    io_event.operation = operation;
+    io_event.source = SOURCE_VFS;
     bpf_get_current_comm(&io_event.comm, sizeof(io_event.comm));
     
-    // 存储当前文件操作信息(简化版，实际需要存储文件描述符等更多信息)
-    u64 id = bpf_get_current_pid_tgid();
-    bpf_map_update_elem(&requests, &id, &io_event, BPF_ANY);
+    bpf_map_update_elem(&vfs_reqs, &id, &io_event, BPF_ANY);
     
     return 0;
 }
 
-// 跟踪VFS读取操作完成
-SEC("kretprobe/vfs_read")
-int trace_vfs_read_exit(struct pt_regs *ctx) {
+// 记录VFS读写的完成，返回值为读写的字节数或负的错误码
+static __always_inline int record_vfs_exit(struct pt_regs *ctx) {
     u64 id = bpf_get_current_pid_tgid();
-    struct io_event_t *io_eventp;
+    struct io_event_t *io_eventp, io_event = {};
     
-    io_eventp = bpf_map_lookup_elem(&requests, &id);
+    io_eventp = bpf_map_lookup_elem(&vfs_reqs, &id);
     if (!io_eventp)
         return 0;
     
-    // 获取返回值（读取的字节数）
-    io_eventp->bytes = PT_REGS_RC(ctx);
-    io_eventp->io_end = bpf_ktime_get_ns();
+    __builtin_memcpy(&io_event, io_eventp, sizeof(io_event));
+    io_event.io_end = bpf_ktime_get_ns();
+    
+    long ret = PT_REGS_RC(ctx);
+    if (ret > 0)
+        io_event.bytes = ret;
     
-    // 计算延迟
-    u64 duration = io_eventp->io_end - io_eventp->io_start;
-    update_latency_stats(io_eventp->pid, duration, io_eventp->operation);
+    update_latency_stats(io_event.pid, io_event.io_end - io_event.io_start, io_event.operation);
     
-    // 删除请求记录
-    bpf_map_delete_elem(&requests, &id);
+    // 将事件发送到用户空间
+    bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &io_event, sizeof(io_event));
+    
+    bpf_map_delete_elem(&vfs_reqs, &id);
     
     return 0;
 }
 
+// 跟踪VFS读取操作
+SEC("kprobe/vfs_read")
+int trace_vfs_read_entry(struct pt_regs *ctx) {
+    return record_vfs_entry((struct file *)PT_REGS_PARM1(ctx), 0);
+}
+
+// 跟踪VFS读取操作完成
+SEC("kretprobe/vfs_read")
+int trace_vfs_read_exit(struct pt_regs *ctx) {
+    return record_vfs_exit(ctx);
+}
+
 // 跟踪VFS写入操作
 SEC("kprobe/vfs_write")
 int trace_vfs_write_entry(struct pt_regs *ctx) {
+    return record_vfs_entry((struct file *)PT_REGS_PARM1(ctx), 1);
+}
+
+// 跟踪VFS写入操作完成
+SEC("kretprobe/vfs_write")
+int trace_vfs_write_exit(struct pt_regs *ctx) {
+    return record_vfs_exit(ctx);
+}
+
+// 跟踪RPC任务开始执行，此时仍处于发起I/O的进程上下文中，可以取得其cgroup
+SEC("kprobe/rpc_execute")
+int trace_rpc_execute(struct pt_regs *ctx) {
+    struct rpc_task *task = (struct rpc_task *)PT_REGS_PARM1(ctx);
     struct io_event_t io_event = {};
     
+    // 只统计NFS客户端的RPC，忽略lockd、rpcbind等
+    u32 prog = BPF_CORE_READ(task, tk_client, cl_prog);
+    if (prog != NFS_PROGRAM)
+        return 0;
+    
+    io_event.cgroup_id = bpf_get_current_cgroup_id();
+    
+    // 未被选中跟踪或未被采样的I/O直接跳过
+    if (!should_trace(io_event.cgroup_id) || !should_sample())
+        return 0;
+    
     io_event.ts = bpf_ktime_get_ns();
     io_event.io_start = io_event.ts;
     io_event.pid = bpf_get_current_pid_tgid() >> 32;
     io_event.tid = bpf_get_current_pid_tgid() & 0xFFFFFFFF;
-    io_event.operation = 1; // write
+    io_event.source = SOURCE_NFS;
+    bpf_get_current_comm(&io_event.comm, sizeof(io_event.comm));
     
-    // 获取进程名称
+    // 重试的RPC会再次进入rpc_execute，保留第一次的开始时间
+    bpf_map_update_elem(&rpc_tasks, &task, &io_event, BPF_NOEXIST);
+    
+    return 0;
+}
+
+// 跟踪RPC任务结束，计算从开始执行到收到应答的往返延迟
+SEC("kprobe/rpc_exit_task")
+int trace_rpc_exit_task(struct pt_regs *ctx) {
+    struct rpc_task *task = (struct rpc_task *)PT_REGS_PARM1(ctx);
+    struct io_event_t *io_eventp, io_event = {};
+    
+    io_eventp = bpf_map_lookup_elem(&rpc_tasks, &task);
+    if (!io_eventp)
+        return 0;
+    
+    __builtin_memcpy(&io_event, io_eventp, sizeof(io_event));
+    io_event.io_end = bpf_ktime_get_ns();
+    
+    // 将事件发送到用户空间
+    bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &io_event, sizeof(io_event));
+    
+    bpf_map_delete_elem(&rpc_tasks, &task);
+    
+    return 0;
+}
+
+// 跟踪libiscsi下发SCSI命令，scsi_cmnd紧跟在所属的块I/O请求之后，据此取得块I/O下发时记录的cgroup和读写信息
+// 命令由blk-mq派发，不一定处于发起I/O的进程上下文中，因此不使用当前进程的cgroup
+SEC("kprobe/iscsi_queuecommand")
+int trace_iscsi_queuecommand(struct pt_regs *ctx) {
+    void *cmd = (void *)PT_REGS_PARM2(ctx);
+    struct request *req = (struct request *)(cmd - bpf_core_type_size(struct request));
+    struct io_event_t *blk_eventp, io_event = {};
+    
+    // 只有被选中跟踪且被采样的块I/O请求才在requests中
+    blk_eventp = bpf_map_lookup_elem(&requests, &req);
+    if (!blk_eventp)
+        return 0;
+    
+    __builtin_memcpy(&io_event, blk_eventp, sizeof(io_event));
+    io_event.ts = bpf_ktime_get_ns();
+    io_event.io_start = io_event.ts;
+    io_event.queue_start = 0;
+    io_event.source = SOURCE_ISCSI;
+    
+    // 重新下发的命令保留第一次的开始时间
+    bpf_map_update_elem(&iscsi_cmds, &cmd, &io_event, BPF_NOEXIST);
+    
+    return 0;
+}
+
+// 跟踪SCSI命令完成，只有iscsi_cmds中的命令来自iSCSI，计算从下发到收到target响应的往返延迟
+SEC("kprobe/scsi_done")
+int trace_iscsi_cmd_done(struct pt_regs *ctx) {
+    void *cmd = (void *)PT_REGS_PARM1(ctx);
+    struct io_event_t *io_eventp, io_event = {};
+    
+    io_eventp = bpf_map_lookup_elem(&iscsi_cmds, &cmd);
+    if (!io_eventp)
+        return 0;
+    
+    __builtin_memcpy(&io_event, io_eventp, sizeof(io_event));
+    io_event.io_end = bpf_ktime_get_ns();
+    
+    // 将事件发送到用户空间
+    bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &io_event, sizeof(io_event));
+    
+    bpf_map_delete_elem(&iscsi_cmds, &cmd);
+    
+    return 0;
+}
+
+// 记录io_uring读写请求的提交，提交时处于发起I/O的进程（或同一cgroup的SQPOLL线程）上下文中
+static __always_inline int record_io_uring_submit(void *req, u8 opcode) {
+    struct io_event_t io_event = {};
+    
+    switch (opcode) {
+    case IORING_OP_READV:
+    case IORING_OP_READ_FIXED:
+    case IORING_OP_READ:
+        io_event.operation = 0; // read
+        break;
+    case IORING_OP_WRITEV:
+    case IORING_OP_WRITE_FIXED:
+    case IORING_OP_WRITE:
+        io_event.operation = 1; // write
+        break;
+    default:
+        return 0;
+    }
+    
+    io_event.cgroup_id = bpf_get_current_cgroup_id();
+    
+    // 未被选中跟踪或未被采样的I/O直接跳过
+    if (!should_trace(io_event.cgroup_id) || !should_sample())
+        return 0;
+    
+    io_event.ts = bpf_ktime_get_ns();
+    io_event.io_start = io_event.ts;
+    io_event.pid = bpf_get_current_pid_tgid() >> 32;
+    io_event.tid = bpf_get_current_pid_tgid() & 0xFFFFFFFF;
+    io_event.source = SOURCE_IO_URING;
     bpf_get_current_comm(&io_event.comm, sizeof(io_event.comm));
     
-    // 存储当前文件操作信息
-    u64 id = bpf_get_current_pid_tgid();
-    bpf_map_update_elem(&requests, &id, &io_event, BPF_ANY);
+    bpf_map_update_elem(&uring_reqs, &req, &io_event, BPF_ANY);
     
     return 0;
 }
 
-// 跟踪VFS写入操作完成
-SEC("kretprobe/vfs_write")
-int trace_vfs_write_exit(struct pt_regs *ctx) {
-    u64 id = bpf_get_current_pid_tgid();
-    struct io_event_t *io_eventp;
+// 跟踪io_uring请求提交（5.15 ~ 6.3内核）
+SEC("tracepoint/io_uring/io_uring_submit_sqe")
+int trace_io_uring_submit_sqe(struct trace_event_raw_io_uring_submit_sqe *ctx) {
+    return record_io_uring_submit(ctx->req, ctx->opcode);
+}
+
+// 跟踪io_uring请求提交（6.4及以上内核，tracepoint更名为io_uring_submit_req）
+SEC("tracepoint/io_uring/io_uring_submit_req")
+int trace_io_uring_submit_req(struct trace_event_raw_io_uring_submit_req *ctx) {
+    return record_io_uring_submit(ctx->req, ctx->opcode);
+}
+
+// 跟踪io_uring请求完成，计算从提交到完成的延迟
+SEC("tracepoint/io_uring/io_uring_complete")
+int trace_io_uring_complete(struct trace_event_raw_io_uring_complete *ctx) {
+    void *req = ctx->req;
+    struct io_event_t *io_eventp, io_event = {};
     
-    io_eventp = bpf_map_lookup_elem(&requests, &id);
+    io_eventp = bpf_map_lookup_elem(&uring_reqs, &req);
     if (!io_eventp)
         return 0;
     
-    // 获取返回值（写入的字节数）
-    io_eventp->bytes = PT_REGS_RC(ctx);
-    io_eventp->io_end = bpf_ktime_get_ns();
+    __builtin_memcpy(&io_event, io_eventp, sizeof(io_event));
+    io_event.io_end = bpf_ktime_get_ns();
     
-    // 计算延迟
-    u64 duration = io_eventp->io_end - io_eventp->io_start;
-    update_latency_stats(io_eventp->pid, duration, io_eventp->operation);
+    // 成功时res为读写的字节数，失败时为负的错误码
+    if (ctx->res > 0)
+        io_event.bytes = ctx->res;
     
-    // 删除请求记录
-    bpf_map_delete_elem(&requests, &id);
+    // 将事件发送到用户空间
+    bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &io_event, sizeof(io_event));
+    
+    bpf_map_delete_elem(&uring_reqs, &req);
     
     return 0;
 }
@@ -2,99 +2,319 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"runtime"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/lizhongxuan/ioeye/pkg/analyzer"
 	"github.com/lizhongxuan/ioeye/pkg/api"
+	grpcapi "github.com/lizhongxuan/ioeye/pkg/api/grpc"
+	"github.com/lizhongxuan/ioeye/pkg/config"
 	"github.com/lizhongxuan/ioeye/pkg/ebpf"
+	kafkaexport "github.com/lizhongxuan/ioeye/pkg/export/kafka"
+	otelexport "github.com/lizhongxuan/ioeye/pkg/export/otel"
+	statsdexport "github.com/lizhongxuan/ioeye/pkg/export/statsd"
 	"github.com/lizhongxuan/ioeye/pkg/k8s"
 	"github.com/lizhongxuan/ioeye/pkg/monitor"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// 构建信息，构建时通过-ldflags "-X main.version=... -X main.commit=... -X main.buildDate=..."注入
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
 func main() {
-	// 命令行参数
-	kubeconfig := flag.String("kubeconfig", "", "Path to kubeconfig file")
-	namespace := flag.String("namespace", "", "Namespace to monitor (empty for all)")
-	interval := flag.Int("interval", 10, "Metrics collection interval in seconds")
-	apiAddr := flag.String("api-addr", ":8080", "Address to bind API server")
+	// 命令行参数，默认值来自内置配置
+	cfg := config.Default()
+	configPath := flag.String("config", "", "Path to a YAML config file; flags set on the command line or via IOEYE_* environment variables override its values")
+	showVersion := flag.Bool("version", false, "Print version information and exit")
+	validate := flag.Bool("validate", false, "Check the configuration, Kubernetes connectivity and eBPF support, print a report and exit non-zero on failure without starting the monitor")
+	flag.StringVar(&cfg.Kubeconfig, "kubeconfig", cfg.Kubeconfig, "Path to kubeconfig file")
+	flag.Var(newStringSetFlag(&cfg.KubeContexts), "kube-context", "Kubeconfig context of a cluster to monitor, repeatable or comma-separated; the first is the cluster IOEye runs in, the rest only contribute pod topology (empty for the current cluster only)")
+	flag.Var(newStringSetFlag(&cfg.Namespaces), "namespace", "Namespace to monitor, repeatable or comma-separated (empty for all)")
+	flag.Var(newStringSetFlag(&cfg.ExcludeNamespaces), "exclude-namespace", "Namespace never to monitor, repeatable or comma-separated; takes precedence over -namespace")
+	flag.Var(newStringSetFlag(&cfg.ExcludePods), "exclude-pod", "Pod name or glob pattern (e.g. ioeye-*) never to monitor, repeatable or comma-separated")
+	flag.IntVar(&cfg.Interval, "interval", cfg.Interval, "Metrics collection interval in seconds")
+	flag.StringVar(&cfg.API.Addr, "api-addr", cfg.API.Addr, "Address to bind API server, host:port or unix:///path/to.sock for a Unix domain socket")
+	flag.StringVar(&cfg.API.GRPCAddr, "grpc-addr", cfg.API.GRPCAddr, "Address to bind the gRPC API server (empty to disable)")
+	flag.BoolVar(&cfg.UseInformer, "use-informer", cfg.UseInformer, "Watch pods via informer cache instead of listing them every interval")
+	flag.StringVar(&cfg.History.Path, "history-path", cfg.History.Path, "Path to the file persisting metrics history across restarts (empty to disable)")
+	flag.DurationVar(&cfg.History.Retention, "history-retention", cfg.History.Retention, "Drop persisted metrics history older than this on startup")
+	flag.IntVar(&cfg.Analyzer.MaxHistoryPerPod, "max-history", cfg.Analyzer.MaxHistoryPerPod, "Number of metrics samples kept per pod for analysis")
+	flag.Float64Var(&cfg.Analyzer.AnomalyThreshold, "anomaly-threshold", cfg.Analyzer.AnomalyThreshold, "Number of standard deviations from the mean that counts as an anomaly")
+	flag.IntVar(&cfg.Analyzer.SustainedAnomalySamples, "sustained-anomaly", cfg.Analyzer.SustainedAnomalySamples, "Number of consecutive samples above (or back under) the anomaly threshold needed to raise (or clear) an anomaly")
+	flag.Float64Var(&cfg.Analyzer.EWMAAlpha, "ewma-alpha", cfg.Analyzer.EWMAAlpha, "Smoothing factor (0, 1] of the exponentially weighted anomaly baseline; 0 uses a simple average of the whole history")
+	flag.DurationVar(&cfg.Analyzer.ReadLatencyThreshold, "read-latency-threshold", cfg.Analyzer.ReadLatencyThreshold, "Read latency above which a pod is considered bottlenecked")
+	flag.DurationVar(&cfg.Analyzer.WriteLatencyThreshold, "write-latency-threshold", cfg.Analyzer.WriteLatencyThreshold, "Write latency above which a pod is considered bottlenecked")
+	flag.DurationVar(&cfg.Analyzer.QueueLatencyThreshold, "queue-latency-threshold", cfg.Analyzer.QueueLatencyThreshold, "Queue latency above which the bottleneck is attributed to the I/O queue")
+	flag.IntVar(&cfg.Analyzer.TopSlowWindow, "topslow-window", cfg.Analyzer.TopSlowWindow, "Number of recent samples per pod the top slow pods ranking is computed over (1 ranks by the latest sample only)")
+	flag.StringVar(&cfg.Analyzer.TopSlowStat, "topslow-stat", cfg.Analyzer.TopSlowStat, "How the top slow pods ranking combines the recent samples: mean or p95")
+	flag.Uint64Var(&cfg.Analyzer.ContentionIOPSThreshold, "contention-iops-threshold", cfg.Analyzer.ContentionIOPSThreshold, "Combined read and write IOPS above which high latency with no dominant component is classified as device contention")
+	flag.Float64Var(&cfg.Analyzer.DeviceUtilizationThreshold, "device-utilization-threshold", cfg.Analyzer.DeviceUtilizationThreshold, "Fraction of time (0, 1] a block device has requests in flight above which it is flagged as saturated")
+	flag.Uint64Var(&cfg.Analyzer.SmallIOSizeThreshold, "small-io-size-threshold", cfg.Analyzer.SmallIOSizeThreshold, "Average read or write request size in bytes below which a busy pod is flagged as doing small I/O that would benefit from batching")
+	flag.StringVar(&cfg.OTLP.Endpoint, "otlp-endpoint", cfg.OTLP.Endpoint, "OTLP/HTTP endpoint (host:port) to push metrics to (empty to disable)")
+	flag.BoolVar(&cfg.OTLP.Insecure, "otlp-insecure", cfg.OTLP.Insecure, "Use plain HTTP instead of HTTPS for the OTLP endpoint")
+	flag.Var(newStringSetFlag(&cfg.Kafka.Brokers), "kafka-brokers", "Kafka broker (host:port) to push per-pod metrics to, repeatable or comma-separated (empty to disable)")
+	flag.StringVar(&cfg.Kafka.Topic, "kafka-topic", cfg.Kafka.Topic, "Kafka topic to write metrics messages to")
+	flag.IntVar(&cfg.Kafka.QueueSize, "kafka-queue-size", cfg.Kafka.QueueSize, "Number of unsent Kafka messages buffered before the oldest are dropped")
+	flag.StringVar(&cfg.StatsD.Addr, "statsd-addr", cfg.StatsD.Addr, "StatsD server (host:port) to send per-pod gauges to over UDP (empty to disable)")
+	flag.StringVar(&cfg.StatsD.Format, "statsd-format", cfg.StatsD.Format, "StatsD tag format: statsd (pod and namespace in the metric name) or dogstatsd (pod and namespace as tags)")
+	flag.StringVar(&cfg.StatsD.Prefix, "statsd-prefix", cfg.StatsD.Prefix, "Prefix of StatsD metric names")
+	flag.StringVar(&cfg.HA.Role, "role", cfg.HA.Role, "Instance role: active attaches eBPF probes and exports metrics once it holds the lease, standby never does and forwards read API requests to the active instance")
+	flag.StringVar(&cfg.HA.LeaseName, "lease-name", cfg.HA.LeaseName, "Name prefix of the per-node Kubernetes Lease electing the single active instance on this node, suffixed with -node-name (empty to always be active; required with -role=standby)")
+	flag.StringVar(&cfg.HA.LeaseNamespace, "lease-namespace", cfg.HA.LeaseNamespace, "Namespace of the Kubernetes Lease")
+	flag.DurationVar(&cfg.HA.LeaseDuration, "lease-duration", cfg.HA.LeaseDuration, "How long the lease holder may go without renewing before another instance takes over")
+	flag.StringVar(&cfg.HA.NodeName, "node-name", cfg.HA.NodeName, "Name of the node this instance runs on, usually spec.nodeName from the downward API (required with -lease-name)")
+	flag.StringVar(&cfg.HA.AdvertiseURL, "advertise-url", cfg.HA.AdvertiseURL, "URL other instances reach this instance's API at (e.g. http://10.0.0.5:8080), recorded as the lease holder so standbys can forward reads to it (empty for the hostname, without forwarding)")
+	flag.StringVar(&cfg.BPF.Object, "bpf-object", cfg.BPF.Object, "Path to the compiled eBPF object file")
+	flag.BoolVar(&cfg.BPF.MockData, "mock-data", cfg.BPF.MockData, "Serve built-in mock I/O data instead of loading eBPF programs")
+	flag.UintVar(&cfg.BPF.SampleRate, "ebpf-sample-rate", cfg.BPF.SampleRate, "Record only 1 in N I/O events in the kernel to reduce overhead on busy nodes; counters are scaled back up by N")
+	flag.Var(newStringSetFlag(&cfg.BPF.FSFilter.IncludeTypes), "ebpf-include-fs-type", "Only trace VFS reads and writes on this filesystem type (e.g. ext4, xfs), repeatable or comma-separated; mutually exclusive with -ebpf-exclude-fs-type")
+	flag.Var(newStringSetFlag(&cfg.BPF.FSFilter.ExcludeTypes), "ebpf-exclude-fs-type", "Do not trace VFS reads and writes on this filesystem type (e.g. overlay, tmpfs), repeatable or comma-separated")
+	flag.Var(newStringSetFlag(&cfg.BPF.FSFilter.IncludeMounts), "ebpf-include-mount", "Only trace VFS reads and writes on the filesystem mounted at this path, repeatable or comma-separated; mutually exclusive with -ebpf-exclude-mount")
+	flag.Var(newStringSetFlag(&cfg.BPF.FSFilter.ExcludeMounts), "ebpf-exclude-mount", "Do not trace VFS reads and writes on the filesystem mounted at this path, repeatable or comma-separated")
+	flag.BoolVar(&cfg.BPF.Require, "require-ebpf", cfg.BPF.Require, "Exit if the block I/O tracer cannot be attached; when false, keep serving Kubernetes pod information without I/O metrics")
+	flag.DurationVar(&cfg.StalenessWindow, "staleness-window", cfg.StalenessWindow, "Mark a pod's metrics as stale when its eBPF data has not been updated for this long")
+	flag.DurationVar(&cfg.RateWindow, "rate-window", cfg.RateWindow, "Window of the interval-averaged pod rates")
+	flag.IntVar(&cfg.SmoothingWindow, "smoothing-window", cfg.SmoothingWindow, "Also report each pod metric averaged over the last N collections as *_avg fields (0 or 1 to disable)")
+	flag.StringVar(&cfg.CgroupRoot, "cgroup-root", cfg.CgroupRoot, "Mount point of the cgroup filesystem used to map I/O to pods")
+	flag.StringVar(&cfg.Alert.Webhook, "alert-webhook", cfg.Alert.Webhook, "URL to POST JSON alerts to when a pod becomes anomalous or bottlenecked (empty to disable)")
+	flag.DurationVar(&cfg.Alert.Cooldown, "alert-cooldown", cfg.Alert.Cooldown, "Minimum interval between alerts for the same pod")
+	flag.IntVar(&cfg.Alert.HistorySize, "alert-history-size", cfg.Alert.HistorySize, "Number of recent anomaly and bottleneck alerts kept for /api/v1/alerts")
+	flag.DurationVar(&cfg.Alert.EscalationWindow, "alert-escalation-window", cfg.Alert.EscalationWindow, "Window in which a pod becoming anomalous or bottlenecked again escalates its alert to warning, bypassing -alert-cooldown")
+	flag.DurationVar(&cfg.Alert.CriticalAfter, "alert-critical-after", cfg.Alert.CriticalAfter, "How long a pod must stay anomalous or bottlenecked before a critical alert is sent, bypassing -alert-cooldown")
+	flag.StringVar(&cfg.API.TLS.Cert, "tls-cert", cfg.API.TLS.Cert, "Path to the TLS certificate for the API server (enables HTTPS with -tls-key)")
+	flag.StringVar(&cfg.API.TLS.Key, "tls-key", cfg.API.TLS.Key, "Path to the TLS private key for the API server")
+	flag.DurationVar(&cfg.API.ShutdownTimeout, "shutdown-timeout", cfg.API.ShutdownTimeout, "How long the API server waits for in-flight requests to finish on shutdown")
+	flag.DurationVar(&cfg.API.Timeouts.ReadHeader, "api-read-header-timeout", cfg.API.Timeouts.ReadHeader, "How long the API server waits for a client to send the request headers")
+	flag.DurationVar(&cfg.API.Timeouts.Read, "api-read-timeout", cfg.API.Timeouts.Read, "How long the API server waits for a client to send the whole request")
+	flag.DurationVar(&cfg.API.Timeouts.Write, "api-write-timeout", cfg.API.Timeouts.Write, "How long the API server may take to write a response; the metrics stream applies it to each event")
+	flag.DurationVar(&cfg.API.Timeouts.Idle, "api-idle-timeout", cfg.API.Timeouts.Idle, "How long the API server keeps an idle keep-alive connection open")
+	flag.Float64Var(&cfg.API.RateLimit.RPS, "api-rate-limit", cfg.API.RateLimit.RPS, "Requests per second allowed per API client, keyed by token when auth is enabled or by client IP otherwise (0 to disable)")
+	flag.IntVar(&cfg.API.RateLimit.Burst, "api-rate-burst", cfg.API.RateLimit.Burst, "Number of API requests a client may burst above -api-rate-limit")
+	flag.Var(newStringSetFlag(&cfg.API.AllowedOrigins), "api-allowed-origin", "Origin (e.g. https://dashboard.example.com, or * for any) allowed to call the API from a browser, repeatable or comma-separated (empty for same-origin only)")
+	flag.StringVar(&cfg.API.Token, "api-token", cfg.API.Token, "Bearer token required by the API (defaults to $IOEYE_API_TOKEN, empty to disable auth)")
+	flag.StringVar(&cfg.Debug.PprofAddr, "pprof-addr", cfg.Debug.PprofAddr, "Address to serve /debug/pprof on, separate from the API and unauthenticated (empty to disable, e.g. localhost:6060)")
+	flag.StringVar(&cfg.Log.Format, "log-format", cfg.Log.Format, "Log output format: console for humans or json for log pipelines")
+	flag.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "Minimum log level: debug, info, warn or error")
+	flag.StringVar(&cfg.LabelSelector, "label-selector", cfg.LabelSelector, "Only monitor pods matching this label selector (e.g. app=mysql)")
 	flag.Parse()
 
-	// 初始化zap日志，配置输出格式和代码行号
-	// 创建自定义编码器配置
-	encoderConfig := zap.NewProductionEncoderConfig()
-	encoderConfig.TimeKey = "time"
-	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	if *showVersion {
+		fmt.Printf("ioeye %s (commit %s, built %s, %s)\n", version, commit, buildDate, runtime.Version())
+		os.Exit(0)
+	}
 
-	// 创建Core
-	core := zapcore.NewCore(
-		zapcore.NewConsoleEncoder(encoderConfig),
-		zapcore.AddSync(os.Stdout),
-		zapcore.InfoLevel,
-	)
+	// 命令行中没有显式设置的参数从IOEYE_*环境变量读取，-version和-validate只能在命令行上指定
+	if err := config.ApplyEnv(flag.CommandLine, "version", "validate"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// 加载配置文件，命令行中显式设置的参数和环境变量优先于文件中的值
+	if *configPath != "" {
+		if err := config.LoadWithFlags(flag.CommandLine, cfg, *configPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	// 只做检查，不启动监控，用于CI或init容器中提前发现配置错误
+	if *validate {
+		os.Exit(runValidation(cfg, os.Stdout))
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
 
-	// 创建Logger，启用调用者信息（文件名和行号）
-	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(0))
+	// 失去租约时在其他defer执行完之后以非0状态退出，由Kubernetes重启为候选实例
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	// 初始化zap日志，配置输出格式和代码行号
+	logger, err := newLogger(cfg.Log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize logger: %v\n", err)
+		os.Exit(2)
+	}
 	defer logger.Sync() // 刷新缓冲区
 	
 	// 替换全局logger
 	zap.ReplaceGlobals(logger)
 
-	zap.L().Info("Starting IOEye - eBPF driven storage performance optimizer")
+	zap.L().Info("Starting IOEye - eBPF driven storage performance optimizer",
+		zap.String("version", version), zap.String("commit", commit), zap.String("build_date", buildDate))
 
 	// 创建上下文，支持优雅退出
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// 初始化Kubernetes客户端
-	zap.L().Info("Initializing Kubernetes client...")
-	k8sClient, err := k8s.NewClient(*kubeconfig)
-	if err != nil {
-		zap.L().Error("Failed to create Kubernetes client", zap.Error(err))
-		os.Exit(1)
+	// 初始化Kubernetes客户端，指定多个context时每个集群一个客户端，第一个为本集群
+	zap.L().Info("Initializing Kubernetes client...", zap.Bool("informer", cfg.UseInformer), zap.Strings("contexts", cfg.KubeContexts))
+	kubeContexts := cfg.KubeContexts
+	if len(kubeContexts) == 0 {
+		kubeContexts = []string{""}
 	}
+	k8sClients := make([]*k8s.Client, 0, len(kubeContexts))
+	for _, kubeContext := range kubeContexts {
+		var client *k8s.Client
+		if cfg.UseInformer {
+			client, err = k8s.NewClientWithInformerForContext(ctx, cfg.Kubeconfig, kubeContext)
+		} else {
+			client, err = k8s.NewClientForContext(cfg.Kubeconfig, kubeContext)
+		}
+		if err != nil {
+			zap.L().Error("Failed to create Kubernetes client", zap.String("context", kubeContext), zap.Error(err))
+			os.Exit(1)
+		}
+
+		// 记录挂载失败、节点磁盘压力等存储事件，在Pod详情中与性能变化对照
+		if err := client.WatchStorageEvents(ctx); err != nil {
+			zap.L().Warn("Failed to watch Kubernetes storage events", zap.String("context", kubeContext), zap.Error(err))
+		}
+		k8sClients = append(k8sClients, client)
+	}
+	k8sClient := k8sClients[0]
 
 	// 初始化eBPF子系统
-	zap.L().Info("Initializing eBPF monitor...")
-	bpfMonitor, err := ebpf.NewMonitor()
+	zap.L().Info("Initializing eBPF monitor...", zap.Bool("mock", cfg.BPF.MockData), zap.Uint("sample_rate", cfg.BPF.SampleRate))
+	bpfOpts := []ebpf.MonitorOption{
+		ebpf.WithObjectFile(cfg.BPF.Object),
+		ebpf.WithSampleRate(uint32(cfg.BPF.SampleRate)),
+		ebpf.WithFSFilter(ebpf.FSFilter(cfg.BPF.FSFilter)),
+		ebpf.WithProbeTargets(cfg.BPF.ProbeTargets()),
+	}
+	if cfg.BPF.MockData {
+		bpfOpts = append(bpfOpts, ebpf.WithMockData())
+	}
+	// 模拟数据直接以Pod名称为key，无需cgroup映射；纯cgroup v1节点上按进程ID关联Pod
+	var cgroupResolver *k8s.CgroupResolver
+	if !cfg.BPF.MockData {
+		cgroupResolver = k8s.NewCgroupResolver(cfg.CgroupRoot)
+		if cgroupResolver.PIDBased() {
+			zap.L().Warn("No cgroup v2 hierarchy found, attributing I/O to pods by process ID; per-pod tracing is unavailable", zap.String("cgroup_root", cfg.CgroupRoot))
+			bpfOpts = append(bpfOpts, ebpf.WithPIDKeys())
+		}
+	}
+	bpfMonitor, err := ebpf.NewMonitor(bpfOpts...)
 	if err != nil {
 		zap.L().Error("Failed to initialize eBPF monitor", zap.Error(err))
 		os.Exit(1)
 	}
 	defer bpfMonitor.Close()
 
-	// 启动eBPF监控
-	zap.L().Info("Starting eBPF monitor...")
-	if err := bpfMonitor.Start(); err != nil {
-		zap.L().Error("Failed to start eBPF monitor", zap.Error(err))
-		os.Exit(1)
-	}
-
 	// 初始化存储性能监控系统
 	zap.L().Info("Initializing storage monitor...")
-	storageMonitor := monitor.NewStorageMonitor(
-		bpfMonitor,
-		k8sClient,
-		monitor.WithNamespace(*namespace),
-		monitor.WithInterval(*interval),
-	)
+	monitorOpts := []monitor.StorageMonitorOption{
+		monitor.WithNamespaces(cfg.Namespaces...),
+		monitor.WithInterval(cfg.Interval),
+		monitor.WithLabelSelector(cfg.LabelSelector),
+		monitor.WithExcludeNamespaces(cfg.ExcludeNamespaces),
+		monitor.WithExcludePods(cfg.ExcludePods),
+		monitor.WithSaturationQueueLatency(uint64(cfg.Analyzer.QueueLatencyThreshold)),
+		monitor.WithStalenessWindow(cfg.StalenessWindow),
+		monitor.WithRateWindow(cfg.RateWindow),
+		monitor.WithSmoothingWindow(cfg.SmoothingWindow),
+		monitor.WithRemoteClusters(k8sClients[1:]...),
+	}
+	if cgroupResolver != nil {
+		monitorOpts = append(monitorOpts, monitor.WithCgroupResolver(cgroupResolver))
+	}
+	storageMonitor := monitor.NewStorageMonitor(bpfMonitor, k8sClient, monitorOpts...)
 
 	// 初始化存储性能分析器
 	zap.L().Info("Initializing storage analyzer...")
 	storageAnalyzer := analyzer.NewStorageAnalyzer(
-		analyzer.WithMaxHistoryPerPod(100),    // 保存100个历史数据点
-		analyzer.WithAnomalyThreshold(2.0),    // 标准差阈值
+		analyzer.WithMaxHistoryPerPod(cfg.Analyzer.MaxHistoryPerPod),
+		analyzer.WithAnomalyThreshold(cfg.Analyzer.AnomalyThreshold),
+		analyzer.WithSustainedAnomaly(cfg.Analyzer.SustainedAnomalySamples),
+		analyzer.WithEWMA(cfg.Analyzer.EWMAAlpha),
+		analyzer.WithReadLatencyThreshold(uint64(cfg.Analyzer.ReadLatencyThreshold)),
+		analyzer.WithWriteLatencyThreshold(uint64(cfg.Analyzer.WriteLatencyThreshold)),
+		analyzer.WithQueueLatencyThreshold(uint64(cfg.Analyzer.QueueLatencyThreshold)),
+		analyzer.WithContentionIOPSThreshold(cfg.Analyzer.ContentionIOPSThreshold),
+		analyzer.WithDeviceUtilizationThreshold(cfg.Analyzer.DeviceUtilizationThreshold),
+		analyzer.WithSmallIOSizeThreshold(cfg.Analyzer.SmallIOSizeThreshold),
+		analyzer.WithTopSlowWindow(cfg.Analyzer.TopSlowWindow),
+		analyzer.WithTopSlowStat(analyzer.RankStat(cfg.Analyzer.TopSlowStat)),
+		analyzer.WithPersistencePath(cfg.History.Path),
+		analyzer.WithHistoryRetention(cfg.History.Retention),
+		analyzer.WithAlertWebhook(cfg.Alert.Webhook),
+		analyzer.WithAlertCooldown(cfg.Alert.Cooldown),
+		analyzer.WithAlertHistorySize(cfg.Alert.HistorySize),
+		analyzer.WithAlertEscalation(cfg.Alert.EscalationWindow, cfg.Alert.CriticalAfter),
 	)
+	storageAnalyzer.RegisterAlertHandler(func(alert analyzer.Alert) {
+		fields := []zap.Field{
+			zap.String("reason", string(alert.Reason)),
+			zap.String("severity", string(alert.Severity)),
+			zap.Time("since", alert.Since),
+			zap.Int("occurrences", alert.Occurrences),
+			zap.String("pod", alert.PodName),
+			zap.String("namespace", alert.Namespace),
+			zap.String("bottleneck", string(alert.Bottleneck)),
+			zap.Uint64("read_latency_ns", alert.ReadLatency),
+			zap.Uint64("write_latency_ns", alert.WriteLatency),
+		}
+		if alert.AnomalyDetail != nil {
+			fields = append(fields,
+				zap.Float64("read_zscore", alert.AnomalyDetail.ReadZScore),
+				zap.Float64("write_zscore", alert.AnomalyDetail.WriteZScore),
+				zap.Float64("anomaly_threshold", alert.AnomalyDetail.Threshold))
+		}
+		// 日志级别与告警的严重程度对应
+		switch alert.Severity {
+		case analyzer.AlertSeverityCritical:
+			zap.L().Error("Storage alert", fields...)
+		case analyzer.AlertSeverityWarning:
+			zap.L().Warn("Storage alert", fields...)
+		default:
+			zap.L().Info("Storage alert", fields...)
+		}
+	})
+
+	// 配置了租约时通过本节点的Lease选举节点上唯一的活动实例，以advertise URL为持有者标识，备用实例据此转发读请求
+	var elector *k8s.LeaseElector
+	identity := cfg.HA.AdvertiseURL
+	if cfg.HA.LeaseName != "" {
+		if identity == "" {
+			identity, _ = os.Hostname()
+		}
+		elector = k8sClient.NewLeaseElector(cfg.HA.LeaseNamespace, cfg.HA.NodeLeaseName(), identity, cfg.HA.LeaseDuration)
+	}
 
-	// 启动API服务器
-	zap.L().Info("Starting API server", zap.String("address", *apiAddr))
-	apiServer := api.NewAPIServer(storageMonitor, storageAnalyzer, *apiAddr)
+	// 启动API服务器，备用实例和等待租约的实例也提供API
+	zap.L().Info("Starting API server", zap.String("address", cfg.API.Addr), zap.Bool("tls", cfg.API.TLS.Cert != ""), zap.Bool("auth", cfg.API.Token != ""))
+	apiOpts := []api.ServerOption{
+		api.WithTLSFiles(cfg.API.TLS.Cert, cfg.API.TLS.Key),
+		api.WithAuthToken(cfg.API.Token),
+		api.WithShutdownTimeout(cfg.API.ShutdownTimeout),
+		api.WithTimeouts(api.Timeouts(cfg.API.Timeouts)),
+		api.WithRateLimit(cfg.API.RateLimit.RPS, cfg.API.RateLimit.Burst),
+		api.WithBuildInfo(api.BuildInfo{Version: version, Commit: commit, BuildDate: buildDate}),
+		api.WithAllowedOrigins(cfg.API.AllowedOrigins),
+	}
+	if elector != nil {
+		apiOpts = append(apiOpts, api.WithHA(elector, identity))
+	}
+	apiServer := api.NewAPIServer(storageMonitor, storageAnalyzer, cfg.API.Addr, apiOpts...)
 	go func() {
 		if err := apiServer.Start(ctx); err != nil {
 			zap.L().Error("Failed to start API server", zap.Error(err))
@@ -102,6 +322,173 @@ func main() {
 		}
 	}()
 
+	// 启动pprof调试服务器，只在显式设置地址时启用
+	if cfg.Debug.PprofAddr != "" {
+		zap.L().Warn("Starting pprof debug server, it is not authenticated", zap.String("address", cfg.Debug.PprofAddr))
+		debugServer := api.NewDebugServer(cfg.Debug.PprofAddr)
+		go func() {
+			if err := debugServer.Start(ctx); err != nil {
+				zap.L().Error("Failed to shut down pprof debug server", zap.Error(err))
+			}
+		}()
+	}
+
+	// 收到SIGHUP时重新加载TLS证书，用于证书轮换
+	if cfg.API.TLS.Cert != "" {
+		hupCh := make(chan os.Signal, 1)
+		signal.Notify(hupCh, syscall.SIGHUP)
+		go func() {
+			for range hupCh {
+				if err := apiServer.ReloadCertificate(); err != nil {
+					zap.L().Error("Failed to reload TLS certificate", zap.Error(err))
+					continue
+				}
+				zap.L().Info("Reloaded TLS certificate")
+			}
+		}()
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	// 备用实例只跟踪租约的持有者；活动实例持有租约后才加载eBPF探针、采集和导出指标，
+	// 失去租约时退出，避免两个实例同时工作
+	electionCtx, stopElection := context.WithCancel(ctx)
+	electionDone := make(chan struct{})
+	var lostLease <-chan struct{}
+	if elector != nil {
+		go func() {
+			defer close(electionDone)
+			if cfg.HA.Role == config.RoleStandby {
+				elector.Observe(electionCtx)
+			} else {
+				elector.Run(electionCtx)
+			}
+		}()
+		lostLease = elector.Lost()
+
+		zap.L().Info("Waiting to become the active instance", zap.String("role", cfg.HA.Role),
+			zap.String("lease", cfg.HA.LeaseNamespace+"/"+cfg.HA.NodeLeaseName()), zap.String("identity", identity))
+		select {
+		case <-elector.Acquired():
+			zap.L().Info("Acquired the lease, becoming the active instance")
+		case <-sigCh:
+			zap.L().Info("Shutting down standby IOEye...")
+			apiServer.Stop()
+			stopElection()
+			<-electionDone
+			return
+		}
+	} else {
+		close(electionDone)
+	}
+
+	// 启动eBPF监控
+	zap.L().Info("Starting eBPF monitor...")
+	// 可选的跟踪程序附加失败只缺少部分指标，块I/O跟踪程序失败时按-require-ebpf决定是否降级运行
+	if err := bpfMonitor.Start(); err != nil {
+		var attachErr *ebpf.AttachError
+		switch {
+		case !errors.As(err, &attachErr) || (attachErr.RequiredFailed() && cfg.BPF.Require):
+			zap.L().Error("Failed to start eBPF monitor", zap.Error(err))
+			os.Exit(1)
+		case attachErr.RequiredFailed():
+			zap.L().Warn("Block I/O tracer unavailable, running in degraded mode without I/O metrics", zap.Error(err))
+		default:
+			zap.L().Warn("Some eBPF tracers failed to attach", zap.Error(err))
+		}
+	}
+
+	if err := storageAnalyzer.StartPersistence(ctx); err != nil {
+		zap.L().Error("Failed to start metrics history persistence", zap.Error(err))
+		os.Exit(1)
+	}
+
+	// 启动gRPC服务器，与HTTP服务器共用监控器和分析器
+	var grpcServer *grpcapi.Server
+	if cfg.API.GRPCAddr != "" {
+		zap.L().Info("Starting gRPC server", zap.String("address", cfg.API.GRPCAddr), zap.Bool("auth", cfg.API.Token != ""))
+		grpcServer = grpcapi.NewServer(storageMonitor, storageAnalyzer, cfg.API.GRPCAddr,
+			grpcapi.WithInterval(time.Duration(cfg.Interval)*time.Second),
+			grpcapi.WithAuthToken(cfg.API.Token),
+		)
+		go func() {
+			if err := grpcServer.Start(ctx); err != nil {
+				zap.L().Error("Failed to start gRPC server", zap.Error(err))
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// 启动OTLP指标导出
+	var otelExporter *otelexport.Exporter
+	if cfg.OTLP.Endpoint != "" {
+		zap.L().Info("Starting OTLP metrics exporter", zap.String("endpoint", cfg.OTLP.Endpoint))
+		exporterOpts := []otelexport.Option{otelexport.WithInterval(time.Duration(cfg.Interval) * time.Second)}
+		if cfg.OTLP.Insecure {
+			exporterOpts = append(exporterOpts, otelexport.WithInsecure())
+		}
+		otelExporter, err = otelexport.NewExporter(ctx, storageMonitor, cfg.OTLP.Endpoint, exporterOpts...)
+		if err != nil {
+			zap.L().Error("Failed to create OTLP metrics exporter", zap.Error(err))
+			os.Exit(1)
+		}
+		go func() {
+			if err := otelExporter.Start(ctx); err != nil {
+				zap.L().Error("Failed to shut down OTLP metrics exporter", zap.Error(err))
+			}
+		}()
+	}
+
+	// 启动Kafka指标导出
+	var kafkaExporter *kafkaexport.Exporter
+	if len(cfg.Kafka.Brokers) > 0 {
+		zap.L().Info("Starting Kafka metrics exporter", zap.Strings("brokers", cfg.Kafka.Brokers), zap.String("topic", cfg.Kafka.Topic))
+		kafkaExporter, err = kafkaexport.NewExporter(storageMonitor, cfg.Kafka.Brokers,
+			kafkaexport.WithTopic(cfg.Kafka.Topic),
+			kafkaexport.WithInterval(time.Duration(cfg.Interval)*time.Second),
+			kafkaexport.WithQueueSize(cfg.Kafka.QueueSize),
+			kafkaexport.WithErrorHandler(func(err error) {
+				zap.L().Warn("Kafka metrics export failed", zap.Error(err))
+			}),
+		)
+		if err != nil {
+			zap.L().Error("Failed to create Kafka metrics exporter", zap.Error(err))
+			os.Exit(1)
+		}
+		go func() {
+			if err := kafkaExporter.Start(ctx); err != nil {
+				zap.L().Error("Failed to shut down Kafka metrics exporter", zap.Error(err))
+			}
+		}()
+	}
+
+	// 启动StatsD指标导出
+	var statsdExporter *statsdexport.Exporter
+	if cfg.StatsD.Addr != "" {
+		zap.L().Info("Starting StatsD metrics exporter", zap.String("addr", cfg.StatsD.Addr), zap.String("format", cfg.StatsD.Format))
+		statsdExporter, err = statsdexport.NewExporter(storageMonitor, cfg.StatsD.Addr,
+			statsdexport.WithFormat(cfg.StatsD.Format),
+			statsdexport.WithPrefix(cfg.StatsD.Prefix),
+			statsdexport.WithInterval(time.Duration(cfg.Interval)*time.Second),
+			statsdexport.WithErrorHandler(func(err error) {
+				zap.L().Warn("StatsD metrics export failed", zap.Error(err))
+			}),
+		)
+		if err != nil {
+			zap.L().Error("Failed to create StatsD metrics exporter", zap.Error(err))
+			os.Exit(1)
+		}
+		go func() {
+			if err := statsdExporter.Start(ctx); err != nil {
+				zap.L().Error("Failed to shut down StatsD metrics exporter", zap.Error(err))
+			}
+		}()
+	}
+
+	// 在启动监控之前订阅，分析器不会错过第一次采集
+	snapshots := storageMonitor.Subscribe()
+
 	// 启动存储监控
 	zap.L().Info("Starting storage monitor...")
 	if err := storageMonitor.Start(ctx); err != nil {
@@ -109,31 +496,26 @@ func main() {
 		os.Exit(1)
 	}
 
-	// 启动数据分析goroutine
+	// 启动数据分析goroutine，每次采集最多分析一次，分析跟不上时订阅缓冲已满期间的快照被跳过，监控停止后通道关闭、goroutine退出
 	go func() {
-		ticker := time.NewTicker(time.Duration(*interval) * time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				// 获取所有Pod的最新指标
-				allMetrics := storageMonitor.GetAllMetrics()
-				
-				// 更新存储分析器
-				storageAnalyzer.AddMetrics(allMetrics)
-				
-				// 获取分析结果示例
-				topSlowPods := storageAnalyzer.GetTopNSlowPods(5)
-				if len(topSlowPods) > 0 {
-					zap.L().Info("Top slow pod detected",
-						zap.String("pod", topSlowPods[0].PodName),
-						zap.Uint64("read_latency_ns", topSlowPods[0].ReadLatency),
-						zap.Uint64("write_latency_ns", topSlowPods[0].WriteLatency))
-				}
-				
-			case <-ctx.Done():
-				return
+		for allMetrics := range snapshots {
+			// 更新存储分析器
+			storageAnalyzer.AddMetrics(allMetrics)
+
+			// 更新块设备饱和状态
+			if devices, err := storageMonitor.GetDeviceMetrics(); err != nil {
+				zap.L().Error("Failed to get device metrics", zap.Error(err))
+			} else {
+				storageAnalyzer.AddDeviceMetrics(devices)
+			}
+			
+			// 获取分析结果示例
+			topSlowPods := storageAnalyzer.GetTopNSlowPods(5)
+			if len(topSlowPods) > 0 {
+				zap.L().Info("Top slow pod detected",
+					zap.String("pod", topSlowPods[0].PodName),
+					zap.Uint64("read_latency_ns", topSlowPods[0].ReadLatency),
+					zap.Uint64("write_latency_ns", topSlowPods[0].WriteLatency))
 			}
 		}
 	}()
@@ -141,18 +523,142 @@ func main() {
 	// 打印可用的API端点
 	zap.L().Info("Available API endpoints")
 	zap.L().Info("- GET /api/v1/metrics            - Get all pod metrics")
+	zap.L().Info("- GET /api/v1/metrics/namespace/{ns} - Get metrics of pods in a namespace")
 	zap.L().Info("- GET /api/v1/metrics/pod/{name} - Get specific pod metrics")
+	zap.L().Info("- GET /api/v1/metrics/pod/{name}/histogram - Get pod latency histogram")
+	zap.L().Info("- GET /api/v1/metrics/pod/{name}/history   - Get pod metrics history")
+	zap.L().Info("- DELETE /api/v1/metrics/pod/{name}/history - Clear pod history and anomaly baseline")
+	zap.L().Info("- GET /api/v1/metrics/pod/{name}/containers - Get per-container metrics of a pod")
+	zap.L().Info("- GET /api/v1/metrics/pod/{name}/rates - Get pod IOPS and throughput from raw sample deltas")
+	zap.L().Info("- POST /api/v1/metrics/pods      - Get metrics of the pods named in a JSON array")
 	zap.L().Info("- GET /api/v1/metrics/topslow    - Get top slow pods")
+	zap.L().Info("- GET /api/v1/metrics/workload   - Get metrics aggregated by workload")
+	zap.L().Info("- GET /api/v1/metrics/storageclass - Get metrics aggregated by storage class")
+	zap.L().Info("- GET /api/v1/metrics/volume     - Get metrics aggregated by PVC")
+	zap.L().Info("- GET /api/v1/metrics/node       - Get metrics aggregated by node")
+	zap.L().Info("- GET /api/v1/metrics/device     - Get per block device metrics")
+	zap.L().Info("- GET /api/v1/metrics/anomalies  - Get pods currently flagged as anomalous")
+	zap.L().Info("- GET /api/v1/metrics/bottlenecks - Get pods whose bottleneck matches the given types")
+	zap.L().Info("- GET /api/v1/alerts             - Get recent anomaly and bottleneck alerts")
+	zap.L().Info("- GET /api/v1/metrics/export.csv - Export pod metrics as CSV")
+	zap.L().Info("- GET /api/v1/metrics/dump       - Stream the retained metrics history as JSON Lines")
+	zap.L().Info("- GET /api/v1/metrics/stream     - Stream pod metrics as Server-Sent Events")
+	zap.L().Info("- GET /api/v1/tracing            - Get per-pod tracing status")
+	zap.L().Info("- DELETE /api/v1/tracing         - Trace all pods")
+	zap.L().Info("- POST/DELETE /api/v1/tracing/pod/{name} - Start/stop tracing a pod")
+	zap.L().Info("- GET/PUT /api/v1/config/interval - Get/change the collection interval")
+	zap.L().Info("- GET/PUT /api/v1/config/thresholds - Get/change the bottleneck and anomaly thresholds")
+	zap.L().Info("- POST /api/v1/control/pause     - Pause collection")
+	zap.L().Info("- POST /api/v1/control/resume    - Resume collection")
+	zap.L().Info("- GET /api/v1/version            - Build version information")
+	zap.L().Info("- GET /api/v1/debug/probes       - Get eBPF kprobe and tracepoint attach status")
 	zap.L().Info("- GET /api/v1/health             - Health check")
+	zap.L().Info("- GET /api/v1/ready              - Readiness check")
+	zap.L().Info("- GET /api/v1/openapi.json       - OpenAPI document")
+	zap.L().Info("- GET /swagger                   - Swagger UI")
+	zap.L().Info("- GET /metrics                   - Prometheus metrics")
+	if cfg.API.GRPCAddr != "" {
+		zap.L().Info("- gRPC ioeye.v1.MetricsService   - GetAllMetrics, GetPodMetrics, GetTopSlowPods, StreamMetrics", zap.String("address", cfg.API.GRPCAddr))
+	}
 
-	// 等待信号退出
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
+
+	// 等待信号退出，或在失去租约时退出
+	select {
+	case <-sigCh:
+	case <-lostLease:
+		zap.L().Error("Lost the lease to another instance, shutting down")
+		exitCode = 1
+	}
 
 	zap.L().Info("Shutting down IOEye...")
 	
 	// 优雅关闭
 	apiServer.Stop()
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
 	storageMonitor.Stop()
-} 
\ No newline at end of file
+	storageAnalyzer.Close()
+	if otelExporter != nil {
+		// 退出前推送最后一批指标
+		if err := otelExporter.Stop(); err != nil {
+			zap.L().Error("Failed to shut down OTLP metrics exporter", zap.Error(err))
+		}
+	}
+	if kafkaExporter != nil {
+		// 退出前发送队列中剩余的消息
+		if err := kafkaExporter.Stop(); err != nil {
+			zap.L().Error("Failed to shut down Kafka metrics exporter", zap.Error(err))
+		}
+	}
+	if statsdExporter != nil {
+		if err := statsdExporter.Stop(); err != nil {
+			zap.L().Error("Failed to shut down StatsD metrics exporter", zap.Error(err))
+		}
+	}
+	// 其他组件都停止后才释放租约，接管的实例不会与本实例同时工作
+	stopElection()
+	<-electionDone
+} 
+
+// stringSetFlag 可重复指定或以逗号分隔的字符串集合参数，重复的值只保留一个
+// 命令行中的值整体替换默认值或配置文件中的值
+type stringSetFlag struct {
+	target *[]string
+	values []string
+}
+
+// newStringSetFlag 创建绑定到target的集合参数
+func newStringSetFlag(target *[]string) *stringSetFlag {
+	return &stringSetFlag{target: target}
+}
+
+// String 返回逗号分隔的当前值
+func (f *stringSetFlag) String() string {
+	if f == nil || f.target == nil {
+		return ""
+	}
+	return strings.Join(*f.target, ",")
+}
+
+// Set 追加逗号分隔的值
+func (f *stringSetFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" || slices.Contains(f.values, v) {
+			continue
+		}
+		f.values = append(f.values, v)
+	}
+	*f.target = append([]string(nil), f.values...)
+	return nil
+}
+
+// newLogger 按配置的输出格式和级别创建zap日志，两种格式都使用ISO8601时间并包含调用者的文件名和行号
+func newLogger(cfg config.LogConfig) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	// 创建自定义编码器配置
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "time"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+
+	var encoder zapcore.Encoder
+	switch cfg.Format {
+	case config.LogFormatJSON:
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case config.LogFormatConsole:
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+
+	core := zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), level)
+
+	// 启用调用者信息（文件名和行号）
+	return zap.New(core, zap.AddCaller()), nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/config"
+	"github.com/lizhongxuan/ioeye/pkg/ebpf"
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// validateK8sTimeout -validate模式下连接每个集群的超时时间
+const validateK8sTimeout = 10 * time.Second
+
+// 检查结果的状态
+const (
+	checkOK   = "OK"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+	checkSkip = "SKIP"
+)
+
+// validationReport 记录-validate模式下各项检查的结果
+type validationReport struct {
+	out    io.Writer
+	failed bool
+}
+
+// add 输出一项检查的结果，FAIL时整个检查失败
+func (r *validationReport) add(status, check, detail string) {
+	if status == checkFail {
+		r.failed = true
+	}
+	fmt.Fprintf(r.out, "[%s] %s: %s\n", status, check, detail)
+}
+
+// runValidation 检查配置、各集群的Kubernetes连接和eBPF跟踪程序能否加载，不启动监控
+// 任意一项失败时返回1，否则返回0，用作进程的退出码
+func runValidation(cfg *config.Config, out io.Writer) int {
+	report := &validationReport{out: out}
+
+	if err := cfg.Validate(); err != nil {
+		report.add(checkFail, "config", err.Error())
+	} else {
+		report.add(checkOK, "config", "valid")
+	}
+
+	validateKubernetes(cfg, report)
+	validateEBPF(cfg, report)
+
+	if report.failed {
+		fmt.Fprintln(out, "validation failed")
+		return 1
+	}
+	fmt.Fprintln(out, "validation passed")
+	return 0
+}
+
+// validateKubernetes 为每个kubeconfig context创建客户端，并列出最多一个Pod确认API可达且有权限
+func validateKubernetes(cfg *config.Config, report *validationReport) {
+	kubeContexts := cfg.KubeContexts
+	if len(kubeContexts) == 0 {
+		kubeContexts = []string{""}
+	}
+
+	for _, kubeContext := range kubeContexts {
+		check := "kubernetes"
+		if kubeContext != "" {
+			check = fmt.Sprintf("kubernetes (context %s)", kubeContext)
+		}
+
+		client, err := k8s.NewClientForContext(cfg.Kubeconfig, kubeContext)
+		if err != nil {
+			report.add(checkFail, check, err.Error())
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), validateK8sTimeout)
+		// ListPods只返回运行中的Pod，不能用Limit只取一个，否则第一个Pod已结束时会误报没有Pod
+		pods, err := client.ListPods(ctx, cfg.Namespaces, metav1.ListOptions{LabelSelector: cfg.LabelSelector})
+		cancel()
+		if err != nil {
+			report.add(checkFail, check, err.Error())
+			continue
+		}
+		if len(pods) == 0 {
+			report.add(checkWarn, check, "connected, but no running pods match the configured namespaces and label selector")
+			continue
+		}
+		report.add(checkOK, check, "connected, listing pods is permitted")
+	}
+}
+
+// validateEBPF 加载并附加所有跟踪程序后立即卸载，块I/O跟踪程序失败且要求eBPF时检查失败
+func validateEBPF(cfg *config.Config, report *validationReport) {
+	if cfg.BPF.MockData {
+		report.add(checkSkip, "ebpf", "mock data enabled, eBPF programs are not loaded")
+		return
+	}
+
+	bpfMonitor, err := ebpf.NewMonitor(
+		ebpf.WithObjectFile(cfg.BPF.Object),
+		ebpf.WithSampleRate(uint32(cfg.BPF.SampleRate)),
+		ebpf.WithFSFilter(ebpf.FSFilter(cfg.BPF.FSFilter)),
+		ebpf.WithProbeTargets(cfg.BPF.ProbeTargets()),
+	)
+	if err != nil {
+		report.add(checkFail, "ebpf", err.Error())
+		return
+	}
+	defer bpfMonitor.Close()
+
+	err = bpfMonitor.Start()
+	var attachErr *ebpf.AttachError
+	switch {
+	case err == nil:
+		report.add(checkOK, "ebpf", "all tracers attached")
+	case !errors.As(err, &attachErr):
+		report.add(checkFail, "ebpf", err.Error())
+	case attachErr.RequiredFailed() && cfg.BPF.Require:
+		report.add(checkFail, "ebpf", err.Error())
+	case attachErr.RequiredFailed():
+		report.add(checkWarn, "ebpf", fmt.Sprintf("block I/O tracer unavailable, would run without I/O metrics: %v", err))
+	default:
+		report.add(checkWarn, "ebpf", fmt.Sprintf("some optional tracers failed to attach: %v", err))
+	}
+}
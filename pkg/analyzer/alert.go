@@ -0,0 +1,190 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// AlertReason 表示触发告警的原因
+type AlertReason string
+
+const (
+	AlertReasonAnomaly    AlertReason = "anomaly"
+	AlertReasonBottleneck AlertReason = "bottleneck"
+)
+
+// Alert 是Pod存储性能告警，同时作为webhook的JSON负载
+type Alert struct {
+	Reason         AlertReason    `json:"reason"`
+	PodName        string         `json:"pod_name"`
+	PodUID         string         `json:"pod_uid,omitempty"`
+	Namespace      string         `json:"namespace"`
+	Bottleneck     BottleneckType `json:"bottleneck"`
+	Anomaly        bool           `json:"anomaly"`
+	ReadLatency    uint64         `json:"read_latency_ns"`
+	WriteLatency   uint64         `json:"write_latency_ns"`
+	QueueLatency   uint64         `json:"queue_latency_ns"`
+	DiskLatency    uint64         `json:"disk_latency_ns"`
+	NetworkLatency uint64         `json:"network_latency_ns"`
+	AnomalyDetail  *AnomalyDetail `json:"anomaly_detail,omitempty"` // 异常检测的基线和z分数，样本不足时为空
+	Severity       AlertSeverity  `json:"severity"`
+	Since          time.Time      `json:"since"`       // 本次连续处于异常或瓶颈状态的开始时间
+	Occurrences    int            `json:"occurrences"` // 升级窗口内进入异常或瓶颈状态的次数，包括本次
+	Timestamp      time.Time      `json:"timestamp"`
+}
+
+// alerter 在Pod进入异常或瓶颈状态时分发告警
+type alerter struct {
+	mu         sync.RWMutex
+	handlers   []func(Alert)
+	webhookURL string
+	cooldown   time.Duration        // 同一Pod两次告警的最小间隔
+	lastAlert  map[string]time.Time // 各Pod上次告警的时间，仅在持有StorageAnalyzer.mu时访问
+	client     *http.Client
+
+	escalationWindow time.Duration               // 统计反复进入异常或瓶颈状态的窗口
+	criticalAfter    time.Duration               // 连续处于异常或瓶颈状态超过该时长时升级为critical
+	escalations      map[string]*alertEscalation // 各Pod的告警升级状态，仅在持有StorageAnalyzer.mu时访问
+}
+
+// WithAlertWebhook 设置告警webhook地址，告警以JSON POST到该地址
+func WithAlertWebhook(url string) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if url != "" {
+			sa.alerter.webhookURL = url
+		}
+	}
+}
+
+// WithAlertCooldown 设置同一Pod重复告警的冷却时间，冷却期内只发送严重程度更高的告警
+func WithAlertCooldown(cooldown time.Duration) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if cooldown > 0 {
+			sa.alerter.cooldown = cooldown
+		}
+	}
+}
+
+// RegisterAlertHandler 注册进程内的告警处理函数
+func (sa *StorageAnalyzer) RegisterAlertHandler(handler func(Alert)) {
+	sa.alerter.mu.Lock()
+	defer sa.alerter.mu.Unlock()
+
+	sa.alerter.handlers = append(sa.alerter.handlers, handler)
+}
+
+// checkAlert 判断Pod是否刚进入异常或瓶颈状态、或连续处于该状态超过升级时长，返回需要发送的告警，调用方需持有写锁
+func (sa *StorageAnalyzer) checkAlert(metrics *monitor.PodStorageMetrics, prevBottleneck BottleneckType, prevAnomaly bool, now time.Time) (Alert, bool) {
+	key := metrics.Key()
+	bottleneck := sa.podBottlenecks[key].Type
+	anomaly := sa.anomalyDetected[key]
+
+	esc := sa.alerter.escalation(key)
+	sa.alerter.track(esc, anomaly || bottleneck != BottleneckTypeNone, now)
+
+	var reason AlertReason
+	onset := true
+	switch {
+	case anomaly && !prevAnomaly:
+		reason = AlertReasonAnomaly
+	case bottleneck != BottleneckTypeNone && bottleneck != prevBottleneck:
+		reason = AlertReasonBottleneck
+	case !esc.critical && sa.alerter.severity(esc, now) == AlertSeverityCritical:
+		// 状态没有变化，但已持续超过升级时长
+		onset = false
+		reason = AlertReasonBottleneck
+		if anomaly {
+			reason = AlertReasonAnomaly
+		}
+	default:
+		return Alert{}, false
+	}
+	if onset {
+		esc.onsets = append(esc.onsets, now)
+	}
+
+	severity := sa.alerter.severity(esc, now)
+	if severity == AlertSeverityCritical {
+		esc.critical = true
+	}
+
+	// 冷却期内不重复告警，除非严重程度比上次更高
+	if last, ok := sa.alerter.lastAlert[key]; ok && now.Sub(last) < sa.alerter.cooldown && severity.rank() <= esc.lastSeverity.rank() {
+		return Alert{}, false
+	}
+	sa.alerter.lastAlert[key] = now
+	esc.lastSeverity = severity
+
+	var detail *AnomalyDetail
+	if d, ok := sa.anomalyDetails[key]; ok {
+		detail = &d
+	}
+
+	return Alert{
+		Reason:         reason,
+		PodName:        metrics.PodName,
+		PodUID:         metrics.PodUID,
+		Namespace:      metrics.Namespace,
+		Bottleneck:     bottleneck,
+		Anomaly:        anomaly,
+		ReadLatency:    metrics.ReadLatency,
+		WriteLatency:   metrics.WriteLatency,
+		QueueLatency:   metrics.QueueLatency,
+		DiskLatency:    metrics.DiskLatency,
+		NetworkLatency: metrics.NetworkLatency,
+		AnomalyDetail:  detail,
+		Severity:       severity,
+		Since:          esc.since,
+		Occurrences:    len(esc.onsets),
+		Timestamp:      now,
+	}, true
+}
+
+// dispatchAlerts 将告警交给已注册的处理函数，并异步发送webhook
+func (sa *StorageAnalyzer) dispatchAlerts(alerts []Alert) {
+	if len(alerts) == 0 {
+		return
+	}
+
+	sa.alerter.mu.RLock()
+	handlers := sa.alerter.handlers
+	sa.alerter.mu.RUnlock()
+
+	for _, alert := range alerts {
+		for _, handler := range handlers {
+			handler(alert)
+		}
+		if sa.alerter.webhookURL != "" {
+			go func(alert Alert) {
+				if err := sa.alerter.postWebhook(alert); err != nil {
+					fmt.Printf("Error sending alert webhook: %v\n", err)
+				}
+			}(alert)
+		}
+	}
+}
+
+// postWebhook 将告警以JSON POST到webhook地址
+func (a *alerter) postWebhook(alert Alert) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %v", err)
+	}
+
+	resp, err := a.client.Post(a.webhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to post alert for pod %s: %v", alert.PodName, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned status %d for pod %s", resp.StatusCode, alert.PodName)
+	}
+	return nil
+}
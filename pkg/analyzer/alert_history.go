@@ -0,0 +1,142 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// DefaultAlertHistorySize 默认保留的告警历史条数
+const DefaultAlertHistorySize = 500
+
+// AlertEvent 告警历史中的一条记录，从Pod进入异常或瓶颈状态开始，到恢复时结束
+// 与Alert不同，告警历史记录每一次状态变化，不受告警冷却时间限制
+type AlertEvent struct {
+	Cluster    string
+	PodName    string
+	PodUID     string // 模拟数据等无法获取时为空
+	Namespace  string
+	Reason     AlertReason
+	Bottleneck BottleneckType // Reason为bottleneck时的瓶颈类型，其余为空
+	StartTime  time.Time
+	ClearTime  time.Time // 恢复的时间，仍在持续时为零值
+	Active     bool
+}
+
+// alertHistory 按开始时间顺序保存最近的告警记录，达到容量上限后覆盖最旧的记录
+type alertHistory struct {
+	events []*AlertEvent
+	start  int // 最旧记录在events中的位置
+	size   int
+	active map[string]*AlertEvent // 仍在持续的记录，key为Pod的key和原因
+}
+
+// WithAlertHistorySize 设置保留的告警历史条数
+func WithAlertHistorySize(size int) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if size > 0 {
+			sa.alertHistory = newAlertHistory(size)
+		}
+	}
+}
+
+// newAlertHistory 创建最多保存size条记录的告警历史
+func newAlertHistory(size int) alertHistory {
+	return alertHistory{
+		events: make([]*AlertEvent, size),
+		active: make(map[string]*AlertEvent),
+	}
+}
+
+// GetRecentAlerts 返回最近的n条告警记录，按开始时间从新到旧排序，n不大于0时返回所有保留的记录
+func (sa *StorageAnalyzer) GetRecentAlerts(n int) []AlertEvent {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	h := &sa.alertHistory
+	if n <= 0 || n > h.size {
+		n = h.size
+	}
+	result := make([]AlertEvent, 0, n)
+	for i := h.size - 1; i >= h.size-n; i-- {
+		result = append(result, *h.events[(h.start+i)%len(h.events)])
+	}
+	return result
+}
+
+// recordAlertTransitions 根据Pod的异常和瓶颈状态变化开始或结束告警记录，调用方需持有写锁
+// 瓶颈类型变化时结束原类型的记录并开始新类型的记录
+func (sa *StorageAnalyzer) recordAlertTransitions(metrics *monitor.PodStorageMetrics, prevBottleneck BottleneckType, prevAnomaly bool, now time.Time) {
+	key := metrics.Key()
+	anomaly := sa.anomalyDetected[key]
+	switch {
+	case anomaly && !prevAnomaly:
+		sa.alertHistory.open(metrics, AlertReasonAnomaly, "", now)
+	case !anomaly && prevAnomaly:
+		sa.alertHistory.clear(key, AlertReasonAnomaly, now)
+	}
+
+	bottleneck := sa.podBottlenecks[key].Type
+	if bottleneck == prevBottleneck {
+		return
+	}
+	if prevBottleneck != BottleneckTypeNone {
+		sa.alertHistory.clear(key, AlertReasonBottleneck, now)
+	}
+	if bottleneck != BottleneckTypeNone {
+		sa.alertHistory.open(metrics, AlertReasonBottleneck, bottleneck, now)
+	}
+}
+
+// open 追加一条仍在持续的记录，已满时覆盖最旧的记录
+func (h *alertHistory) open(metrics *monitor.PodStorageMetrics, reason AlertReason, bottleneck BottleneckType, now time.Time) {
+	event := &AlertEvent{
+		Cluster:    metrics.Cluster,
+		PodName:    metrics.PodName,
+		PodUID:     metrics.PodUID,
+		Namespace:  metrics.Namespace,
+		Reason:     reason,
+		Bottleneck: bottleneck,
+		StartTime:  now,
+		Active:     true,
+	}
+
+	if h.size == len(h.events) {
+		// 被覆盖的记录即使仍在持续也不再保留
+		oldest := h.events[h.start]
+		if key := alertKey(oldest.podKey(), oldest.Reason); h.active[key] == oldest {
+			delete(h.active, key)
+		}
+		h.events[h.start] = event
+		h.start = (h.start + 1) % len(h.events)
+	} else {
+		h.events[(h.start+h.size)%len(h.events)] = event
+		h.size++
+	}
+	h.active[alertKey(metrics.Key(), reason)] = event
+}
+
+// clear 结束Pod该原因仍在持续的记录，没有时忽略
+func (h *alertHistory) clear(podKey string, reason AlertReason, now time.Time) {
+	key := alertKey(podKey, reason)
+	event, ok := h.active[key]
+	if !ok {
+		return
+	}
+	event.ClearTime = now
+	event.Active = false
+	delete(h.active, key)
+}
+
+// alertKey 返回持续中记录的key
+func alertKey(podKey string, reason AlertReason) string {
+	return podKey + "/" + string(reason)
+}
+
+// podKey 返回记录所属Pod的key，与PodStorageMetrics.Key一致
+func (e *AlertEvent) podKey() string {
+	if e.PodUID != "" {
+		return e.PodUID
+	}
+	return e.PodName
+}
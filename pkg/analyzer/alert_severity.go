@@ -0,0 +1,95 @@
+package analyzer
+
+import "time"
+
+// AlertSeverity 表示告警的严重程度，按Pod持续或反复处于异常、瓶颈状态的情况升级
+type AlertSeverity string
+
+const (
+	// AlertSeverityInfo 窗口内第一次进入异常或瓶颈状态
+	AlertSeverityInfo AlertSeverity = "info"
+	// AlertSeverityWarning 窗口内反复进入异常或瓶颈状态
+	AlertSeverityWarning AlertSeverity = "warning"
+	// AlertSeverityCritical 连续处于异常或瓶颈状态超过升级时长
+	AlertSeverityCritical AlertSeverity = "critical"
+)
+
+// 告警升级的默认参数
+const (
+	DefaultAlertEscalationWindow = 10 * time.Minute
+	DefaultAlertCriticalAfter    = 5 * time.Minute
+)
+
+// rank 返回严重程度的顺序，未发送过告警时为0
+func (s AlertSeverity) rank() int {
+	switch s {
+	case AlertSeverityInfo:
+		return 1
+	case AlertSeverityWarning:
+		return 2
+	case AlertSeverityCritical:
+		return 3
+	}
+	return 0
+}
+
+// alertEscalation 单个Pod的告警升级状态，仅在持有StorageAnalyzer.mu时访问
+type alertEscalation struct {
+	since        time.Time     // 本次连续处于异常或瓶颈状态的开始时间，未处于该状态时为零值
+	onsets       []time.Time   // 升级窗口内触发告警的状态变化时间，包括冷却期内未发送的
+	critical     bool          // 本次持续期间是否已升级为critical
+	lastSeverity AlertSeverity // 上次发送的告警的严重程度
+}
+
+// WithAlertEscalation 设置告警升级的参数：window内第二次及以后进入异常或瓶颈状态时告警升级为warning，
+// 连续处于异常或瓶颈状态超过criticalAfter时发送一次critical告警；不大于0的参数不生效
+func WithAlertEscalation(window, criticalAfter time.Duration) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if window > 0 {
+			sa.alerter.escalationWindow = window
+		}
+		if criticalAfter > 0 {
+			sa.alerter.criticalAfter = criticalAfter
+		}
+	}
+}
+
+// escalation 返回Pod的告警升级状态，没有时创建，调用方需持有写锁
+func (a *alerter) escalation(key string) *alertEscalation {
+	esc, ok := a.escalations[key]
+	if !ok {
+		esc = &alertEscalation{}
+		a.escalations[key] = esc
+	}
+	return esc
+}
+
+// track 根据Pod当前是否处于异常或瓶颈状态更新连续时长，并丢弃升级窗口之外的记录
+func (a *alerter) track(esc *alertEscalation, active bool, now time.Time) {
+	switch {
+	case !active:
+		esc.since = time.Time{}
+		esc.critical = false
+	case esc.since.IsZero():
+		esc.since = now
+	}
+
+	cutoff := now.Add(-a.escalationWindow)
+	i := 0
+	for i < len(esc.onsets) && esc.onsets[i].Before(cutoff) {
+		i++
+	}
+	esc.onsets = esc.onsets[i:]
+}
+
+// severity 计算当前告警的严重程度：持续超过criticalAfter为critical，窗口内多次触发为warning，否则为info
+func (a *alerter) severity(esc *alertEscalation, now time.Time) AlertSeverity {
+	switch {
+	case !esc.since.IsZero() && now.Sub(esc.since) >= a.criticalAfter:
+		return AlertSeverityCritical
+	case len(esc.onsets) > 1:
+		return AlertSeverityWarning
+	default:
+		return AlertSeverityInfo
+	}
+}
@@ -0,0 +1,178 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+)
+
+// AnomalyStreak Pod最近连续超过或低于异常阈值的样本数，两者中至多一个非零
+type AnomalyStreak struct {
+	Above int // 连续超过阈值的样本数
+	Below int // 连续低于阈值的样本数
+}
+
+// AnomalyDetail Pod最新一次异常检测使用的读写延迟基线和z分数，用于解释Pod为何被判定为异常
+// 均值和标准差的单位为纳秒；启用EWMA时为指数加权的均值和标准差
+type AnomalyDetail struct {
+	ReadMean    float64 `json:"read_mean_ns"`
+	ReadStdDev  float64 `json:"read_stddev_ns"`
+	ReadZScore  float64 `json:"read_zscore"`
+	WriteMean   float64 `json:"write_mean_ns"`
+	WriteStdDev float64 `json:"write_stddev_ns"`
+	WriteZScore float64 `json:"write_zscore"`
+	Threshold   float64 `json:"threshold"` // 任一z分数超过该值即视为超过阈值
+	Samples     int     `json:"samples"`   // 建立基线使用的样本数
+}
+
+// Severity 返回读写z分数中较高者，作为异常的严重程度
+func (d AnomalyDetail) Severity() float64 {
+	return math.Max(d.ReadZScore, d.WriteZScore)
+}
+
+// WithSustainedAnomaly 要求连续n个样本超过异常阈值才判定为异常，判定后需连续n个样本恢复才解除
+// 用于过滤偶发的延迟尖峰，默认为1，即每个样本独立判定
+func WithSustainedAnomaly(n int) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if n > 0 {
+			sa.sustainedAnomalySamples = n
+		}
+	}
+}
+
+// GetAnomalyDetail 获取Pod最新一次异常检测的基线和z分数，读取的是检测时缓存的结果
+// Pod不存在或历史样本不足以建立基线时返回错误
+func (sa *StorageAnalyzer) GetAnomalyDetail(podName string) (AnomalyDetail, error) {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	detail, exists := sa.anomalyDetails[podName]
+	if !exists {
+		return AnomalyDetail{}, fmt.Errorf("no anomaly baseline for pod %s", podName)
+	}
+	return detail, nil
+}
+
+// GetAnomalyStreak 获取Pod当前连续超过或低于异常阈值的样本数
+func (sa *StorageAnalyzer) GetAnomalyStreak(podName string) (AnomalyStreak, bool) {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	streak, exists := sa.anomalyStreaks[podName]
+	return streak, exists
+}
+
+// updateAnomaly 根据最新样本是否超过阈值更新连续计数，返回Pod是否处于异常状态
+// 调用方需持有sa.mu写锁
+func (sa *StorageAnalyzer) updateAnomaly(podName string, exceeded bool) bool {
+	streak := sa.anomalyStreaks[podName]
+	if exceeded {
+		streak.Above++
+		streak.Below = 0
+	} else {
+		streak.Below++
+		streak.Above = 0
+	}
+	sa.anomalyStreaks[podName] = streak
+
+	// 滞回：进入和解除异常都需要连续sustainedAnomalySamples个样本
+	active := sa.anomalyDetected[podName]
+	if !active && streak.Above >= sa.sustainedAnomalySamples {
+		active = true
+	} else if active && streak.Below >= sa.sustainedAnomalySamples {
+		active = false
+	}
+	return active
+}
+
+// ewmaBaseline Pod读写延迟的指数加权移动平均和方差
+type ewmaBaseline struct {
+	read    ewmaStat
+	write   ewmaStat
+	samples int // 已计入基线的样本数
+}
+
+// ewmaStat 单个指标的指数加权移动平均和方差
+type ewmaStat struct {
+	mean     float64
+	variance float64
+}
+
+// update 将新样本计入平均值和方差，第一个样本直接作为平均值
+func (s *ewmaStat) update(x, alpha float64, first bool) {
+	if first {
+		s.mean = x
+		s.variance = 0
+		return
+	}
+	diff := x - s.mean
+	incr := alpha * diff
+	s.mean += incr
+	s.variance = (1 - alpha) * (s.variance + diff*incr)
+}
+
+// zScore 计算x相对于当前基线的z分数
+func (s *ewmaStat) zScore(x float64) float64 {
+	return zScore(x, s.mean, math.Sqrt(s.variance))
+}
+
+// WithEWMA 使用指数加权移动平均和方差作为异常检测的基线，alpha取值范围为(0, 1]
+// alpha越大基线适应越快，等效窗口约为2/alpha-1个样本，例如alpha=0.1约等于最近19个样本
+// 未设置时使用全部历史数据的简单平均
+func WithEWMA(alpha float64) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if alpha > 0 && alpha <= 1 {
+			sa.ewmaAlpha = alpha
+		}
+	}
+}
+
+// detectAnomalyEWMA 以最新样本之前的EWMA基线计算z分数，再将最新样本计入基线
+// 基线不存在时（例如从持久化数据恢复后）用历史数据重建
+// 调用方需持有sa.mu写锁
+func (sa *StorageAnalyzer) detectAnomalyEWMA(podName string) bool {
+	history := sa.recentHistory(podName)
+	if len(history) == 0 {
+		return false
+	}
+	latest := history[len(history)-1]
+
+	baseline, exists := sa.ewmaBaselines[podName]
+	if !exists {
+		baseline = &ewmaBaseline{}
+		for _, metrics := range history[:len(history)-1] {
+			sa.updateEWMA(baseline, float64(metrics.ReadLatency), float64(metrics.WriteLatency))
+		}
+		sa.ewmaBaselines[podName] = baseline
+	}
+
+	// 与简单平均一致，需要足够的样本建立基线
+	anomaly := false
+	if baseline.samples >= minAnomalySamples-1 {
+		readZScore := baseline.read.zScore(float64(latest.ReadLatency))
+		writeZScore := baseline.write.zScore(float64(latest.WriteLatency))
+		sa.anomalyDetails[podName] = AnomalyDetail{
+			ReadMean:    baseline.read.mean,
+			ReadStdDev:  math.Sqrt(baseline.read.variance),
+			ReadZScore:  readZScore,
+			WriteMean:   baseline.write.mean,
+			WriteStdDev: math.Sqrt(baseline.write.variance),
+			WriteZScore: writeZScore,
+			Threshold:   sa.anomalyThreshold,
+			Samples:     baseline.samples,
+		}
+		anomaly = readZScore > sa.anomalyThreshold || writeZScore > sa.anomalyThreshold
+	} else {
+		delete(sa.anomalyDetails, podName)
+	}
+
+	sa.updateEWMA(baseline, float64(latest.ReadLatency), float64(latest.WriteLatency))
+	return anomaly
+}
+
+// updateEWMA 将一个样本计入基线
+func (sa *StorageAnalyzer) updateEWMA(baseline *ewmaBaseline, readLatency, writeLatency float64) {
+	first := baseline.samples == 0
+	baseline.read.update(readLatency, sa.ewmaAlpha, first)
+	baseline.write.update(writeLatency, sa.ewmaAlpha, first)
+	baseline.samples++
+}
@@ -0,0 +1,77 @@
+package analyzer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEWMAStatStepChange(t *testing.T) {
+	const alpha = 0.2
+	var s ewmaStat
+
+	s.update(1000, alpha, true)
+	for i := 0; i < 10; i++ {
+		s.update(1000, alpha, false)
+	}
+	if s.mean != 1000 || s.variance != 0 {
+		t.Fatalf("constant input: mean %v, variance %v, want 1000 and 0", s.mean, s.variance)
+	}
+
+	// 阶跃后第k个样本时与新水平的差距为(1-alpha)^k倍的阶跃幅度
+	for k := 1; k <= 30; k++ {
+		s.update(5000, alpha, false)
+		want := 5000 - 4000*math.Pow(1-alpha, float64(k))
+		if math.Abs(s.mean-want) > 1e-6 {
+			t.Fatalf("sample %d after the step: mean %v, want %v", k, s.mean, want)
+		}
+	}
+	if s.variance <= 0 {
+		t.Error("variance did not grow after the step")
+	}
+}
+
+func TestEWMABaselineConvergesAfterStepChange(t *testing.T) {
+	const alpha = 0.2
+	ewma := NewStorageAnalyzer(WithEWMA(alpha))
+	simple := NewStorageAnalyzer()
+
+	// 1ms上下小幅波动的基线
+	for i := 0; i < 30; i++ {
+		latency := uint64(1_000_000 + (i%2)*100_000)
+		addLatency(ewma, latency)
+		addLatency(simple, latency)
+	}
+	if ewma.HasAnomalyDetected(testPodKey) {
+		t.Fatal("baseline detected as anomaly")
+	}
+
+	// 延迟阶跃到5ms并保持，第一个样本应判定为异常
+	addLatency(ewma, 5_000_000)
+	addLatency(simple, 5_000_000)
+	if !ewma.HasAnomalyDetected(testPodKey) {
+		detail, _ := ewma.GetAnomalyDetail(testPodKey)
+		t.Fatalf("step change not detected, detail = %+v", detail)
+	}
+
+	// 约ln(0.01)/ln(1-alpha)≈21个样本后EWMA基线收敛到新水平的1%以内，异常解除
+	for i := 0; i < 24; i++ {
+		addLatency(ewma, 5_000_000)
+		addLatency(simple, 5_000_000)
+	}
+	if mean := ewma.ewmaBaselines[testPodKey].read.mean; math.Abs(mean-5_000_000) > 50_000 {
+		t.Errorf("EWMA mean after 25 samples at the new level = %.0f, want within 1%% of 5000000", mean)
+	}
+	if ewma.HasAnomalyDetected(testPodKey) {
+		detail, _ := ewma.GetAnomalyDetail(testPodKey)
+		t.Errorf("anomaly not cleared after the baseline converged, detail = %+v", detail)
+	}
+
+	// 简单平均仍受阶跃前的数据影响
+	detail, err := simple.GetAnomalyDetail(testPodKey)
+	if err != nil {
+		t.Fatalf("GetAnomalyDetail() error = %v", err)
+	}
+	if detail.ReadMean > 3_500_000 {
+		t.Errorf("simple mean = %.0f, expected it to lag well behind the EWMA baseline", detail.ReadMean)
+	}
+}
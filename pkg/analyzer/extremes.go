@@ -0,0 +1,61 @@
+package analyzer
+
+import (
+	"fmt"
+	"time"
+)
+
+// LatencySample 一个采集周期的延迟及其采集时间
+type LatencySample struct {
+	Latency   uint64    // 纳秒
+	Timestamp time.Time // 窗口内没有该方向的I/O时为零值
+}
+
+// LatencyExtremes Pod在时间窗口内读写延迟最小和最大的采集周期
+// 只统计有对应方向I/O的周期，没有读或写的周期延迟为0，计入最小值会掩盖真实的最快请求
+type LatencyExtremes struct {
+	Samples  int // 窗口内的采集周期数
+	MinRead  LatencySample
+	MaxRead  LatencySample
+	MinWrite LatencySample
+	MaxWrite LatencySample
+}
+
+// GetLatencyExtremes 获取Pod最近window时间内读写延迟的最小值和最大值及其出现的时间
+// 与平均值和百分位不同，最大值反映窗口内单个周期的最坏情况；窗口受maxHistoryPerPod限制，窗口内没有样本时返回错误
+func (sa *StorageAnalyzer) GetLatencyExtremes(podName string, window time.Duration) (LatencyExtremes, error) {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	history, err := sa.history.Query(podName, time.Now().Add(-window))
+	if err != nil {
+		return LatencyExtremes{}, fmt.Errorf("failed to query metrics history for pod %s: %v", podName, err)
+	}
+	if len(history) == 0 {
+		return LatencyExtremes{}, fmt.Errorf("no metrics history in the last %v for pod %s", window, podName)
+	}
+	if len(history) > sa.maxHistoryPerPod {
+		history = history[len(history)-sa.maxHistoryPerPod:]
+	}
+
+	extremes := LatencyExtremes{Samples: len(history)}
+	for _, metrics := range history {
+		if metrics.ReadIOPS > 0 {
+			observeExtremes(&extremes.MinRead, &extremes.MaxRead, metrics.ReadLatency, metrics.Timestamp)
+		}
+		if metrics.WriteIOPS > 0 {
+			observeExtremes(&extremes.MinWrite, &extremes.MaxWrite, metrics.WriteLatency, metrics.Timestamp)
+		}
+	}
+	return extremes, nil
+}
+
+// observeExtremes 用一个周期的延迟更新最小值和最大值，相同的值保留较早的周期
+func observeExtremes(lowest, highest *LatencySample, latency uint64, timestamp time.Time) {
+	if lowest.Timestamp.IsZero() || latency < lowest.Latency {
+		*lowest = LatencySample{Latency: latency, Timestamp: timestamp}
+	}
+	if highest.Timestamp.IsZero() || latency > highest.Latency {
+		*highest = LatencySample{Latency: latency, Timestamp: timestamp}
+	}
+}
@@ -0,0 +1,91 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// minForecastSamples 预测所需的最少历史样本数
+	minForecastSamples = 10
+	// MinForecastR2 线性拟合的决定系数低于该值时认为延迟没有线性趋势，不给出预测
+	MinForecastR2 = 0.5
+	// forecastZ 预测区间使用的正态分布分位数，对应约95%的置信度
+	forecastZ = 1.96
+)
+
+// ForecastLatency 对历史窗口内的读写总延迟做线性回归，预测最新样本之后horizon时的总延迟（纳秒）
+// confidenceInterval为约95%的预测区间，下界不小于0；样本不足或拟合优度低于MinForecastR2时返回错误
+// 历史延迟恒定时视为完全拟合，预测值和区间都等于该常数
+func (sa *StorageAnalyzer) ForecastLatency(podName string, horizon time.Duration) (predicted uint64, confidenceInterval [2]uint64, err error) {
+	if horizon <= 0 {
+		return 0, confidenceInterval, fmt.Errorf("forecast horizon must be positive, got %v", horizon)
+	}
+
+	sa.mu.RLock()
+	history := sa.recentHistory(podName)
+	sa.mu.RUnlock()
+
+	if len(history) < minForecastSamples {
+		return 0, confidenceInterval, fmt.Errorf("insufficient data for pod %s: %d samples, need %d", podName, len(history), minForecastSamples)
+	}
+
+	// 以第一个样本的时间为原点，x为秒
+	origin := history[0].Timestamp
+	n := float64(len(history))
+	xs := make([]float64, len(history))
+	ys := make([]float64, len(history))
+	var sumX, sumY float64
+	for i, metrics := range history {
+		latency, _ := MetricKindLatency.value(metrics)
+		xs[i] = metrics.Timestamp.Sub(origin).Seconds()
+		ys[i] = float64(latency)
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var sxx, sxy, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return 0, confidenceInterval, fmt.Errorf("insufficient data for pod %s: all samples have the same timestamp", podName)
+	}
+	if syy == 0 {
+		value := uint64(meanY)
+		return value, [2]uint64{value, value}, nil
+	}
+
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	var sse float64
+	for i := range xs {
+		residual := ys[i] - (intercept + slope*xs[i])
+		sse += residual * residual
+	}
+	if r2 := 1 - sse/syy; r2 < MinForecastR2 {
+		return 0, confidenceInterval, fmt.Errorf("latency of pod %s has no linear trend (R²=%.2f, need %.2f)", podName, r2, MinForecastR2)
+	}
+
+	// 预测点离样本中心越远，区间越宽
+	x0 := history[len(history)-1].Timestamp.Add(horizon).Sub(origin).Seconds()
+	y0 := intercept + slope*x0
+	stdErr := math.Sqrt(sse / (n - 2))
+	margin := forecastZ * stdErr * math.Sqrt(1+1/n+(x0-meanX)*(x0-meanX)/sxx)
+
+	return nonNegative(y0), [2]uint64{nonNegative(y0 - margin), nonNegative(y0 + margin)}, nil
+}
+
+// nonNegative 将预测值转换为纳秒，延迟不会为负
+func nonNegative(v float64) uint64 {
+	if v <= 0 {
+		return 0
+	}
+	return uint64(math.Round(v))
+}
@@ -0,0 +1,187 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// historyPruneInterval 分析器调用HistoryStore.Prune的最小间隔
+const historyPruneInterval = time.Minute
+
+// HistoryStore 保存Pod指标历史的存储后端，实现需要并发安全
+// 默认使用进程内的MemoryHistoryStore，可通过WithHistoryStore替换为外部时序数据库等实现
+type HistoryStore interface {
+	// Append 追加一条Pod指标，同一Pod的样本按时间顺序追加，调用方之后不会修改metrics
+	Append(podName string, metrics *monitor.PodStorageMetrics) error
+	// Query 返回Pod不早于since的样本，按时间升序排列，since为零值时返回全部样本
+	// Pod没有任何样本时返回nil，有样本但都早于since时返回空切片；调用方不应修改返回的指标
+	Query(podName string, since time.Time) ([]*monitor.PodStorageMetrics, error)
+	// Prune 丢弃早于before的样本，不再有样本的Pod随之移除
+	Prune(before time.Time) error
+	// Pods 返回有样本的Pod名称
+	Pods() ([]string, error)
+	// Delete 删除Pod的所有样本，Pod没有样本时不返回错误
+	Delete(podName string) error
+}
+
+// WithHistoryStore 设置指标历史的存储后端，store为nil时使用默认的进程内存储
+// 使用外部存储时WithMaxHistoryPerPod不再限制存储的样本数，由WithHistoryRetention控制保留时长
+func WithHistoryStore(store HistoryStore) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if store != nil {
+			sa.history = store
+		}
+	}
+}
+
+// MemoryHistoryStore 进程内的指标历史存储，每个Pod最多保存固定数量的最新样本
+// 每个Pod的样本保存在固定容量的环形缓冲区中，达到上限后原地覆盖最旧的样本
+type MemoryHistoryStore struct {
+	mu        sync.RWMutex
+	maxPerPod int
+	history   map[string]*historyRing
+}
+
+// NewMemoryHistoryStore 创建进程内历史存储，maxPerPod为每个Pod保存的最大样本数，不大于0时不限制
+func NewMemoryHistoryStore(maxPerPod int) *MemoryHistoryStore {
+	return &MemoryHistoryStore{
+		maxPerPod: maxPerPod,
+		history:   make(map[string]*historyRing),
+	}
+}
+
+// Append 追加一条Pod指标的副本，超出样本数上限时删除最旧的样本
+func (s *MemoryHistoryStore) Append(podName string, metrics *monitor.PodStorageMetrics) error {
+	metricsCopy := *metrics
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history, exists := s.history[podName]
+	if !exists {
+		history = newHistoryRing(s.maxPerPod)
+		s.history[podName] = history
+	}
+	history.push(&metricsCopy)
+	return nil
+}
+
+// Query 返回Pod不早于since的样本
+func (s *MemoryHistoryStore) Query(podName string, since time.Time) ([]*monitor.PodStorageMetrics, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	history, exists := s.history[podName]
+	if !exists {
+		return nil, nil
+	}
+
+	return history.since(history.search(since)), nil
+}
+
+// Prune 丢弃早于before的样本
+func (s *MemoryHistoryStore) Prune(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for podName, history := range s.history {
+		start := history.search(before)
+		switch {
+		case start == history.len():
+			delete(s.history, podName)
+		case start > 0:
+			history.dropOldest(start)
+		}
+	}
+	return nil
+}
+
+// Pods 返回有样本的Pod名称
+func (s *MemoryHistoryStore) Pods() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pods := make([]string, 0, len(s.history))
+	for podName := range s.history {
+		pods = append(pods, podName)
+	}
+	return pods, nil
+}
+
+// Delete 删除Pod的所有样本
+func (s *MemoryHistoryStore) Delete(podName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.history, podName)
+	return nil
+}
+
+// recentHistory 返回Pod最新的maxHistoryPerPod条历史样本，用于瓶颈、异常和百分位分析
+// 外部存储可能保存了更长时间的数据，分析仍只使用最近的样本；存储出错时打印错误并视为没有历史
+func (sa *StorageAnalyzer) recentHistory(podName string) []*monitor.PodStorageMetrics {
+	history, err := sa.history.Query(podName, time.Time{})
+	if err != nil {
+		fmt.Printf("Error querying metrics history for pod %s: %v\n", podName, err)
+		return nil
+	}
+	if len(history) > sa.maxHistoryPerPod {
+		history = history[len(history)-sa.maxHistoryPerPod:]
+	}
+	return history
+}
+
+// historyPods 返回有历史样本的Pod名称，存储出错时打印错误并视为没有Pod
+func (sa *StorageAnalyzer) historyPods() []string {
+	pods, err := sa.history.Pods()
+	if err != nil {
+		fmt.Printf("Error listing metrics history pods: %v\n", err)
+		return nil
+	}
+	return pods
+}
+
+// DumpHistory 依次将每个Pod不早于since的历史样本交给fn，since为零值时为全部样本
+// Pod按key排序，同一Pod的样本按时间升序；每次只在读锁下取出一个Pod的样本，调用fn时不持有锁，
+// 向慢速客户端输出时不会阻塞采集；fn返回错误时停止并返回该错误，fn不应修改指标
+func (sa *StorageAnalyzer) DumpHistory(since time.Time, fn func(podKey string, metrics *monitor.PodStorageMetrics) error) error {
+	sa.mu.RLock()
+	pods := sa.historyPods()
+	sa.mu.RUnlock()
+	sort.Strings(pods)
+
+	for _, podKey := range pods {
+		sa.mu.RLock()
+		history, err := sa.history.Query(podKey, since)
+		sa.mu.RUnlock()
+		if err != nil {
+			return fmt.Errorf("failed to query metrics history for pod %s: %v", podKey, err)
+		}
+
+		for _, metrics := range history {
+			if err := fn(podKey, metrics); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pruneHistory 按保留时长清理历史，两次清理至少间隔historyPruneInterval，调用方需持有sa.mu写锁
+// current为本次采集的指标，清理后历史已被全部丢弃且不在current中的Pod的分析状态同时被清除
+func (sa *StorageAnalyzer) pruneHistory(current map[string]*monitor.PodStorageMetrics, now time.Time) {
+	if now.Sub(sa.lastPrune) < historyPruneInterval {
+		return
+	}
+	sa.lastPrune = now
+
+	if err := sa.history.Prune(now.Add(-sa.persistence.retention)); err != nil {
+		fmt.Printf("Error pruning metrics history: %v\n", err)
+		return
+	}
+	sa.forgetVanishedPods(current, now)
+}
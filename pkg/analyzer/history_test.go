@@ -0,0 +1,226 @@
+package analyzer
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// fakeHistoryStore 模拟外部时序数据库的HistoryStore，不限制样本数并记录Prune调用
+type fakeHistoryStore struct {
+	mu       sync.Mutex
+	samples  map[string][]*monitor.PodStorageMetrics
+	pruned   []time.Time // 每次Prune的before参数
+	queryErr error       // 不为nil时Query返回该错误
+}
+
+func newFakeHistoryStore() *fakeHistoryStore {
+	return &fakeHistoryStore{samples: make(map[string][]*monitor.PodStorageMetrics)}
+}
+
+func (s *fakeHistoryStore) Append(podName string, metrics *monitor.PodStorageMetrics) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples[podName] = append(s.samples[podName], metrics)
+	return nil
+}
+
+func (s *fakeHistoryStore) Query(podName string, since time.Time) ([]*monitor.PodStorageMetrics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.queryErr != nil {
+		return nil, s.queryErr
+	}
+	samples, ok := s.samples[podName]
+	if !ok {
+		return nil, nil
+	}
+	result := []*monitor.PodStorageMetrics{}
+	for _, metrics := range samples {
+		if !metrics.Timestamp.Before(since) {
+			result = append(result, metrics)
+		}
+	}
+	return result, nil
+}
+
+func (s *fakeHistoryStore) Prune(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruned = append(s.pruned, before)
+	for podName, samples := range s.samples {
+		var kept []*monitor.PodStorageMetrics
+		for _, metrics := range samples {
+			if !metrics.Timestamp.Before(before) {
+				kept = append(kept, metrics)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.samples, podName)
+		} else {
+			s.samples[podName] = kept
+		}
+	}
+	return nil
+}
+
+func (s *fakeHistoryStore) Pods() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pods := make([]string, 0, len(s.samples))
+	for podName := range s.samples {
+		pods = append(pods, podName)
+	}
+	return pods, nil
+}
+
+func (s *fakeHistoryStore) Delete(podName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.samples, podName)
+	return nil
+}
+
+// sampleAt 返回时间戳为ts、ReadIOPS为iops的样本
+func sampleAt(ts time.Time, iops uint64) *monitor.PodStorageMetrics {
+	return &monitor.PodStorageMetrics{PodName: "a", PodUID: "uid-a", ReadIOPS: iops, Timestamp: ts}
+}
+
+// readIOPS 返回样本的ReadIOPS序列
+func readIOPS(history []*monitor.PodStorageMetrics) []uint64 {
+	values := make([]uint64, 0, len(history))
+	for _, metrics := range history {
+		values = append(values, metrics.ReadIOPS)
+	}
+	return values
+}
+
+func TestMemoryHistoryStore(t *testing.T) {
+	store := NewMemoryHistoryStore(3)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		if err := store.Append("uid-a", sampleAt(start.Add(time.Duration(i)*time.Minute), uint64(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 超出上限时丢弃最旧的样本
+	history, _ := store.Query("uid-a", time.Time{})
+	if got := readIOPS(history); !reflect.DeepEqual(got, []uint64{2, 3, 4}) {
+		t.Errorf("Query(all) = %v, want the 3 newest samples", got)
+	}
+	history, _ = store.Query("uid-a", start.Add(3*time.Minute))
+	if got := readIOPS(history); !reflect.DeepEqual(got, []uint64{3, 4}) {
+		t.Errorf("Query(since) = %v, want [3 4]", got)
+	}
+	if history, _ := store.Query("uid-a", start.Add(time.Hour)); history == nil || len(history) != 0 {
+		t.Errorf("Query(after all samples) = %v, want an empty non-nil slice", history)
+	}
+	if history, _ := store.Query("uid-b", time.Time{}); history != nil {
+		t.Errorf("Query(unknown pod) = %v, want nil", history)
+	}
+
+	// 保存的是副本
+	original := sampleAt(start.Add(5*time.Minute), 5)
+	store.Append("uid-a", original)
+	original.ReadIOPS = 100
+	history, _ = store.Query("uid-a", start.Add(5*time.Minute))
+	if got := readIOPS(history); !reflect.DeepEqual(got, []uint64{5}) {
+		t.Errorf("stored sample = %v after modifying the original, want [5]", got)
+	}
+
+	store.Append("uid-b", sampleAt(start, 0))
+	pods, _ := store.Pods()
+	sort.Strings(pods)
+	if !reflect.DeepEqual(pods, []string{"uid-a", "uid-b"}) {
+		t.Errorf("Pods() = %v, want [uid-a uid-b]", pods)
+	}
+
+	// 清理后没有样本的Pod被移除
+	if err := store.Prune(start.Add(4 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	history, _ = store.Query("uid-a", time.Time{})
+	if got := readIOPS(history); !reflect.DeepEqual(got, []uint64{4, 5}) {
+		t.Errorf("Query after Prune = %v, want [4 5]", got)
+	}
+	if pods, _ := store.Pods(); !reflect.DeepEqual(pods, []string{"uid-a"}) {
+		t.Errorf("Pods() after Prune = %v, want [uid-a]", pods)
+	}
+
+	if err := store.Delete("uid-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("uid-a"); err != nil {
+		t.Errorf("Delete(pod without samples) error = %v", err)
+	}
+	if pods, _ := store.Pods(); len(pods) != 0 {
+		t.Errorf("Pods() after Delete = %v, want none", pods)
+	}
+}
+
+func TestAnalyzerWithFakeHistoryStore(t *testing.T) {
+	store := newFakeHistoryStore()
+	sa := NewStorageAnalyzer(WithHistoryStore(store), WithMaxHistoryPerPod(10), WithHistoryRetention(time.Hour))
+
+	now := time.Now()
+	for i := 0; i < 30; i++ {
+		sa.AddMetrics(map[string]*monitor.PodStorageMetrics{
+			"uid-a": sampleAt(now.Add(time.Duration(i-29)*time.Minute), uint64(i+1)),
+		})
+	}
+
+	// 外部存储保存全部样本，分析器只使用最近maxHistoryPerPod条
+	if n := len(store.samples["uid-a"]); n != 30 {
+		t.Errorf("store has %d samples, want all 30", n)
+	}
+	history := sa.GetPodHistory("uid-a", 2*time.Hour)
+	if got := readIOPS(history); len(got) != 10 || got[0] != 21 || got[9] != 30 {
+		t.Errorf("GetPodHistory() = %v, want the 10 newest samples", got)
+	}
+	if trend, _, err := sa.GetMetricTrend("uid-a", MetricKindReadIOPS, 2*time.Hour); err != nil || trend != "increased" {
+		t.Errorf("GetMetricTrend() = %s, %v, want increased", trend, err)
+	}
+
+	// 按保留时长清理外部存储
+	if len(store.pruned) == 0 {
+		t.Fatal("Prune was not called")
+	}
+	if before := store.pruned[len(store.pruned)-1]; before.Before(now.Add(-time.Hour)) || before.After(time.Now().Add(-time.Hour)) {
+		t.Errorf("Prune(%v), want a cutoff one hour before the collection", before)
+	}
+
+	if err := sa.ResetPod("uid-a"); err != nil {
+		t.Fatalf("ResetPod() error = %v", err)
+	}
+	if _, ok := store.samples["uid-a"]; ok {
+		t.Error("ResetPod did not delete the samples from the store")
+	}
+}
+
+func TestAnalyzerHistoryStoreQueryError(t *testing.T) {
+	store := newFakeHistoryStore()
+	sa := NewStorageAnalyzer(WithHistoryStore(store))
+	sa.AddMetrics(map[string]*monitor.PodStorageMetrics{"uid-a": sampleAt(time.Now(), 1)})
+
+	// 存储出错时视为没有历史，而不是panic或返回部分数据
+	store.queryErr = errors.New("connection refused")
+	if history := sa.GetPodHistory("uid-a", time.Hour); history != nil {
+		t.Errorf("GetPodHistory() = %v with a failing store, want nil", history)
+	}
+	if _, _, err := sa.GetLatencyTrend("uid-a", time.Hour); err == nil {
+		t.Error("GetLatencyTrend() error = nil with a failing store")
+	}
+	sa.AddMetrics(map[string]*monitor.PodStorageMetrics{"uid-a": sampleAt(time.Now(), 2)})
+}
@@ -0,0 +1,35 @@
+package analyzer
+
+import "github.com/lizhongxuan/ioeye/pkg/monitor"
+
+// SmallIOSizeThreshold 判定I/O过小的默认平均请求大小（字节），可通过WithSmallIOSizeThreshold覆盖
+// 小于一个内存页的请求通常说明应用在逐条写入，合并成批量I/O能显著降低IOPS和延迟
+const SmallIOSizeThreshold = 4096
+
+// smallIOMinIOPS 判定I/O过小所需的最低IOPS，请求很少时平均大小没有意义
+const smallIOMinIOPS = 100
+
+// WithSmallIOSizeThreshold 设置判定I/O过小的平均请求大小（字节）
+func WithSmallIOSizeThreshold(threshold uint64) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if threshold > 0 {
+			sa.smallIOSizeThreshold = threshold
+		}
+	}
+}
+
+// HasSmallIO 判断Pod最近一次的平均读或写请求大小是否低于阈值，即可能受益于批量I/O
+func (sa *StorageAnalyzer) HasSmallIO(podName string) bool {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	return sa.smallIOPods[podName]
+}
+
+// detectSmallIO 判断Pod的读或写是否以大量的小请求为主，只考虑IOPS足够高的方向
+func (sa *StorageAnalyzer) detectSmallIO(metrics *monitor.PodStorageMetrics) bool {
+	small := func(iops, avgSize uint64) bool {
+		return iops >= smallIOMinIOPS && avgSize > 0 && avgSize < sa.smallIOSizeThreshold
+	}
+	return small(metrics.ReadIOPS, metrics.AvgReadSize) || small(metrics.WriteIOPS, metrics.AvgWriteSize)
+}
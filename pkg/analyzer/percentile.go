@@ -0,0 +1,155 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// LatencyPercentiles 常用的延迟百分位（纳秒）
+type LatencyPercentiles struct {
+	P50 uint64 `json:"p50_ns"`
+	P95 uint64 `json:"p95_ns"`
+	P99 uint64 `json:"p99_ns"`
+}
+
+// HistogramPercentile 从直方图中估算第pct百分位的延迟（纳秒）
+// bounds为各桶的上界，桶的下界为前一个桶的上界，落在桶内的百分位按线性插值估算
+func HistogramPercentile(bounds, counts []uint64, pct float64) (uint64, error) {
+	if pct <= 0 || pct > 100 {
+		return 0, fmt.Errorf("percentile must be in (0, 100], got %v", pct)
+	}
+	if len(bounds) != len(counts) {
+		return 0, fmt.Errorf("histogram has %d bounds but %d counts", len(bounds), len(counts))
+	}
+
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return 0, fmt.Errorf("histogram is empty")
+	}
+
+	rank := pct / 100 * float64(total)
+	var cumulative uint64
+	for i, c := range counts {
+		if c == 0 {
+			continue
+		}
+		if float64(cumulative+c) >= rank {
+			var lower uint64
+			if i > 0 {
+				lower = bounds[i-1]
+			}
+			fraction := (rank - float64(cumulative)) / float64(c)
+			return lower + uint64(fraction*float64(bounds[i]-lower)), nil
+		}
+		cumulative += c
+	}
+
+	return bounds[len(bounds)-1], nil
+}
+
+// HistogramPercentiles 从直方图中估算p50/p95/p99延迟
+func HistogramPercentiles(bounds, counts []uint64) (LatencyPercentiles, error) {
+	var p LatencyPercentiles
+	var err error
+
+	if p.P50, err = HistogramPercentile(bounds, counts, 50); err != nil {
+		return p, err
+	}
+	if p.P95, err = HistogramPercentile(bounds, counts, 95); err != nil {
+		return p, err
+	}
+	if p.P99, err = HistogramPercentile(bounds, counts, 99); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// PodSLOReport 单个Pod在历史窗口内的延迟百分位
+type PodSLOReport struct {
+	PodName   string             `json:"pod_name"`
+	Namespace string             `json:"namespace"`
+	Samples   int                `json:"samples"`
+	Read      LatencyPercentiles `json:"read"`
+	Write     LatencyPercentiles `json:"write"`
+}
+
+// GetLatencyPercentile 计算Pod历史窗口内读写延迟的第pct百分位（纳秒）
+// 样本数不足以区分该百分位时返回错误，而不是返回误导性的0
+func (sa *StorageAnalyzer) GetLatencyPercentile(podName string, pct float64) (read, write uint64, err error) {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	return sa.latencyPercentile(podName, pct)
+}
+
+// GetSLOReport 返回每个Pod的p50/p95/p99读写延迟
+// 历史样本不足以计算p99的Pod不包含在报告中
+func (sa *StorageAnalyzer) GetSLOReport() map[string]*PodSLOReport {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	report := make(map[string]*PodSLOReport)
+	for _, podName := range sa.historyPods() {
+		history := sa.recentHistory(podName)
+		if len(history) < minPercentileSamples(99) {
+			continue
+		}
+
+		podReport := &PodSLOReport{
+			PodName:   history[len(history)-1].PodName,
+			Namespace: history[len(history)-1].Namespace,
+			Samples:   len(history),
+		}
+		podReport.Read.P50, podReport.Write.P50, _ = sa.latencyPercentile(podName, 50)
+		podReport.Read.P95, podReport.Write.P95, _ = sa.latencyPercentile(podName, 95)
+		podReport.Read.P99, podReport.Write.P99, _ = sa.latencyPercentile(podName, 99)
+		report[podName] = podReport
+	}
+
+	return report
+}
+
+// latencyPercentile 按最近秩法计算百分位，调用方需持有读锁
+func (sa *StorageAnalyzer) latencyPercentile(podName string, pct float64) (read, write uint64, err error) {
+	if pct <= 0 || pct > 100 {
+		return 0, 0, fmt.Errorf("percentile must be in (0, 100], got %v", pct)
+	}
+
+	history := sa.recentHistory(podName)
+	if len(history) == 0 {
+		return 0, 0, fmt.Errorf("no metrics history for pod %s", podName)
+	}
+
+	if needed := minPercentileSamples(pct); len(history) < needed {
+		return 0, 0, fmt.Errorf("insufficient data for p%v of pod %s: have %d samples, need %d",
+			pct, podName, len(history), needed)
+	}
+
+	reads := make([]uint64, len(history))
+	writes := make([]uint64, len(history))
+	for i, metrics := range history {
+		reads[i] = metrics.ReadLatency
+		writes[i] = metrics.WriteLatency
+	}
+	sort.Slice(reads, func(i, j int) bool { return reads[i] < reads[j] })
+	sort.Slice(writes, func(i, j int) bool { return writes[i] < writes[j] })
+
+	idx := int(math.Ceil(pct/100*float64(len(history)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return reads[idx], writes[idx], nil
+}
+
+// minPercentileSamples 返回区分第pct百分位与最大值所需的最少样本数
+// 例如p99至少需要100个样本，p50至少需要2个样本
+func minPercentileSamples(pct float64) int {
+	if pct >= 100 {
+		return 1
+	}
+	return int(math.Ceil(100 / (100 - pct)))
+}
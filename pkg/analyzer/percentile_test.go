@@ -0,0 +1,65 @@
+package analyzer
+
+import "testing"
+
+func TestHistogramPercentile(t *testing.T) {
+	bounds := []uint64{1000, 2000, 4000, 8000}
+	counts := []uint64{10, 20, 30, 40}
+
+	tests := []struct {
+		name    string
+		bounds  []uint64
+		counts  []uint64
+		pct     float64
+		want    uint64
+		wantErr bool
+	}{
+		{name: "end of first bucket", bounds: bounds, counts: counts, pct: 10, want: 1000},
+		{name: "inside second bucket", bounds: bounds, counts: counts, pct: 25, want: 1750},
+		{name: "p50", bounds: bounds, counts: counts, pct: 50, want: 3333},
+		{name: "inside last bucket", bounds: bounds, counts: counts, pct: 75, want: 5500},
+		{name: "p95", bounds: bounds, counts: counts, pct: 95, want: 7500},
+		{name: "p100", bounds: bounds, counts: counts, pct: 100, want: 8000},
+		{name: "single bucket p50", bounds: []uint64{1000}, counts: []uint64{4}, pct: 50, want: 500},
+		{name: "single bucket p100", bounds: []uint64{1000}, counts: []uint64{4}, pct: 100, want: 1000},
+		{name: "only middle bucket filled", bounds: []uint64{1000, 2000, 4000}, counts: []uint64{0, 8, 0}, pct: 50, want: 1500},
+		{name: "only middle bucket filled p99", bounds: []uint64{1000, 2000, 4000}, counts: []uint64{0, 8, 0}, pct: 99, want: 1990},
+		{name: "empty histogram", bounds: bounds, counts: []uint64{0, 0, 0, 0}, pct: 50, wantErr: true},
+		{name: "no buckets", pct: 50, wantErr: true},
+		{name: "mismatched lengths", bounds: bounds, counts: []uint64{1, 2}, pct: 50, wantErr: true},
+		{name: "zero percentile", bounds: bounds, counts: counts, pct: 0, wantErr: true},
+		{name: "percentile above 100", bounds: bounds, counts: counts, pct: 101, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HistogramPercentile(tt.bounds, tt.counts, tt.pct)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("HistogramPercentile() = %d, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("HistogramPercentile() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("HistogramPercentile() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistogramPercentiles(t *testing.T) {
+	got, err := HistogramPercentiles([]uint64{1000, 2000, 4000, 8000}, []uint64{10, 20, 30, 40})
+	if err != nil {
+		t.Fatalf("HistogramPercentiles() error = %v", err)
+	}
+	if got.P50 != 3333 || got.P95 != 7500 || got.P99 < got.P95 || got.P99 > 8000 {
+		t.Errorf("HistogramPercentiles() = %+v, want p50 3333, p95 7500, p95 <= p99 <= 8000", got)
+	}
+
+	if _, err := HistogramPercentiles([]uint64{1000}, []uint64{0}); err == nil {
+		t.Error("HistogramPercentiles(empty) error = nil")
+	}
+}
@@ -0,0 +1,223 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+	bolt "go.etcd.io/bbolt"
+)
+
+// historyBucket 保存指标历史的bolt bucket名称，key为Pod名称，value为JSON编码的历史记录
+var historyBucket = []byte("metrics_history")
+
+// persistence 将指标历史定期快照到本地bolt文件
+type persistence struct {
+	path      string
+	interval  time.Duration // 快照周期
+	retention time.Duration // 加载和定期清理历史时丢弃早于该时间窗口的数据
+	db        *bolt.DB
+	stopChan  chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// WithPersistencePath 启用历史数据持久化，path为bolt数据库文件路径
+func WithPersistencePath(path string) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if path != "" {
+			sa.persistence.path = path
+		}
+	}
+}
+
+// WithPersistenceInterval 设置历史数据快照周期
+func WithPersistenceInterval(interval time.Duration) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if interval > 0 {
+			sa.persistence.interval = interval
+		}
+	}
+}
+
+// WithHistoryRetention 设置历史数据保留的时间窗口，加载持久化数据和定期清理历史时丢弃更早的数据
+func WithHistoryRetention(retention time.Duration) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if retention > 0 {
+			sa.persistence.retention = retention
+		}
+	}
+}
+
+// StartPersistence 从持久化文件加载历史数据，并在后台定期快照
+// 未设置持久化路径时直接返回；ctx取消或调用Close时写入最后一次快照
+func (sa *StorageAnalyzer) StartPersistence(ctx context.Context) error {
+	p := &sa.persistence
+	if p.path == "" {
+		return nil
+	}
+
+	db, err := bolt.Open(p.path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("failed to open history file %s: %v", p.path, err)
+	}
+	p.db = db
+
+	if err := sa.loadHistory(); err != nil {
+		db.Close()
+		p.db = nil
+		return err
+	}
+
+	p.stopChan = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+		defer db.Close()
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := sa.saveHistory(); err != nil {
+					fmt.Printf("Error saving metrics history: %v\n", err)
+				}
+			case <-ctx.Done():
+				sa.finalSave()
+				return
+			case <-p.stopChan:
+				sa.finalSave()
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Close 停止后台快照，写入最后一次快照并关闭持久化文件
+func (sa *StorageAnalyzer) Close() {
+	p := &sa.persistence
+	if p.stopChan == nil {
+		return
+	}
+
+	p.closeOnce.Do(func() {
+		close(p.stopChan)
+	})
+	<-p.done
+}
+
+// finalSave 退出前写入最后一次快照
+func (sa *StorageAnalyzer) finalSave() {
+	if err := sa.saveHistory(); err != nil {
+		fmt.Printf("Error saving metrics history: %v\n", err)
+	}
+}
+
+// saveHistory 将当前的指标历史写入持久化文件
+func (sa *StorageAnalyzer) saveHistory() error {
+	// 仅在编码期间持有读锁
+	sa.mu.RLock()
+	pods := sa.historyPods()
+	encoded := make(map[string][]byte, len(pods))
+	for _, podName := range pods {
+		data, err := json.Marshal(sa.recentHistory(podName))
+		if err != nil {
+			sa.mu.RUnlock()
+			return fmt.Errorf("failed to encode history for pod %s: %v", podName, err)
+		}
+		encoded[podName] = data
+	}
+	sa.mu.RUnlock()
+
+	return sa.persistence.db.Update(func(tx *bolt.Tx) error {
+		// 整体替换bucket，清除已消失的Pod
+		if err := tx.DeleteBucket(historyBucket); err != nil && err != bolt.ErrBucketNotFound {
+			return fmt.Errorf("failed to reset history bucket: %v", err)
+		}
+		bucket, err := tx.CreateBucket(historyBucket)
+		if err != nil {
+			return fmt.Errorf("failed to create history bucket: %v", err)
+		}
+
+		for podName, data := range encoded {
+			if err := bucket.Put([]byte(podName), data); err != nil {
+				return fmt.Errorf("failed to write history for pod %s: %v", podName, err)
+			}
+		}
+		return nil
+	})
+}
+
+// loadHistory 从持久化文件恢复指标历史
+// 丢弃超出保留窗口的数据，并保留每个Pod最新的maxHistoryPerPod条记录
+func (sa *StorageAnalyzer) loadHistory() error {
+	cutoff := time.Now().Add(-sa.persistence.retention)
+	loaded := make(map[string][]*monitor.PodStorageMetrics)
+
+	err := sa.persistence.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(historyBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			var history []*monitor.PodStorageMetrics
+			if err := json.Unmarshal(v, &history); err != nil {
+				return fmt.Errorf("failed to decode history for pod %s: %v", k, err)
+			}
+
+			var kept []*monitor.PodStorageMetrics
+			for _, metrics := range history {
+				if metrics != nil && !metrics.Timestamp.Before(cutoff) {
+					kept = append(kept, metrics)
+				}
+			}
+			if len(kept) > sa.maxHistoryPerPod {
+				kept = kept[len(kept)-sa.maxHistoryPerPod:]
+			}
+			if len(kept) > 0 {
+				loaded[string(k)] = kept
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load metrics history: %v", err)
+	}
+
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	for podName, history := range loaded {
+		// 存储中已有的样本不再重复追加，例如外部存储本身已持久化了这些数据
+		var latest time.Time
+		if existing := sa.recentHistory(podName); len(existing) > 0 {
+			latest = existing[len(existing)-1].Timestamp
+		}
+		for _, metrics := range history {
+			if !metrics.Timestamp.After(latest) {
+				continue
+			}
+			if err := sa.history.Append(podName, metrics); err != nil {
+				return fmt.Errorf("failed to restore history for pod %s: %v", podName, err)
+			}
+		}
+
+		history = sa.recentHistory(podName)
+		if len(history) == 0 {
+			continue
+		}
+		sa.podBottlenecks[podName] = sa.analyzeBottleneck(history[len(history)-1])
+		sa.anomalyDetected[podName] = sa.updateAnomaly(podName, sa.detectAnomaly(podName))
+	}
+
+	return nil
+}
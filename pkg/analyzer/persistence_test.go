@@ -0,0 +1,126 @@
+package analyzer
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// startPersistence 以path为持久化文件创建并启动分析器
+func startPersistence(t *testing.T, path string) *StorageAnalyzer {
+	t.Helper()
+
+	sa := NewStorageAnalyzer(WithPersistencePath(path), WithPersistenceInterval(time.Hour))
+	if err := sa.StartPersistence(context.Background()); err != nil {
+		t.Fatalf("StartPersistence() error = %v", err)
+	}
+	return sa
+}
+
+func TestPersistenceRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	sa := startPersistence(t, path)
+
+	// 过去一小时内每分钟一个样本，各指标走势不同
+	now := time.Now()
+	for i := 0; i < 60; i++ {
+		sa.AddMetrics(map[string]*monitor.PodStorageMetrics{
+			"uid-a": {
+				PodName:         "a",
+				PodUID:          "uid-a",
+				Namespace:       "default",
+				ReadLatency:     1_000_000 + uint64(i)*50_000,
+				WriteLatency:    2_000_000,
+				ReadIOPS:        uint64(1000 - i*10),
+				WriteIOPS:       500,
+				ReadThroughput:  uint64(i) << 20,
+				WriteThroughput: 64 << 20,
+				Timestamp:       now.Add(time.Duration(i-59) * time.Minute),
+			},
+		})
+	}
+
+	kinds := []MetricKind{
+		MetricKindLatency,
+		MetricKindReadIOPS,
+		MetricKindWriteIOPS,
+		MetricKindReadThroughput,
+		MetricKindWriteThroughput,
+	}
+	durations := []time.Duration{10 * time.Minute, 30 * time.Minute, time.Hour}
+
+	type trendResult struct {
+		trend  string
+		change float64
+	}
+	want := make(map[MetricKind]map[time.Duration]trendResult)
+	for _, kind := range kinds {
+		want[kind] = make(map[time.Duration]trendResult)
+		for _, d := range durations {
+			trend, change, err := sa.GetMetricTrend("uid-a", kind, d)
+			if err != nil {
+				t.Fatalf("GetMetricTrend(%s, %v) error = %v", kind, d, err)
+			}
+			want[kind][d] = trendResult{trend, change}
+		}
+	}
+	wantHistory := sa.GetPodHistory("uid-a", 2*time.Hour)
+
+	// Close写入最后一次快照，新的分析器从同一文件恢复
+	sa.Close()
+	restored := startPersistence(t, path)
+	defer restored.Close()
+
+	history := restored.GetPodHistory("uid-a", 2*time.Hour)
+	if len(history) != len(wantHistory) {
+		t.Fatalf("restored %d samples, want %d", len(history), len(wantHistory))
+	}
+	for i := range history {
+		if !history[i].Timestamp.Equal(wantHistory[i].Timestamp) || history[i].ReadLatency != wantHistory[i].ReadLatency {
+			t.Errorf("restored sample %d = %+v, want %+v", i, history[i], wantHistory[i])
+		}
+	}
+
+	for _, kind := range kinds {
+		for _, d := range durations {
+			trend, change, err := restored.GetMetricTrend("uid-a", kind, d)
+			if err != nil {
+				t.Fatalf("restored GetMetricTrend(%s, %v) error = %v", kind, d, err)
+			}
+			if got := (trendResult{trend, change}); got != want[kind][d] {
+				t.Errorf("GetMetricTrend(%s, %v) = %+v after restore, want %+v", kind, d, got, want[kind][d])
+			}
+		}
+	}
+}
+
+func TestPersistenceDropsExpiredHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	sa := startPersistence(t, path)
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		sa.AddMetrics(map[string]*monitor.PodStorageMetrics{
+			"uid-a": {PodName: "a", PodUID: "uid-a", ReadIOPS: uint64(i), Timestamp: now.Add(time.Duration(i-9) * time.Minute)},
+		})
+	}
+	sa.Close()
+
+	// 以更短的保留时长加载，只保留最近5分钟内的样本
+	restored := NewStorageAnalyzer(WithPersistencePath(path), WithHistoryRetention(5*time.Minute-time.Second))
+	if err := restored.StartPersistence(context.Background()); err != nil {
+		t.Fatalf("StartPersistence() error = %v", err)
+	}
+	defer restored.Close()
+
+	history := restored.GetPodHistory("uid-a", time.Hour)
+	if len(history) != 5 {
+		t.Fatalf("restored %d samples, want the 5 within retention", len(history))
+	}
+	if history[0].ReadIOPS != 5 {
+		t.Errorf("oldest restored sample ReadIOPS = %d, want 5", history[0].ReadIOPS)
+	}
+}
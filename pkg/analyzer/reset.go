@@ -0,0 +1,112 @@
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// ResetPod 清除Pod的历史样本以及瓶颈、异常检测和小I/O状态，用于修复慢Pod后丢弃旧的基线
+// 仍在持续的告警记录随之结束，告警冷却时间和升级状态也被清除；之后的样本从零开始重新建立基线
+func (sa *StorageAnalyzer) ResetPod(podName string) error {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	return sa.resetPod(podName, time.Now())
+}
+
+// ResetAll 清除所有Pod的历史样本和分析状态，单个Pod清除失败时继续清除其余Pod并返回第一个错误
+func (sa *StorageAnalyzer) ResetAll() error {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	// 有分析状态但历史已被清理的Pod同样需要清除
+	pods := make(map[string]bool)
+	for _, podName := range sa.historyPods() {
+		pods[podName] = true
+	}
+	for podName := range sa.podBottlenecks {
+		pods[podName] = true
+	}
+
+	var firstErr error
+	now := time.Now()
+	for podName := range pods {
+		if err := sa.resetPod(podName, now); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// resetPod 清除单个Pod的历史和分析状态，调用方需持有sa.mu写锁
+// 历史存储删除失败时仍清除内存中的状态
+func (sa *StorageAnalyzer) resetPod(podName string, now time.Time) error {
+	sa.clearPodState(podName, now)
+
+	if err := sa.history.Delete(podName); err != nil {
+		return fmt.Errorf("failed to delete metrics history for pod %s: %v", podName, err)
+	}
+	return nil
+}
+
+// clearPodState 清除单个Pod在内存中的分析和告警状态，不修改历史存储，调用方需持有sa.mu写锁
+func (sa *StorageAnalyzer) clearPodState(podName string, now time.Time) {
+	delete(sa.podBottlenecks, podName)
+	delete(sa.anomalyDetected, podName)
+	delete(sa.anomalyStreaks, podName)
+	delete(sa.anomalyDetails, podName)
+	delete(sa.ewmaBaselines, podName)
+	delete(sa.smallIOPods, podName)
+	delete(sa.alerter.lastAlert, podName)
+	delete(sa.alerter.escalations, podName)
+	sa.alertHistory.clear(podName, AlertReasonAnomaly, now)
+	sa.alertHistory.clear(podName, AlertReasonBottleneck, now)
+}
+
+// forgetVanishedPods 清除已没有历史样本、且不在本次采集中的Pod的分析状态，调用方需持有sa.mu写锁
+// Pod删除或重建后UID不再出现，其状态在历史超出保留时长被清理后随之释放
+func (sa *StorageAnalyzer) forgetVanishedPods(current map[string]*monitor.PodStorageMetrics, now time.Time) {
+	pods, err := sa.history.Pods()
+	if err != nil {
+		// 无法确定哪些Pod仍有历史时不清除任何状态
+		fmt.Printf("Error listing metrics history pods: %v\n", err)
+		return
+	}
+	retained := make(map[string]bool, len(pods))
+	for _, podName := range pods {
+		retained[podName] = true
+	}
+
+	vanished := make(map[string]bool)
+	for _, keys := range [][]string{
+		mapKeys(sa.podBottlenecks),
+		mapKeys(sa.anomalyDetected),
+		mapKeys(sa.anomalyStreaks),
+		mapKeys(sa.anomalyDetails),
+		mapKeys(sa.ewmaBaselines),
+		mapKeys(sa.smallIOPods),
+		mapKeys(sa.alerter.lastAlert),
+		mapKeys(sa.alerter.escalations),
+	} {
+		for _, podName := range keys {
+			if _, ok := current[podName]; !ok && !retained[podName] {
+				vanished[podName] = true
+			}
+		}
+	}
+
+	for podName := range vanished {
+		sa.clearPodState(podName, now)
+	}
+}
+
+// mapKeys 返回map的所有key
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
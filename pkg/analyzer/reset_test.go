@@ -0,0 +1,99 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// podState 返回Pod在各状态map中是否仍有记录，key为map名称
+func podState(sa *StorageAnalyzer, podKey string) map[string]bool {
+	state := make(map[string]bool)
+	if _, ok := sa.podBottlenecks[podKey]; ok {
+		state["podBottlenecks"] = true
+	}
+	if _, ok := sa.anomalyDetected[podKey]; ok {
+		state["anomalyDetected"] = true
+	}
+	if _, ok := sa.anomalyStreaks[podKey]; ok {
+		state["anomalyStreaks"] = true
+	}
+	if _, ok := sa.ewmaBaselines[podKey]; ok {
+		state["ewmaBaselines"] = true
+	}
+	if _, ok := sa.smallIOPods[podKey]; ok {
+		state["smallIOPods"] = true
+	}
+	if _, ok := sa.alerter.lastAlert[podKey]; ok {
+		state["lastAlert"] = true
+	}
+	if _, ok := sa.alerter.escalations[podKey]; ok {
+		state["escalations"] = true
+	}
+	return state
+}
+
+// slowPodMetrics 返回队列延迟超过阈值的Pod指标，会产生瓶颈告警
+func slowPodMetrics(uid, name string) *monitor.PodStorageMetrics {
+	return &monitor.PodStorageMetrics{
+		PodName:      name,
+		PodUID:       uid,
+		Namespace:    "default",
+		ReadLatency:  20_000_000,
+		WriteLatency: 20_000_000,
+		QueueLatency: 15_000_000,
+		ReadIOPS:     100,
+		WriteIOPS:    100,
+		Timestamp:    time.Now(),
+	}
+}
+
+func TestRecreatedPodStateIsForgotten(t *testing.T) {
+	sa := NewStorageAnalyzer(WithHistoryRetention(time.Millisecond), WithEWMA(0.3))
+
+	// StatefulSet重启后Pod名称不变，UID不同
+	sa.AddMetrics(map[string]*monitor.PodStorageMetrics{"uid-old": slowPodMetrics("uid-old", "db-0")})
+	if state := podState(sa, "uid-old"); !state["podBottlenecks"] || !state["lastAlert"] || !state["escalations"] {
+		t.Fatalf("state after first sample = %v, want bottleneck and alert state", state)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	sa.lastPrune = time.Time{}
+	sa.AddMetrics(map[string]*monitor.PodStorageMetrics{"uid-new": slowPodMetrics("uid-new", "db-0")})
+
+	if state := podState(sa, "uid-old"); len(state) > 0 {
+		t.Errorf("state of the deleted pod was not removed: %v", state)
+	}
+	if state := podState(sa, "uid-new"); !state["podBottlenecks"] {
+		t.Errorf("state of the current pod = %v, want it kept", state)
+	}
+}
+
+func TestPodStateKeptWhileHistoryRetained(t *testing.T) {
+	sa := NewStorageAnalyzer()
+
+	sa.AddMetrics(map[string]*monitor.PodStorageMetrics{"uid-a": slowPodMetrics("uid-a", "a")})
+	sa.lastPrune = time.Time{}
+	sa.AddMetrics(map[string]*monitor.PodStorageMetrics{"uid-b": slowPodMetrics("uid-b", "b")})
+
+	// uid-a这次没有采集到，但历史仍在保留时长内
+	if state := podState(sa, "uid-a"); !state["podBottlenecks"] {
+		t.Errorf("state of a pod with retained history = %v, want it kept", state)
+	}
+}
+
+func TestResetPod(t *testing.T) {
+	sa := NewStorageAnalyzer()
+	sa.AddMetrics(map[string]*monitor.PodStorageMetrics{"uid-a": slowPodMetrics("uid-a", "a")})
+
+	if err := sa.ResetPod("uid-a"); err != nil {
+		t.Fatalf("ResetPod() error = %v", err)
+	}
+	if state := podState(sa, "uid-a"); len(state) > 0 {
+		t.Errorf("state after ResetPod = %v, want none", state)
+	}
+	if history := sa.GetPodHistory("uid-a", time.Hour); history != nil {
+		t.Errorf("history after ResetPod = %d samples, want none", len(history))
+	}
+}
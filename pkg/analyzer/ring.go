@@ -0,0 +1,102 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// historyRingMinCap 未限制样本数时环形缓冲区的初始容量
+const historyRingMinCap = 8
+
+// historyRing 按时间顺序保存一个Pod的样本，达到容量上限后覆盖最旧的样本，不再重新分配底层数组
+// max不大于0时不限制样本数，缓冲区写满后按倍数扩容
+type historyRing struct {
+	samples []*monitor.PodStorageMetrics
+	start   int // 最旧样本在samples中的位置
+	size    int
+	max     int
+}
+
+// newHistoryRing 创建最多保存max个样本的环形缓冲区
+func newHistoryRing(max int) *historyRing {
+	capacity := max
+	if capacity <= 0 {
+		capacity = historyRingMinCap
+	}
+	return &historyRing{
+		samples: make([]*monitor.PodStorageMetrics, capacity),
+		max:     max,
+	}
+}
+
+// len 返回样本数
+func (r *historyRing) len() int {
+	return r.size
+}
+
+// at 返回第i旧的样本，i为0时为最旧的样本
+func (r *historyRing) at(i int) *monitor.PodStorageMetrics {
+	return r.samples[(r.start+i)%len(r.samples)]
+}
+
+// push 追加一个样本，已达到上限时覆盖最旧的样本
+func (r *historyRing) push(metrics *monitor.PodStorageMetrics) {
+	if r.size == len(r.samples) {
+		if r.max > 0 {
+			r.samples[r.start] = metrics
+			r.start = (r.start + 1) % len(r.samples)
+			return
+		}
+		r.grow()
+	}
+	r.samples[(r.start+r.size)%len(r.samples)] = metrics
+	r.size++
+}
+
+// grow 将容量扩大一倍，并把样本按顺序移到新数组的开头
+func (r *historyRing) grow() {
+	samples := make([]*monitor.PodStorageMetrics, 2*len(r.samples))
+	r.copyTo(samples, 0)
+	r.samples = samples
+	r.start = 0
+}
+
+// search 返回第一个不早于since的样本位置，样本都早于since时返回len
+func (r *historyRing) search(since time.Time) int {
+	return sort.Search(r.size, func(i int) bool {
+		return !r.at(i).Timestamp.Before(since)
+	})
+}
+
+// since 按时间升序返回从第from旧的样本开始的所有样本
+func (r *historyRing) since(from int) []*monitor.PodStorageMetrics {
+	result := make([]*monitor.PodStorageMetrics, r.size-from)
+	r.copyTo(result, from)
+	return result
+}
+
+// copyTo 将从第from旧的样本开始的样本按顺序复制到dst
+func (r *historyRing) copyTo(dst []*monitor.PodStorageMetrics, from int) {
+	if from >= r.size {
+		return
+	}
+	begin := (r.start + from) % len(r.samples)
+	end := begin + r.size - from
+	if end <= len(r.samples) {
+		copy(dst, r.samples[begin:end])
+		return
+	}
+	n := copy(dst, r.samples[begin:])
+	copy(dst[n:], r.samples[:end-len(r.samples)])
+}
+
+// dropOldest 丢弃最旧的n个样本，并清除引用以便回收
+func (r *historyRing) dropOldest(n int) {
+	for i := 0; i < n; i++ {
+		r.samples[(r.start+i)%len(r.samples)] = nil
+	}
+	r.start = (r.start + n) % len(r.samples)
+	r.size -= n
+}
@@ -0,0 +1,193 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// newRingWith 返回依次追加了ReadIOPS为values的样本的环形缓冲区
+func newRingWith(max int, values ...uint64) *historyRing {
+	r := newHistoryRing(max)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, v := range values {
+		r.push(sampleAt(start.Add(time.Duration(v)*time.Minute), v))
+	}
+	return r
+}
+
+// ringValues 按时间顺序返回缓冲区中样本的ReadIOPS
+func ringValues(r *historyRing) []uint64 {
+	values := make([]uint64, 0, r.len())
+	for i := 0; i < r.len(); i++ {
+		values = append(values, r.at(i).ReadIOPS)
+	}
+	return values
+}
+
+func TestHistoryRingOverwritesOldest(t *testing.T) {
+	r := newRingWith(4, 0, 1, 2, 3, 4, 5)
+
+	if got := ringValues(r); !reflect.DeepEqual(got, []uint64{2, 3, 4, 5}) {
+		t.Errorf("samples = %v, want [2 3 4 5]", got)
+	}
+	if len(r.samples) != 4 {
+		t.Errorf("capacity = %d after overwriting, want 4", len(r.samples))
+	}
+	if r.start != 2 {
+		t.Errorf("start = %d, want 2", r.start)
+	}
+}
+
+func TestHistoryRingGrowsWhenUnbounded(t *testing.T) {
+	values := make([]uint64, historyRingMinCap+3)
+	for i := range values {
+		values[i] = uint64(i)
+	}
+	r := newRingWith(0, values...)
+
+	if got := ringValues(r); !reflect.DeepEqual(got, values) {
+		t.Errorf("samples = %v, want %v", got, values)
+	}
+	if len(r.samples) != 2*historyRingMinCap {
+		t.Errorf("capacity = %d, want %d", len(r.samples), 2*historyRingMinCap)
+	}
+
+	// 环绕后扩容仍保持时间顺序
+	r = newRingWith(0, 0, 1, 2, 3, 4, 5, 6, 7)
+	r.dropOldest(3)
+	for v := uint64(8); v < 12; v++ {
+		r.push(sampleAt(time.Time{}, v))
+	}
+	if got := ringValues(r); !reflect.DeepEqual(got, []uint64{3, 4, 5, 6, 7, 8, 9, 10, 11}) {
+		t.Errorf("samples after wraparound and grow = %v", got)
+	}
+}
+
+func TestHistoryRingCopyTo(t *testing.T) {
+	// 容量为4，写入6个样本后start为2，样本跨越数组末尾
+	wrapped := newRingWith(4, 0, 1, 2, 3, 4, 5)
+	// 未环绕：start为0
+	linear := newRingWith(4, 0, 1, 2)
+
+	tests := []struct {
+		name string
+		ring *historyRing
+		from int
+		want []uint64
+	}{
+		{"wrapped from oldest", wrapped, 0, []uint64{2, 3, 4, 5}},
+		{"wrapped from before the boundary", wrapped, 1, []uint64{3, 4, 5}},
+		{"wrapped from the boundary", wrapped, 2, []uint64{4, 5}},
+		{"wrapped from after the boundary", wrapped, 3, []uint64{5}},
+		{"wrapped from len", wrapped, 4, []uint64{}},
+		{"linear from oldest", linear, 0, []uint64{0, 1, 2}},
+		{"linear from middle", linear, 2, []uint64{2}},
+	}
+	for _, tt := range tests {
+		if got := readIOPS(tt.ring.since(tt.from)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: since(%d) = %v, want %v", tt.name, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryRingDropOldest(t *testing.T) {
+	// start为2，丢弃3个样本跨越数组末尾
+	r := newRingWith(4, 0, 1, 2, 3, 4, 5)
+	r.dropOldest(3)
+
+	if got := ringValues(r); !reflect.DeepEqual(got, []uint64{5}) {
+		t.Errorf("samples = %v, want [5]", got)
+	}
+	if r.start != 1 {
+		t.Errorf("start = %d, want 1", r.start)
+	}
+	// 被丢弃的样本不再被引用
+	for i, metrics := range r.samples {
+		if i != r.start && metrics != nil {
+			t.Errorf("samples[%d] still references a dropped sample", i)
+		}
+	}
+
+	// 丢弃后继续写入并再次环绕
+	for v := uint64(6); v < 10; v++ {
+		r.push(sampleAt(time.Time{}, v))
+	}
+	if got := ringValues(r); !reflect.DeepEqual(got, []uint64{6, 7, 8, 9}) {
+		t.Errorf("samples after refilling = %v, want [6 7 8 9]", got)
+	}
+}
+
+func TestHistoryRingSearch(t *testing.T) {
+	r := newRingWith(4, 0, 1, 2, 3, 4, 5)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		since time.Time
+		want  int
+	}{
+		{time.Time{}, 0},
+		{start.Add(2 * time.Minute), 0},
+		{start.Add(3*time.Minute - time.Second), 1},
+		{start.Add(5 * time.Minute), 3},
+		{start.Add(time.Hour), 4},
+	}
+	for _, tt := range tests {
+		if got := r.search(tt.since); got != tt.want {
+			t.Errorf("search(%v) = %d, want %d", tt.since, got, tt.want)
+		}
+	}
+}
+
+// sliceHistory 环形缓冲区之前的实现：追加到切片末尾，超出上限时重新切片
+type sliceHistory map[string][]*monitor.PodStorageMetrics
+
+func (h sliceHistory) append(podName string, metrics *monitor.PodStorageMetrics, max int) {
+	history := append(h[podName], metrics)
+	if len(history) > max {
+		history = history[len(history)-max:]
+	}
+	h[podName] = history
+}
+
+// BenchmarkHistoryAppend 比较重新切片和环形缓冲区追加样本的分配，样本本身不复制，只统计历史结构的分配
+func BenchmarkHistoryAppend(b *testing.B) {
+	const pods, maxPerPod = 1000, 100
+
+	podNames := make([]string, pods)
+	for i := range podNames {
+		podNames[i] = fmt.Sprintf("uid-%d", i)
+	}
+	metrics := sampleAt(time.Now(), 1)
+
+	b.Run("slice", func(b *testing.B) {
+		h := make(sliceHistory, pods)
+		// 先写满，只测量稳定状态下的追加
+		for i := 0; i < pods*maxPerPod; i++ {
+			h.append(podNames[i%pods], metrics, maxPerPod)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			h.append(podNames[i%pods], metrics, maxPerPod)
+		}
+	})
+
+	b.Run("ring", func(b *testing.B) {
+		h := make(map[string]*historyRing, pods)
+		for _, podName := range podNames {
+			h[podName] = newHistoryRing(maxPerPod)
+		}
+		for i := 0; i < pods*maxPerPod; i++ {
+			h[podNames[i%pods]].push(metrics)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			h[podNames[i%pods]].push(metrics)
+		}
+	})
+}
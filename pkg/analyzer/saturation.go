@@ -0,0 +1,47 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// DeviceUtilizationThreshold 判定块设备饱和的默认利用率，可通过WithDeviceUtilizationThreshold覆盖
+const DeviceUtilizationThreshold = 0.9
+
+// WithDeviceUtilizationThreshold 设置判定块设备饱和的利用率（0-1]
+func WithDeviceUtilizationThreshold(threshold float64) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if threshold > 0 && threshold <= 1 {
+			sa.deviceUtilizationThreshold = threshold
+		}
+	}
+}
+
+// AddDeviceMetrics 根据最新的块设备指标更新设备饱和状态，设备刚进入饱和时输出警告
+// 本次没有出现的设备不再视为饱和
+func (sa *StorageAnalyzer) AddDeviceMetrics(devices []*monitor.DeviceMetrics) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	saturated := make(map[string]bool, len(devices))
+	for _, device := range devices {
+		if device.Utilization < sa.deviceUtilizationThreshold {
+			continue
+		}
+		saturated[device.Device] = true
+		if !sa.saturatedDevices[device.Device] {
+			fmt.Printf("Warning: block device %s (%s) is saturated, utilization %.0f%%\n",
+				device.Device, device.Name, device.Utilization*100)
+		}
+	}
+	sa.saturatedDevices = saturated
+}
+
+// IsDeviceSaturated 判断块设备（major:minor）最近一次的利用率是否超过饱和阈值
+func (sa *StorageAnalyzer) IsDeviceSaturated(device string) bool {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	return sa.saturatedDevices[device]
+}
@@ -0,0 +1,166 @@
+package analyzer
+
+import (
+	"math"
+	"sort"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// SlowScore 表示慢Pod排序的评分方式
+type SlowScore string
+
+const (
+	// SlowScoreLatency 只按延迟排序，不考虑Pod的I/O量
+	SlowScoreLatency SlowScore = "latency"
+	// SlowScoreWeighted 按延迟乘以IOPS排序，即Pod每秒等待I/O的总时间，反映慢I/O的实际影响
+	SlowScoreWeighted SlowScore = "weighted"
+)
+
+// Valid 判断评分方式是否受支持
+func (s SlowScore) Valid() bool {
+	switch s {
+	case SlowScoreLatency, SlowScoreWeighted:
+		return true
+	}
+	return false
+}
+
+// RankStat 表示慢Pod排名如何合并最近多个样本的评分
+type RankStat string
+
+const (
+	// RankStatMean 取最近样本评分的平均值
+	RankStatMean RankStat = "mean"
+	// RankStatP95 取最近样本评分的p95，比平均值更关注持续偏高的延迟
+	RankStatP95 RankStat = "p95"
+)
+
+// Valid 判断统计方式是否受支持
+func (s RankStat) Valid() bool {
+	switch s {
+	case RankStatMean, RankStatP95:
+		return true
+	}
+	return false
+}
+
+// WithTopSlowWindow 慢Pod排名使用每个Pod最近k个样本评分的统计值，而不是只看最新样本，避免一个周期的尖峰改变排名
+// 默认为1，即只看最新样本；历史不足k个样本的Pod使用已有的全部样本，k超过最大历史记录数时以后者为准
+func WithTopSlowWindow(k int) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if k > 0 {
+			sa.topSlowWindow = k
+		}
+	}
+}
+
+// WithTopSlowStat 设置合并最近样本评分的统计方式，默认为RankStatMean，只在WithTopSlowWindow大于1时生效
+func WithTopSlowStat(stat RankStat) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if stat.Valid() {
+			sa.topSlowStat = stat
+		}
+	}
+}
+
+// ScoreFunc 根据Pod的最新指标计算慢Pod评分，评分越高排名越靠前
+type ScoreFunc func(metrics *monitor.PodStorageMetrics) float64
+
+// ScoreFunc 返回按by选择读、写或读写合计的评分函数
+func (s SlowScore) ScoreFunc(by LatencyRankBy) ScoreFunc {
+	weighted := s == SlowScoreWeighted
+	return func(metrics *monitor.PodStorageMetrics) float64 {
+		read := float64(metrics.ReadLatency)
+		write := float64(metrics.WriteLatency)
+		if weighted {
+			read *= float64(metrics.ReadIOPS)
+			write *= float64(metrics.WriteIOPS)
+		}
+
+		switch by {
+		case LatencyRankByRead:
+			return read
+		case LatencyRankByWrite:
+			return write
+		default:
+			return read + write
+		}
+	}
+}
+
+// GetTopNSlowPodsScored 按自定义评分获取match返回true的Pod中评分最高的N个，match为nil时不过滤
+// 评分为最近topSlowWindow个样本评分的统计值，返回的是各Pod的最新指标；最新指标已过期的Pod不参与排名
+func (sa *StorageAnalyzer) GetTopNSlowPodsScored(n int, match func(*monitor.PodStorageMetrics) bool, score ScoreFunc) []*monitor.PodStorageMetrics {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	type podScore struct {
+		score   float64
+		metrics *monitor.PodStorageMetrics
+	}
+
+	var scores []podScore
+
+	// 获取每个Pod的最新指标
+	for _, podName := range sa.historyPods() {
+		history := sa.recentHistory(podName)
+		if len(history) == 0 {
+			continue
+		}
+
+		latestMetrics := history[len(history)-1]
+		if match != nil && !match(latestMetrics) {
+			continue
+		}
+		// 过期的指标不代表Pod当前的延迟
+		if latestMetrics.Stale {
+			continue
+		}
+
+		scores = append(scores, podScore{
+			score:   sa.windowScore(history, score),
+			metrics: latestMetrics,
+		})
+	}
+
+	// 按评分排序
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].score > scores[j].score
+	})
+
+	// 获取前N个
+	result := make([]*monitor.PodStorageMetrics, 0, n)
+	for i := 0; i < n && i < len(scores); i++ {
+		result = append(result, scores[i].metrics)
+	}
+
+	return result
+}
+
+// windowScore 计算history中最近topSlowWindow个样本评分的统计值，history按时间升序排列且不为空
+func (sa *StorageAnalyzer) windowScore(history []*monitor.PodStorageMetrics, score ScoreFunc) float64 {
+	if len(history) > sa.topSlowWindow {
+		history = history[len(history)-sa.topSlowWindow:]
+	}
+	if len(history) == 1 {
+		return score(history[0])
+	}
+
+	values := make([]float64, len(history))
+	for i, metrics := range history {
+		values[i] = score(metrics)
+	}
+
+	if sa.topSlowStat == RankStatP95 {
+		// 最近秩法，样本较少时即为最大值
+		sort.Float64s(values)
+		return values[int(math.Ceil(0.95*float64(len(values))))-1]
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -2,6 +2,9 @@ package analyzer
 
 import (
 	"fmt"
+	"math"
+	"net/http"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -9,13 +12,23 @@ import (
 	"github.com/lizhongxuan/ioeye/pkg/monitor"
 )
 
-// LatencyThreshold 定义I/O延迟阈值（纳秒）
+// LatencyThreshold 定义默认的I/O延迟阈值（纳秒），可通过With*LatencyThreshold选项覆盖
 const (
 	ReadLatencyThreshold  = 10 * 1000 * 1000 // 10ms
 	WriteLatencyThreshold = 20 * 1000 * 1000 // 20ms
 	QueueLatencyThreshold = 5 * 1000 * 1000  // 5ms
 )
 
+// ContentionIOPSThreshold 判定设备整体过载的默认读写IOPS之和，可通过WithContentionIOPSThreshold覆盖
+const ContentionIOPSThreshold = 1000
+
+// contentionMinRatio 判定过载时第二高的延迟组件至少达到最高组件的比例，低于该比例视为最高组件的瓶颈
+// 本地盘的网络延迟总为0，因此以前两名的比例而不是占三者之和的比例判断
+const contentionMinRatio = 0.5
+
+// minAnomalySamples 异常检测所需的最少样本数，样本不足时无法建立可靠的基线
+const minAnomalySamples = 10
+
 // BottleneckType 表示瓶颈类型
 type BottleneckType string
 
@@ -24,27 +37,145 @@ const (
 	BottleneckTypeQueue   BottleneckType = "queue"
 	BottleneckTypeDisk    BottleneckType = "disk"
 	BottleneckTypeNetwork BottleneckType = "network"
-	BottleneckTypeUnknown BottleneckType = "unknown"
+	// BottleneckTypeContention 延迟高但没有单一组件占主导，且IOPS很高，说明设备整体过载
+	BottleneckTypeContention BottleneckType = "contention"
+	BottleneckTypeUnknown    BottleneckType = "unknown"
+)
+
+// BottleneckTypes 所有瓶颈类型
+var BottleneckTypes = []BottleneckType{
+	BottleneckTypeNone, BottleneckTypeQueue, BottleneckTypeDisk,
+	BottleneckTypeNetwork, BottleneckTypeContention, BottleneckTypeUnknown,
+}
+
+// Valid 判断是否为已知的瓶颈类型
+func (t BottleneckType) Valid() bool {
+	return slices.Contains(BottleneckTypes, t)
+}
+
+// BottleneckContributions 队列、磁盘、网络延迟各自占三者之和的比例，三者都为0时均为0
+type BottleneckContributions struct {
+	Queue   float64 `json:"queue"`
+	Disk    float64 `json:"disk"`
+	Network float64 `json:"network"`
+}
+
+// BottleneckAnalysis 瓶颈分类结果
+type BottleneckAnalysis struct {
+	Type BottleneckType
+	// Confidence 分类的置信度（0-1），为占主导的延迟组件领先第二名的幅度，即1-第二名/第一名；
+	// contention为前两名接近的程度，即第二名/第一名；没有占主导组件的none和unknown为0
+	Confidence    float64
+	Contributions BottleneckContributions
+}
+
+// LatencyRankBy 表示慢Pod排序所依据的延迟
+type LatencyRankBy string
+
+const (
+	LatencyRankByRead  LatencyRankBy = "read"
+	LatencyRankByWrite LatencyRankBy = "write"
+	LatencyRankByTotal LatencyRankBy = "total"
 )
 
+// MetricKind 表示趋势分析所针对的指标
+type MetricKind string
+
+const (
+	MetricKindLatency         MetricKind = "latency" // 读写总延迟
+	MetricKindReadIOPS        MetricKind = "read-iops"
+	MetricKindWriteIOPS       MetricKind = "write-iops"
+	MetricKindReadThroughput  MetricKind = "read-throughput"
+	MetricKindWriteThroughput MetricKind = "write-throughput"
+)
+
+// Valid 判断是否为支持的指标类型
+func (k MetricKind) Valid() bool {
+	_, ok := k.value(&monitor.PodStorageMetrics{})
+	return ok
+}
+
+// value 从Pod指标中取出该类指标的值，ok为false表示不支持的指标类型
+func (k MetricKind) value(metrics *monitor.PodStorageMetrics) (v uint64, ok bool) {
+	switch k {
+	case MetricKindLatency:
+		return metrics.ReadLatency + metrics.WriteLatency, true
+	case MetricKindReadIOPS:
+		return metrics.ReadIOPS, true
+	case MetricKindWriteIOPS:
+		return metrics.WriteIOPS, true
+	case MetricKindReadThroughput:
+		return metrics.ReadThroughput, true
+	case MetricKindWriteThroughput:
+		return metrics.WriteThroughput, true
+	}
+	return 0, false
+}
+
 // StorageAnalyzer 存储性能分析器
 type StorageAnalyzer struct {
-	mu               sync.RWMutex
-	metricsHistory   map[string][]*monitor.PodStorageMetrics
-	maxHistoryPerPod int
-	podBottlenecks   map[string]BottleneckType
-	anomalyDetected  map[string]bool
-	anomalyThreshold float64 // 异常检测阈值
+	mu                         sync.RWMutex
+	history                    HistoryStore
+	maxHistoryPerPod           int
+	lastPrune                  time.Time // 上次清理历史的时间，受mu保护
+	podBottlenecks             map[string]BottleneckAnalysis
+	anomalyDetected            map[string]bool
+	anomalyThreshold           float64 // 异常检测阈值
+	anomalyStreaks             map[string]AnomalyStreak
+	anomalyDetails             map[string]AnomalyDetail // 各Pod最新一次异常检测的基线和z分数
+	sustainedAnomalySamples    int                      // 进入或解除异常所需的连续样本数
+	ewmaAlpha                  float64                  // EWMA平滑系数，为0时使用简单平均
+	ewmaBaselines              map[string]*ewmaBaseline
+	readLatencyThreshold       uint64  // 读延迟阈值（纳秒）
+	writeLatencyThreshold      uint64  // 写延迟阈值（纳秒）
+	queueLatencyThreshold      uint64  // 队列延迟阈值（纳秒）
+	contentionIOPSThreshold    uint64  // 判定设备过载的读写IOPS之和
+	deviceUtilizationThreshold float64 // 判定块设备饱和的利用率
+	saturatedDevices           map[string]bool
+	smallIOSizeThreshold       uint64 // 判定I/O过小的平均请求大小（字节）
+	smallIOPods                map[string]bool
+	topSlowWindow              int      // 慢Pod排名使用的最近样本数
+	topSlowStat                RankStat // 合并最近样本评分的统计方式
+	persistence                persistence
+	alerter                    alerter
+	alertHistory               alertHistory
 }
 
 // NewStorageAnalyzer 创建新的存储性能分析器
 func NewStorageAnalyzer(options ...func(*StorageAnalyzer)) *StorageAnalyzer {
 	sa := &StorageAnalyzer{
-		metricsHistory:   make(map[string][]*monitor.PodStorageMetrics),
-		maxHistoryPerPod: 100, // 默认每个Pod保存100个历史数据点
-		podBottlenecks:   make(map[string]BottleneckType),
-		anomalyDetected:  make(map[string]bool),
-		anomalyThreshold: 2.0, // 默认标准差阈值
+		maxHistoryPerPod:           100, // 默认每个Pod保存100个历史数据点
+		podBottlenecks:             make(map[string]BottleneckAnalysis),
+		anomalyDetected:            make(map[string]bool),
+		anomalyThreshold:           2.0, // 默认标准差阈值
+		anomalyStreaks:             make(map[string]AnomalyStreak),
+		anomalyDetails:             make(map[string]AnomalyDetail),
+		sustainedAnomalySamples:    1, // 默认每个样本独立判定
+		ewmaBaselines:              make(map[string]*ewmaBaseline),
+		readLatencyThreshold:       ReadLatencyThreshold,
+		writeLatencyThreshold:      WriteLatencyThreshold,
+		queueLatencyThreshold:      QueueLatencyThreshold,
+		contentionIOPSThreshold:    ContentionIOPSThreshold,
+		deviceUtilizationThreshold: DeviceUtilizationThreshold,
+		saturatedDevices:           make(map[string]bool),
+		smallIOSizeThreshold:       SmallIOSizeThreshold,
+		smallIOPods:                make(map[string]bool),
+		topSlowWindow:              1, // 默认只看最新样本
+		topSlowStat:                RankStatMean,
+		persistence: persistence{
+			interval:  time.Minute,    // 默认每分钟快照一次
+			retention: 24 * time.Hour, // 默认保留24小时内的数据
+		},
+		alerter: alerter{
+			cooldown:  5 * time.Minute, // 默认同一Pod 5分钟内只告警一次
+			lastAlert: make(map[string]time.Time),
+			client:    &http.Client{Timeout: 5 * time.Second},
+
+			escalationWindow: DefaultAlertEscalationWindow,
+			criticalAfter:    DefaultAlertCriticalAfter,
+			escalations:      make(map[string]*alertEscalation),
+		},
+		alertHistory: newAlertHistory(DefaultAlertHistorySize),
 	}
 
 	// 应用选项
@@ -52,10 +183,15 @@ func NewStorageAnalyzer(options ...func(*StorageAnalyzer)) *StorageAnalyzer {
 		option(sa)
 	}
 
+	// 未通过WithHistoryStore指定存储时使用进程内存储
+	if sa.history == nil {
+		sa.history = NewMemoryHistoryStore(sa.maxHistoryPerPod)
+	}
+
 	return sa
 }
 
-// WithMaxHistoryPerPod 设置每个Pod的最大历史记录数
+// WithMaxHistoryPerPod 设置每个Pod的最大历史记录数，同时限制分析时使用的样本数
 func WithMaxHistoryPerPod(max int) func(*StorageAnalyzer) {
 	return func(sa *StorageAnalyzer) {
 		if max > 0 {
@@ -73,73 +209,108 @@ func WithAnomalyThreshold(threshold float64) func(*StorageAnalyzer) {
 	}
 }
 
-// AddMetrics 添加新的指标数据
+// WithReadLatencyThreshold 设置判定高读延迟的阈值（纳秒）
+func WithReadLatencyThreshold(threshold uint64) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if threshold > 0 {
+			sa.readLatencyThreshold = threshold
+		}
+	}
+}
+
+// WithWriteLatencyThreshold 设置判定高写延迟的阈值（纳秒）
+func WithWriteLatencyThreshold(threshold uint64) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if threshold > 0 {
+			sa.writeLatencyThreshold = threshold
+		}
+	}
+}
+
+// WithQueueLatencyThreshold 设置判定队列瓶颈的队列延迟阈值（纳秒）
+func WithQueueLatencyThreshold(threshold uint64) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if threshold > 0 {
+			sa.queueLatencyThreshold = threshold
+		}
+	}
+}
+
+// WithContentionIOPSThreshold 设置判定设备整体过载的读写IOPS之和
+func WithContentionIOPSThreshold(threshold uint64) func(*StorageAnalyzer) {
+	return func(sa *StorageAnalyzer) {
+		if threshold > 0 {
+			sa.contentionIOPSThreshold = threshold
+		}
+	}
+}
+
+// AddMetrics 添加新的指标数据，metrics与StorageMonitor.GetAllMetrics一样以Pod的key（UID，没有UID时为名称）为key
+// 分析器按该key保存历史和分析状态，各查询方法的podName参数同样为该key
 func (sa *StorageAnalyzer) AddMetrics(metrics map[string]*monitor.PodStorageMetrics) {
 	sa.mu.Lock()
-	defer sa.mu.Unlock()
+
+	var alerts []Alert
+	now := time.Now()
 
 	// 添加新数据
 	for podName, podMetrics := range metrics {
+		prevBottleneck := BottleneckTypeNone
+		if prev, hasPrev := sa.podBottlenecks[podName]; hasPrev {
+			prevBottleneck = prev.Type
+		}
+		prevAnomaly := sa.anomalyDetected[podName]
+
 		// 深拷贝指标
 		metricsCopy := *podMetrics
 
-		// 添加到历史记录
-		sa.metricsHistory[podName] = append(sa.metricsHistory[podName], &metricsCopy)
-
-		// 如果超出历史记录限制，则删除最旧的记录
-		if len(sa.metricsHistory[podName]) > sa.maxHistoryPerPod {
-			sa.metricsHistory[podName] = sa.metricsHistory[podName][1:]
+		// 添加到历史记录，超出历史记录限制时由存储删除最旧的记录
+		if err := sa.history.Append(podName, &metricsCopy); err != nil {
+			fmt.Printf("Error appending metrics history for pod %s: %v\n", podName, err)
 		}
 
 		// 分析瓶颈
 		sa.podBottlenecks[podName] = sa.analyzeBottleneck(podMetrics)
 
-		// 检测异常
-		sa.anomalyDetected[podName] = sa.detectAnomaly(podName)
-	}
-}
+		// 检测异常，持续超过阈值才进入异常状态
+		sa.anomalyDetected[podName] = sa.updateAnomaly(podName, sa.detectAnomaly(podName))
 
-// GetTopNSlowPods 获取延迟最高的N个Pod
-func (sa *StorageAnalyzer) GetTopNSlowPods(n int) []*monitor.PodStorageMetrics {
-	sa.mu.RLock()
-	defer sa.mu.RUnlock()
+		// 检测以小请求为主、可能受益于批量I/O的Pod
+		sa.smallIOPods[podName] = sa.detectSmallIO(podMetrics)
 
-	type podLatency struct {
-		podName string
-		latency uint64 // 总延迟（读+写）
-		metrics *monitor.PodStorageMetrics
+		// 记录告警历史，并在进入异常或瓶颈状态时告警
+		sa.recordAlertTransitions(&metricsCopy, prevBottleneck, prevAnomaly, now)
+		if alert, ok := sa.checkAlert(&metricsCopy, prevBottleneck, prevAnomaly, now); ok {
+			alerts = append(alerts, alert)
+		}
 	}
 
-	var latencies []podLatency
+	// 清理超出保留时长的历史，已消失的Pod的历史和分析状态一并清除
+	sa.pruneHistory(metrics, now)
 
-	// 获取每个Pod的最新指标
-	for podName, history := range sa.metricsHistory {
-		if len(history) == 0 {
-			continue
-		}
+	sa.mu.Unlock()
 
-		latestMetrics := history[len(history)-1]
-		totalLatency := latestMetrics.ReadLatency + latestMetrics.WriteLatency
+	// 在锁外分发告警，避免处理函数阻塞分析器
+	sa.dispatchAlerts(alerts)
+}
 
-		latencies = append(latencies, podLatency{
-			podName: podName,
-			latency: totalLatency,
-			metrics: latestMetrics,
-		})
-	}
+// GetTopNSlowPods 获取延迟最高的N个Pod
+func (sa *StorageAnalyzer) GetTopNSlowPods(n int) []*monitor.PodStorageMetrics {
+	return sa.GetTopNSlowPodsBy(n, LatencyRankByTotal)
+}
 
-	// 按延迟排序
-	sort.Slice(latencies, func(i, j int) bool {
-		return latencies[i].latency > latencies[j].latency
-	})
+// GetTopNSlowPodsBy 按读延迟、写延迟或总延迟获取延迟最高的N个Pod
+func (sa *StorageAnalyzer) GetTopNSlowPodsBy(n int, by LatencyRankBy) []*monitor.PodStorageMetrics {
+	return sa.GetTopNSlowPodsInNamespace(n, by, "")
+}
 
-	// 获取前N个
-	result := make([]*monitor.PodStorageMetrics, 0, n)
-	for i := 0; i < n && i < len(latencies); i++ {
-		result = append(result, latencies[i].metrics)
+// GetTopNSlowPodsInNamespace 获取指定命名空间内延迟最高的N个Pod，namespace为空时不限命名空间
+func (sa *StorageAnalyzer) GetTopNSlowPodsInNamespace(n int, by LatencyRankBy, namespace string) []*monitor.PodStorageMetrics {
+	var match func(*monitor.PodStorageMetrics) bool
+	if namespace != "" {
+		match = func(metrics *monitor.PodStorageMetrics) bool { return metrics.Namespace == namespace }
 	}
-
-	return result
+	return sa.GetTopNSlowPodsScored(n, match, SlowScoreLatency.ScoreFunc(by))
 }
 
 // GetBottleneckType 获取Pod的瓶颈类型
@@ -152,7 +323,21 @@ func (sa *StorageAnalyzer) GetBottleneckType(podName string) BottleneckType {
 		return BottleneckTypeUnknown
 	}
 
-	return bottleneck
+	return bottleneck.Type
+}
+
+// GetBottleneckAnalysis 获取Pod的瓶颈类型、分类置信度和各延迟组件的占比
+// 没有数据的Pod返回unknown和0置信度
+func (sa *StorageAnalyzer) GetBottleneckAnalysis(podName string) (BottleneckType, float64, BottleneckContributions) {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	bottleneck, exists := sa.podBottlenecks[podName]
+	if !exists {
+		return BottleneckTypeUnknown, 0, BottleneckContributions{}
+	}
+
+	return bottleneck.Type, bottleneck.Confidence, bottleneck.Contributions
 }
 
 // HasAnomalyDetected 检查Pod是否检测到异常
@@ -168,13 +353,52 @@ func (sa *StorageAnalyzer) HasAnomalyDetected(podName string) bool {
 	return anomaly
 }
 
+// GetPodHistory 获取Pod在最近since时间内记录的指标，按时间升序排列
+// 窗口受maxHistoryPerPod限制；Pod没有任何历史记录时返回nil
+func (sa *StorageAnalyzer) GetPodHistory(podName string, since time.Duration) []*monitor.PodStorageMetrics {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	history, err := sa.history.Query(podName, time.Now().Add(-since))
+	if err != nil {
+		fmt.Printf("Error querying metrics history for pod %s: %v\n", podName, err)
+		return nil
+	}
+	if history == nil {
+		return nil
+	}
+
+	// 外部存储不限制样本数，只保留窗口内最新的maxHistoryPerPod条
+	if len(history) > sa.maxHistoryPerPod {
+		history = history[len(history)-sa.maxHistoryPerPod:]
+	}
+
+	result := make([]*monitor.PodStorageMetrics, 0, len(history))
+	for _, metrics := range history {
+		// 返回副本而非原始对象
+		metricsCopy := *metrics
+		result = append(result, &metricsCopy)
+	}
+
+	return result
+}
+
 // GetLatencyTrend 获取Pod的延迟趋势
 func (sa *StorageAnalyzer) GetLatencyTrend(podName string, duration time.Duration) (trend string, change float64, err error) {
+	return sa.GetMetricTrend(podName, MetricKindLatency, duration)
+}
+
+// GetMetricTrend 获取Pod指定指标在duration时间范围内的趋势
+func (sa *StorageAnalyzer) GetMetricTrend(podName string, metric MetricKind, duration time.Duration) (trend string, change float64, err error) {
+	if !metric.Valid() {
+		return "unknown", 0, fmt.Errorf("unsupported metric kind %q", metric)
+	}
+
 	sa.mu.RLock()
 	defer sa.mu.RUnlock()
 
-	history, exists := sa.metricsHistory[podName]
-	if !exists || len(history) < 2 {
+	history := sa.recentHistory(podName)
+	if len(history) < 2 {
 		return "unknown", 0, fmt.Errorf("insufficient data for pod %s", podName)
 	}
 
@@ -196,20 +420,20 @@ func (sa *StorageAnalyzer) GetLatencyTrend(podName string, duration time.Duratio
 		oldestInRange = history[0]
 	}
 
-	// 计算总延迟变化
-	oldTotalLatency := oldestInRange.ReadLatency + oldestInRange.WriteLatency
-	newTotalLatency := latest.ReadLatency + latest.WriteLatency
+	// 计算指标变化
+	oldValue, _ := metric.value(oldestInRange)
+	newValue, _ := metric.value(latest)
 
-	// 没有初始延迟的情况
-	if oldTotalLatency == 0 {
-		if newTotalLatency > 0 {
+	// 没有初始值的情况
+	if oldValue == 0 {
+		if newValue > 0 {
 			return "increased", 100, nil
 		}
 		return "stable", 0, nil
 	}
 
 	// 计算变化百分比
-	changePercent := (float64(newTotalLatency) - float64(oldTotalLatency)) / float64(oldTotalLatency) * 100
+	changePercent := (float64(newValue) - float64(oldValue)) / float64(oldValue) * 100
 
 	// 确定趋势
 	if changePercent > 10 {
@@ -222,38 +446,90 @@ func (sa *StorageAnalyzer) GetLatencyTrend(podName string, duration time.Duratio
 
 // 内部方法
 
-// analyzeBottleneck 分析存储瓶颈
-func (sa *StorageAnalyzer) analyzeBottleneck(metrics *monitor.PodStorageMetrics) BottleneckType {
-	// 首先检查是否有明显瓶颈
-	if metrics.QueueLatency > QueueLatencyThreshold &&
-		metrics.QueueLatency > metrics.DiskLatency &&
-		metrics.QueueLatency > metrics.NetworkLatency {
-		return BottleneckTypeQueue
-	}
+// analyzeBottleneck 分析存储瓶颈，并根据占主导的延迟组件领先其他组件的幅度给出置信度
+func (sa *StorageAnalyzer) analyzeBottleneck(metrics *monitor.PodStorageMetrics) BottleneckAnalysis {
+	// 取读写两个方向中较差的一个，避免一个方向的积压被另一个方向平均掉
+	// 汇总字段兼容没有读写拆分的历史数据
+	queueLatency := max(metrics.QueueLatency, metrics.ReadQueueLatency, metrics.WriteQueueLatency)
+	diskLatency := max(metrics.DiskLatency, metrics.ReadDiskLatency, metrics.WriteDiskLatency)
 
-	if metrics.DiskLatency > metrics.QueueLatency &&
-		metrics.DiskLatency > metrics.NetworkLatency {
-		return BottleneckTypeDisk
+	analysis := BottleneckAnalysis{
+		Contributions: latencyContributions(queueLatency, diskLatency, metrics.NetworkLatency),
 	}
 
-	if metrics.NetworkLatency > metrics.QueueLatency &&
-		metrics.NetworkLatency > metrics.DiskLatency {
-		return BottleneckTypeNetwork
+	highLatency := metrics.ReadLatency > sa.readLatencyThreshold ||
+		metrics.WriteLatency > sa.writeLatencyThreshold
+	winner, runnerUp := topTwo(queueLatency, diskLatency, metrics.NetworkLatency)
+
+	// 首先检查是否有明显瓶颈
+	switch {
+	case highLatency && winner > 0 &&
+		metrics.ReadIOPS+metrics.WriteIOPS >= sa.contentionIOPSThreshold &&
+		float64(runnerUp) >= float64(winner)*contentionMinRatio:
+		// 延迟高、各组件都不占主导且IOPS很高，设备只是被打满
+		analysis.Type = BottleneckTypeContention
+		analysis.Confidence = 1 - dominance(winner, runnerUp)
+	case queueLatency > sa.queueLatencyThreshold &&
+		queueLatency > diskLatency &&
+		queueLatency > metrics.NetworkLatency:
+		analysis.Type = BottleneckTypeQueue
+		analysis.Confidence = dominance(queueLatency, max(diskLatency, metrics.NetworkLatency))
+	case diskLatency > queueLatency &&
+		diskLatency > metrics.NetworkLatency:
+		analysis.Type = BottleneckTypeDisk
+		analysis.Confidence = dominance(diskLatency, max(queueLatency, metrics.NetworkLatency))
+	case metrics.NetworkLatency > queueLatency &&
+		metrics.NetworkLatency > diskLatency:
+		analysis.Type = BottleneckTypeNetwork
+		analysis.Confidence = dominance(metrics.NetworkLatency, max(queueLatency, diskLatency))
+	case highLatency:
+		// 没有明显瓶颈但存在高延迟
+		analysis.Type = BottleneckTypeUnknown
+	default:
+		analysis.Type = BottleneckTypeNone
 	}
 
-	// 如果没有明显瓶颈但存在高延迟
-	if metrics.ReadLatency > ReadLatencyThreshold ||
-		metrics.WriteLatency > WriteLatencyThreshold {
-		return BottleneckTypeUnknown
+	return analysis
+}
+
+// dominance 返回最大组件领先第二名的幅度（0-1），两者相等时为0，其他组件都为0时为1
+func dominance(winner, runnerUp uint64) float64 {
+	if winner == 0 {
+		return 0
 	}
+	return 1 - float64(runnerUp)/float64(winner)
+}
 
-	return BottleneckTypeNone
+// topTwo 返回三个延迟中的最大值和第二大的值
+func topTwo(a, b, c uint64) (uint64, uint64) {
+	values := []uint64{a, b, c}
+	sort.Slice(values, func(i, j int) bool { return values[i] > values[j] })
+	return values[0], values[1]
+}
+
+// latencyContributions 计算队列、磁盘、网络延迟各自占三者之和的比例
+func latencyContributions(queueLatency, diskLatency, networkLatency uint64) BottleneckContributions {
+	total := float64(queueLatency) + float64(diskLatency) + float64(networkLatency)
+	if total == 0 {
+		return BottleneckContributions{}
+	}
+	return BottleneckContributions{
+		Queue:   float64(queueLatency) / total,
+		Disk:    float64(diskLatency) / total,
+		Network: float64(networkLatency) / total,
+	}
 }
 
-// detectAnomaly 检测Pod存储性能异常
+// detectAnomaly 检测Pod存储性能异常，启用EWMA时以EWMA基线计算z分数，否则使用全部历史数据的简单平均
+// 使用的基线和z分数记录在anomalyDetails中，样本不足时清除，调用方需持有sa.mu写锁
 func (sa *StorageAnalyzer) detectAnomaly(podName string) bool {
-	history, exists := sa.metricsHistory[podName]
-	if !exists || len(history) < 10 { // 需要足够的历史数据
+	if sa.ewmaAlpha > 0 {
+		return sa.detectAnomalyEWMA(podName)
+	}
+
+	history := sa.recentHistory(podName)
+	if len(history) < minAnomalySamples { // 需要足够的历史数据
+		delete(sa.anomalyDetails, podName)
 		return false
 	}
 
@@ -275,15 +551,25 @@ func (sa *StorageAnalyzer) detectAnomaly(podName string) bool {
 		sumSqDiffWrite += diffWrite * diffWrite
 	}
 
-	stdDevRead := sumSqDiffRead / float64(len(history))
-	stdDevWrite := sumSqDiffWrite / float64(len(history))
+	stdDevRead := math.Sqrt(sumSqDiffRead / float64(len(history)))
+	stdDevWrite := math.Sqrt(sumSqDiffWrite / float64(len(history)))
 
 	// 获取最新指标
 	latest := history[len(history)-1]
 
 	// 检查是否超过标准差阈值
-	readZScore := (float64(latest.ReadLatency) - avgRead) / stdDevRead
-	writeZScore := (float64(latest.WriteLatency) - avgWrite) / stdDevWrite
+	readZScore := zScore(float64(latest.ReadLatency), avgRead, stdDevRead)
+	writeZScore := zScore(float64(latest.WriteLatency), avgWrite, stdDevWrite)
+	sa.anomalyDetails[podName] = AnomalyDetail{
+		ReadMean:    avgRead,
+		ReadStdDev:  stdDevRead,
+		ReadZScore:  readZScore,
+		WriteMean:   avgWrite,
+		WriteStdDev: stdDevWrite,
+		WriteZScore: writeZScore,
+		Threshold:   sa.anomalyThreshold,
+		Samples:     len(history),
+	}
 
 	// 如果任一延迟超过阈值
 	if readZScore > sa.anomalyThreshold || writeZScore > sa.anomalyThreshold {
@@ -292,3 +578,12 @@ func (sa *StorageAnalyzer) detectAnomaly(podName string) bool {
 
 	return false
 }
+
+// zScore 计算value相对于均值和标准差的z分数
+// 标准差为0（历史数据恒定）时返回0，避免产生+Inf/NaN
+func zScore(value, mean, stdDev float64) float64 {
+	if stdDev == 0 {
+		return 0
+	}
+	return (value - mean) / stdDev
+}
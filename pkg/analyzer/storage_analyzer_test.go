@@ -0,0 +1,256 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+const testPodKey = "uid-test"
+
+// addLatency 以读写延迟均为latency的样本调用一次AddMetrics
+func addLatency(sa *StorageAnalyzer, latency uint64) {
+	sa.AddMetrics(map[string]*monitor.PodStorageMetrics{
+		testPodKey: {
+			PodName:      "test",
+			PodUID:       testPodKey,
+			Namespace:    "default",
+			ReadLatency:  latency,
+			WriteLatency: latency,
+			Timestamp:    time.Now(),
+		},
+	})
+}
+
+func TestDetectAnomalyConstantSeries(t *testing.T) {
+	sa := NewStorageAnalyzer()
+
+	for i := 0; i < 30; i++ {
+		addLatency(sa, 1_000_000)
+		if sa.HasAnomalyDetected(testPodKey) {
+			t.Fatalf("sample %d: constant latency detected as anomaly", i)
+		}
+	}
+
+	// 标准差为0时z分数为0，而不是NaN或+Inf
+	detail, err := sa.GetAnomalyDetail(testPodKey)
+	if err != nil {
+		t.Fatalf("GetAnomalyDetail() error = %v", err)
+	}
+	if detail.ReadZScore != 0 || detail.WriteZScore != 0 || detail.ReadStdDev != 0 {
+		t.Errorf("detail = %+v, want zero z-scores and standard deviation", detail)
+	}
+}
+
+func TestDetectAnomalySingleSpike(t *testing.T) {
+	sa := NewStorageAnalyzer()
+
+	// 1ms上下小幅波动的基线
+	for i := 0; i < 20; i++ {
+		addLatency(sa, 1_000_000+uint64(i%3)*50_000)
+	}
+	if sa.HasAnomalyDetected(testPodKey) {
+		t.Fatal("baseline detected as anomaly")
+	}
+
+	addLatency(sa, 10_000_000)
+	if !sa.HasAnomalyDetected(testPodKey) {
+		detail, _ := sa.GetAnomalyDetail(testPodKey)
+		t.Fatalf("spike not detected as anomaly, detail = %+v", detail)
+	}
+
+	// 尖峰之后恢复正常
+	addLatency(sa, 1_000_000)
+	if sa.HasAnomalyDetected(testPodKey) {
+		t.Error("anomaly not cleared after latency returned to the baseline")
+	}
+}
+
+func TestDetectAnomalyNeedsEnoughSamples(t *testing.T) {
+	sa := NewStorageAnalyzer()
+
+	for i := 0; i < minAnomalySamples-2; i++ {
+		addLatency(sa, 1_000_000)
+	}
+	addLatency(sa, 50_000_000)
+	if sa.HasAnomalyDetected(testPodKey) {
+		t.Errorf("anomaly detected with only %d samples", minAnomalySamples-1)
+	}
+	if _, err := sa.GetAnomalyDetail(testPodKey); err == nil {
+		t.Error("GetAnomalyDetail() returned a baseline before enough samples")
+	}
+}
+
+func TestDetectAnomalyGradualRise(t *testing.T) {
+	sa := NewStorageAnalyzer()
+
+	// 线性上升时最新样本相对于全部样本的z分数趋近于sqrt(3)≈1.73，低于默认阈值2
+	for i := 0; i < 100; i++ {
+		addLatency(sa, 1_000_000+uint64(i)*20_000)
+		if sa.HasAnomalyDetected(testPodKey) {
+			detail, _ := sa.GetAnomalyDetail(testPodKey)
+			t.Fatalf("sample %d: gradual rise detected as anomaly, detail = %+v", i, detail)
+		}
+	}
+
+	// 缓慢上升不是异常，但应体现在趋势中
+	trend, change, err := sa.GetLatencyTrend(testPodKey, time.Hour)
+	if err != nil {
+		t.Fatalf("GetLatencyTrend() error = %v", err)
+	}
+	if trend != "increased" {
+		t.Errorf("GetLatencyTrend() = %s (%.1f%%), want increased", trend, change)
+	}
+}
+
+func TestLatencyThresholdsChangeClassification(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics monitor.PodStorageMetrics
+		option  func(*StorageAnalyzer)
+		want    BottleneckType
+	}{
+		{
+			// 队列延迟占主导但低于默认的5ms
+			name:    "queue threshold",
+			metrics: monitor.PodStorageMetrics{ReadLatency: 3_000_000, WriteLatency: 3_000_000, QueueLatency: 2_000_000, DiskLatency: 500_000},
+			option:  WithQueueLatencyThreshold(1_000_000),
+			want:    BottleneckTypeQueue,
+		},
+		{
+			// 没有延迟分解，读延迟低于默认的10ms
+			name:    "read threshold",
+			metrics: monitor.PodStorageMetrics{ReadLatency: 5_000_000},
+			option:  WithReadLatencyThreshold(2_000_000),
+			want:    BottleneckTypeUnknown,
+		},
+		{
+			// 没有延迟分解，写延迟低于默认的20ms
+			name:    "write threshold",
+			metrics: monitor.PodStorageMetrics{WriteLatency: 15_000_000},
+			option:  WithWriteLatencyThreshold(10_000_000),
+			want:    BottleneckTypeUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewStorageAnalyzer().analyzeBottleneck(&tt.metrics).Type; got != BottleneckTypeNone {
+				t.Fatalf("default thresholds classified as %s, want %s", got, BottleneckTypeNone)
+			}
+			if got := NewStorageAnalyzer(tt.option).analyzeBottleneck(&tt.metrics).Type; got != tt.want {
+				t.Errorf("lowered threshold classified as %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetThresholdsAppliesToNextSample(t *testing.T) {
+	sa := NewStorageAnalyzer()
+	metrics := &monitor.PodStorageMetrics{
+		PodUID:       testPodKey,
+		ReadLatency:  3_000_000,
+		WriteLatency: 3_000_000,
+		QueueLatency: 2_000_000,
+		DiskLatency:  500_000,
+		Timestamp:    time.Now(),
+	}
+
+	sa.AddMetrics(map[string]*monitor.PodStorageMetrics{testPodKey: metrics})
+	if got := sa.GetBottleneckType(testPodKey); got != BottleneckTypeNone {
+		t.Fatalf("GetBottleneckType() = %s with default thresholds, want %s", got, BottleneckTypeNone)
+	}
+
+	thresholds := sa.Thresholds()
+	thresholds.QueueLatency = 1_000_000
+	if err := sa.SetThresholds(thresholds); err != nil {
+		t.Fatalf("SetThresholds() error = %v", err)
+	}
+	sa.AddMetrics(map[string]*monitor.PodStorageMetrics{testPodKey: metrics})
+	if got := sa.GetBottleneckType(testPodKey); got != BottleneckTypeQueue {
+		t.Errorf("GetBottleneckType() = %s after lowering the queue threshold, want %s", got, BottleneckTypeQueue)
+	}
+}
+
+func TestAnalyzeBottleneckContention(t *testing.T) {
+	const ms = 1_000_000
+
+	tests := []struct {
+		name    string
+		metrics monitor.PodStorageMetrics
+		want    BottleneckType
+	}{
+		{
+			name:    "balanced components under high load",
+			metrics: monitor.PodStorageMetrics{ReadLatency: 15 * ms, QueueLatency: 5 * ms, DiskLatency: 6 * ms, NetworkLatency: 4 * ms, ReadIOPS: 2000, WriteIOPS: 1000},
+			want:    BottleneckTypeContention,
+		},
+		{
+			name:    "balanced read/write split components under high write latency",
+			metrics: monitor.PodStorageMetrics{WriteLatency: 25 * ms, WriteQueueLatency: 6 * ms, WriteDiskLatency: 7 * ms, ReadIOPS: 500, WriteIOPS: 1500},
+			want:    BottleneckTypeContention,
+		},
+		{
+			name:    "queue dominates",
+			metrics: monitor.PodStorageMetrics{ReadLatency: 15 * ms, QueueLatency: 12 * ms, DiskLatency: 2 * ms, ReadIOPS: 2000, WriteIOPS: 1000},
+			want:    BottleneckTypeQueue,
+		},
+		{
+			name:    "disk dominates",
+			metrics: monitor.PodStorageMetrics{ReadLatency: 15 * ms, QueueLatency: 1 * ms, DiskLatency: 12 * ms, ReadIOPS: 2000, WriteIOPS: 1000},
+			want:    BottleneckTypeDisk,
+		},
+		{
+			name:    "network dominates",
+			metrics: monitor.PodStorageMetrics{ReadLatency: 15 * ms, DiskLatency: 2 * ms, NetworkLatency: 12 * ms, ReadIOPS: 2000, WriteIOPS: 1000},
+			want:    BottleneckTypeNetwork,
+		},
+		{
+			// IOPS不高时按最大的组件判定
+			name:    "balanced components under low load",
+			metrics: monitor.PodStorageMetrics{ReadLatency: 15 * ms, QueueLatency: 5 * ms, DiskLatency: 6 * ms, NetworkLatency: 4 * ms, ReadIOPS: 100, WriteIOPS: 100},
+			want:    BottleneckTypeDisk,
+		},
+		{
+			// 延迟不高时不是设备过载
+			name:    "balanced components with low latency",
+			metrics: monitor.PodStorageMetrics{ReadLatency: 3 * ms, WriteLatency: 3 * ms, QueueLatency: 1 * ms, DiskLatency: 1 * ms, NetworkLatency: 1 * ms, ReadIOPS: 4000, WriteIOPS: 1000},
+			want:    BottleneckTypeNone,
+		},
+		{
+			name:    "high latency without a breakdown",
+			metrics: monitor.PodStorageMetrics{ReadLatency: 15 * ms, ReadIOPS: 2000, WriteIOPS: 1000},
+			want:    BottleneckTypeUnknown,
+		},
+	}
+
+	sa := NewStorageAnalyzer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := sa.analyzeBottleneck(&tt.metrics)
+			if analysis.Type != tt.want {
+				t.Errorf("analyzeBottleneck() = %s, want %s (contributions %+v)", analysis.Type, tt.want, analysis.Contributions)
+			}
+			if analysis.Confidence < 0 || analysis.Confidence > 1 {
+				t.Errorf("confidence = %v, want within [0, 1]", analysis.Confidence)
+			}
+		})
+	}
+}
+
+func TestContentionIOPSThreshold(t *testing.T) {
+	metrics := &monitor.PodStorageMetrics{ReadLatency: 15_000_000, QueueLatency: 5_000_000, DiskLatency: 6_000_000, ReadIOPS: 300, WriteIOPS: 200}
+
+	if got := NewStorageAnalyzer().analyzeBottleneck(metrics).Type; got != BottleneckTypeDisk {
+		t.Fatalf("default threshold classified as %s, want %s", got, BottleneckTypeDisk)
+	}
+	analysis := NewStorageAnalyzer(WithContentionIOPSThreshold(500)).analyzeBottleneck(metrics)
+	if analysis.Type != BottleneckTypeContention {
+		t.Errorf("lowered IOPS threshold classified as %s, want %s", analysis.Type, BottleneckTypeContention)
+	}
+	// 两个最大组件越接近，越可能是整体过载
+	if want := 5.0 / 6.0; analysis.Confidence < want-1e-9 || analysis.Confidence > want+1e-9 {
+		t.Errorf("contention confidence = %v, want %v", analysis.Confidence, want)
+	}
+}
@@ -0,0 +1,51 @@
+package analyzer
+
+import "fmt"
+
+// Thresholds 分析器判定瓶颈和异常使用的阈值，延迟单位为纳秒
+type Thresholds struct {
+	ReadLatency  uint64  // 读延迟超过该值视为高延迟
+	WriteLatency uint64  // 写延迟超过该值视为高延迟
+	QueueLatency uint64  // 队列延迟超过该值才可能判定为队列瓶颈
+	Anomaly      float64 // 异常检测的z分数阈值
+}
+
+// Validate 检查阈值是否合法，延迟阈值和异常阈值都必须为正数
+func (t Thresholds) Validate() error {
+	if t.ReadLatency == 0 || t.WriteLatency == 0 || t.QueueLatency == 0 {
+		return fmt.Errorf("latency thresholds must be positive")
+	}
+	if t.Anomaly <= 0 {
+		return fmt.Errorf("anomaly threshold must be positive, got %v", t.Anomaly)
+	}
+	return nil
+}
+
+// Thresholds 返回当前使用的阈值
+func (sa *StorageAnalyzer) Thresholds() Thresholds {
+	sa.mu.RLock()
+	defer sa.mu.RUnlock()
+
+	return Thresholds{
+		ReadLatency:  sa.readLatencyThreshold,
+		WriteLatency: sa.writeLatencyThreshold,
+		QueueLatency: sa.queueLatencyThreshold,
+		Anomaly:      sa.anomalyThreshold,
+	}
+}
+
+// SetThresholds 在运行时修改阈值，从下一次AddMetrics开始生效，已有的瓶颈和异常状态不会立即重新判定
+func (sa *StorageAnalyzer) SetThresholds(t Thresholds) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	sa.readLatencyThreshold = t.ReadLatency
+	sa.writeLatencyThreshold = t.WriteLatency
+	sa.queueLatencyThreshold = t.QueueLatency
+	sa.anomalyThreshold = t.Anomaly
+	return nil
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/analyzer"
+)
+
+// 告警历史的API路径
+const alertsPath = "/api/v1/alerts"
+
+// 告警历史查询的limit参数默认值和上限
+const (
+	defaultAlertsLimit = 100
+	maxAlertsLimit     = 10000
+)
+
+// AlertsResponse 是告警历史的API响应格式，按开始时间从新到旧排序
+type AlertsResponse struct {
+	Timestamp time.Time    `json:"timestamp"`
+	Alerts    []*AlertInfo `json:"alerts"`
+}
+
+// AlertInfo 是单条告警记录的API响应格式
+type AlertInfo struct {
+	Cluster    string     `json:"cluster,omitempty"`
+	PodName    string     `json:"pod_name"`
+	PodUID     string     `json:"pod_uid,omitempty"`
+	Namespace  string     `json:"namespace"`
+	Reason     string     `json:"reason" description:"anomaly或bottleneck"`
+	Bottleneck string     `json:"bottleneck,omitempty" description:"reason为bottleneck时的瓶颈类型"`
+	StartTime  time.Time  `json:"start_time"`
+	ClearTime  *time.Time `json:"clear_time,omitempty" description:"恢复的时间，仍在持续时为空"`
+	Active     bool       `json:"active"`
+}
+
+// BuildAlerts 构建满足filter的最近limit条告警记录，activeOnly为true时只返回仍在持续的告警
+// storageAnalyzer为nil时返回空列表
+func BuildAlerts(storageAnalyzer *analyzer.StorageAnalyzer, filter PodFilter, activeOnly bool, limit int) *AlertsResponse {
+	response := &AlertsResponse{
+		Timestamp: time.Now(),
+		Alerts:    make([]*AlertInfo, 0),
+	}
+	if storageAnalyzer == nil {
+		return response
+	}
+
+	for _, event := range storageAnalyzer.GetRecentAlerts(0) {
+		if len(response.Alerts) >= limit {
+			break
+		}
+		if !filter.matchPod(event.Namespace, event.Cluster) || (activeOnly && !event.Active) {
+			continue
+		}
+
+		alert := &AlertInfo{
+			Cluster:    event.Cluster,
+			PodName:    event.PodName,
+			PodUID:     event.PodUID,
+			Namespace:  event.Namespace,
+			Reason:     string(event.Reason),
+			Bottleneck: string(event.Bottleneck),
+			StartTime:  event.StartTime,
+			Active:     event.Active,
+		}
+		if !event.Active {
+			clearTime := event.ClearTime
+			alert.ClearTime = &clearTime
+		}
+		response.Alerts = append(response.Alerts, alert)
+	}
+	return response
+}
+
+// handleAlerts 处理获取告警历史的请求，支持?limit=、?active=true、?namespace=和?cluster=
+func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := defaultAlertsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxAlertsLimit {
+			writeJSONError(w, fmt.Sprintf("limit must be an integer between 1 and %d", maxAlertsLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	activeOnly := false
+	if v := r.URL.Query().Get("active"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeJSONError(w, "active must be a boolean", http.StatusBadRequest)
+			return
+		}
+		activeOnly = b
+	}
+
+	response := BuildAlerts(s.storageAnalyzer, podFilterFromQuery(r), activeOnly, limit)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// healthPath 健康检查路径，供探针使用，不需要认证
+const healthPath = "/api/v1/health"
+
+// readyPath 就绪检查路径，供探针使用，不需要认证
+const readyPath = "/api/v1/ready"
+
+// WithAuthToken 要求请求携带"Authorization: Bearer <token>"头，token为空时不启用认证
+func WithAuthToken(token string) ServerOption {
+	return func(s *Server) {
+		s.authToken = token
+	}
+}
+
+// authMiddleware 校验Bearer token，缺失或不匹配时返回401
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	if s.authToken == "" {
+		return next
+	}
+
+	expected := []byte(s.authToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == healthPath || r.URL.Path == readyPath ||
+			r.URL.Path == openAPIPath || r.URL.Path == swaggerPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		// 使用常量时间比较，避免通过响应时间推测token
+		if !ok || subtle.ConstantTimeCompare([]byte(token), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ioeye"`)
+			writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/analyzer"
+)
+
+// 批量获取Pod指标的API路径
+const batchPodMetricsPath = "/api/v1/metrics/pods"
+
+// maxBatchPods 单个批量请求最多查询的Pod数量
+const maxBatchPods = 100
+
+// maxBatchBodySize 批量请求体的最大字节数，足够容纳maxBatchPods个最长的Pod名称
+const maxBatchBodySize = 64 << 10
+
+// BatchPodMetricsResponse 是批量获取Pod指标的API响应格式
+type BatchPodMetricsResponse struct {
+	Timestamp time.Time                     `json:"timestamp"`
+	Pods      map[string]*PodDetailResponse `json:"pods" description:"以Pod名称为key，与单个Pod接口的响应相同"`
+	NotFound  []string                      `json:"not_found" description:"请求中没有指标的Pod，按请求中的顺序排列"`
+}
+
+// handleBatchPodMetrics 处理批量获取Pod指标的请求，请求体为Pod名称的JSON数组
+// 每个Pod的结果与GET /api/v1/metrics/pod/{pod_name}相同，不存在的Pod列在not_found中而不是使整个请求失败
+func (s *Server) handleBatchPodMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var podNames []string
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize)).Decode(&podNames); err != nil {
+		writeJSONError(w, fmt.Sprintf("Invalid request body, expected a JSON array of pod names: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(podNames) == 0 {
+		writeJSONError(w, "At least one pod name is required", http.StatusBadRequest)
+		return
+	}
+	if len(podNames) > maxBatchPods {
+		writeJSONError(w, fmt.Sprintf("At most %d pods can be requested at once, got %d", maxBatchPods, len(podNames)), http.StatusBadRequest)
+		return
+	}
+
+	// trend参数与单个Pod接口相同，对所有Pod生效
+	metric := analyzer.MetricKindLatency
+	if v := r.URL.Query().Get("trend"); v != "" {
+		metric = analyzer.MetricKind(v)
+		if !metric.Valid() {
+			writeJSONError(w, "trend must be one of latency, read-iops, write-iops, read-throughput, write-throughput", http.StatusBadRequest)
+			return
+		}
+	}
+
+	response := &BatchPodMetricsResponse{
+		Timestamp: time.Now(),
+		Pods:      make(map[string]*PodDetailResponse, len(podNames)),
+		NotFound:  []string{},
+	}
+	freshness := s.requestFreshness(r)
+	seen := make(map[string]bool, len(podNames))
+	for _, podName := range podNames {
+		if seen[podName] {
+			continue
+		}
+		seen[podName] = true
+
+		detail, err := buildPodDetail(s.storageMonitor, s.storageAnalyzer, podName, metric, freshness)
+		if err != nil {
+			response.NotFound = append(response.NotFound, podName)
+			continue
+		}
+		response.Pods[podName] = detail
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
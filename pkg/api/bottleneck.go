@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/analyzer"
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// 按瓶颈类型筛选Pod的API路径
+const bottlenecksPath = "/api/v1/metrics/bottlenecks"
+
+// BottlenecksResponse 是按瓶颈类型筛选的Pod列表的API响应格式
+type BottlenecksResponse struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Pods      []*PodBottleneck `json:"pods" description:"按读写总延迟从高到低排序，没有满足条件的Pod时为空数组"`
+}
+
+// PodBottleneck 是单个Pod及其瓶颈分类的API响应格式
+type PodBottleneck struct {
+	PodMetrics    *PodMetrics                  `json:"pod_metrics"`
+	Bottleneck    string                       `json:"bottleneck"`
+	Confidence    float64                      `json:"confidence"`
+	Contributions *BottleneckContributionsInfo `json:"contributions"`
+}
+
+// handleGetBottlenecks 处理按瓶颈类型筛选Pod的请求
+// bottleneck参数可以是逗号分隔的多个类型，为空时返回所有Pod，支持?namespace=和?cluster=过滤
+func (s *Server) handleGetBottlenecks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	types, err := parseBottleneckTypes(r.URL.Query().Get("bottleneck"))
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := BuildBottlenecks(s.storageMonitor, s.storageAnalyzer, types, podFilterFromQuery(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// BuildBottlenecks 构建满足filter且瓶颈类型属于types的Pod列表，按读写总延迟从高到低排序
+// types为空时不按瓶颈类型过滤；storageAnalyzer为nil时没有瓶颈分类，返回空列表
+func BuildBottlenecks(storageMonitor *monitor.StorageMonitor, storageAnalyzer *analyzer.StorageAnalyzer, types map[analyzer.BottleneckType]bool, filter PodFilter) *BottlenecksResponse {
+	response := &BottlenecksResponse{
+		Timestamp: time.Now(),
+		Pods:      make([]*PodBottleneck, 0),
+	}
+	if storageAnalyzer == nil {
+		return response
+	}
+
+	for key, metrics := range storageMonitor.GetAllMetrics() {
+		if !filter.Match(metrics) {
+			continue
+		}
+		bottleneck, confidence, contributions := storageAnalyzer.GetBottleneckAnalysis(key)
+		if len(types) > 0 && !types[bottleneck] {
+			continue
+		}
+
+		response.Pods = append(response.Pods, &PodBottleneck{
+			PodMetrics: convertToPodMetrics(metrics),
+			Bottleneck: string(bottleneck),
+			Confidence: confidence,
+			Contributions: &BottleneckContributionsInfo{
+				Queue:   contributions.Queue,
+				Disk:    contributions.Disk,
+				Network: contributions.Network,
+			},
+		})
+	}
+
+	// 总延迟相同时按Pod名称排序，保证输出稳定
+	sort.Slice(response.Pods, func(i, j int) bool {
+		a, b := response.Pods[i].PodMetrics, response.Pods[j].PodMetrics
+		if totalA, totalB := a.ReadLatency+a.WriteLatency, b.ReadLatency+b.WriteLatency; totalA != totalB {
+			return totalA > totalB
+		}
+		return a.PodName < b.PodName
+	})
+
+	return response
+}
+
+// parseBottleneckTypes 解析逗号分隔的瓶颈类型，v为空时返回nil
+func parseBottleneckTypes(v string) (map[analyzer.BottleneckType]bool, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	types := make(map[analyzer.BottleneckType]bool)
+	for _, name := range strings.Split(v, ",") {
+		t := analyzer.BottleneckType(strings.TrimSpace(name))
+		if !t.Valid() {
+			return nil, fmt.Errorf("unknown bottleneck type %q, must be one of %s", t, bottleneckTypeNames())
+		}
+		types[t] = true
+	}
+	return types, nil
+}
+
+// bottleneckTypeNames 返回逗号分隔的所有瓶颈类型，用于错误信息
+func bottleneckTypeNames() string {
+	names := make([]string, 0, len(analyzer.BottleneckTypes))
+	for _, t := range analyzer.BottleneckTypes {
+		names = append(names, string(t))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// minGzipSize 响应体小于该字节数时不压缩，如健康检查，压缩收益抵不过开销
+const minGzipSize = 1024
+
+// gzipMiddleware 在客户端声明支持gzip时压缩响应体
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 响应内容随Accept-Encoding变化，告知缓存按该头区分
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer gw.Close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip 判断Accept-Encoding是否接受gzip，q=0表示明确拒绝
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		if weight, err := strconv.ParseFloat(q, 64); err == nil && weight > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter 先缓冲响应体，达到minGzipSize后才切换为gzip输出
+// 小响应在Close时原样写出
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	buf         bytes.Buffer
+	gz          *gzip.Writer
+	plain       bool // 已决定不压缩，之后的写入直接透传
+}
+
+// WriteHeader 记录状态码，真正写出推迟到确定是否压缩之后
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.status = status
+	w.wroteHeader = true
+}
+
+// Write 缓冲响应体，超过阈值时开始压缩
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	if w.plain {
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf.Write(p)
+	if w.buf.Len() < minGzipSize {
+		return len(p), nil
+	}
+
+	// 处理器已自行编码时不再压缩
+	if w.Header().Get("Content-Encoding") != "" {
+		if err := w.flushPlain(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	if err := w.startGzip(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// startGzip 设置压缩相关的响应头并写出已缓冲的数据
+func (w *gzipResponseWriter) startGzip() error {
+	header := w.Header()
+	w.setContentType()
+	header.Set("Content-Encoding", "gzip")
+	// 压缩后长度改变，由分块传输代替
+	header.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	_, err := w.gz.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// flushPlain 不压缩，原样写出状态码和已缓冲的数据
+func (w *gzipResponseWriter) flushPlain() error {
+	if w.buf.Len() > 0 {
+		w.setContentType()
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	w.plain = true
+	return err
+}
+
+// setContentType 处理器未设置Content-Type时按未压缩的内容推断
+// 否则net/http会对压缩后的数据进行推断
+func (w *gzipResponseWriter) setContentType() {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", http.DetectContentType(w.buf.Bytes()))
+	}
+}
+
+// Flush 将已缓冲的数据发送给客户端，尚未决定是否压缩时立即开始压缩，用于流式响应
+func (w *gzipResponseWriter) Flush() {
+	if w.gz == nil && !w.plain {
+		var err error
+		if w.Header().Get("Content-Encoding") != "" {
+			err = w.flushPlain()
+		} else {
+			err = w.startGzip()
+		}
+		if err != nil {
+			return
+		}
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap 返回底层的ResponseWriter，供http.ResponseController设置连接超时
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Close 结束响应，未达到压缩阈值的数据原样写出
+func (w *gzipResponseWriter) Close() error {
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	if w.plain {
+		return nil
+	}
+	return w.flushPlain()
+}
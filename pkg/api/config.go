@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/analyzer"
+)
+
+// 运行时配置的API路径
+const (
+	intervalPath   = "/api/v1/config/interval"
+	thresholdsPath = "/api/v1/config/thresholds"
+)
+
+// maxConfigBodySize 配置请求体的最大字节数
+const maxConfigBodySize = 4096
+
+// IntervalRequest 是修改采集周期的请求格式，Interval为Go时长字符串，例如"5s"
+type IntervalRequest struct {
+	Interval string `json:"interval"`
+}
+
+// IntervalResponse 是采集周期的API响应格式
+type IntervalResponse struct {
+	Interval        string    `json:"interval"`
+	IntervalSeconds float64   `json:"interval_seconds"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// handleInterval 处理采集周期请求：GET返回当前周期，PUT在运行时修改周期
+func (s *Server) handleInterval(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req IntervalRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxConfigBodySize)).Decode(&req); err != nil {
+			writeJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		interval, err := time.ParseDuration(req.Interval)
+		if err != nil {
+			writeJSONError(w, "interval must be a duration such as 5s", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.storageMonitor.SetInterval(interval); err != nil {
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	interval := s.storageMonitor.Interval()
+	response := &IntervalResponse{
+		Interval:        interval.String(),
+		IntervalSeconds: interval.Seconds(),
+		Timestamp:       time.Now(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ThresholdsRequest 是修改分析阈值的请求格式，延迟阈值为Go时长字符串，例如"10ms"
+// 省略的字段保持当前值
+type ThresholdsRequest struct {
+	ReadLatencyThreshold  string   `json:"read_latency_threshold,omitempty"`
+	WriteLatencyThreshold string   `json:"write_latency_threshold,omitempty"`
+	QueueLatencyThreshold string   `json:"queue_latency_threshold,omitempty"`
+	AnomalyThreshold      *float64 `json:"anomaly_threshold,omitempty" description:"异常检测的z分数阈值"`
+}
+
+// ThresholdsResponse 是分析阈值的API响应格式
+type ThresholdsResponse struct {
+	ReadLatencyThreshold    string    `json:"read_latency_threshold"`
+	ReadLatencyThresholdNs  uint64    `json:"read_latency_threshold_ns"`
+	WriteLatencyThreshold   string    `json:"write_latency_threshold"`
+	WriteLatencyThresholdNs uint64    `json:"write_latency_threshold_ns"`
+	QueueLatencyThreshold   string    `json:"queue_latency_threshold"`
+	QueueLatencyThresholdNs uint64    `json:"queue_latency_threshold_ns"`
+	AnomalyThreshold        float64   `json:"anomaly_threshold" description:"异常检测的z分数阈值"`
+	Timestamp               time.Time `json:"timestamp"`
+}
+
+// handleThresholds 处理分析阈值请求：GET返回当前阈值，PUT在运行时修改阈值
+func (s *Server) handleThresholds(w http.ResponseWriter, r *http.Request) {
+	if s.storageAnalyzer == nil {
+		writeJSONError(w, "Analyzer is not available", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req ThresholdsRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxConfigBodySize)).Decode(&req); err != nil {
+			writeJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		thresholds := s.storageAnalyzer.Thresholds()
+		for _, field := range []struct {
+			name  string
+			value string
+			dst   *uint64
+		}{
+			{"read_latency_threshold", req.ReadLatencyThreshold, &thresholds.ReadLatency},
+			{"write_latency_threshold", req.WriteLatencyThreshold, &thresholds.WriteLatency},
+			{"queue_latency_threshold", req.QueueLatencyThreshold, &thresholds.QueueLatency},
+		} {
+			if field.value == "" {
+				continue
+			}
+			d, err := time.ParseDuration(field.value)
+			if err != nil || d <= 0 {
+				writeJSONError(w, fmt.Sprintf("%s must be a positive duration such as 10ms", field.name), http.StatusBadRequest)
+				return
+			}
+			*field.dst = uint64(d)
+		}
+		if req.AnomalyThreshold != nil {
+			thresholds.Anomaly = *req.AnomalyThreshold
+		}
+
+		if err := s.storageAnalyzer.SetThresholds(thresholds); err != nil {
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := convertToThresholdsResponse(s.storageAnalyzer.Thresholds())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// convertToThresholdsResponse 将分析器的阈值转换为API响应结构
+func convertToThresholdsResponse(thresholds analyzer.Thresholds) *ThresholdsResponse {
+	return &ThresholdsResponse{
+		ReadLatencyThreshold:    time.Duration(thresholds.ReadLatency).String(),
+		ReadLatencyThresholdNs:  thresholds.ReadLatency,
+		WriteLatencyThreshold:   time.Duration(thresholds.WriteLatency).String(),
+		WriteLatencyThresholdNs: thresholds.WriteLatency,
+		QueueLatencyThreshold:   time.Duration(thresholds.QueueLatency).String(),
+		QueueLatencyThresholdNs: thresholds.QueueLatency,
+		AnomalyThreshold:        thresholds.Anomaly,
+		Timestamp:               time.Now(),
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// 监控控制的API路径
+const (
+	pausePath  = "/api/v1/control/pause"
+	resumePath = "/api/v1/control/resume"
+)
+
+// PauseStatusResponse 是监控暂停状态的API响应格式
+type PauseStatusResponse struct {
+	Paused    bool       `json:"paused"`
+	PausedAt  *time.Time `json:"paused_at,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+}
+
+// handlePause 处理暂停采集请求
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writePauseStatus(w, s.storageMonitor.Pause())
+}
+
+// handleResume 处理恢复采集请求
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writePauseStatus(w, s.storageMonitor.Resume())
+}
+
+// writePauseStatus 返回监控的暂停状态
+func writePauseStatus(w http.ResponseWriter, status monitor.PauseStatus) {
+	response := &PauseStatusResponse{
+		Paused:    status.Paused,
+		Timestamp: time.Now(),
+	}
+	if status.Paused {
+		response.PausedAt = &status.PausedAt
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
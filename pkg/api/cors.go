@@ -0,0 +1,81 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// CORS响应头中允许的方法和请求头，以及允许浏览器读取的响应头
+const (
+	corsAllowMethods  = "GET, POST, PUT, DELETE, OPTIONS"
+	corsAllowHeaders  = "Authorization, Content-Type, X-Request-ID"
+	corsExposeHeaders = "X-Request-ID, Retry-After, X-Metrics-Collected-At, X-Metrics-Age"
+	corsMaxAge        = "600"
+)
+
+// WithAllowedOrigins 允许这些源的浏览器页面跨域调用API，例如"https://dashboard.example.com"，"*"表示任意源
+// 为空时不输出任何CORS头，浏览器只允许同源访问
+func WithAllowedOrigins(origins []string) ServerOption {
+	return func(s *Server) {
+		s.allowedOrigins = origins
+	}
+}
+
+// ValidateAllowedOrigins 校验允许的源，源必须是"*"或不带路径的scheme://host[:port]
+func ValidateAllowedOrigins(origins []string) error {
+	for _, origin := range origins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("invalid CORS origin %q, expected scheme://host[:port]", origin)
+		}
+	}
+	return nil
+}
+
+// corsMiddleware 为允许的源设置CORS头，并直接响应预检请求
+// 需要放在authMiddleware之前，浏览器发送的预检请求不携带Authorization头
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	if len(s.allowedOrigins) == 0 {
+		return next
+	}
+
+	allowAny := slices.Contains(s.allowedOrigins, "*")
+	allowed := make(map[string]bool, len(s.allowedOrigins))
+	for _, origin := range s.allowedOrigins {
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		// 响应随Origin变化，避免缓存把一个源的响应返回给另一个源
+		w.Header().Add("Vary", "Origin")
+		if origin == "" || (!allowAny && !allowed[origin]) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if allowAny {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		w.Header().Set("Access-Control-Expose-Headers", corsExposeHeaders)
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
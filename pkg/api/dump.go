@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// 以JSON Lines格式导出分析器保存的全部历史指标的API路径
+const dumpPath = "/api/v1/metrics/dump"
+
+// dumpFlushLines 每写出多少行刷新一次，使导出边生成边发送
+const dumpFlushLines = 500
+
+// handleDumpHistory 以JSON Lines格式流式导出分析器保存的历史指标，每行一个PodMetrics样本
+// 支持since参数只导出较新的样本，以及namespace和cluster参数过滤Pod
+func (s *Server) handleDumpHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.storageAnalyzer == nil {
+		writeJSONError(w, "Metrics history is not available", http.StatusNotFound)
+		return
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		if since, err = parseDumpSince(v, time.Now()); err != nil {
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	filter := podFilterFromQuery(r)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	lines := 0
+	err := s.storageAnalyzer.DumpHistory(since, func(_ string, metrics *monitor.PodStorageMetrics) error {
+		if !filter.Match(metrics) {
+			return nil
+		}
+		if err := encoder.Encode(convertToPodMetrics(metrics)); err != nil {
+			return err
+		}
+
+		lines++
+		if lines%dumpFlushLines == 0 {
+			if flusher != nil {
+				flusher.Flush()
+			}
+			// 导出大量历史需要较长时间，每批都顺延写超时，见extendWriteDeadline
+			return s.extendWriteDeadline(w)
+		}
+		return nil
+	})
+	if err != nil {
+		// 响应头已经发出，只能中止输出
+		fmt.Printf("Error writing metrics history dump: %v\n", err)
+	}
+}
+
+// parseDumpSince 解析since参数，可以是Go duration格式的时间窗口（如1h），也可以是RFC3339格式的时间点
+func parseDumpSince(v string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(v); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("since must be a positive duration such as 1h or an RFC3339 time such as 2023-05-15T10:00:00Z")
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ErrorResponse 所有API错误响应的结构
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail 错误详情
+type ErrorDetail struct {
+	Code    string `json:"code"` // 由HTTP状态码得到，例如not_found、bad_request
+	Message string `json:"message"`
+}
+
+// writeJSONError 以JSON格式返回错误，参数与http.Error相同
+func writeJSONError(w http.ResponseWriter, message string, status int) {
+	response := ErrorResponse{
+		Error: ErrorDetail{
+			Code:    errorCode(status),
+			Message: message,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// errorCode 将HTTP状态码转换为错误码，例如404转换为not_found
+func errorCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
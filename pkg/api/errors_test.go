@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSONError(t *testing.T) {
+	tests := []struct {
+		status int
+		code   string
+	}{
+		{http.StatusBadRequest, "bad_request"},
+		{http.StatusNotFound, "not_found"},
+		{http.StatusMethodNotAllowed, "method_not_allowed"},
+		{http.StatusTooManyRequests, "too_many_requests"},
+		{http.StatusServiceUnavailable, "service_unavailable"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		writeJSONError(rec, "something went wrong", tt.status)
+
+		resp := rec.Result()
+		if resp.StatusCode != tt.status {
+			t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+		}
+		if got := resp.Header.Get("X-Content-Type-Options"); got != "nosniff" {
+			t.Errorf("X-Content-Type-Options = %q, want nosniff", got)
+		}
+		body := decodeError(t, resp)
+		if body.Error.Code != tt.code || body.Error.Message != "something went wrong" {
+			t.Errorf("%d: error = %+v, want code %s", tt.status, body.Error, tt.code)
+		}
+	}
+}
+
+func TestHandlerErrorEnvelope(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		status  int
+		code    string
+		message string
+	}{
+		{"wrong method", s.handleGetAllMetrics, http.MethodPost, "/api/v1/metrics", http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed"},
+		{"invalid limit", s.handleGetTopSlowPods, http.MethodGet, "/api/v1/metrics/topslow?limit=abc", http.StatusBadRequest, "bad_request", "limit must be an integer"},
+		{"invalid trend", s.handleGetPodMetrics, http.MethodGet, "/api/v1/metrics/pod/pod1?trend=bogus", http.StatusBadRequest, "bad_request", "trend must be one of"},
+		{"missing pod name", s.handleGetPodMetrics, http.MethodGet, "/api/v1/metrics/pod/", http.StatusBadRequest, "bad_request", "Pod name is required"},
+		{"unknown pod", s.handleGetPodMetrics, http.MethodGet, "/api/v1/metrics/pod/no-such-pod", http.StatusNotFound, "not_found", "no-such-pod"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.handler(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+			continue
+		}
+		body := decodeError(t, rec.Result())
+		if body.Error.Code != tt.code || !strings.Contains(body.Error.Message, tt.message) {
+			t.Errorf("%s: error = %+v, want code %s with message containing %q", tt.name, body.Error, tt.code, tt.message)
+		}
+	}
+}
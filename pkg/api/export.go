@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// exportCSVPath CSV导出的API路径
+const exportCSVPath = "/api/v1/metrics/export.csv"
+
+// csvFlushRows 每写出多少行刷新一次，使大集群的导出边生成边发送
+const csvFlushRows = 500
+
+// csvHeader CSV导出的列，cluster列放在最后以兼容按列位置解析的脚本
+var csvHeader = []string{
+	"namespace", "pod", "node",
+	"read_latency_ns", "write_latency_ns",
+	"queue_latency_ns", "disk_latency_ns",
+	"read_queue_latency_ns", "write_queue_latency_ns",
+	"read_disk_latency_ns", "write_disk_latency_ns",
+	"network_latency_ns",
+	"read_iops", "write_iops",
+	"read_throughput_bps", "write_throughput_bps",
+	"bottleneck", "anomaly", "timestamp",
+	"cluster",
+}
+
+// handleExportCSV 以CSV格式导出Pod指标，每个Pod一行，支持namespace和cluster查询参数过滤
+func (s *Server) handleExportCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter := podFilterFromQuery(r)
+	allPodMetrics := s.storageMonitor.GetAllMetrics()
+
+	// 按命名空间和Pod名称排序，保证输出稳定
+	podKeys := make([]string, 0, len(allPodMetrics))
+	for key, metrics := range allPodMetrics {
+		if !filter.Match(metrics) {
+			continue
+		}
+		podKeys = append(podKeys, key)
+	}
+	sort.Slice(podKeys, func(i, j int) bool {
+		a, b := allPodMetrics[podKeys[i]], allPodMetrics[podKeys[j]]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.PodName < b.PodName
+	})
+
+	now := time.Now()
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="ioeye-metrics-%s.csv"`, now.UTC().Format("20060102T150405Z")))
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+
+	for i, key := range podKeys {
+		if err := cw.Write(s.csvRecord(allPodMetrics[key])); err != nil {
+			// 响应头已经发出，只能中止输出
+			fmt.Printf("Error writing CSV export: %v\n", err)
+			return
+		}
+
+		if (i+1)%csvFlushRows == 0 {
+			cw.Flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		fmt.Printf("Error writing CSV export: %v\n", err)
+	}
+}
+
+// csvRecord 将Pod指标转换为一行CSV，没有存储分析器时瓶颈和异常列为空
+func (s *Server) csvRecord(metrics *monitor.PodStorageMetrics) []string {
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+
+	var bottleneck, anomaly string
+	if s.storageAnalyzer != nil {
+		bottleneck = string(s.storageAnalyzer.GetBottleneckType(metrics.Key()))
+		anomaly = strconv.FormatBool(s.storageAnalyzer.HasAnomalyDetected(metrics.Key()))
+	}
+
+	return []string{
+		metrics.Namespace, metrics.PodName, metrics.NodeName,
+		u(metrics.ReadLatency), u(metrics.WriteLatency),
+		u(metrics.QueueLatency), u(metrics.DiskLatency),
+		u(metrics.ReadQueueLatency), u(metrics.WriteQueueLatency),
+		u(metrics.ReadDiskLatency), u(metrics.WriteDiskLatency),
+		u(metrics.NetworkLatency),
+		u(metrics.ReadIOPS), u(metrics.WriteIOPS),
+		u(metrics.ReadThroughput), u(metrics.WriteThroughput),
+		bottleneck, anomaly, metrics.Timestamp.Format(time.RFC3339),
+		metrics.Cluster,
+	}
+}
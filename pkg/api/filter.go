@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// PodFilter 按命名空间和集群过滤Pod，字段为空时不按该字段过滤
+type PodFilter struct {
+	Namespace string
+	Cluster   string
+}
+
+// podFilterFromQuery 从namespace和cluster查询参数构造过滤条件
+func podFilterFromQuery(r *http.Request) PodFilter {
+	return PodFilter{
+		Namespace: r.URL.Query().Get("namespace"),
+		Cluster:   r.URL.Query().Get("cluster"),
+	}
+}
+
+// Match 判断Pod是否满足过滤条件
+func (f PodFilter) Match(metrics *monitor.PodStorageMetrics) bool {
+	return f.matchPod(metrics.Namespace, metrics.Cluster)
+}
+
+// matchPod 判断所在命名空间和集群是否满足过滤条件
+func (f PodFilter) matchPod(namespace, cluster string) bool {
+	if f.Namespace != "" && namespace != f.Namespace {
+		return false
+	}
+	if f.Cluster != "" && cluster != f.Cluster {
+		return false
+	}
+	return true
+}
+
+// matcher 返回传给分析器的过滤函数，没有过滤条件时返回nil
+func (f PodFilter) matcher() func(*monitor.PodStorageMetrics) bool {
+	if f == (PodFilter{}) {
+		return nil
+	}
+	return f.Match
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// 指标新鲜度响应头，尚未成功采集时不输出
+const (
+	collectedAtHeader = "X-Metrics-Collected-At" // 上次成功采集的时间，RFC3339格式
+	metricsAgeHeader  = "X-Metrics-Age"          // 距上次成功采集的秒数
+)
+
+// Freshness 是响应中数据的采集时间，嵌入到指标快照的响应中
+// 顶层的timestamp是处理请求的时间，采集停滞时仍是当前时间，客户端应根据collected_at和age_seconds判断数据是否过期
+type Freshness struct {
+	CollectedAt *time.Time `json:"collected_at,omitempty" description:"上次成功采集的时间，尚未成功采集时省略"`
+	AgeSeconds  *int64     `json:"age_seconds,omitempty" description:"处理请求时距上次成功采集的秒数，尚未成功采集时省略"`
+}
+
+// newFreshness 返回now时刻数据的新鲜度，尚未成功采集时为空
+func newFreshness(storageMonitor *monitor.StorageMonitor, now time.Time) Freshness {
+	collectedAt := storageMonitor.LastCollectionTime()
+	if collectedAt.IsZero() {
+		return Freshness{}
+	}
+	age := metricsAge(collectedAt, now)
+	return Freshness{CollectedAt: &collectedAt, AgeSeconds: &age}
+}
+
+// metricsAge 返回采集时间到now的整秒数，时钟回拨时为0
+func metricsAge(collectedAt, now time.Time) int64 {
+	age := int64(now.Sub(collectedAt) / time.Second)
+	if age < 0 {
+		return 0
+	}
+	return age
+}
+
+// reportsFreshness 判断路径是否返回采集的指标，流式接口的每个事件自带时间戳，不输出新鲜度头
+func reportsFreshness(path string) bool {
+	return strings.HasPrefix(path, "/api/v1/metrics") && path != streamPath
+}
+
+// freshnessKey 请求上下文中保存freshnessMiddleware计算的新鲜度的key
+type freshnessKey struct{}
+
+// freshnessMiddleware 为指标接口的响应添加采集时间和数据年龄头，便于仪表盘在采集停滞时识别过期数据
+// 新鲜度每个请求只计算一次并保存在请求上下文中，响应体通过requestFreshness取得相同的值，采集在请求处理期间完成时头和响应体也不会不一致
+// 位于standbyMiddleware之后，转发给活动实例的请求返回活动实例的采集时间
+func (s *Server) freshnessMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reportsFreshness(r.URL.Path) {
+			freshness := newFreshness(s.storageMonitor, time.Now())
+			if freshness.CollectedAt != nil {
+				w.Header().Set(collectedAtHeader, freshness.CollectedAt.UTC().Format(time.RFC3339))
+				w.Header().Set(metricsAgeHeader, strconv.FormatInt(*freshness.AgeSeconds, 10))
+			}
+			r = r.WithContext(context.WithValue(r.Context(), freshnessKey{}, freshness))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestFreshness 返回freshnessMiddleware为请求计算的新鲜度，请求未经过该中间件时按当前时间计算
+func (s *Server) requestFreshness(r *http.Request) Freshness {
+	if freshness, ok := r.Context().Value(freshnessKey{}).(Freshness); ok {
+		return freshness
+	}
+	return newFreshness(s.storageMonitor, time.Now())
+}
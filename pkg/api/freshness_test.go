@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/analyzer"
+	"github.com/lizhongxuan/ioeye/pkg/ebpf"
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newCollectedServer 返回已完成至少一次采集的API服务器
+func newCollectedServer(t *testing.T) *Server {
+	t.Helper()
+
+	bpfMonitor, err := ebpf.NewMonitor(ebpf.WithMockData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	storageMonitor := monitor.NewStorageMonitor(bpfMonitor, k8s.NewClientForClientset(fake.NewSimpleClientset()), monitor.WithInterval(1))
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := storageMonitor.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for storageMonitor.LastCollectionTime().IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("no collection finished")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return NewAPIServer(storageMonitor, analyzer.NewStorageAnalyzer(), "127.0.0.1:0")
+}
+
+// getFreshness 经过freshnessMiddleware请求path，返回响应和响应体中的新鲜度
+func getFreshness(t *testing.T, s *Server, path string, handler http.HandlerFunc) (*httptest.ResponseRecorder, Freshness) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	s.freshnessMiddleware(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s status = %d, want 200", path, rec.Code)
+	}
+	var body Freshness
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response of %s: %v", path, err)
+	}
+	return rec, body
+}
+
+func TestFreshnessHeadersMatchBody(t *testing.T) {
+	s := newCollectedServer(t)
+
+	for path, handler := range map[string]http.HandlerFunc{
+		"/api/v1/metrics":           s.handleGetAllMetrics,
+		"/api/v1/metrics/topslow":   s.handleGetTopSlowPods,
+		"/api/v1/metrics/anomalies": s.handleGetAnomalies,
+		"/api/v1/metrics/node":      s.handleGetNodeMetrics,
+	} {
+		rec, body := getFreshness(t, s, path, handler)
+		if body.CollectedAt == nil || body.AgeSeconds == nil {
+			t.Errorf("%s: body freshness = %+v, want collected_at and age_seconds", path, body)
+			continue
+		}
+		if got, want := rec.Header().Get(collectedAtHeader), body.CollectedAt.UTC().Format(time.RFC3339); got != want {
+			t.Errorf("%s: %s = %q, want %q from the body", path, collectedAtHeader, got, want)
+		}
+		if got, want := rec.Header().Get(metricsAgeHeader), strconv.FormatInt(*body.AgeSeconds, 10); got != want {
+			t.Errorf("%s: %s = %q, want %q from the body", path, metricsAgeHeader, got, want)
+		}
+	}
+}
+
+func TestFreshnessHeadersBeforeCollection(t *testing.T) {
+	s := newTestServer(t)
+
+	rec, body := getFreshness(t, s, "/api/v1/metrics", s.handleGetAllMetrics)
+	if body.CollectedAt != nil || body.AgeSeconds != nil {
+		t.Errorf("body freshness = %+v before any collection, want empty", body)
+	}
+	for _, header := range []string{collectedAtHeader, metricsAgeHeader} {
+		if v, ok := rec.Header()[header]; ok {
+			t.Errorf("%s = %q before any collection, want absent", header, v)
+		}
+	}
+
+	// 不返回采集指标的接口不输出新鲜度头
+	rec = httptest.NewRecorder()
+	s.freshnessMiddleware(http.HandlerFunc(s.handleVersion)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, versionPath, nil))
+	if _, ok := rec.Header()[collectedAtHeader]; ok {
+		t.Errorf("%s set on %s", collectedAtHeader, versionPath)
+	}
+}
+
+func TestRequestFreshnessComputedOnce(t *testing.T) {
+	s := newTestServer(t)
+
+	// 响应体使用中间件保存在请求上下文中的新鲜度，而不是再次读取采集时间
+	collectedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	age := int64(42)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/metrics", nil)
+	req = req.WithContext(context.WithValue(req.Context(), freshnessKey{}, Freshness{CollectedAt: &collectedAt, AgeSeconds: &age}))
+	rec := httptest.NewRecorder()
+	s.handleGetAllMetrics(rec, req)
+
+	var body Freshness
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.CollectedAt == nil || !body.CollectedAt.Equal(collectedAt) || body.AgeSeconds == nil || *body.AgeSeconds != age {
+		t.Errorf("body freshness = %+v, want the request freshness", body)
+	}
+}
@@ -0,0 +1,260 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/analyzer"
+	"github.com/lizhongxuan/ioeye/pkg/api"
+	"github.com/lizhongxuan/ioeye/pkg/api/grpc/ioeyepb"
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ServerOption 配置gRPC服务器的选项
+type ServerOption func(*Server)
+
+// Server 通过gRPC提供与REST接口一致的指标查询，与HTTP服务器共用监控器和分析器
+type Server struct {
+	ioeyepb.UnimplementedMetricsServiceServer
+
+	grpcServer      *grpclib.Server
+	storageMonitor  *monitor.StorageMonitor
+	storageAnalyzer *analyzer.StorageAnalyzer
+	address         string
+	interval        time.Duration // StreamMetrics推送快照的周期
+	authToken       string
+}
+
+// WithInterval 设置StreamMetrics推送快照的周期，通常与采集周期一致
+func WithInterval(interval time.Duration) ServerOption {
+	return func(s *Server) {
+		if interval > 0 {
+			s.interval = interval
+		}
+	}
+}
+
+// WithAuthToken 要求请求携带"authorization: Bearer <token>"元数据，token为空时不启用认证
+func WithAuthToken(token string) ServerOption {
+	return func(s *Server) {
+		s.authToken = token
+	}
+}
+
+// NewServer 创建一个新的gRPC服务器
+func NewServer(storageMonitor *monitor.StorageMonitor, storageAnalyzer *analyzer.StorageAnalyzer, address string, opts ...ServerOption) *Server {
+	if address == "" {
+		address = ":9090" // 默认监听所有接口的9090端口
+	}
+
+	s := &Server{
+		storageMonitor:  storageMonitor,
+		storageAnalyzer: storageAnalyzer,
+		address:         address,
+		interval:        10 * time.Second, // 默认10秒推送一次
+	}
+
+	// 应用选项
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Start 启动gRPC服务器，阻塞直到上下文取消
+func (s *Server) Start(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", s.address, err)
+	}
+
+	s.grpcServer = grpclib.NewServer(
+		grpclib.UnaryInterceptor(s.unaryAuthInterceptor),
+		grpclib.StreamInterceptor(s.streamAuthInterceptor),
+	)
+	ioeyepb.RegisterMetricsServiceServer(s.grpcServer, s)
+
+	// 在后台启动gRPC服务器
+	go func() {
+		if err := s.grpcServer.Serve(lis); err != nil && err != grpclib.ErrServerStopped {
+			fmt.Printf("gRPC server error: %v\n", err)
+		}
+	}()
+
+	fmt.Printf("gRPC server started on %s\n", s.address)
+
+	// 等待上下文取消信号后优雅关闭
+	<-ctx.Done()
+	s.grpcServer.GracefulStop()
+	return nil
+}
+
+// Stop 停止gRPC服务器，正在进行的流式请求会被中断
+func (s *Server) Stop() {
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
+	}
+}
+
+// GetAllMetrics 获取所有Pod的指标
+func (s *Server) GetAllMetrics(ctx context.Context, req *ioeyepb.GetAllMetricsRequest) (*ioeyepb.GetAllMetricsResponse, error) {
+	return convertAllMetrics(api.BuildAllMetrics(s.storageMonitor, s.storageAnalyzer)), nil
+}
+
+// GetPodMetrics 获取单个Pod的指标
+func (s *Server) GetPodMetrics(ctx context.Context, req *ioeyepb.GetPodMetricsRequest) (*ioeyepb.GetPodMetricsResponse, error) {
+	if req.GetPodName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "pod name is required")
+	}
+
+	// trend参数选择趋势指标，默认为延迟
+	metric := analyzer.MetricKindLatency
+	if req.GetTrend() != "" {
+		metric = analyzer.MetricKind(req.GetTrend())
+		if !metric.Valid() {
+			return nil, status.Error(codes.InvalidArgument, "trend must be one of latency, read-iops, write-iops, read-throughput, write-throughput")
+		}
+	}
+
+	detail, err := api.BuildPodDetail(s.storageMonitor, s.storageAnalyzer, req.GetPodName(), metric)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "failed to get metrics for pod %s: %v", req.GetPodName(), err)
+	}
+
+	response := &ioeyepb.GetPodMetricsResponse{
+		Timestamp:  timestamppb.New(detail.Timestamp),
+		PodMetrics: convertPodMetrics(detail.PodMetrics),
+		Bottleneck: detail.Bottleneck,
+		Anomaly:    detail.Anomaly,
+	}
+	if detail.Trend != nil {
+		response.Trend = &ioeyepb.Trend{
+			Metric:        string(detail.Trend.Metric),
+			Direction:     detail.Trend.Direction,
+			ChangePercent: detail.Trend.ChangePercent,
+			Period:        detail.Trend.Period,
+		}
+	}
+	return response, nil
+}
+
+// GetTopSlowPods 获取延迟最高的Pod
+func (s *Server) GetTopSlowPods(ctx context.Context, req *ioeyepb.GetTopSlowPodsRequest) (*ioeyepb.GetTopSlowPodsResponse, error) {
+	limit, by, err := api.ParseTopSlowQuery(int(req.GetLimit()), req.GetBy())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &ioeyepb.GetTopSlowPodsResponse{
+		Timestamp:   timestamppb.Now(),
+		TopSlowPods: convertPodMetricsList(api.BuildTopSlowPods(s.storageAnalyzer, limit, by, analyzer.SlowScoreLatency)),
+	}, nil
+}
+
+// StreamMetrics 立即推送一次所有Pod的指标快照，之后每个周期推送一次，直到客户端断开
+func (s *Server) StreamMetrics(req *ioeyepb.StreamMetricsRequest, stream ioeyepb.MetricsService_StreamMetricsServer) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := stream.Send(convertAllMetrics(api.BuildAllMetrics(s.storageMonitor, s.storageAnalyzer))); err != nil {
+			return err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// unaryAuthInterceptor 校验一元请求的Bearer token
+func (s *Server) unaryAuthInterceptor(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (interface{}, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamAuthInterceptor 校验流式请求的Bearer token
+func (s *Server) streamAuthInterceptor(srv interface{}, stream grpclib.ServerStream, info *grpclib.StreamServerInfo, handler grpclib.StreamHandler) error {
+	if err := s.authorize(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// authorize 校验请求元数据中的Bearer token，与HTTP接口使用同一个token
+func (s *Server) authorize(ctx context.Context) error {
+	if s.authToken == "" {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(value, "Bearer ")
+		// 使用常量时间比较，避免通过响应时间推测token
+		if ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.authToken)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+}
+
+// convertAllMetrics 将REST响应结构转换为protobuf消息
+func convertAllMetrics(response *api.PodMetricsResponse) *ioeyepb.GetAllMetricsResponse {
+	podMetrics := make(map[string]*ioeyepb.PodMetrics, len(response.PodMetrics))
+	for podName, metrics := range response.PodMetrics {
+		podMetrics[podName] = convertPodMetrics(metrics)
+	}
+
+	return &ioeyepb.GetAllMetricsResponse{
+		Timestamp:   timestamppb.New(response.Timestamp),
+		PodMetrics:  podMetrics,
+		TopSlowPods: convertPodMetricsList(response.TopSlowPods),
+		Bottlenecks: response.Bottlenecks,
+		Anomalies:   response.Anomalies,
+	}
+}
+
+// convertPodMetricsList 转换Pod指标列表
+func convertPodMetricsList(pods []*api.PodMetrics) []*ioeyepb.PodMetrics {
+	result := make([]*ioeyepb.PodMetrics, 0, len(pods))
+	for _, pod := range pods {
+		result = append(result, convertPodMetrics(pod))
+	}
+	return result
+}
+
+// convertPodMetrics 将REST的Pod指标转换为protobuf消息
+func convertPodMetrics(metrics *api.PodMetrics) *ioeyepb.PodMetrics {
+	return &ioeyepb.PodMetrics{
+		PodName:             metrics.PodName,
+		Namespace:           metrics.Namespace,
+		ReadLatencyNs:       metrics.ReadLatency,
+		WriteLatencyNs:      metrics.WriteLatency,
+		ReadIops:            metrics.ReadIOPS,
+		WriteIops:           metrics.WriteIOPS,
+		ReadThroughputBps:   metrics.ReadThroughput,
+		WriteThroughputBps:  metrics.WriteThroughput,
+		QueueLatencyNs:      metrics.QueueLatency,
+		DiskLatencyNs:       metrics.DiskLatency,
+		NetworkLatencyNs:    metrics.NetworkLatency,
+		Timestamp:           timestamppb.New(metrics.Timestamp),
+		ReadQueueLatencyNs:  metrics.ReadQueueLatency,
+		WriteQueueLatencyNs: metrics.WriteQueueLatency,
+		ReadDiskLatencyNs:   metrics.ReadDiskLatency,
+		WriteDiskLatencyNs:  metrics.WriteDiskLatency,
+	}
+}
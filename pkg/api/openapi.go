@@ -0,0 +1,458 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/analyzer"
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// OpenAPI文档和Swagger UI的路径，不包含指标数据，不需要认证
+const (
+	openAPIPath = "/api/v1/openapi.json"
+	swaggerPath = "/swagger"
+)
+
+// apiParam 描述一个路径或查询参数
+type apiParam struct {
+	Name        string
+	In          string // path或query
+	Description string
+	Type        string // string、integer或boolean
+	Enum        []string
+}
+
+// apiOperation 描述一个API操作，Request和Response为请求体和响应体的Go类型零值
+// OpenAPI文档中的schema由这些类型通过反射生成，修改响应结构体后文档自动更新
+type apiOperation struct {
+	Method      string
+	Path        string
+	Summary     string
+	Params      []apiParam
+	Request     interface{}
+	Response    interface{}
+	ContentType string // 响应类型，默认为application/json
+	Errors      []int  // 除200外可能返回的状态码
+	NoAuth      bool
+	// UnavailableBody 为true时503响应带有与200相同的响应体
+	UnavailableBody bool
+}
+
+// apiOperations 返回API服务器提供的所有操作，新增或修改路由时需要同步更新
+// 与Server.routes注册的路由和处理函数接受的方法由TestRoutesMatchAPIOperations校验
+func apiOperations() []apiOperation {
+	podName := apiParam{Name: "pod_name", In: "path", Description: "Pod名称或UID，多个命名空间中有同名Pod时需使用UID", Type: "string"}
+	cluster := apiParam{Name: "cluster", In: "query", Description: "只返回该集群（kubeconfig中的context名称）的数据", Type: "string"}
+
+	return []apiOperation{
+		{Method: http.MethodGet, Path: "/api/v1/metrics", Summary: "获取所有Pod的存储指标",
+			Params: []apiParam{cluster}, Response: PodMetricsResponse{}},
+		{Method: http.MethodGet, Path: "/api/v1/metrics/namespace/{namespace}", Summary: "获取命名空间内Pod的存储指标",
+			Params:   []apiParam{{Name: "namespace", In: "path", Description: "命名空间", Type: "string"}, cluster},
+			Response: PodMetricsResponse{}, Errors: []int{http.StatusBadRequest}},
+		{Method: http.MethodGet, Path: "/api/v1/metrics/pod/{pod_name}", Summary: "获取特定Pod的存储指标",
+			Params: []apiParam{podName, {Name: "trend", In: "query", Description: "趋势分析的指标，默认latency", Type: "string",
+				Enum: []string{
+					string(analyzer.MetricKindLatency), string(analyzer.MetricKindReadIOPS), string(analyzer.MetricKindWriteIOPS),
+					string(analyzer.MetricKindReadThroughput), string(analyzer.MetricKindWriteThroughput),
+				}},
+				{Name: "forecast", In: "query", Description: "预测该时长之后的总延迟，Go duration格式，例如30m，未指定时不预测", Type: "string"}},
+			Response: PodDetailResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodGet, Path: "/api/v1/metrics/pod/{pod_name}/histogram", Summary: "获取Pod的延迟直方图",
+			Params: []apiParam{podName}, Response: PodHistogramResponse{}, Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodGet, Path: "/api/v1/metrics/pod/{pod_name}/history", Summary: "获取Pod的历史指标",
+			Params:   []apiParam{podName, {Name: "since", In: "query", Description: "时间窗口，Go duration格式，默认15m", Type: "string"}},
+			Response: PodHistoryResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodDelete, Path: "/api/v1/metrics/pod/{pod_name}/history", Summary: "清除Pod的历史指标和异常、瓶颈状态",
+			Params: []apiParam{podName}, Response: PodHistoryResetResponse{}, Errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+		{Method: http.MethodGet, Path: "/api/v1/metrics/pod/{pod_name}/containers", Summary: "获取Pod中各容器的存储指标",
+			Params:   []apiParam{podName, {Name: "namespace", In: "query", Description: "Pod所在的命名空间，为空时不校验", Type: "string"}},
+			Response: PodContainersResponse{}, Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodGet, Path: "/api/v1/metrics/pod/{pod_name}/rates", Summary: "获取Pod按原始样本时间差计算的IOPS和吞吐量",
+			Params: []apiParam{podName, {Name: "mode", In: "query", Description: "计算方式，默认instantaneous", Type: "string", Enum: []string{
+				string(monitor.RateModeInstant), string(monitor.RateModeAverage),
+			}}},
+			Response: PodRatesResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodPost, Path: batchPodMetricsPath, Summary: "批量获取Pod的存储指标，请求体为Pod名称数组，最多100个",
+			Params: []apiParam{{Name: "trend", In: "query", Description: "趋势分析的指标，默认latency", Type: "string",
+				Enum: []string{
+					string(analyzer.MetricKindLatency), string(analyzer.MetricKindReadIOPS), string(analyzer.MetricKindWriteIOPS),
+					string(analyzer.MetricKindReadThroughput), string(analyzer.MetricKindWriteThroughput),
+				}}},
+			Request: []string{}, Response: BatchPodMetricsResponse{}, Errors: []int{http.StatusBadRequest}},
+		{Method: http.MethodGet, Path: "/api/v1/metrics/topslow", Summary: "获取延迟最高的Pod",
+			Params: []apiParam{
+				{Name: "limit", In: "query", Description: "返回的Pod数量，默认5，取值范围1~1000", Type: "integer"},
+				{Name: "by", In: "query", Description: "排序依据，默认total", Type: "string", Enum: []string{
+					string(analyzer.LatencyRankByRead), string(analyzer.LatencyRankByWrite), string(analyzer.LatencyRankByTotal),
+				}},
+				{Name: "score", In: "query", Description: "评分方式，latency只按延迟，weighted按延迟乘以IOPS，默认latency", Type: "string", Enum: []string{
+					string(analyzer.SlowScoreLatency), string(analyzer.SlowScoreWeighted),
+				}},
+				cluster,
+			},
+			Response: TopSlowPodsResponse{}, Errors: []int{http.StatusBadRequest}},
+		{Method: http.MethodGet, Path: "/api/v1/metrics/workload", Summary: "获取按工作负载聚合的指标",
+			Params: []apiParam{cluster}, Response: WorkloadMetricsResponse{}},
+		{Method: http.MethodGet, Path: "/api/v1/metrics/storageclass", Summary: "获取按存储类聚合的指标",
+			Params: []apiParam{cluster}, Response: StorageClassMetricsResponse{}},
+		{Method: http.MethodGet, Path: volumeMetricsPath, Summary: "获取按PVC聚合的指标，挂载多个PVC的Pod会计入每个PVC并标记为ambiguous",
+			Params:   []apiParam{{Name: "namespace", In: "query", Description: "只返回该命名空间内的PVC", Type: "string"}, cluster},
+			Response: VolumeMetricsResponse{}},
+		{Method: http.MethodGet, Path: "/api/v1/metrics/node", Summary: "获取按节点聚合的指标",
+			Params: []apiParam{cluster}, Response: NodeMetricsResponse{}},
+		{Method: http.MethodGet, Path: "/api/v1/metrics/anomalies", Summary: "获取当前被判定为异常的Pod，按严重程度从高到低排序",
+			Params:   []apiParam{{Name: "namespace", In: "query", Description: "只返回该命名空间内的Pod", Type: "string"}, cluster},
+			Response: AnomaliesResponse{}},
+		{Method: http.MethodGet, Path: bottlenecksPath, Summary: "获取瓶颈类型满足条件的Pod，按读写总延迟从高到低排序",
+			Params: []apiParam{
+				{Name: "bottleneck", In: "query", Description: "逗号分隔的瓶颈类型：none、queue、disk、network、contention、unknown，为空时返回所有Pod", Type: "string"},
+				{Name: "namespace", In: "query", Description: "只返回该命名空间内的Pod", Type: "string"},
+				cluster,
+			},
+			Response: BottlenecksResponse{}, Errors: []int{http.StatusBadRequest}},
+		{Method: http.MethodGet, Path: alertsPath, Summary: "获取最近的异常和瓶颈告警记录，按开始时间从新到旧排序",
+			Params: []apiParam{
+				{Name: "limit", In: "query", Description: "返回的记录数量，默认100，取值范围1~10000", Type: "integer"},
+				{Name: "active", In: "query", Description: "为true时只返回仍在持续的告警", Type: "boolean"},
+				{Name: "namespace", In: "query", Description: "只返回该命名空间内的Pod", Type: "string"},
+				cluster,
+			},
+			Response: AlertsResponse{}, Errors: []int{http.StatusBadRequest}},
+		{Method: http.MethodGet, Path: "/api/v1/metrics/device", Summary: "获取按块设备的指标",
+			Response: DeviceMetricsResponse{}, Errors: []int{http.StatusInternalServerError}},
+		{Method: http.MethodGet, Path: exportCSVPath, Summary: "以CSV格式导出Pod指标",
+			Params:      []apiParam{{Name: "namespace", In: "query", Description: "只导出该命名空间内的Pod", Type: "string"}, cluster},
+			ContentType: "text/csv"},
+		{Method: http.MethodGet, Path: dumpPath, Summary: "以JSON Lines格式流式导出分析器保存的全部历史指标，每行一个PodMetrics",
+			Params: []apiParam{
+				{Name: "since", In: "query", Description: "只导出不早于该时间的样本，Go duration格式的时间窗口或RFC3339时间，默认全部", Type: "string"},
+				{Name: "namespace", In: "query", Description: "只导出该命名空间内的Pod", Type: "string"},
+				cluster,
+			},
+			Response: PodMetrics{}, ContentType: "application/x-ndjson", Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodGet, Path: streamPath, Summary: "以Server-Sent Events推送Pod指标，每个事件的data为PodMetricsResponse",
+			Params:   []apiParam{{Name: "namespace", In: "query", Description: "只推送该命名空间内的Pod", Type: "string"}, cluster},
+			Response: PodMetricsResponse{}, ContentType: "text/event-stream", Errors: []int{http.StatusServiceUnavailable}},
+		{Method: http.MethodGet, Path: tracingPath, Summary: "获取按Pod跟踪的状态",
+			Response: TracingStatusResponse{}},
+		{Method: http.MethodDelete, Path: tracingPath, Summary: "恢复跟踪所有Pod",
+			Response: TracingStatusResponse{}, Errors: []int{http.StatusInternalServerError}},
+		{Method: http.MethodPost, Path: tracingPodPath + "{pod_name}", Summary: "开始跟踪Pod",
+			Params: []apiParam{podName}, Response: TracingStatusResponse{}, Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodDelete, Path: tracingPodPath + "{pod_name}", Summary: "停止跟踪Pod",
+			Params: []apiParam{podName}, Response: TracingStatusResponse{}, Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodGet, Path: intervalPath, Summary: "获取采集周期",
+			Response: IntervalResponse{}},
+		{Method: http.MethodPut, Path: intervalPath, Summary: "修改采集周期",
+			Request: IntervalRequest{}, Response: IntervalResponse{}, Errors: []int{http.StatusBadRequest}},
+		{Method: http.MethodGet, Path: thresholdsPath, Summary: "获取瓶颈和异常判定的阈值",
+			Response: ThresholdsResponse{}, Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodPut, Path: thresholdsPath, Summary: "修改瓶颈和异常判定的阈值，省略的字段保持不变",
+			Request: ThresholdsRequest{}, Response: ThresholdsResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodPost, Path: pausePath, Summary: "暂停采集",
+			Response: PauseStatusResponse{}},
+		{Method: http.MethodPost, Path: resumePath, Summary: "恢复采集",
+			Response: PauseStatusResponse{}},
+		{Method: http.MethodGet, Path: versionPath, Summary: "获取版本信息",
+			Response: VersionResponse{}},
+		{Method: http.MethodGet, Path: probesPath, Summary: "获取eBPF kprobe和tracepoint的附加状态及跟踪程序的附加错误",
+			Response: ProbesResponse{}},
+		{Method: http.MethodGet, Path: healthPath, Summary: "存活检查",
+			Response: HealthResponse{}, NoAuth: true},
+		{Method: http.MethodGet, Path: readyPath, Summary: "就绪检查",
+			Response: ReadyResponse{}, Errors: []int{http.StatusServiceUnavailable}, NoAuth: true, UnavailableBody: true},
+		{Method: http.MethodGet, Path: "/metrics", Summary: "Prometheus指标",
+			ContentType: "text/plain"},
+	}
+}
+
+// handleOpenAPI 返回OpenAPI 3文档
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// 文档只取决于路由和结构体定义，生成一次即可
+	s.openAPIOnce.Do(func() {
+		s.openAPIDoc, s.openAPIErr = json.Marshal(buildOpenAPISpec(apiOperations(), s.authToken != ""))
+	})
+	if s.openAPIErr != nil {
+		writeJSONError(w, "Failed to generate OpenAPI document", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(s.openAPIDoc)
+}
+
+// swaggerPage Swagger UI页面，静态资源从公共CDN加载
+const swaggerPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>IOEye API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "` + openAPIPath + `", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+// handleSwagger 返回Swagger UI页面
+func (s *Server) handleSwagger(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(swaggerPage))
+}
+
+// openAPISpec 根据API操作生成的OpenAPI 3文档
+type openAPISpec struct {
+	OpenAPI    string                            `json:"openapi"`
+	Info       map[string]string                 `json:"info"`
+	Paths      map[string]map[string]interface{} `json:"paths"`
+	Components map[string]interface{}            `json:"components"`
+	Security   []map[string][]string             `json:"security,omitempty"`
+}
+
+// buildOpenAPISpec 生成OpenAPI 3文档，authEnabled为true时声明Bearer认证
+func buildOpenAPISpec(ops []apiOperation, authEnabled bool) *openAPISpec {
+	gen := &schemaGenerator{schemas: make(map[string]interface{})}
+
+	spec := &openAPISpec{
+		OpenAPI: "3.0.3",
+		Info: map[string]string{
+			"title":       "IOEye API",
+			"description": "Kubernetes Pod存储性能监控API",
+			"version":     "v1",
+		},
+		Paths:      make(map[string]map[string]interface{}),
+		Components: map[string]interface{}{"schemas": gen.schemas},
+	}
+	if authEnabled {
+		spec.Components["securitySchemes"] = map[string]interface{}{
+			"bearerAuth": map[string]string{"type": "http", "scheme": "bearer"},
+		}
+		spec.Security = []map[string][]string{{"bearerAuth": {}}}
+	}
+
+	for _, op := range ops {
+		operation := map[string]interface{}{
+			"summary":   op.Summary,
+			"responses": gen.responses(op),
+		}
+
+		if len(op.Params) > 0 {
+			params := make([]interface{}, 0, len(op.Params))
+			for _, p := range op.Params {
+				schema := map[string]interface{}{"type": p.Type}
+				if len(p.Enum) > 0 {
+					schema["enum"] = p.Enum
+				}
+				params = append(params, map[string]interface{}{
+					"name":        p.Name,
+					"in":          p.In,
+					"description": p.Description,
+					"required":    p.In == "path",
+					"schema":      schema,
+				})
+			}
+			operation["parameters"] = params
+		}
+
+		if op.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": gen.schema(reflect.TypeOf(op.Request))},
+				},
+			}
+		}
+
+		if op.NoAuth && authEnabled {
+			operation["security"] = []map[string][]string{}
+		}
+
+		if spec.Paths[op.Path] == nil {
+			spec.Paths[op.Path] = make(map[string]interface{})
+		}
+		spec.Paths[op.Path][strings.ToLower(op.Method)] = operation
+	}
+
+	return spec
+}
+
+// schemaGenerator 通过反射将Go类型转换为OpenAPI schema，命名结构体放入components中复用
+type schemaGenerator struct {
+	schemas map[string]interface{}
+}
+
+// responses 生成操作的响应定义，错误响应为ErrorResponse
+func (g *schemaGenerator) responses(op apiOperation) map[string]interface{} {
+	contentType := op.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	ok := map[string]interface{}{"description": http.StatusText(http.StatusOK)}
+	if op.Response != nil {
+		ok["content"] = map[string]interface{}{
+			contentType: map[string]interface{}{"schema": g.schema(reflect.TypeOf(op.Response))},
+		}
+	} else {
+		ok["content"] = map[string]interface{}{
+			contentType: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+		}
+	}
+
+	errorContent := map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": g.schema(reflect.TypeOf(ErrorResponse{}))},
+	}
+	errorResponse := func(code int) map[string]interface{} {
+		return map[string]interface{}{"description": http.StatusText(code), "content": errorContent}
+	}
+
+	responses := map[string]interface{}{"200": ok}
+	for _, code := range op.Errors {
+		response := errorResponse(code)
+		if code == http.StatusServiceUnavailable && op.UnavailableBody {
+			response["content"] = ok["content"]
+		}
+		responses[strconv.Itoa(code)] = response
+	}
+	// 探针既不需要认证也不限流
+	if !op.NoAuth {
+		responses[strconv.Itoa(http.StatusUnauthorized)] = errorResponse(http.StatusUnauthorized)
+		responses[strconv.Itoa(http.StatusTooManyRequests)] = errorResponse(http.StatusTooManyRequests)
+	}
+
+	return responses
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema 生成Go类型对应的schema，命名结构体返回$ref引用
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+		if _, exists := g.schemas[t.Name()]; !exists {
+			// 先占位，避免自引用的类型无限递归
+			g.schemas[t.Name()] = nil
+			g.schemas[t.Name()] = g.structSchema(t)
+		}
+		return ref
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema 按encoding/json的规则生成结构体的schema：匿名嵌入的结构体字段被展开，
+// 没有omitempty的字段视为必需字段，description标签作为字段说明
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	g.collectFields(t, properties, &required)
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// collectFields 收集结构体的JSON字段
+func (g *schemaGenerator) collectFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				// 嵌入的指针为nil时其字段都不输出，不列为必需字段
+				if field.Type.Kind() == reflect.Pointer {
+					var optional []string
+					g.collectFields(embedded, properties, &optional)
+				} else {
+					g.collectFields(embedded, properties, required)
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := g.schema(field.Type)
+		if description := field.Tag.Get("description"); description != "" {
+			// $ref不能与其他属性并列，使用allOf包装
+			if _, isRef := schema["$ref"]; isRef {
+				schema = map[string]interface{}{"allOf": []interface{}{schema}}
+			}
+			schema["description"] = description
+		}
+		properties[name] = schema
+
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
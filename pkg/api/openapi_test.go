@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// undocumentedRoutes 不在OpenAPI文档中描述的路由：文档本身和Swagger UI
+var undocumentedRoutes = map[string]bool{
+	openAPIPath: true,
+	swaggerPath: true,
+}
+
+// allMethods 检查路由是否接受的请求方法
+var allMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// operationURL 将操作路径中的路径参数替换为示例值
+func operationURL(path string) string {
+	return strings.NewReplacer("{pod_name}", "test-pod", "{namespace}", "default").Replace(path)
+}
+
+// routeStatus 通过mux处理一个请求，返回匹配的路由模式和响应状态码
+// 请求的上下文已取消，流式接口不会持续推送
+func routeStatus(mux *http.ServeMux, method, url string) (string, int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(method, url, nil).WithContext(ctx)
+
+	_, pattern := mux.Handler(req)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return pattern, rec.Code
+}
+
+func TestRoutesMatchAPIOperations(t *testing.T) {
+	s := newTestServer(t)
+	mux := http.NewServeMux()
+	for _, rt := range s.routes() {
+		mux.HandleFunc(rt.pattern, rt.handler)
+	}
+
+	// 每个文档中的操作都有对应的路由，且处理函数接受该方法
+	documented := make(map[string]map[string]bool)
+	covered := make(map[string]bool)
+	for _, op := range apiOperations() {
+		url := operationURL(op.Path)
+		if documented[url] == nil {
+			documented[url] = make(map[string]bool)
+		}
+		documented[url][op.Method] = true
+
+		pattern, status := routeStatus(mux, op.Method, url)
+		if pattern == "" {
+			t.Errorf("%s %s is documented but no route is registered", op.Method, op.Path)
+			continue
+		}
+		covered[pattern] = true
+		if status == http.StatusMethodNotAllowed {
+			t.Errorf("%s %s is documented but the handler rejects the method", op.Method, op.Path)
+		}
+	}
+
+	// 每个路由都有文档
+	for _, rt := range s.routes() {
+		if !covered[rt.pattern] && !undocumentedRoutes[rt.pattern] {
+			t.Errorf("route %s is registered but not in apiOperations", rt.pattern)
+		}
+	}
+
+	// 文档中的路径不接受未描述的方法
+	for url, methods := range documented {
+		for _, method := range allMethods {
+			if methods[method] {
+				continue
+			}
+			if _, status := routeStatus(mux, method, url); status != http.StatusMethodNotAllowed {
+				t.Errorf("%s %s returned %d but the method is not in apiOperations", method, url, status)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// DebugServer 在独立端口上提供net/http/pprof性能分析接口
+// 与API服务器分开监听，不经过认证，只应在显式启用时绑定到本机或受控网络
+type DebugServer struct {
+	httpServer *http.Server
+	address    string
+}
+
+// NewDebugServer 创建一个新的调试服务器，address为空时不应启动
+func NewDebugServer(address string) *DebugServer {
+	return &DebugServer{
+		address: address,
+	}
+}
+
+// Start 启动调试服务器，阻塞直到上下文取消
+func (d *DebugServer) Start(ctx context.Context) error {
+	if d.address == "" {
+		return fmt.Errorf("debug server address is required")
+	}
+
+	// 使用独立的mux，避免pprof处理器出现在http.DefaultServeMux以外的任何地方
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	d.httpServer = &http.Server{
+		Addr:    d.address,
+		Handler: mux,
+	}
+
+	go func() {
+		if err := d.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("Debug server error: %v\n", err)
+		}
+	}()
+
+	fmt.Printf("Debug server started on %s\n", d.address)
+
+	// 等待上下文取消信号后优雅关闭
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return d.httpServer.Shutdown(shutdownCtx)
+}
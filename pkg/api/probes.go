@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// eBPF探针附加状态的API路径
+const probesPath = "/api/v1/debug/probes"
+
+// ProbesResponse 是eBPF探针附加状态的API响应格式
+type ProbesResponse struct {
+	Timestamp     time.Time         `json:"timestamp"`
+	Probes        []ProbeInfo       `json:"probes" description:"Start时尝试附加的探针，按附加顺序排列，模拟数据模式下为空"`
+	FailedTracers map[string]string `json:"failed_tracers,omitempty" description:"附加失败的eBPF跟踪程序及原因，包括附加探针之前就失败的跟踪程序"`
+}
+
+// ProbeInfo 是单个kprobe或tracepoint附加状态的API响应格式
+type ProbeInfo struct {
+	Tracer   string `json:"tracer"`
+	Type     string `json:"type" description:"kprobe、kretprobe或tracepoint"`
+	Symbol   string `json:"symbol" description:"kprobe为内核函数名，tracepoint为子系统/名称"`
+	Attached bool   `json:"attached"`
+	Error    string `json:"error,omitempty"`
+}
+
+// handleProbes 返回各eBPF探针的附加状态，用于排查没有数据的原因
+func (s *Server) handleProbes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	probes := s.storageMonitor.GetAttachedProbes()
+	response := &ProbesResponse{
+		Timestamp: time.Now(),
+		Probes:    make([]ProbeInfo, 0, len(probes)),
+	}
+	for _, probe := range probes {
+		response.Probes = append(response.Probes, ProbeInfo{
+			Tracer:   probe.Tracer,
+			Type:     probe.Type,
+			Symbol:   probe.Symbol,
+			Attached: probe.Attached,
+			Error:    probe.Error,
+		})
+	}
+	if failed := s.storageMonitor.FailedTracers(); len(failed) > 0 {
+		response.FailedTracers = failed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,463 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// promContentType Prometheus文本暴露格式的Content-Type
+const promContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// openMetricsContentType OpenMetrics文本格式的Content-Type
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// promMetric 描述一个Prometheus指标族
+type promMetric struct {
+	name    string
+	help    string
+	typ     string
+	unit    string // 只在OpenMetrics格式中输出
+	samples []promSample
+}
+
+// promSample 表示指标族中的单个样本
+type promSample struct {
+	suffix   string // 样本名相对于指标族名的后缀，如_bucket
+	labels   [][2]string
+	value    float64
+	exemplar *promExemplar // 只在OpenMetrics格式中输出
+}
+
+// promExemplar 样本附带的exemplar，用于从指标跳转到具体的Pod
+type promExemplar struct {
+	labels    [][2]string
+	value     float64
+	timestamp time.Time
+}
+
+// podGauge 描述如何从Pod指标中提取一个gauge值
+type podGauge struct {
+	name  string
+	help  string
+	value func(m *monitor.PodStorageMetrics) uint64
+}
+
+// podGauges 按Pod导出的gauge指标
+var podGauges = []podGauge{
+	{"ioeye_pod_read_latency_ns", "Average read latency of the pod in nanoseconds.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.ReadLatency }},
+	{"ioeye_pod_write_latency_ns", "Average write latency of the pod in nanoseconds.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.WriteLatency }},
+	{"ioeye_pod_read_iops", "Read operations per second of the pod.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.ReadIOPS }},
+	{"ioeye_pod_write_iops", "Write operations per second of the pod.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.WriteIOPS }},
+	{"ioeye_pod_read_throughput_bps", "Read throughput of the pod in bytes per second.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.ReadThroughput }},
+	{"ioeye_pod_write_throughput_bps", "Write throughput of the pod in bytes per second.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.WriteThroughput }},
+	{"ioeye_pod_queue_latency_ns", "Average I/O queue latency of the pod in nanoseconds.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.QueueLatency }},
+	{"ioeye_pod_disk_latency_ns", "Average disk latency of the pod in nanoseconds.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.DiskLatency }},
+	{"ioeye_pod_network_latency_ns", "Average network storage latency of the pod in nanoseconds.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.NetworkLatency }},
+}
+
+// podCounters 按Pod导出的counter指标，与podGauges中的速率不同，值自IOEye启动以来单调递增
+var podCounters = []podGauge{
+	{"ioeye_pod_read_ops_total", "Read operations of the pod since IOEye started.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.ReadOpsTotal }},
+	{"ioeye_pod_write_ops_total", "Write operations of the pod since IOEye started.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.WriteOpsTotal }},
+	{"ioeye_pod_read_bytes_total", "Bytes read by the pod since IOEye started.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.ReadBytesTotal }},
+	{"ioeye_pod_write_bytes_total", "Bytes written by the pod since IOEye started.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.WriteBytesTotal }},
+}
+
+// handlePrometheusMetrics 以Prometheus文本格式输出所有Pod的存储指标
+// 请求头Accept包含application/openmetrics-text时输出OpenMetrics格式，并附加带exemplar的延迟直方图
+func (s *Server) handlePrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// 响应格式随Accept变化
+	w.Header().Add("Vary", "Accept")
+	openMetrics := acceptsOpenMetrics(r.Header.Get("Accept"))
+
+	allPodMetrics := s.storageMonitor.GetAllMetrics()
+
+	// 按Pod名称排序，保证输出稳定；同名Pod再按key排序
+	podKeys := make([]string, 0, len(allPodMetrics))
+	for key := range allPodMetrics {
+		podKeys = append(podKeys, key)
+	}
+	sort.Slice(podKeys, func(i, j int) bool {
+		a, b := allPodMetrics[podKeys[i]], allPodMetrics[podKeys[j]]
+		if a.PodName != b.PodName {
+			return a.PodName < b.PodName
+		}
+		return podKeys[i] < podKeys[j]
+	})
+
+	families := make([]*promMetric, 0, len(podGauges)+len(podCounters)+4)
+	for _, g := range podGauges {
+		family := &promMetric{name: g.name, help: g.help, typ: "gauge"}
+		for _, key := range podKeys {
+			metrics := allPodMetrics[key]
+			family.samples = append(family.samples, promSample{
+				labels: podLabels(metrics),
+				value:  float64(g.value(metrics)),
+			})
+		}
+		families = append(families, family)
+	}
+
+	// OpenMetrics的counter指标族名不带_total后缀，样本名带_total
+	for _, c := range podCounters {
+		family := &promMetric{name: c.name, help: c.help, typ: "counter"}
+		suffix := ""
+		if openMetrics {
+			family.name = strings.TrimSuffix(c.name, "_total")
+			suffix = "_total"
+		}
+		for _, key := range podKeys {
+			metrics := allPodMetrics[key]
+			family.samples = append(family.samples, promSample{
+				suffix: suffix,
+				labels: podLabels(metrics),
+				value:  float64(c.value(metrics)),
+			})
+		}
+		families = append(families, family)
+	}
+
+	// 利用率是比例而不是整数，不放在podGauges中
+	utilization := &promMetric{
+		name: "ioeye_pod_utilization",
+		help: "Fraction of time the pod had at least one block I/O request in flight on a device.",
+		typ:  "gauge",
+	}
+	for _, key := range podKeys {
+		metrics := allPodMetrics[key]
+		utilization.samples = append(utilization.samples, promSample{
+			labels: podLabels(metrics),
+			value:  metrics.Utilization,
+		})
+	}
+	families = append(families, utilization)
+
+	if deviceMetrics, err := s.storageMonitor.GetDeviceMetrics(); err == nil {
+		families = append(families, deviceUtilizationMetric(deviceMetrics))
+	}
+
+	// 异常和瓶颈信息来自存储分析器
+	if s.storageAnalyzer != nil {
+		anomaly := &promMetric{
+			name: "ioeye_pod_anomaly",
+			help: "Whether a storage performance anomaly is detected for the pod (1 = anomaly).",
+			typ:  "gauge",
+		}
+		bottleneck := &promMetric{
+			name: "ioeye_pod_bottleneck",
+			help: "Storage bottleneck type of the pod.",
+			typ:  "gauge",
+		}
+		for _, key := range podKeys {
+			metrics := allPodMetrics[key]
+
+			var value float64
+			if s.storageAnalyzer.HasAnomalyDetected(key) {
+				value = 1
+			}
+			anomaly.samples = append(anomaly.samples, promSample{
+				labels: podLabels(metrics),
+				value:  value,
+			})
+
+			bottleneckType := s.storageAnalyzer.GetBottleneckType(key)
+			bottleneck.samples = append(bottleneck.samples, promSample{
+				labels: append(podLabels(metrics), [2]string{"type", string(bottleneckType)}),
+				value:  1,
+			})
+		}
+		families = append(families, anomaly, bottleneck)
+	}
+
+	families = append(families, collectionMetrics(s.storageMonitor.GetCollectionStats(), openMetrics)...)
+
+	// Prometheus文本格式不支持gaugehistogram，延迟直方图只在OpenMetrics格式中输出
+	if openMetrics {
+		families = append(families, s.latencyHistogramMetric(podKeys, allPodMetrics))
+	}
+
+	var buf bytes.Buffer
+	for _, family := range families {
+		writePromMetric(&buf, family, openMetrics)
+	}
+
+	contentType := promContentType
+	if openMetrics {
+		buf.WriteString("# EOF\n")
+		contentType = openMetricsContentType
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// deviceUtilizationMetric 输出各块设备至少有一个请求在处理的时间比例
+func deviceUtilizationMetric(devices []*monitor.DeviceMetrics) *promMetric {
+	family := &promMetric{
+		name: "ioeye_device_utilization",
+		help: "Fraction of time the block device had at least one traced request in flight.",
+		typ:  "gauge",
+	}
+	for _, device := range devices {
+		family.samples = append(family.samples, promSample{
+			labels: [][2]string{{"device", device.Device}, {"name", device.Name}},
+			value:  device.Utilization,
+		})
+	}
+	return family
+}
+
+// collectionMetrics 输出IOEye自身的采集耗时直方图、各阶段的失败次数和最近一次处理的Pod数
+func collectionMetrics(stats monitor.CollectionStats, openMetrics bool) []*promMetric {
+	duration := &promMetric{
+		name: "ioeye_collection_duration_seconds",
+		help: "Duration of a storage metrics collection pass.",
+		typ:  "histogram",
+		unit: "seconds",
+	}
+	var cumulative uint64
+	for i, count := range stats.DurationCounts {
+		cumulative += count
+		le := "+Inf"
+		if i < len(monitor.CollectionDurationBuckets) {
+			le = strconv.FormatFloat(monitor.CollectionDurationBuckets[i], 'g', -1, 64)
+		}
+		duration.samples = append(duration.samples, promSample{
+			suffix: "_bucket",
+			labels: [][2]string{{"le", le}},
+			value:  float64(cumulative),
+		})
+	}
+	duration.samples = append(duration.samples,
+		promSample{suffix: "_sum", value: stats.DurationSum},
+		promSample{suffix: "_count", value: float64(stats.DurationCount)},
+	)
+
+	// OpenMetrics的counter指标族名不带_total后缀，样本名带_total
+	errors := &promMetric{
+		name: "ioeye_collection_errors_total",
+		help: "Number of failed storage metrics collection passes by stage.",
+		typ:  "counter",
+	}
+	errorsSuffix := ""
+	if openMetrics {
+		errors.name = "ioeye_collection_errors"
+		errorsSuffix = "_total"
+	}
+	for _, stage := range monitor.CollectStages {
+		errors.samples = append(errors.samples, promSample{
+			suffix: errorsSuffix,
+			labels: [][2]string{{"stage", stage}},
+			value:  float64(stats.Errors[stage]),
+		})
+	}
+
+	pods := &promMetric{
+		name:    "ioeye_collection_pods",
+		help:    "Number of pods processed by the latest successful collection pass.",
+		typ:     "gauge",
+		samples: []promSample{{value: float64(stats.LastPodsCount)}},
+	}
+
+	return []*promMetric{duration, errors, pods}
+}
+
+// latencyHistogramMetric 将Pod最近一个统计窗口的读写延迟直方图转换为OpenMetrics的gaugehistogram
+// 平均延迟所在的桶附带以Pod UID为标签的exemplar
+func (s *Server) latencyHistogramMetric(podKeys []string, allPodMetrics map[string]*monitor.PodStorageMetrics) *promMetric {
+	family := &promMetric{
+		name: "ioeye_pod_io_latency_seconds",
+		help: "I/O latency distribution of the pod in the latest statistics window.",
+		typ:  "gaugehistogram",
+		unit: "seconds",
+	}
+
+	for _, key := range podKeys {
+		metrics := allPodMetrics[key]
+		hist, err := s.storageMonitor.GetLatencyHistogram(key)
+		if err != nil {
+			continue
+		}
+
+		for _, op := range []struct {
+			name       string
+			counts     []uint64
+			avgLatency uint64
+		}{
+			{"read", hist.ReadCounts, metrics.ReadLatency},
+			{"write", hist.WriteCounts, metrics.WriteLatency},
+		} {
+			labels := append(podLabels(metrics), [2]string{"op", op.name})
+			exemplarBucket := latencyBucket(hist.Bounds, op.avgLatency)
+
+			var cumulative uint64
+			for i, count := range op.counts {
+				cumulative += count
+
+				// 最后一个桶还包含超出上界的延迟，作为+Inf桶输出
+				le := "+Inf"
+				if i < len(op.counts)-1 {
+					le = strconv.FormatFloat(nsToSeconds(hist.Bounds[i]), 'g', -1, 64)
+				}
+				sample := promSample{
+					suffix: "_bucket",
+					labels: append(append([][2]string(nil), labels...), [2]string{"le", le}),
+					value:  float64(cumulative),
+				}
+
+				if metrics.PodUID != "" && count > 0 && i == exemplarBucket {
+					sample.exemplar = &promExemplar{
+						labels:    [][2]string{{"pod_uid", metrics.PodUID}},
+						value:     nsToSeconds(op.avgLatency),
+						timestamp: hist.Timestamp,
+					}
+				}
+				family.samples = append(family.samples, sample)
+			}
+
+			family.samples = append(family.samples,
+				promSample{suffix: "_gcount", labels: labels, value: float64(cumulative)},
+				promSample{suffix: "_gsum", labels: labels, value: nsToSeconds(op.avgLatency) * float64(cumulative)},
+			)
+		}
+	}
+
+	return family
+}
+
+// latencyBucket 返回延迟所在的桶，超出所有上界时归入最后一个桶
+func latencyBucket(bounds []uint64, latencyNs uint64) int {
+	for i := 0; i < len(bounds)-1; i++ {
+		if latencyNs < bounds[i] {
+			return i
+		}
+	}
+	return len(bounds) - 1
+}
+
+// nsToSeconds 将纳秒转换为秒
+func nsToSeconds(ns uint64) float64 {
+	return float64(ns) / float64(time.Second)
+}
+
+// acceptsOpenMetrics 判断Accept头是否接受OpenMetrics文本格式，q=0表示明确拒绝
+func acceptsOpenMetrics(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.ToLower(strings.TrimSpace(mediaType)) != "application/openmetrics-text" {
+			continue
+		}
+
+		for _, param := range strings.Split(params, ";") {
+			q, ok := strings.CutPrefix(strings.TrimSpace(param), "q=")
+			if !ok {
+				continue
+			}
+			if weight, err := strconv.ParseFloat(q, 64); err != nil || weight <= 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// podLabels 返回Pod指标的公共标签，监控多个集群时带有cluster标签
+func podLabels(metrics *monitor.PodStorageMetrics) [][2]string {
+	labels := [][2]string{
+		{"pod", metrics.PodName},
+		{"namespace", metrics.Namespace},
+	}
+	if metrics.Cluster != "" {
+		labels = append(labels, [2]string{"cluster", metrics.Cluster})
+	}
+	return labels
+}
+
+// writePromMetric 将一个指标族按文本暴露格式写入buf，openMetrics为true时按OpenMetrics格式输出UNIT和exemplar
+func writePromMetric(buf *bytes.Buffer, family *promMetric, openMetrics bool) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", family.name, escapePromHelp(family.help, openMetrics))
+	fmt.Fprintf(buf, "# TYPE %s %s\n", family.name, family.typ)
+	if openMetrics && family.unit != "" {
+		fmt.Fprintf(buf, "# UNIT %s %s\n", family.name, family.unit)
+	}
+
+	for _, sample := range family.samples {
+		buf.WriteString(family.name)
+		buf.WriteString(sample.suffix)
+		writePromLabels(buf, sample.labels)
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatFloat(sample.value, 'g', -1, 64))
+		if openMetrics && sample.exemplar != nil {
+			buf.WriteString(" # ")
+			writePromLabels(buf, sample.exemplar.labels)
+			buf.WriteByte(' ')
+			buf.WriteString(strconv.FormatFloat(sample.exemplar.value, 'g', -1, 64))
+			buf.WriteByte(' ')
+			buf.WriteString(strconv.FormatFloat(float64(sample.exemplar.timestamp.UnixMilli())/1000, 'f', 3, 64))
+		}
+		buf.WriteByte('\n')
+	}
+}
+
+// writePromLabels 写入标签集合，没有标签时不输出
+func writePromLabels(buf *bytes.Buffer, labels [][2]string) {
+	if len(labels) == 0 {
+		return
+	}
+	buf.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(label[0])
+		buf.WriteString(`="`)
+		buf.WriteString(escapePromLabelValue(label[1]))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('}')
+}
+
+// promLabelEscaper 转义标签值中的反斜杠、双引号和换行符
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promHelpEscaper 转义HELP文本中的反斜杠和换行符，Prometheus文本格式的HELP中双引号不转义
+var promHelpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapePromLabelValue(v string) string {
+	return promLabelEscaper.Replace(v)
+}
+
+// escapePromHelp 转义HELP文本，OpenMetrics格式与标签值一样还需转义双引号
+func escapePromHelp(v string, openMetrics bool) string {
+	if openMetrics {
+		return promLabelEscaper.Replace(v)
+	}
+	return promHelpEscaper.Replace(v)
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePromMetric(t *testing.T) {
+	// HELP和标签值中包含反斜杠、双引号和换行符
+	gauge := &promMetric{
+		name: "ioeye_test_latency",
+		help: "Path C:\\dir\nnext \"quoted\"",
+		typ:  "gauge",
+		unit: "seconds",
+		samples: []promSample{
+			{labels: [][2]string{{"pod", "a\\b\"c\nd"}, {"namespace", "default"}}, value: 1.5},
+			{value: 2},
+		},
+	}
+	counter := &promMetric{
+		name: "ioeye_test_ops",
+		help: "Operations.",
+		typ:  "counter",
+		samples: []promSample{{
+			suffix: "_total",
+			labels: [][2]string{{"stage", "k8s"}},
+			value:  3,
+			exemplar: &promExemplar{
+				labels:    [][2]string{{"pod_uid", "uid-1"}},
+				value:     0.25,
+				timestamp: time.UnixMilli(1700000000123),
+			},
+		}},
+	}
+
+	tests := []struct {
+		name        string
+		family      *promMetric
+		openMetrics bool
+		want        string
+	}{
+		{"text gauge", gauge, false, `# HELP ioeye_test_latency Path C:\\dir\nnext "quoted"
+# TYPE ioeye_test_latency gauge
+ioeye_test_latency{pod="a\\b\"c\nd",namespace="default"} 1.5
+ioeye_test_latency 2
+`},
+		{"openmetrics gauge", gauge, true, `# HELP ioeye_test_latency Path C:\\dir\nnext \"quoted\"
+# TYPE ioeye_test_latency gauge
+# UNIT ioeye_test_latency seconds
+ioeye_test_latency{pod="a\\b\"c\nd",namespace="default"} 1.5
+ioeye_test_latency 2
+`},
+		{"text counter without exemplar", counter, false, `# HELP ioeye_test_ops Operations.
+# TYPE ioeye_test_ops counter
+ioeye_test_ops_total{stage="k8s"} 3
+`},
+		{"openmetrics counter with exemplar", counter, true, `# HELP ioeye_test_ops Operations.
+# TYPE ioeye_test_ops counter
+ioeye_test_ops_total{stage="k8s"} 3 # {pod_uid="uid-1"} 0.25 1700000000.123
+`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		writePromMetric(&buf, tt.family, tt.openMetrics)
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s:\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// promSuffixes 各指标类型的样本名允许的后缀
+var promSuffixes = map[string][]string{
+	"gauge":          {""},
+	"counter":        {""},
+	"histogram":      {"_bucket", "_sum", "_count"},
+	"gaugehistogram": {"_bucket", "_gcount", "_gsum"},
+}
+
+// checkPromFamilies 校验每个指标族只有一组HELP和TYPE，样本紧跟在所属指标族之后，返回各指标族的类型
+func checkPromFamilies(t *testing.T, body string, openMetrics bool) map[string]string {
+	t.Helper()
+
+	types := make(map[string]string)
+	var family, typ string
+	lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "# HELP "):
+			family = strings.Fields(line)[2]
+			if _, ok := types[family]; ok {
+				t.Errorf("family %s has more than one HELP line", family)
+			}
+			next := ""
+			if i+1 < len(lines) {
+				next = lines[i+1]
+			}
+			fields := strings.Fields(next)
+			if len(fields) != 4 || fields[1] != "TYPE" || fields[2] != family {
+				t.Errorf("HELP of %s not followed by its TYPE line: %q", family, next)
+				continue
+			}
+			typ = fields[3]
+			types[family] = typ
+		case strings.HasPrefix(line, "# TYPE "), strings.HasPrefix(line, "# UNIT "):
+			if name := strings.Fields(line)[2]; name != family {
+				t.Errorf("%q outside of its family", line)
+			}
+		case line == "# EOF":
+			if !openMetrics || i != len(lines)-1 {
+				t.Errorf("unexpected # EOF at line %d", i+1)
+			}
+		default:
+			name, _, _ := strings.Cut(line, " ")
+			name, _, _ = strings.Cut(name, "{")
+			suffixes := promSuffixes[typ]
+			if openMetrics && typ == "counter" {
+				suffixes = []string{"_total"}
+			}
+			ok := false
+			for _, suffix := range suffixes {
+				ok = ok || name == family+suffix
+			}
+			if !ok {
+				t.Errorf("sample %s is not a %s sample of family %s", name, typ, family)
+			}
+		}
+	}
+	if openMetrics && !strings.HasSuffix(body, "# EOF\n") {
+		t.Error("OpenMetrics output does not end with # EOF")
+	}
+	return types
+}
+
+func TestPrometheusMetricsFormat(t *testing.T) {
+	s := newTestServer(t)
+
+	for _, tt := range []struct {
+		accept      string
+		openMetrics bool
+		contentType string
+	}{
+		{"", false, promContentType},
+		{"text/plain;version=0.0.4", false, promContentType},
+		{"application/openmetrics-text;version=1.0.0,text/plain;q=0.5", true, openMetricsContentType},
+		{"application/openmetrics-text;q=0", false, promContentType},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept", tt.accept)
+		rec := httptest.NewRecorder()
+		s.handlePrometheusMetrics(rec, req)
+
+		if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("Accept %q: Content-Type = %q, want %q", tt.accept, ct, tt.contentType)
+		}
+		types := checkPromFamilies(t, rec.Body.String(), tt.openMetrics)
+
+		// Prometheus文本格式的counter指标族名带_total，OpenMetrics的指标族名不带、样本名带
+		for family, typ := range types {
+			if typ != "counter" {
+				continue
+			}
+			if hasTotal := strings.HasSuffix(family, "_total"); hasTotal == tt.openMetrics {
+				t.Errorf("Accept %q: counter family %s, want _total suffix only in the text format", tt.accept, family)
+			}
+		}
+		for _, family := range []string{"ioeye_pod_read_ops", "ioeye_collection_errors"} {
+			if !tt.openMetrics {
+				family += "_total"
+			}
+			if types[family] != "counter" {
+				t.Errorf("Accept %q: family %s has type %q, want counter", tt.accept, family, types[family])
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterIdleTimeout 客户端超过该时长没有请求时丢弃其令牌桶
+const rateLimiterIdleTimeout = 5 * time.Minute
+
+// rateLimiter 按客户端划分的令牌桶限流器
+type rateLimiter struct {
+	rps       rate.Limit
+	burst     int
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+// clientLimiter 单个客户端的令牌桶
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// WithRateLimit 按客户端限制API请求速率，每个客户端每秒补充rps个令牌，最多累积burst个
+// 启用认证时按token区分客户端，否则按客户端IP区分；rps不大于0时不限流，burst小于1时按1处理
+func WithRateLimit(rps float64, burst int) ServerOption {
+	return func(s *Server) {
+		if rps <= 0 {
+			s.rateLimiter = nil
+			return
+		}
+		s.rateLimiter = &rateLimiter{
+			rps:     rate.Limit(rps),
+			burst:   max(burst, 1),
+			clients: make(map[string]*clientLimiter),
+		}
+	}
+}
+
+// rateLimitMiddleware 超出速率的请求返回429，并通过Retry-After告知客户端何时重试
+// 探针请求不限流，避免存活和就绪检查因仪表盘的请求失败
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	if s.rateLimiter == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == healthPath || r.URL.Path == readyPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if ok, retryAfter := s.rateLimiter.allow(s.rateLimitKey(r), time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writeJSONError(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitKey 返回限流使用的客户端标识，启用认证时为请求携带的token（已由authMiddleware校验），否则为客户端IP
+func (s *Server) rateLimitKey(r *http.Request) string {
+	if s.authToken != "" {
+		return "token:" + r.Header.Get("Authorization")
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// allow 从客户端的令牌桶中取一个令牌，令牌不足时返回需要等待的时长
+func (rl *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.sweep(now)
+
+	client, ok := rl.clients[key]
+	if !ok {
+		client = &clientLimiter{limiter: rate.NewLimiter(rl.rps, rl.burst)}
+		rl.clients[key] = client
+	}
+	client.lastSeen = now
+
+	reservation := client.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		// 本次请求被拒绝，归还预留的令牌
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep 丢弃长时间没有请求的客户端，每个空闲周期最多执行一次，调用方需持有rl.mu
+func (rl *rateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rateLimiterIdleTimeout {
+		return
+	}
+	rl.lastSweep = now
+
+	for key, client := range rl.clients {
+		if now.Sub(client.lastSeen) >= rateLimiterIdleTimeout {
+			delete(rl.clients, key)
+		}
+	}
+}
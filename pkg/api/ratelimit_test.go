@@ -0,0 +1,114 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// okHandler 始终返回200
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+// serve 以remoteAddr为客户端地址发送GET请求
+func serve(h http.Handler, path, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	const burst = 3
+	s := newTestServer(t, WithRateLimit(1, burst))
+	h := s.rateLimitMiddleware(okHandler)
+
+	for i := 0; i < burst; i++ {
+		if rec := serve(h, "/api/v1/metrics", "10.0.0.1:40000", nil); rec.Code != http.StatusOK {
+			t.Fatalf("request %d status = %d, want 200", i+1, rec.Code)
+		}
+	}
+
+	// 令牌桶耗尽
+	rec := serve(h, "/api/v1/metrics", "10.0.0.1:40001", nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("request %d status = %d, want 429", burst+1, rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+	if body := decodeError(t, rec.Result()); body.Error.Code != "too_many_requests" {
+		t.Errorf("error code = %q, want too_many_requests", body.Error.Code)
+	}
+
+	// 其他客户端和探针请求不受影响
+	if rec := serve(h, "/api/v1/metrics", "10.0.0.2:40000", nil); rec.Code != http.StatusOK {
+		t.Errorf("other client status = %d, want 200", rec.Code)
+	}
+	if rec := serve(h, healthPath, "10.0.0.1:40002", nil); rec.Code != http.StatusOK {
+		t.Errorf("health probe status = %d, want 200", rec.Code)
+	}
+}
+
+func TestRateLimitKeyedByToken(t *testing.T) {
+	s := newTestServer(t, WithRateLimit(1, 1), WithAuthToken("secret"))
+	h := s.rateLimitMiddleware(okHandler)
+
+	tokenA := http.Header{"Authorization": {"Bearer a"}}
+	tokenB := http.Header{"Authorization": {"Bearer b"}}
+	if rec := serve(h, "/api/v1/metrics", "10.0.0.1:40000", tokenA); rec.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, want 200", rec.Code)
+	}
+	// 同一IP的不同token各有令牌桶
+	if rec := serve(h, "/api/v1/metrics", "10.0.0.1:40000", tokenB); rec.Code != http.StatusOK {
+		t.Errorf("other token status = %d, want 200", rec.Code)
+	}
+	if rec := serve(h, "/api/v1/metrics", "10.0.0.2:40000", tokenA); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("same token from another IP status = %d, want 429", rec.Code)
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	s := newTestServer(t, WithRateLimit(2, 2))
+	rl := s.rateLimiter
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := rl.allow("ip:10.0.0.1", now); !ok {
+			t.Fatalf("request %d rejected within the burst", i+1)
+		}
+	}
+	ok, retryAfter := rl.allow("ip:10.0.0.1", now)
+	if ok || retryAfter != 500*time.Millisecond {
+		t.Fatalf("allow() = %v, %v, want rejected for 500ms", ok, retryAfter)
+	}
+	// 被拒绝的请求不消耗令牌
+	if ok, _ := rl.allow("ip:10.0.0.1", now.Add(500*time.Millisecond)); !ok {
+		t.Error("request rejected after a token was refilled")
+	}
+
+	// 空闲的客户端被清理
+	rl.allow("ip:10.0.0.2", now.Add(rateLimiterIdleTimeout+time.Second))
+	if _, ok := rl.clients["ip:10.0.0.1"]; ok {
+		t.Error("idle client limiter was not swept")
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	s := newTestServer(t, WithRateLimit(0, 10))
+	if s.rateLimiter != nil {
+		t.Fatal("rate limiter enabled with rps 0")
+	}
+	h := s.rateLimitMiddleware(okHandler)
+	for i := 0; i < 100; i++ {
+		if rec := serve(h, "/api/v1/metrics", "10.0.0.1:40000", nil); rec.Code != http.StatusOK {
+			t.Fatalf("request %d status = %d with rate limiting disabled", i+1, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// PodRatesResponse 是Pod按原始样本时间差计算的速率的API响应格式
+type PodRatesResponse struct {
+	Timestamp          time.Time `json:"timestamp" description:"最新样本的原始采集时间"`
+	PodName            string    `json:"pod_name"`
+	Mode               string    `json:"mode" description:"instantaneous或interval-averaged"`
+	ReadIOPS           float64   `json:"read_iops"`
+	WriteIOPS          float64   `json:"write_iops"`
+	ReadThroughputBps  float64   `json:"read_throughput_bps"`
+	WriteThroughputBps float64   `json:"write_throughput_bps"`
+	WindowSeconds      float64   `json:"window_seconds" description:"计算速率的两个样本之间的时间差"`
+}
+
+// handleGetPodRates 处理获取Pod按原始样本时间差计算的IOPS和吞吐量的请求
+// mode参数默认为instantaneous，即最近两个样本之间的速率
+func (s *Server) handleGetPodRates(w http.ResponseWriter, r *http.Request, podName string) {
+	if podName == "" {
+		writeJSONError(w, "Pod name is required", http.StatusBadRequest)
+		return
+	}
+
+	mode := monitor.RateModeInstant
+	if v := r.URL.Query().Get("mode"); v != "" {
+		mode = monitor.RateMode(v)
+		if !mode.Valid() {
+			writeJSONError(w, fmt.Sprintf("mode must be %s or %s", monitor.RateModeInstant, monitor.RateModeAverage), http.StatusBadRequest)
+			return
+		}
+	}
+
+	rates, err := s.storageMonitor.GetPodRates(podName, mode)
+	if err != nil {
+		writeJSONError(w, fmt.Sprintf("Failed to get rates for pod %s: %v", podName, err), http.StatusNotFound)
+		return
+	}
+
+	response := &PodRatesResponse{
+		Timestamp:          rates.Timestamp,
+		PodName:            rates.PodName,
+		Mode:               string(rates.Mode),
+		ReadIOPS:           rates.ReadIOPS,
+		WriteIOPS:          rates.WriteIOPS,
+		ReadThroughputBps:  rates.ReadThroughput,
+		WriteThroughputBps: rates.WriteThroughput,
+		WindowSeconds:      rates.Window.Seconds(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -2,21 +2,57 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lizhongxuan/ioeye/pkg/analyzer"
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
 	"github.com/lizhongxuan/ioeye/pkg/monitor"
 )
 
+// 慢Pod查询的limit参数默认值和上限
+const (
+	defaultTopSlowLimit = 5
+	maxTopSlowLimit     = 1000
+)
+
+// defaultHistorySince 历史查询since参数的默认值
+const defaultHistorySince = 15 * time.Minute
+
+// DefaultShutdownTimeout 优雅关闭时等待进行中请求完成的默认时长
+const DefaultShutdownTimeout = 5 * time.Second
+
 // Server 代表API服务器
 type Server struct {
 	httpServer    *http.Server
 	storageMonitor *monitor.StorageMonitor
 	storageAnalyzer *analyzer.StorageAnalyzer
 	address       string
+	tlsConfig     *tls.Config
+	certReloader  *certReloader
+	authToken     string
+	openAPIOnce   sync.Once
+	openAPIDoc    []byte // 首次请求时生成的OpenAPI文档
+	openAPIErr    error
+	maxStreamClients int64
+	streamClients    atomic.Int64 // 当前连接的流式客户端数
+	shutdownTimeout  time.Duration
+	shutdownOnce     sync.Once
+	shuttingDown     chan struct{} // 开始关闭时关闭，通知流式连接退出
+	rateLimiter      *rateLimiter  // 为nil时不限流
+	buildInfo        BuildInfo
+	allowedOrigins   []string // 允许跨域访问的源，为空时只允许同源访问
+	timeouts         Timeouts
+	haStatus         HAStatus // 为nil时未启用主备模式
+	haIdentity       string
 }
 
 // PodMetricsResponse 是Pod指标的API响应格式
@@ -26,12 +62,71 @@ type PodMetricsResponse struct {
 	TopSlowPods  []*PodMetrics                    `json:"top_slow_pods,omitempty"`
 	Bottlenecks  map[string]string                `json:"bottlenecks,omitempty"`
 	Anomalies    map[string]bool                  `json:"anomalies,omitempty"`
+	Freshness
 }
 
 // PodMetrics 包含单个Pod的存储性能指标
 type PodMetrics struct {
-	PodName         string    `json:"pod_name"`
-	Namespace       string    `json:"namespace"`
+	Cluster           string       `json:"cluster,omitempty"`
+	PodName           string       `json:"pod_name"`
+	PodUID            string       `json:"pod_uid,omitempty" description:"Pod的UID，可代替Pod名称用于查询，模拟数据中为空"`
+	Namespace         string       `json:"namespace"`
+	NodeName          string       `json:"node,omitempty"`
+	ReadLatency       uint64       `json:"read_latency_ns"`
+	WriteLatency      uint64       `json:"write_latency_ns"`
+	ReadIOPS          uint64       `json:"read_iops"`
+	WriteIOPS         uint64       `json:"write_iops"`
+	ReadThroughput    uint64       `json:"read_throughput_bps"`
+	WriteThroughput   uint64       `json:"write_throughput_bps"`
+	QueueLatency      uint64       `json:"queue_latency_ns,omitempty"`
+	DiskLatency       uint64       `json:"disk_latency_ns,omitempty"`
+	ReadQueueLatency  uint64       `json:"read_queue_latency_ns,omitempty"`
+	WriteQueueLatency uint64       `json:"write_queue_latency_ns,omitempty"`
+	ReadDiskLatency   uint64       `json:"read_disk_latency_ns,omitempty"`
+	WriteDiskLatency  uint64       `json:"write_disk_latency_ns,omitempty"`
+	NetworkLatency    uint64       `json:"network_latency_ns,omitempty"`
+	AvgReadSize       uint64       `json:"avg_read_size_bytes"`
+	AvgWriteSize      uint64       `json:"avg_write_size_bytes"`
+	ReadRatio         float64      `json:"read_ratio" description:"读操作占读写操作总数的比例（0-1）"`
+	Utilization       float64      `json:"utilization" description:"Pod至少有一个块I/O请求在设备上处理的时间比例（0-1）"`
+	// 累计计数，与上面的速率字段不同，自IOEye启动以来单调递增
+	ReadOpsTotal      uint64       `json:"read_ops_total" description:"自IOEye启动以来的累计读操作次数"`
+	WriteOpsTotal     uint64       `json:"write_ops_total" description:"自IOEye启动以来的累计写操作次数"`
+	ReadBytesTotal    uint64       `json:"read_bytes_total" description:"自IOEye启动以来的累计读取字节数"`
+	WriteBytesTotal   uint64       `json:"write_bytes_total" description:"自IOEye启动以来的累计写入字节数"`
+	// 滑动平均，启用smoothing_window时与上面的即时值一同输出
+	*SmoothedPodMetrics
+	Volumes           []VolumeInfo `json:"volumes,omitempty"`
+	Stale             bool         `json:"stale" description:"超过过期时间没有新的eBPF数据，指标为最后一次收到数据时的值"`
+	LastDataTime      *time.Time   `json:"last_data_time,omitempty" description:"最后一次收到该Pod的eBPF数据的时间"`
+	Timestamp         time.Time    `json:"timestamp"`
+}
+
+// SmoothedPodMetrics 是Pod最近若干个采集周期滑动平均的API响应格式，字段名为即时值字段加_avg后缀
+type SmoothedPodMetrics struct {
+	ReadLatency     uint64 `json:"read_latency_ns_avg" description:"按读IOPS加权的平均读延迟"`
+	WriteLatency    uint64 `json:"write_latency_ns_avg" description:"按写IOPS加权的平均写延迟"`
+	QueueLatency    uint64 `json:"queue_latency_ns_avg"`
+	DiskLatency     uint64 `json:"disk_latency_ns_avg"`
+	NetworkLatency  uint64 `json:"network_latency_ns_avg"`
+	ReadIOPS        uint64 `json:"read_iops_avg"`
+	WriteIOPS       uint64 `json:"write_iops_avg"`
+	ReadThroughput  uint64 `json:"read_throughput_bps_avg"`
+	WriteThroughput uint64 `json:"write_throughput_bps_avg"`
+	Samples         int    `json:"smoothing_samples" description:"参与平均的采集周期数，启动后窗口未填满时小于smoothing_window"`
+}
+
+// VolumeInfo 是Pod挂载的PVC及其PV的API响应格式
+type VolumeInfo struct {
+	PVCName      string `json:"pvc"`
+	PVName       string `json:"pv,omitempty"`
+	StorageClass string `json:"storage_class,omitempty"`
+	CSIDriver    string `json:"csi_driver,omitempty"`
+}
+
+// AggregateMetrics 是一组Pod聚合指标的API响应格式
+type AggregateMetrics struct {
+	Pods            []string  `json:"pods"`
 	ReadLatency     uint64    `json:"read_latency_ns"`
 	WriteLatency    uint64    `json:"write_latency_ns"`
 	ReadIOPS        uint64    `json:"read_iops"`
@@ -44,16 +139,239 @@ type PodMetrics struct {
 	Timestamp       time.Time `json:"timestamp"`
 }
 
+// WorkloadMetrics 是工作负载聚合指标的API响应格式
+type WorkloadMetrics struct {
+	Cluster   string `json:"cluster,omitempty"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace"`
+	AggregateMetrics
+}
+
+// NodeMetrics 是节点聚合指标的API响应格式
+type NodeMetrics struct {
+	Cluster   string `json:"cluster,omitempty"`
+	NodeName  string `json:"node"`
+	Saturated bool   `json:"saturated"`
+	AggregateMetrics
+}
+
+// DeviceMetrics 是块设备指标的API响应格式
+type DeviceMetrics struct {
+	Device          string    `json:"device" description:"major:minor"`
+	Name            string    `json:"name,omitempty" description:"/proc/partitions中的设备名"`
+	ReadIOPS        uint64    `json:"read_iops"`
+	WriteIOPS       uint64    `json:"write_iops"`
+	ReadThroughput  uint64    `json:"read_throughput_bps"`
+	WriteThroughput uint64    `json:"write_throughput_bps"`
+	QueueLatency    uint64    `json:"queue_latency_ns"`
+	DiskLatency     uint64    `json:"disk_latency_ns"`
+	Utilization     float64   `json:"utilization" description:"设备至少有一个请求在处理的时间比例（0-1），即iostat的%util"`
+	Saturated       bool      `json:"saturated" description:"利用率达到-device-utilization-threshold"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// StorageClassMetrics 是存储类聚合指标的API响应格式
+type StorageClassMetrics struct {
+	Cluster      string   `json:"cluster,omitempty"`
+	StorageClass string   `json:"storage_class"`
+	CSIDrivers   []string `json:"csi_drivers"`
+	AggregateMetrics
+}
+
+// HistogramBucket 是延迟直方图中单个桶的API响应格式
+type HistogramBucket struct {
+	UpperBound uint64 `json:"le_ns"`
+	ReadCount  uint64 `json:"read_count"`
+	WriteCount uint64 `json:"write_count"`
+}
+
+// PodHistogramResponse 是Pod延迟直方图的API响应格式
+type PodHistogramResponse struct {
+	Timestamp   time.Time                              `json:"timestamp"`
+	PodName     string                                 `json:"pod_name"`
+	Buckets     []HistogramBucket                      `json:"buckets"`
+	Percentiles map[string]analyzer.LatencyPercentiles `json:"percentiles" description:"read和write方向的百分位，直方图为空时省略对应方向"`
+}
+
+// PodHistoryResponse 是Pod历史指标的API响应格式
+type PodHistoryResponse struct {
+	Timestamp time.Time     `json:"timestamp"`
+	PodName   string        `json:"pod_name"`
+	Since     string        `json:"since"`
+	Samples   []*PodMetrics `json:"samples"`
+}
+
+// PodHistoryResetResponse 是清除Pod历史指标的API响应格式
+type PodHistoryResetResponse struct {
+	Timestamp time.Time `json:"timestamp"`
+	PodName   string    `json:"pod_name"`
+}
+
+// ContainerMetrics 是Pod中单个容器存储指标的API响应格式
+type ContainerMetrics struct {
+	ContainerName   string `json:"container"`
+	ReadLatency     uint64 `json:"read_latency_ns"`
+	WriteLatency    uint64 `json:"write_latency_ns"`
+	ReadIOPS        uint64 `json:"read_iops"`
+	WriteIOPS       uint64 `json:"write_iops"`
+	ReadThroughput  uint64 `json:"read_throughput_bps"`
+	WriteThroughput uint64 `json:"write_throughput_bps"`
+	QueueLatency    uint64 `json:"queue_latency_ns,omitempty"`
+	DiskLatency     uint64 `json:"disk_latency_ns,omitempty"`
+	NetworkLatency  uint64 `json:"network_latency_ns,omitempty"`
+}
+
+// PodContainersResponse 是Pod各容器指标的API响应格式
+type PodContainersResponse struct {
+	Timestamp  time.Time          `json:"timestamp"`
+	Pod        *PodMetrics        `json:"pod" description:"Pod级指标，是各容器的合计"`
+	Containers []ContainerMetrics `json:"containers" description:"无法按容器归属I/O时为空数组"`
+}
+
+// TopSlowPodsResponse 是延迟最高Pod的API响应格式
+type TopSlowPodsResponse struct {
+	Timestamp   time.Time          `json:"timestamp"`
+	Score       analyzer.SlowScore `json:"score" description:"排序使用的评分方式"`
+	TopSlowPods []*PodMetrics      `json:"top_slow_pods"`
+	Freshness
+}
+
+// WorkloadMetricsResponse 是按工作负载聚合指标的API响应格式
+type WorkloadMetricsResponse struct {
+	Timestamp time.Time          `json:"timestamp"`
+	Workloads []*WorkloadMetrics `json:"workloads"`
+	Freshness
+}
+
+// NodeMetricsResponse 是按节点聚合指标的API响应格式
+type NodeMetricsResponse struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Nodes     []*NodeMetrics `json:"nodes"`
+	Freshness
+}
+
+// DeviceMetricsResponse 是块设备指标的API响应格式
+type DeviceMetricsResponse struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Devices   []*DeviceMetrics `json:"devices"`
+	Freshness
+}
+
+// StorageClassMetricsResponse 是按存储类聚合指标的API响应格式
+type StorageClassMetricsResponse struct {
+	Timestamp      time.Time              `json:"timestamp"`
+	StorageClasses []*StorageClassMetrics `json:"storage_classes"`
+	Freshness
+}
+
+// HealthResponse 是存活检查的API响应格式
+type HealthResponse struct {
+	Status    string     `json:"status"`
+	Paused    bool       `json:"paused"`
+	PausedAt  *time.Time `json:"paused_at,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+}
+
+// ReadyResponse 是就绪检查的API响应格式
+type ReadyResponse struct {
+	Status          string              `json:"status" description:"ready、degraded、not ready、paused或standby"`
+	Role            string              `json:"role,omitempty" description:"启用主备模式时为active或standby"`
+	ActiveInstance  string              `json:"active_instance,omitempty" description:"启用主备模式时为持有租约的实例标识"`
+	TracersAttached bool                `json:"tracers_attached"`
+	Degraded        bool                `json:"degraded" description:"块I/O跟踪程序附加失败，只有Kubernetes中的Pod信息"`
+	FailedTracers   map[string]string   `json:"failed_tracers,omitempty" description:"附加失败的eBPF跟踪程序及原因"`
+	Paused          bool                `json:"paused"`
+	LastCollection  *time.Time          `json:"last_collection,omitempty"`
+	Kubernetes      []ClusterHealthInfo `json:"kubernetes,omitempty" description:"各集群API server的连接状态，第一个为本集群"`
+	Timestamp       time.Time           `json:"timestamp"`
+}
+
+// ClusterHealthInfo 是就绪检查中单个集群API server的连接状态
+type ClusterHealthInfo struct {
+	Cluster     string     `json:"cluster,omitempty"`
+	Reachable   bool       `json:"reachable"`
+	Error       string     `json:"error,omitempty"`
+	LastPodList *time.Time `json:"last_pod_list,omitempty" description:"上次成功列出Pod的时间"`
+}
+
+// readyPingTimeout 就绪检查中每个集群API server连接检查的超时时间
+const readyPingTimeout = 2 * time.Second
+
 // NewAPIServer 创建一个新的API服务器
-func NewAPIServer(storageMonitor *monitor.StorageMonitor, storageAnalyzer *analyzer.StorageAnalyzer, address string) *Server {
+// address为host:port时监听TCP，为unix:///path/to.sock时监听Unix domain socket
+// 通过WithTLSFiles或WithTLSConfig启用HTTPS，否则使用HTTP
+func NewAPIServer(storageMonitor *monitor.StorageMonitor, storageAnalyzer *analyzer.StorageAnalyzer, address string, opts ...ServerOption) *Server {
 	if address == "" {
 		address = ":8080" // 默认监听所有接口的8080端口
 	}
 	
-	return &Server{
+	s := &Server{
 		storageMonitor: storageMonitor,
 		storageAnalyzer: storageAnalyzer,
 		address:       address,
+		maxStreamClients: defaultMaxStreamClients,
+		shutdownTimeout:  DefaultShutdownTimeout,
+		shuttingDown:     make(chan struct{}),
+		timeouts:         DefaultTimeouts(),
+	}
+	
+	// 应用选项
+	for _, opt := range opts {
+		opt(s)
+	}
+	
+	return s
+}
+
+// WithShutdownTimeout 设置优雅关闭时等待进行中请求完成的最长时间，d不大于0时不生效
+func WithShutdownTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		if d > 0 {
+			s.shutdownTimeout = d
+		}
+	}
+}
+
+// route 注册到ServeMux的一个路由，以/结尾的pattern匹配该前缀下的所有路径
+type route struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+// routes 返回API服务器注册的所有路由，处理函数自行校验请求方法
+// 新增路由或方法时需要同步更新apiOperations，TestRoutesMatchAPIOperations校验两者一致
+func (s *Server) routes() []route {
+	return []route{
+		{"/api/v1/metrics", s.handleGetAllMetrics},
+		{"/api/v1/metrics/pod/", s.handleGetPodMetrics},
+		{batchPodMetricsPath, s.handleBatchPodMetrics},
+		{"/api/v1/metrics/namespace/", s.handleGetNamespaceMetrics},
+		{"/api/v1/metrics/topslow", s.handleGetTopSlowPods},
+		{"/api/v1/metrics/workload", s.handleGetWorkloadMetrics},
+		{"/api/v1/metrics/storageclass", s.handleGetStorageClassMetrics},
+		{volumeMetricsPath, s.handleGetVolumeMetrics},
+		{"/api/v1/metrics/node", s.handleGetNodeMetrics},
+		{"/api/v1/metrics/device", s.handleGetDeviceMetrics},
+		{"/api/v1/metrics/anomalies", s.handleGetAnomalies},
+		{bottlenecksPath, s.handleGetBottlenecks},
+		{alertsPath, s.handleAlerts},
+		{exportCSVPath, s.handleExportCSV},
+		{dumpPath, s.handleDumpHistory},
+		{streamPath, s.handleMetricsStream},
+		{tracingPath, s.handleTracing},
+		{tracingPodPath, s.handleTracingPod},
+		{intervalPath, s.handleInterval},
+		{thresholdsPath, s.handleThresholds},
+		{pausePath, s.handlePause},
+		{resumePath, s.handleResume},
+		{versionPath, s.handleVersion},
+		{probesPath, s.handleProbes},
+		{healthPath, s.handleHealth},
+		{readyPath, s.handleReady},
+		{"/metrics", s.handlePrometheusMetrics},
+		{openAPIPath, s.handleOpenAPI},
+		{swaggerPath, s.handleSwagger},
 	}
 }
 
@@ -62,30 +380,61 @@ func (s *Server) Start(ctx context.Context) error {
 	mux := http.NewServeMux()
 	
 	// 注册API路由
-	mux.HandleFunc("/api/v1/metrics", s.handleGetAllMetrics)
-	mux.HandleFunc("/api/v1/metrics/pod/", s.handleGetPodMetrics)
-	mux.HandleFunc("/api/v1/metrics/topslow", s.handleGetTopSlowPods)
-	mux.HandleFunc("/api/v1/health", s.handleHealth)
+	for _, rt := range s.routes() {
+		mux.HandleFunc(rt.pattern, rt.handler)
+	}
+	
+	tlsConfig, err := s.buildTLSConfig()
+	if err != nil {
+		return err
+	}
+	
+	// Unix socket在启动前同步监听，以便返回清理遗留socket文件或监听失败的错误
+	var listener net.Listener
+	if path, ok := unixSocketPath(s.address); ok {
+		listener, err = listenUnix(path)
+		if err != nil {
+			return err
+		}
+	}
 	
 	s.httpServer = &http.Server{
-		Addr:    s.address,
-		Handler: mux,
+		Addr:      s.address,
+		Handler:   loggingMiddleware(s.corsMiddleware(s.authMiddleware(s.rateLimitMiddleware(s.standbyMiddleware(s.freshnessMiddleware(gzipMiddleware(mux))))))),
+		TLSConfig: tlsConfig,
 	}
+	s.applyTimeouts(s.httpServer)
+	// 流式连接不会自行空闲，关闭时通知其退出，否则Shutdown总要等到超时
+	s.httpServer.RegisterOnShutdown(func() {
+		s.shutdownOnce.Do(func() { close(s.shuttingDown) })
+	})
 	
-	// 在后台启动HTTP服务器
+	// 在后台启动HTTP服务器，配置了TLS时使用HTTPS，证书由TLSConfig提供
+	// 关闭时http.Server会关闭Unix socket监听器，监听器随之删除socket文件
 	go func() {
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		switch {
+		case listener != nil && tlsConfig != nil:
+			err = s.httpServer.ServeTLS(listener, "", "")
+		case listener != nil:
+			err = s.httpServer.Serve(listener)
+		case tlsConfig != nil:
+			err = s.httpServer.ListenAndServeTLS("", "")
+		default:
+			err = s.httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			fmt.Printf("HTTP server error: %v\n", err)
 		}
 	}()
 	
-	fmt.Printf("API server started on %s\n", s.address)
+	fmt.Printf("API server started on %s (tls=%v)\n", s.address, tlsConfig != nil)
 	
 	// 等待上下文取消信号
 	<-ctx.Done()
 	
 	// 优雅关闭HTTP服务器
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
 	defer cancel()
 	
 	return s.httpServer.Shutdown(shutdownCtx)
@@ -94,57 +443,43 @@ func (s *Server) Start(ctx context.Context) error {
 // Stop 停止API服务器
 func (s *Server) Stop() error {
 	if s.httpServer != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
 		defer cancel()
 		return s.httpServer.Shutdown(ctx)
 	}
 	return nil
 }
 
-// handleGetAllMetrics 处理获取所有Pod指标的请求
+// handleGetAllMetrics 处理获取所有Pod指标的请求，支持?cluster=过滤
 func (s *Server) handleGetAllMetrics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 	
-	// 从存储监控器获取所有Pod的指标
-	allPodMetrics := s.storageMonitor.GetAllMetrics()
-	
-	// 转换为API响应格式
-	podMetricsMap := make(map[string]*PodMetrics)
-	bottlenecks := make(map[string]string)
-	anomalies := make(map[string]bool)
+	response := buildFilteredMetrics(s.storageMonitor, s.storageAnalyzer, PodFilter{Cluster: r.URL.Query().Get("cluster")}, s.requestFreshness(r))
 	
-	for podName, metrics := range allPodMetrics {
-		podMetricsMap[podName] = convertToPodMetrics(metrics)
-		
-		// 获取瓶颈类型
-		if s.storageAnalyzer != nil {
-			bottleneckType := s.storageAnalyzer.GetBottleneckType(podName)
-			bottlenecks[podName] = string(bottleneckType)
-			
-			// 获取异常检测结果
-			anomalies[podName] = s.storageAnalyzer.HasAnomalyDetected(podName)
-		}
+	// 返回JSON响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetNamespaceMetrics 处理获取指定命名空间内所有Pod指标的请求，支持?cluster=过滤
+func (s *Server) handleGetNamespaceMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 	
-	// 获取延迟最高的5个Pod
-	var topSlowPods []*PodMetrics
-	if s.storageAnalyzer != nil {
-		slowPods := s.storageAnalyzer.GetTopNSlowPods(5)
-		for _, pod := range slowPods {
-			topSlowPods = append(topSlowPods, convertToPodMetrics(pod))
-		}
+	// 从URL路径中提取命名空间
+	namespace := strings.TrimSuffix(r.URL.Path[len("/api/v1/metrics/namespace/"):], "/")
+	if namespace == "" {
+		writeJSONError(w, "Namespace is required", http.StatusBadRequest)
+		return
 	}
 	
-	response := PodMetricsResponse{
-		Timestamp:   time.Now(),
-		PodMetrics:  podMetricsMap,
-		TopSlowPods: topSlowPods,
-		Bottlenecks: bottlenecks,
-		Anomalies:   anomalies,
-	}
+	response := buildFilteredMetrics(s.storageMonitor, s.storageAnalyzer, PodFilter{Namespace: namespace, Cluster: r.URL.Query().Get("cluster")}, s.requestFreshness(r))
 	
 	// 返回JSON响应
 	w.Header().Set("Content-Type", "application/json")
@@ -154,89 +489,286 @@ func (s *Server) handleGetAllMetrics(w http.ResponseWriter, r *http.Request) {
 
 // handleGetPodMetrics 处理获取单个Pod指标的请求
 func (s *Server) handleGetPodMetrics(w http.ResponseWriter, r *http.Request) {
+	// 从URL路径中提取Pod名称
+	podName := r.URL.Path[len("/api/v1/metrics/pod/"):]
+
+	// 历史指标还支持DELETE清除，其余路径只支持GET
+	if name, ok := strings.CutSuffix(podName, "/history"); ok && r.Method == http.MethodDelete {
+		s.handleResetPodHistory(w, name)
+		return
+	}
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 	
-	// 从URL路径中提取Pod名称
-	podName := r.URL.Path[len("/api/v1/metrics/pod/"):]
+	if name, ok := strings.CutSuffix(podName, "/histogram"); ok {
+		s.handleGetPodHistogram(w, name)
+		return
+	}
+	if name, ok := strings.CutSuffix(podName, "/history"); ok {
+		s.handleGetPodHistory(w, r, name)
+		return
+	}
+	if name, ok := strings.CutSuffix(podName, "/containers"); ok {
+		s.handleGetPodContainers(w, r, name)
+		return
+	}
+	if name, ok := strings.CutSuffix(podName, "/rates"); ok {
+		s.handleGetPodRates(w, r, name)
+		return
+	}
 	if podName == "" {
-		http.Error(w, "Pod name is required", http.StatusBadRequest)
+		writeJSONError(w, "Pod name is required", http.StatusBadRequest)
 		return
 	}
 	
+	// trend参数选择趋势指标，默认为延迟
+	metric := analyzer.MetricKindLatency
+	if v := r.URL.Query().Get("trend"); v != "" {
+		metric = analyzer.MetricKind(v)
+		if !metric.Valid() {
+			writeJSONError(w, "trend must be one of latency, read-iops, write-iops, read-throughput, write-throughput", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// forecast参数指定延迟预测的时间跨度，未指定时不预测
+	var horizon time.Duration
+	if v := r.URL.Query().Get("forecast"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeJSONError(w, "forecast must be a positive duration such as 30m", http.StatusBadRequest)
+			return
+		}
+		horizon = d
+	}
+	
 	// 获取指定Pod的指标
-	metrics, err := s.storageMonitor.GetPodMetrics(podName)
+	response, err := buildPodDetail(s.storageMonitor, s.storageAnalyzer, podName, metric, s.requestFreshness(r))
+	if err != nil {
+		writeJSONError(w, fmt.Sprintf("Failed to get metrics for pod %s: %v", podName, err), http.StatusNotFound)
+		return
+	}
+	if horizon > 0 && s.storageAnalyzer != nil {
+		response.Forecast = BuildLatencyForecast(s.storageAnalyzer, s.podKey(podName), horizon)
+	}
+	
+	// 返回JSON响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// podKey 将URL中的Pod UID或名称解析为分析器使用的key
+// Pod已不在监控中时原样返回，已删除的Pod仍可按UID查询或清除其保留的历史
+func (s *Server) podKey(podID string) string {
+	if key, err := s.storageMonitor.ResolvePodKey(podID); err == nil {
+		return key
+	}
+	return podID
+}
+
+// handleGetPodHistogram 处理获取单个Pod延迟直方图的请求
+func (s *Server) handleGetPodHistogram(w http.ResponseWriter, podName string) {
+	if podName == "" {
+		writeJSONError(w, "Pod name is required", http.StatusBadRequest)
+		return
+	}
+	
+	hist, err := s.storageMonitor.GetLatencyHistogram(podName)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get metrics for pod %s: %v", podName, err), http.StatusNotFound)
+		writeJSONError(w, fmt.Sprintf("Failed to get latency histogram for pod %s: %v", podName, err), http.StatusNotFound)
 		return
 	}
 	
-	// 转换为API响应格式
-	podMetrics := convertToPodMetrics(metrics)
+	buckets := make([]HistogramBucket, len(hist.Bounds))
+	for i, bound := range hist.Bounds {
+		buckets[i] = HistogramBucket{
+			UpperBound: bound,
+			ReadCount:  hist.ReadCounts[i],
+			WriteCount: hist.WriteCounts[i],
+		}
+	}
 	
-	// 添加瓶颈和异常信息
-	bottleneck := ""
-	var anomaly bool
+	response := &PodHistogramResponse{
+		Timestamp:   hist.Timestamp,
+		PodName:     podName,
+		Buckets:     buckets,
+		Percentiles: make(map[string]analyzer.LatencyPercentiles),
+	}
 	
-	if s.storageAnalyzer != nil {
-		bottleneck = string(s.storageAnalyzer.GetBottleneckType(podName))
-		anomaly = s.storageAnalyzer.HasAnomalyDetected(podName)
+	// 直方图为空时不返回对应的百分位
+	if p, err := analyzer.HistogramPercentiles(hist.Bounds, hist.ReadCounts); err == nil {
+		response.Percentiles["read"] = p
+	}
+	if p, err := analyzer.HistogramPercentiles(hist.Bounds, hist.WriteCounts); err == nil {
+		response.Percentiles["write"] = p
 	}
 	
-	// 构建响应
-	response := map[string]interface{}{
-		"timestamp":  time.Now(),
-		"pod_metrics": podMetrics,
-		"bottleneck": bottleneck,
-		"anomaly":    anomaly,
-	}
-	
-	// 如果存储分析器可用，添加趋势信息
-	if s.storageAnalyzer != nil {
-		trend, change, err := s.storageAnalyzer.GetLatencyTrend(podName, 5*time.Minute)
-		if err == nil {
-			response["trend"] = map[string]interface{}{
-				"direction":      trend,
-				"change_percent": change,
-				"period":         "5m",
-			}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetPodHistory 处理获取单个Pod历史指标的请求
+func (s *Server) handleGetPodHistory(w http.ResponseWriter, r *http.Request, podName string) {
+	if podName == "" {
+		writeJSONError(w, "Pod name is required", http.StatusBadRequest)
+		return
+	}
+	
+	if s.storageAnalyzer == nil {
+		writeJSONError(w, "Metrics history is not available", http.StatusNotFound)
+		return
+	}
+	
+	// 默认返回最近15分钟的数据
+	since := defaultHistorySince
+	if v := r.URL.Query().Get("since"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeJSONError(w, "since must be a positive duration such as 15m", http.StatusBadRequest)
+			return
 		}
+		since = d
+	}
+	
+	history := s.storageAnalyzer.GetPodHistory(s.podKey(podName), since)
+	if history == nil {
+		writeJSONError(w, fmt.Sprintf("No metrics history found for pod %s", podName), http.StatusNotFound)
+		return
+	}
+	
+	samples := make([]*PodMetrics, 0, len(history))
+	for _, metrics := range history {
+		samples = append(samples, convertToPodMetrics(metrics))
+	}
+	
+	response := &PodHistoryResponse{
+		Timestamp: time.Now(),
+		PodName:   podName,
+		Since:     since.String(),
+		Samples:   samples,
 	}
 	
-	// 返回JSON响应
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
-// handleGetTopSlowPods 处理获取延迟最高的Pod请求
+// handleResetPodHistory 处理清除Pod历史指标和分析状态的请求，Pod没有历史时同样返回成功
+func (s *Server) handleResetPodHistory(w http.ResponseWriter, podName string) {
+	if podName == "" {
+		writeJSONError(w, "Pod name is required", http.StatusBadRequest)
+		return
+	}
+	
+	if s.storageAnalyzer == nil {
+		writeJSONError(w, "Metrics history is not available", http.StatusNotFound)
+		return
+	}
+	
+	if err := s.storageAnalyzer.ResetPod(s.podKey(podName)); err != nil {
+		writeJSONError(w, fmt.Sprintf("Failed to reset history for pod %s: %v", podName, err), http.StatusInternalServerError)
+		return
+	}
+	
+	response := &PodHistoryResetResponse{
+		Timestamp: time.Now(),
+		PodName:   podName,
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetPodContainers 处理获取Pod各容器指标的请求
+func (s *Server) handleGetPodContainers(w http.ResponseWriter, r *http.Request, podName string) {
+	if podName == "" {
+		writeJSONError(w, "Pod name is required", http.StatusBadRequest)
+		return
+	}
+	
+	namespace := r.URL.Query().Get("namespace")
+	containers, err := s.storageMonitor.GetContainerMetrics(namespace, podName)
+	if err != nil {
+		writeJSONError(w, fmt.Sprintf("Failed to get container metrics for pod %s: %v", podName, err), http.StatusNotFound)
+		return
+	}
+	metrics, err := s.storageMonitor.GetPodMetrics(podName)
+	if err != nil {
+		writeJSONError(w, fmt.Sprintf("Failed to get metrics for pod %s: %v", podName, err), http.StatusNotFound)
+		return
+	}
+	
+	response := &PodContainersResponse{
+		Timestamp:  time.Now(),
+		Pod:        convertToPodMetrics(metrics),
+		Containers: make([]ContainerMetrics, 0, len(containers)),
+	}
+	for _, container := range containers {
+		response.Containers = append(response.Containers, ContainerMetrics{
+			ContainerName:   container.ContainerName,
+			ReadLatency:     container.ReadLatency,
+			WriteLatency:    container.WriteLatency,
+			ReadIOPS:        container.ReadIOPS,
+			WriteIOPS:       container.WriteIOPS,
+			ReadThroughput:  container.ReadThroughput,
+			WriteThroughput: container.WriteThroughput,
+			QueueLatency:    container.QueueLatency,
+			DiskLatency:     container.DiskLatency,
+			NetworkLatency:  container.NetworkLatency,
+		})
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetTopSlowPods 处理获取延迟最高的Pod请求，支持?cluster=过滤
 func (s *Server) handleGetTopSlowPods(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 	
-	// 默认返回前5个延迟最高的Pod
-	limit := 5
+	// 默认返回前5个延迟最高的Pod，limit取值范围为[1, maxTopSlowLimit]
+	limit := defaultTopSlowLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeJSONError(w, fmt.Sprintf("limit must be an integer between 1 and %d", maxTopSlowLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
 	
-	var slowPods []*PodMetrics
+	// 排序依据：read、write或total（默认）
+	limit, by, err := ParseTopSlowQuery(limit, r.URL.Query().Get("by"))
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	
-	if s.storageAnalyzer != nil {
-		// 获取延迟最高的Pod
-		topSlowPodsMetrics := s.storageAnalyzer.GetTopNSlowPods(limit)
-		
-		// 转换为API响应格式
-		for _, pod := range topSlowPodsMetrics {
-			slowPods = append(slowPods, convertToPodMetrics(pod))
-		}
+	// 评分方式：latency（默认）只看延迟，weighted按IOPS加权
+	score, err := ParseSlowScore(r.URL.Query().Get("score"))
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 	
+	slowPods := buildTopSlowPods(s.storageAnalyzer, limit, by, score, PodFilter{Cluster: r.URL.Query().Get("cluster")})
+	
 	// 构建响应
-	response := map[string]interface{}{
-		"timestamp": time.Now(),
-		"top_slow_pods": slowPods,
+	now := time.Now()
+	response := &TopSlowPodsResponse{
+		Timestamp:   now,
+		Freshness:   s.requestFreshness(r),
+		Score:       score,
+		TopSlowPods: slowPods,
 	}
 	
 	// 返回JSON响应
@@ -245,16 +777,178 @@ func (s *Server) handleGetTopSlowPods(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// handleHealth 处理健康检查请求
+// handleGetWorkloadMetrics 处理按工作负载聚合指标的请求，支持?cluster=过滤
+func (s *Server) handleGetWorkloadMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	
+	cluster := r.URL.Query().Get("cluster")
+	workloads := make([]*WorkloadMetrics, 0)
+	for _, workload := range s.storageMonitor.GetWorkloadMetrics() {
+		if cluster != "" && workload.Cluster != cluster {
+			continue
+		}
+		workloads = append(workloads, &WorkloadMetrics{
+			Cluster:          workload.Cluster,
+			Kind:             workload.Kind,
+			Name:             workload.Name,
+			Namespace:        workload.Namespace,
+			AggregateMetrics: convertToAggregateMetrics(workload.AggregateMetrics),
+		})
+	}
+	
+	now := time.Now()
+	response := &WorkloadMetricsResponse{
+		Timestamp: now,
+		Freshness: s.requestFreshness(r),
+		Workloads: workloads,
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetNodeMetrics 处理按节点聚合指标的请求，支持?cluster=过滤
+func (s *Server) handleGetNodeMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	
+	cluster := r.URL.Query().Get("cluster")
+	nodes := make([]*NodeMetrics, 0)
+	for _, node := range s.storageMonitor.GetNodeMetrics() {
+		if cluster != "" && node.Cluster != cluster {
+			continue
+		}
+		nodes = append(nodes, &NodeMetrics{
+			Cluster:          node.Cluster,
+			NodeName:         node.NodeName,
+			Saturated:        node.Saturated,
+			AggregateMetrics: convertToAggregateMetrics(node.AggregateMetrics),
+		})
+	}
+	
+	now := time.Now()
+	response := &NodeMetricsResponse{
+		Timestamp: now,
+		Freshness: s.requestFreshness(r),
+		Nodes:     nodes,
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetAnomalies 处理获取当前异常Pod的请求，支持?namespace=和?cluster=过滤
+func (s *Server) handleGetAnomalies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	
+	response := buildAnomalies(s.storageMonitor, s.storageAnalyzer, podFilterFromQuery(r), s.requestFreshness(r))
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetDeviceMetrics 处理块设备指标的请求
+func (s *Server) handleGetDeviceMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	
+	deviceMetrics, err := s.storageMonitor.GetDeviceMetrics()
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	devices := make([]*DeviceMetrics, 0, len(deviceMetrics))
+	for _, device := range deviceMetrics {
+		devices = append(devices, &DeviceMetrics{
+			Device:          device.Device,
+			Name:            device.Name,
+			ReadIOPS:        device.ReadIOPS,
+			WriteIOPS:       device.WriteIOPS,
+			ReadThroughput:  device.ReadThroughput,
+			WriteThroughput: device.WriteThroughput,
+			QueueLatency:    device.QueueLatency,
+			DiskLatency:     device.DiskLatency,
+			Utilization:     device.Utilization,
+			Saturated:       s.storageAnalyzer != nil && s.storageAnalyzer.IsDeviceSaturated(device.Device),
+			Timestamp:       device.Timestamp,
+		})
+	}
+	
+	now := time.Now()
+	response := &DeviceMetricsResponse{
+		Timestamp: now,
+		Freshness: s.requestFreshness(r),
+		Devices:   devices,
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetStorageClassMetrics 处理按存储类聚合指标的请求，支持?cluster=过滤
+func (s *Server) handleGetStorageClassMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	
+	cluster := r.URL.Query().Get("cluster")
+	classes := make([]*StorageClassMetrics, 0)
+	for _, class := range s.storageMonitor.GetStorageClassMetrics() {
+		if cluster != "" && class.Cluster != cluster {
+			continue
+		}
+		classes = append(classes, &StorageClassMetrics{
+			Cluster:          class.Cluster,
+			StorageClass:     class.StorageClass,
+			CSIDrivers:       class.CSIDrivers,
+			AggregateMetrics: convertToAggregateMetrics(class.AggregateMetrics),
+		})
+	}
+	
+	now := time.Now()
+	response := &StorageClassMetricsResponse{
+		Timestamp:      now,
+		Freshness:      s.requestFreshness(r),
+		StorageClasses: classes,
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleHealth 处理存活检查请求，只要进程能响应即视为健康
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 	
-	response := map[string]interface{}{
-		"status":    "healthy",
-		"timestamp": time.Now(),
+	pause := s.storageMonitor.GetPauseStatus()
+
+	response := &HealthResponse{
+		Status:    "healthy",
+		Paused:    pause.Paused,
+		Timestamp: time.Now(),
+	}
+	if pause.Paused {
+		response.PausedAt = &pause.PausedAt
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
@@ -262,20 +956,176 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleReady 处理就绪检查请求，eBPF跟踪程序已附加、至少成功采集过一次且未暂停时才返回200，否则返回503
+// 以-require-ebpf=false降级运行时没有I/O指标，但API仍可提供Pod信息，因此返回200并标记为degraded
+// 主备模式中的备用实例只要能访问本集群就返回200并标记为standby，读请求由活动实例处理
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	attached := s.storageMonitor.TracersAttached()
+	degraded := s.storageMonitor.TracersDegraded()
+	lastCollection := s.storageMonitor.LastCollectionTime()
+	paused := s.storageMonitor.Paused()
+
+	response := &ReadyResponse{
+		Status:          "ready",
+		TracersAttached: attached,
+		Degraded:        degraded,
+		Paused:          paused,
+		Timestamp:       time.Now(),
+	}
+	if !lastCollection.IsZero() {
+		response.LastCollection = &lastCollection
+	}
+	if failed := s.storageMonitor.FailedTracers(); len(failed) > 0 {
+		response.FailedTracers = failed
+	}
+	response.Role = s.role()
+	if s.haStatus != nil {
+		response.ActiveInstance = s.haStatus.Holder()
+	}
+
+	// 检查API server连接，区分"进程正常但无法获取Pod"和"完全健康"
+	ctx, cancel := context.WithTimeout(r.Context(), readyPingTimeout)
+	defer cancel()
+	localReachable, remoteReachable := true, true
+	for i, cluster := range s.storageMonitor.CheckClusters(ctx) {
+		info := ClusterHealthInfo{Cluster: cluster.Cluster, Reachable: cluster.Err == nil}
+		if cluster.Err != nil {
+			info.Error = cluster.Err.Error()
+			if i == 0 {
+				localReachable = false
+			} else {
+				remoteReachable = false
+			}
+		}
+		if !cluster.LastPodList.IsZero() {
+			lastPodList := cluster.LastPodList
+			info.LastPodList = &lastPodList
+		}
+		response.Kubernetes = append(response.Kubernetes, info)
+	}
+
+	statusCode := http.StatusOK
+	if response.Role == roleStandby {
+		// 备用实例不采集指标，能访问本集群即可转发读请求
+		response.Status = "standby"
+		if !localReachable {
+			statusCode = http.StatusServiceUnavailable
+		}
+	} else if (!attached && !degraded) || lastCollection.IsZero() || !localReachable {
+		response.Status = "not ready"
+		statusCode = http.StatusServiceUnavailable
+	} else if paused {
+		// 暂停期间指标不再更新，不应继续接收流量
+		response.Status = "paused"
+		statusCode = http.StatusServiceUnavailable
+	} else if degraded || !remoteReachable {
+		// 远程集群不可达时只缺少该集群的Pod，本节点的采集不受影响
+		response.Status = "degraded"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
 // 辅助函数，将内部指标结构转换为API响应结构
 func convertToPodMetrics(metrics *monitor.PodStorageMetrics) *PodMetrics {
+	var lastDataTime *time.Time
+	if !metrics.LastDataTime.IsZero() {
+		t := metrics.LastDataTime
+		lastDataTime = &t
+	}
+
 	return &PodMetrics{
-		PodName:         metrics.PodName,
-		Namespace:       metrics.Namespace,
-		ReadLatency:     metrics.ReadLatency,
-		WriteLatency:    metrics.WriteLatency,
-		ReadIOPS:        metrics.ReadIOPS,
-		WriteIOPS:       metrics.WriteIOPS,
-		ReadThroughput:  metrics.ReadThroughput,
-		WriteThroughput: metrics.WriteThroughput,
-		QueueLatency:    metrics.QueueLatency,
-		DiskLatency:     metrics.DiskLatency,
-		NetworkLatency:  metrics.NetworkLatency,
-		Timestamp:       metrics.Timestamp,
+		Cluster:           metrics.Cluster,
+		PodName:           metrics.PodName,
+		PodUID:            metrics.PodUID,
+		Namespace:         metrics.Namespace,
+		NodeName:          metrics.NodeName,
+		ReadLatency:       metrics.ReadLatency,
+		WriteLatency:      metrics.WriteLatency,
+		ReadIOPS:          metrics.ReadIOPS,
+		WriteIOPS:         metrics.WriteIOPS,
+		ReadThroughput:    metrics.ReadThroughput,
+		WriteThroughput:   metrics.WriteThroughput,
+		QueueLatency:      metrics.QueueLatency,
+		DiskLatency:       metrics.DiskLatency,
+		ReadQueueLatency:  metrics.ReadQueueLatency,
+		WriteQueueLatency: metrics.WriteQueueLatency,
+		ReadDiskLatency:   metrics.ReadDiskLatency,
+		WriteDiskLatency:  metrics.WriteDiskLatency,
+		NetworkLatency:    metrics.NetworkLatency,
+		AvgReadSize:       metrics.AvgReadSize,
+		AvgWriteSize:      metrics.AvgWriteSize,
+		ReadRatio:         metrics.ReadRatio,
+		Utilization:       metrics.Utilization,
+		ReadOpsTotal:      metrics.ReadOpsTotal,
+		WriteOpsTotal:     metrics.WriteOpsTotal,
+		ReadBytesTotal:    metrics.ReadBytesTotal,
+		WriteBytesTotal:   metrics.WriteBytesTotal,
+		SmoothedPodMetrics: convertToSmoothedMetrics(metrics.Smoothed),
+		Volumes:           convertToVolumeInfo(metrics.Volumes),
+		Stale:             metrics.Stale,
+		LastDataTime:      lastDataTime,
+		Timestamp:         metrics.Timestamp,
+	}
+}
+
+// convertToSmoothedMetrics 将滑动平均转换为API响应结构，未启用时返回nil
+func convertToSmoothedMetrics(smoothed *monitor.SmoothedMetrics) *SmoothedPodMetrics {
+	if smoothed == nil {
+		return nil
+	}
+	return &SmoothedPodMetrics{
+		ReadLatency:     smoothed.ReadLatency,
+		WriteLatency:    smoothed.WriteLatency,
+		QueueLatency:    smoothed.QueueLatency,
+		DiskLatency:     smoothed.DiskLatency,
+		NetworkLatency:  smoothed.NetworkLatency,
+		ReadIOPS:        smoothed.ReadIOPS,
+		WriteIOPS:       smoothed.WriteIOPS,
+		ReadThroughput:  smoothed.ReadThroughput,
+		WriteThroughput: smoothed.WriteThroughput,
+		Samples:         smoothed.Samples,
+	}
+}
+
+// convertToVolumeInfo 将PVC/PV信息转换为API响应结构
+func convertToVolumeInfo(volumes []k8s.VolumeInfo) []VolumeInfo {
+	if len(volumes) == 0 {
+		return nil
+	}
+	
+	result := make([]VolumeInfo, 0, len(volumes))
+	for _, volume := range volumes {
+		result = append(result, VolumeInfo{
+			PVCName:      volume.PVCName,
+			PVName:       volume.PVName,
+			StorageClass: volume.StorageClass,
+			CSIDriver:    volume.CSIDriver,
+		})
+	}
+	return result
+}
+
+// convertToAggregateMetrics 将聚合指标转换为API响应结构
+func convertToAggregateMetrics(agg monitor.AggregateMetrics) AggregateMetrics {
+	return AggregateMetrics{
+		Pods:            agg.Pods,
+		ReadLatency:     agg.ReadLatency,
+		WriteLatency:    agg.WriteLatency,
+		ReadIOPS:        agg.ReadIOPS,
+		WriteIOPS:       agg.WriteIOPS,
+		ReadThroughput:  agg.ReadThroughput,
+		WriteThroughput: agg.WriteThroughput,
+		QueueLatency:    agg.QueueLatency,
+		DiskLatency:     agg.DiskLatency,
+		NetworkLatency:  agg.NetworkLatency,
+		Timestamp:       agg.Timestamp,
 	}
 } 
\ No newline at end of file
@@ -0,0 +1,395 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/analyzer"
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// 以下函数构建与传输方式无关的响应，HTTP和gRPC接口共用，保证两者返回一致的数据
+
+// trendPeriod Pod详情中趋势分析的时间范围
+const trendPeriod = 5 * time.Minute
+
+// PodDetailResponse 是单个Pod指标的API响应格式
+type PodDetailResponse struct {
+	Timestamp               time.Time                    `json:"timestamp"`
+	PodMetrics              *PodMetrics                  `json:"pod_metrics"`
+	Bottleneck              string                       `json:"bottleneck"`
+	BottleneckConfidence    float64                      `json:"bottleneck_confidence"`
+	BottleneckContributions *BottleneckContributionsInfo `json:"bottleneck_contributions,omitempty"`
+	Anomaly                 bool                         `json:"anomaly"`
+	AnomalyStreak           *AnomalyStreakInfo           `json:"anomaly_streak,omitempty"`
+	AnomalyDetail           *AnomalyDetailInfo           `json:"anomaly_detail,omitempty"`
+	SmallIO                 bool                         `json:"small_io"`
+	Trend                   *TrendInfo                   `json:"trend,omitempty"`
+	MaxLatency              *MaxLatencyInfo              `json:"max_latency,omitempty"`
+	Forecast                *ForecastInfo                `json:"forecast,omitempty"`
+	Events                  []*StorageEventInfo          `json:"events,omitempty"`
+	Freshness
+}
+
+// StorageEventInfo 是趋势分析时间范围内与Pod或其所在节点相关的存储事件的API响应格式
+type StorageEventInfo struct {
+	Kind      string    `json:"kind" description:"事件所属对象，Pod或Node"`
+	Name      string    `json:"name"`
+	Reason    string    `json:"reason" description:"例如FailedMount、VolumeResizeFailed、NodeHasDiskPressure"`
+	Message   string    `json:"message"`
+	Type      string    `json:"type"`
+	Count     int32     `json:"count"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// BottleneckContributionsInfo 是队列、磁盘、网络延迟各自占三者之和的比例（0-1）的API响应格式
+type BottleneckContributionsInfo struct {
+	Queue   float64 `json:"queue"`
+	Disk    float64 `json:"disk"`
+	Network float64 `json:"network"`
+}
+
+// AnomalyDetailInfo 是Pod最新一次异常检测的基线和z分数的API响应格式，均值和标准差单位为纳秒
+type AnomalyDetailInfo struct {
+	ReadMean    float64 `json:"read_mean_ns"`
+	ReadStdDev  float64 `json:"read_stddev_ns"`
+	ReadZScore  float64 `json:"read_zscore"`
+	WriteMean   float64 `json:"write_mean_ns"`
+	WriteStdDev float64 `json:"write_stddev_ns"`
+	WriteZScore float64 `json:"write_zscore"`
+	Threshold   float64 `json:"threshold"`
+	Samples     int     `json:"samples"`
+}
+
+// AnomalyStreakInfo 是Pod连续超过或低于异常阈值的样本数的API响应格式
+type AnomalyStreakInfo struct {
+	Above int `json:"above"`
+	Below int `json:"below"`
+}
+
+// AnomaliesResponse 是当前被判定为异常的Pod列表的API响应格式
+type AnomaliesResponse struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Anomalies []*PodAnomaly `json:"anomalies"`
+	Freshness
+}
+
+// PodAnomaly 是单个异常Pod的API响应格式
+type PodAnomaly struct {
+	PodMetrics    *PodMetrics        `json:"pod_metrics"`
+	Bottleneck    string             `json:"bottleneck"`
+	ReadZScore    float64            `json:"read_zscore" description:"最新读延迟相对于基线的z分数"`
+	WriteZScore   float64            `json:"write_zscore" description:"最新写延迟相对于基线的z分数"`
+	Severity      float64            `json:"severity" description:"读写z分数中较高者，列表按该值从高到低排序"`
+	AnomalyStreak *AnomalyStreakInfo `json:"anomaly_streak,omitempty"`
+}
+
+// ForecastInfo 是Pod总延迟线性预测的API响应格式，无法预测时只有horizon和error
+type ForecastInfo struct {
+	Horizon          string `json:"horizon" description:"预测最新样本之后多长时间的延迟"`
+	PredictedLatency uint64 `json:"predicted_latency_ns,omitempty"`
+	LowerLatency     uint64 `json:"lower_latency_ns,omitempty" description:"约95%预测区间的下界"`
+	UpperLatency     uint64 `json:"upper_latency_ns,omitempty" description:"约95%预测区间的上界"`
+	Error            string `json:"error,omitempty" description:"样本不足或延迟没有线性趋势时的原因"`
+}
+
+// MaxLatencyInfo 是趋势分析时间范围内读写延迟最大的采集周期的API响应格式，用于发现被平均值掩盖的瞬时尖峰
+type MaxLatencyInfo struct {
+	Period       string     `json:"period"`
+	Samples      int        `json:"samples" description:"时间范围内的采集周期数"`
+	ReadLatency  uint64     `json:"read_latency_ns,omitempty"`
+	ReadAt       *time.Time `json:"read_at,omitempty" description:"读延迟最大的周期的采集时间，时间范围内没有读时省略"`
+	WriteLatency uint64     `json:"write_latency_ns,omitempty"`
+	WriteAt      *time.Time `json:"write_at,omitempty" description:"写延迟最大的周期的采集时间，时间范围内没有写时省略"`
+}
+
+// TrendInfo 是Pod指标趋势的API响应格式
+type TrendInfo struct {
+	Metric        analyzer.MetricKind `json:"metric"`
+	Direction     string              `json:"direction"`
+	ChangePercent float64             `json:"change_percent"`
+	Period        string              `json:"period"`
+}
+
+// BuildAllMetrics 构建所有Pod指标的响应，storageAnalyzer可以为nil
+func BuildAllMetrics(storageMonitor *monitor.StorageMonitor, storageAnalyzer *analyzer.StorageAnalyzer) *PodMetricsResponse {
+	return BuildNamespaceMetrics(storageMonitor, storageAnalyzer, "")
+}
+
+// BuildNamespaceMetrics 构建指定命名空间内Pod指标的响应，namespace为空时包含所有Pod
+// 命名空间没有被监控的Pod时返回空响应而不是错误
+func BuildNamespaceMetrics(storageMonitor *monitor.StorageMonitor, storageAnalyzer *analyzer.StorageAnalyzer, namespace string) *PodMetricsResponse {
+	return BuildFilteredMetrics(storageMonitor, storageAnalyzer, PodFilter{Namespace: namespace})
+}
+
+// BuildFilteredMetrics 构建满足filter的Pod指标的响应，没有满足条件的Pod时返回空响应
+func BuildFilteredMetrics(storageMonitor *monitor.StorageMonitor, storageAnalyzer *analyzer.StorageAnalyzer, filter PodFilter) *PodMetricsResponse {
+	return buildFilteredMetrics(storageMonitor, storageAnalyzer, filter, newFreshness(storageMonitor, time.Now()))
+}
+
+// buildFilteredMetrics 与BuildFilteredMetrics相同，使用调用方计算的新鲜度
+func buildFilteredMetrics(storageMonitor *monitor.StorageMonitor, storageAnalyzer *analyzer.StorageAnalyzer, filter PodFilter, freshness Freshness) *PodMetricsResponse {
+	// 从存储监控器获取所有Pod的指标
+	allPodMetrics := storageMonitor.GetAllMetrics()
+
+	// 转换为API响应格式
+	podMetricsMap := make(map[string]*PodMetrics)
+	bottlenecks := make(map[string]string)
+	anomalies := make(map[string]bool)
+
+	// 响应以Pod名称为key，分析器以UID等内部key查询
+	for key, metrics := range allPodMetrics {
+		if !filter.Match(metrics) {
+			continue
+		}
+		podName := metrics.PodName
+		podMetricsMap[podName] = convertToPodMetrics(metrics)
+
+		// 获取瓶颈类型
+		if storageAnalyzer != nil {
+			bottleneckType := storageAnalyzer.GetBottleneckType(key)
+			bottlenecks[podName] = string(bottleneckType)
+
+			// 获取异常检测结果
+			anomalies[podName] = storageAnalyzer.HasAnomalyDetected(key)
+		}
+	}
+
+	return &PodMetricsResponse{
+		Timestamp:   time.Now(),
+		Freshness:   freshness,
+		PodMetrics:  podMetricsMap,
+		TopSlowPods: buildTopSlowPods(storageAnalyzer, defaultTopSlowLimit, analyzer.LatencyRankByTotal, analyzer.SlowScoreLatency, filter),
+		Bottlenecks: bottlenecks,
+		Anomalies:   anomalies,
+	}
+}
+
+// BuildAnomalies 构建满足filter且当前被判定为异常的Pod列表，按严重程度从高到低排序
+// 没有异常Pod或storageAnalyzer为nil时返回空列表
+func BuildAnomalies(storageMonitor *monitor.StorageMonitor, storageAnalyzer *analyzer.StorageAnalyzer, filter PodFilter) *AnomaliesResponse {
+	return buildAnomalies(storageMonitor, storageAnalyzer, filter, newFreshness(storageMonitor, time.Now()))
+}
+
+// buildAnomalies 与BuildAnomalies相同，使用调用方计算的新鲜度
+func buildAnomalies(storageMonitor *monitor.StorageMonitor, storageAnalyzer *analyzer.StorageAnalyzer, filter PodFilter, freshness Freshness) *AnomaliesResponse {
+	response := &AnomaliesResponse{
+		Timestamp: time.Now(),
+		Freshness: freshness,
+		Anomalies: make([]*PodAnomaly, 0),
+	}
+	if storageAnalyzer == nil {
+		return response
+	}
+
+	for key, metrics := range storageMonitor.GetAllMetrics() {
+		if !filter.Match(metrics) {
+			continue
+		}
+		if !storageAnalyzer.HasAnomalyDetected(key) {
+			continue
+		}
+
+		anomaly := &PodAnomaly{
+			PodMetrics: convertToPodMetrics(metrics),
+			Bottleneck: string(storageAnalyzer.GetBottleneckType(key)),
+		}
+		if detail, err := storageAnalyzer.GetAnomalyDetail(key); err == nil {
+			anomaly.ReadZScore = detail.ReadZScore
+			anomaly.WriteZScore = detail.WriteZScore
+			anomaly.Severity = detail.Severity()
+		}
+		if streak, ok := storageAnalyzer.GetAnomalyStreak(key); ok {
+			anomaly.AnomalyStreak = &AnomalyStreakInfo{
+				Above: streak.Above,
+				Below: streak.Below,
+			}
+		}
+		response.Anomalies = append(response.Anomalies, anomaly)
+	}
+
+	// 严重程度相同时按Pod名称排序，保证输出稳定
+	sort.Slice(response.Anomalies, func(i, j int) bool {
+		a, b := response.Anomalies[i], response.Anomalies[j]
+		if a.Severity != b.Severity {
+			return a.Severity > b.Severity
+		}
+		return a.PodMetrics.PodName < b.PodMetrics.PodName
+	})
+
+	return response
+}
+
+// BuildLatencyForecast 构建Pod在horizon之后的总延迟预测，无法预测时在Error中给出原因
+// podKey为分析器使用的Pod key，即PodStorageMetrics.Key
+func BuildLatencyForecast(storageAnalyzer *analyzer.StorageAnalyzer, podKey string, horizon time.Duration) *ForecastInfo {
+	forecast := &ForecastInfo{Horizon: horizon.String()}
+	predicted, interval, err := storageAnalyzer.ForecastLatency(podKey, horizon)
+	if err != nil {
+		forecast.Error = err.Error()
+		return forecast
+	}
+
+	forecast.PredictedLatency = predicted
+	forecast.LowerLatency = interval[0]
+	forecast.UpperLatency = interval[1]
+	return forecast
+}
+
+// BuildPodDetail 构建单个Pod指标的响应，podID为Pod UID或名称，Pod不存在时返回错误
+func BuildPodDetail(storageMonitor *monitor.StorageMonitor, storageAnalyzer *analyzer.StorageAnalyzer, podID string, metric analyzer.MetricKind) (*PodDetailResponse, error) {
+	return buildPodDetail(storageMonitor, storageAnalyzer, podID, metric, newFreshness(storageMonitor, time.Now()))
+}
+
+// buildPodDetail 与BuildPodDetail相同，使用调用方计算的新鲜度
+func buildPodDetail(storageMonitor *monitor.StorageMonitor, storageAnalyzer *analyzer.StorageAnalyzer, podID string, metric analyzer.MetricKind, freshness Freshness) (*PodDetailResponse, error) {
+	// 获取指定Pod的指标
+	metrics, err := storageMonitor.GetPodMetrics(podID)
+	if err != nil {
+		return nil, err
+	}
+	podKey := metrics.Key()
+
+	response := &PodDetailResponse{
+		Timestamp:  time.Now(),
+		Freshness:  freshness,
+		PodMetrics: convertToPodMetrics(metrics),
+	}
+
+	// 附上趋势分析时间范围内的存储事件，便于判断性能变化是否由挂载失败或节点磁盘压力引起
+	if events, err := storageMonitor.GetStorageEvents(podKey, response.Timestamp.Add(-trendPeriod)); err == nil {
+		for _, event := range events {
+			response.Events = append(response.Events, &StorageEventInfo{
+				Kind:      event.Kind,
+				Name:      event.Name,
+				Reason:    event.Reason,
+				Message:   event.Message,
+				Type:      event.Type,
+				Count:     event.Count,
+				Timestamp: event.Timestamp,
+			})
+		}
+	}
+
+	// 添加瓶颈、异常和趋势信息
+	if storageAnalyzer != nil {
+		bottleneck, confidence, contributions := storageAnalyzer.GetBottleneckAnalysis(podKey)
+		response.Bottleneck = string(bottleneck)
+		response.BottleneckConfidence = confidence
+		response.BottleneckContributions = &BottleneckContributionsInfo{
+			Queue:   contributions.Queue,
+			Disk:    contributions.Disk,
+			Network: contributions.Network,
+		}
+		response.Anomaly = storageAnalyzer.HasAnomalyDetected(podKey)
+		response.SmallIO = storageAnalyzer.HasSmallIO(podKey)
+		if detail, err := storageAnalyzer.GetAnomalyDetail(podKey); err == nil {
+			response.AnomalyDetail = convertToAnomalyDetailInfo(detail)
+		}
+		if streak, ok := storageAnalyzer.GetAnomalyStreak(podKey); ok {
+			response.AnomalyStreak = &AnomalyStreakInfo{
+				Above: streak.Above,
+				Below: streak.Below,
+			}
+		}
+
+		trend, change, err := storageAnalyzer.GetMetricTrend(podKey, metric, trendPeriod)
+		if err == nil {
+			response.Trend = &TrendInfo{
+				Metric:        metric,
+				Direction:     trend,
+				ChangePercent: change,
+				Period:        "5m",
+			}
+		}
+
+		if extremes, err := storageAnalyzer.GetLatencyExtremes(podKey, trendPeriod); err == nil {
+			response.MaxLatency = convertToMaxLatencyInfo(extremes)
+		}
+	}
+
+	return response, nil
+}
+
+// convertToMaxLatencyInfo 将分析器的延迟极值转换为API响应结构，只包含最大值
+func convertToMaxLatencyInfo(extremes analyzer.LatencyExtremes) *MaxLatencyInfo {
+	info := &MaxLatencyInfo{
+		Period:  "5m",
+		Samples: extremes.Samples,
+	}
+	if !extremes.MaxRead.Timestamp.IsZero() {
+		info.ReadLatency = extremes.MaxRead.Latency
+		info.ReadAt = &extremes.MaxRead.Timestamp
+	}
+	if !extremes.MaxWrite.Timestamp.IsZero() {
+		info.WriteLatency = extremes.MaxWrite.Latency
+		info.WriteAt = &extremes.MaxWrite.Timestamp
+	}
+	return info
+}
+
+// convertToAnomalyDetailInfo 将分析器的异常检测详情转换为API响应结构
+func convertToAnomalyDetailInfo(detail analyzer.AnomalyDetail) *AnomalyDetailInfo {
+	return &AnomalyDetailInfo{
+		ReadMean:    detail.ReadMean,
+		ReadStdDev:  detail.ReadStdDev,
+		ReadZScore:  detail.ReadZScore,
+		WriteMean:   detail.WriteMean,
+		WriteStdDev: detail.WriteStdDev,
+		WriteZScore: detail.WriteZScore,
+		Threshold:   detail.Threshold,
+		Samples:     detail.Samples,
+	}
+}
+
+// BuildTopSlowPods 按score评分构建最慢的Pod列表，storageAnalyzer为nil时返回nil
+func BuildTopSlowPods(storageAnalyzer *analyzer.StorageAnalyzer, limit int, by analyzer.LatencyRankBy, score analyzer.SlowScore) []*PodMetrics {
+	return buildTopSlowPods(storageAnalyzer, limit, by, score, PodFilter{})
+}
+
+// buildTopSlowPods 构建满足filter的Pod中最慢的Pod列表
+func buildTopSlowPods(storageAnalyzer *analyzer.StorageAnalyzer, limit int, by analyzer.LatencyRankBy, score analyzer.SlowScore, filter PodFilter) []*PodMetrics {
+	if storageAnalyzer == nil {
+		return nil
+	}
+
+	var slowPods []*PodMetrics
+	for _, pod := range storageAnalyzer.GetTopNSlowPodsScored(limit, filter.matcher(), score.ScoreFunc(by)) {
+		slowPods = append(slowPods, convertToPodMetrics(pod))
+	}
+	return slowPods
+}
+
+// ParseTopSlowQuery 校验慢Pod查询参数，limit为0时使用默认值，by为空时按总延迟排序
+func ParseTopSlowQuery(limit int, by string) (int, analyzer.LatencyRankBy, error) {
+	if limit == 0 {
+		limit = defaultTopSlowLimit
+	}
+	if limit < 0 || limit > maxTopSlowLimit {
+		return 0, "", fmt.Errorf("limit must be an integer between 1 and %d", maxTopSlowLimit)
+	}
+
+	rankBy := analyzer.LatencyRankByTotal
+	if by != "" {
+		switch analyzer.LatencyRankBy(by) {
+		case analyzer.LatencyRankByRead, analyzer.LatencyRankByWrite, analyzer.LatencyRankByTotal:
+			rankBy = analyzer.LatencyRankBy(by)
+		default:
+			return 0, "", fmt.Errorf("by must be one of read, write, total")
+		}
+	}
+
+	return limit, rankBy, nil
+}
+
+// ParseSlowScore 校验慢Pod的评分方式，为空时只按延迟排序
+func ParseSlowScore(score string) (analyzer.SlowScore, error) {
+	if score == "" {
+		return analyzer.SlowScoreLatency, nil
+	}
+	if !analyzer.SlowScore(score).Valid() {
+		return "", fmt.Errorf("score must be one of latency, weighted")
+	}
+	return analyzer.SlowScore(score), nil
+}
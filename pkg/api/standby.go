@@ -0,0 +1,98 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// 实例在主备模式中的角色，出现在就绪检查的响应中
+const (
+	roleActive  = "active"
+	roleStandby = "standby"
+)
+
+// HAStatus 提供主备模式中本实例是否为活动实例以及当前活动实例的标识，由k8s.LeaseElector实现
+type HAStatus interface {
+	Leading() bool
+	Holder() string
+}
+
+// WithHA 启用主备模式，status报告本实例不是活动实例时API只读：
+// 读请求转发给活动实例，活动实例的标识为其advertise URL；写请求返回503
+// identity为本实例的标识，用于避免把请求转发给自己；status为nil时不启用
+func WithHA(status HAStatus, identity string) ServerOption {
+	return func(s *Server) {
+		s.haStatus = status
+		s.haIdentity = identity
+	}
+}
+
+// role 返回本实例当前的角色，未启用主备模式时为空
+func (s *Server) role() string {
+	switch {
+	case s.haStatus == nil:
+		return ""
+	case s.haStatus.Leading():
+		return roleActive
+	default:
+		return roleStandby
+	}
+}
+
+// servedLocally 判断备用实例是否自己处理该路径，而不是转发给活动实例
+// 探针、版本和API文档描述的是本实例；/metrics不转发，避免Prometheus从每个实例都抓到活动实例的指标
+func servedLocally(path string) bool {
+	switch path {
+	case healthPath, readyPath, versionPath, probesPath, openAPIPath, swaggerPath, "/metrics":
+		return true
+	}
+	return false
+}
+
+// standbyMiddleware 本实例不是活动实例时把读请求转发给活动实例，拒绝写请求
+// 位于gzipMiddleware之前，转发的响应保持活动实例的编码，不会被再次压缩
+func (s *Server) standbyMiddleware(next http.Handler) http.Handler {
+	if s.haStatus == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.haStatus.Leading() || servedLocally(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeJSONError(w, "This is a read-only standby instance, send write requests to the active instance", http.StatusServiceUnavailable)
+			return
+		}
+
+		holder := s.haStatus.Holder()
+		target, err := url.Parse(holder)
+		switch {
+		case holder == "":
+			writeJSONError(w, "No active instance holds the lease", http.StatusServiceUnavailable)
+			return
+		case holder == s.haIdentity:
+			// 本实例刚启动，租约上还是上一次运行时的记录
+			writeJSONError(w, "Waiting for the lease held by a previous run of this instance to expire", http.StatusServiceUnavailable)
+			return
+		case err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "":
+			writeJSONError(w, fmt.Sprintf("Active instance %q has no advertise URL to forward requests to", holder), http.StatusServiceUnavailable)
+			return
+		}
+
+		proxy := &httputil.ReverseProxy{
+			Rewrite: func(pr *httputil.ProxyRequest) {
+				pr.SetURL(target)
+				pr.SetXForwarded()
+			},
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				writeJSONError(w, fmt.Sprintf("Failed to forward request to active instance %s: %v", holder, err), http.StatusBadGateway)
+			},
+		}
+		proxy.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// streamPath 以Server-Sent Events推送指标的API路径
+const streamPath = "/api/v1/metrics/stream"
+
+// defaultMaxStreamClients 默认允许同时连接的流式客户端数
+const defaultMaxStreamClients = 100
+
+// streamRetryAfter 流式客户端数达到上限时建议客户端等待的时间
+const streamRetryAfter = 10 * time.Second
+
+// WithMaxStreamClients 设置允许同时连接的流式客户端数，超过后新连接返回503，n不大于0时不生效
+// 每个流式连接占用一个goroutine直到客户端断开，限制数量以防止耗尽资源
+func WithMaxStreamClients(n int) ServerOption {
+	return func(s *Server) {
+		if n > 0 {
+			s.maxStreamClients = int64(n)
+		}
+	}
+}
+
+// acquireStreamClient 占用一个流式客户端名额，已达上限时返回false
+func (s *Server) acquireStreamClient() bool {
+	if s.streamClients.Add(1) > s.maxStreamClients {
+		s.streamClients.Add(-1)
+		return false
+	}
+	return true
+}
+
+// releaseStreamClient 释放流式客户端名额
+func (s *Server) releaseStreamClient() {
+	s.streamClients.Add(-1)
+}
+
+// handleMetricsStream 以Server-Sent Events推送所有Pod的指标，立即推送一次，之后每个采集周期推送一次，直到客户端断开
+// 支持namespace和cluster查询参数过滤，每个事件的data为与GET /api/v1/metrics相同的JSON
+func (s *Server) handleMetricsStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	if !s.acquireStreamClient() {
+		w.Header().Set("Retry-After", strconv.Itoa(int(streamRetryAfter.Seconds())))
+		writeJSONError(w, "Too many stream clients", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.releaseStreamClient()
+
+	filter := podFilterFromQuery(r)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// 避免反向代理缓冲事件
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	interval := s.storageMonitor.Interval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		data, err := json.Marshal(BuildFilteredMetrics(s.storageMonitor, s.storageAnalyzer, filter))
+		if err != nil {
+			fmt.Printf("Error encoding stream event: %v\n", err)
+			return
+		}
+		// 每个事件都有完整的写超时，连接不会因为服务器级的WriteTimeout而被断开
+		if err := s.extendWriteDeadline(w); err != nil {
+			fmt.Printf("Error extending stream write deadline: %v\n", err)
+			return
+		}
+		if _, err := fmt.Fprintf(w, "event: metrics\ndata: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-ticker.C:
+			// 采集周期可能在运行时修改
+			if current := s.storageMonitor.Interval(); current != interval {
+				interval = current
+				ticker.Reset(interval)
+			}
+		case <-r.Context().Done():
+			return
+		case <-s.shuttingDown:
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/analyzer"
+	"github.com/lizhongxuan/ioeye/pkg/ebpf"
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// newTestServer 返回使用模拟eBPF数据、没有Kubernetes客户端的API服务器
+func newTestServer(t *testing.T, opts ...ServerOption) *Server {
+	t.Helper()
+
+	bpfMonitor, err := ebpf.NewMonitor(ebpf.WithMockData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAPIServer(monitor.NewStorageMonitor(bpfMonitor, nil), analyzer.NewStorageAnalyzer(), "127.0.0.1:0", opts...)
+}
+
+// decodeError 解析错误响应的JSON信封
+func decodeError(t *testing.T, resp *http.Response) ErrorResponse {
+	t.Helper()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var body ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	return body
+}
+
+// openStream 连接指标流并读到第一个事件，确保服务器已占用名额
+func openStream(t *testing.T, url string) *http.Response {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		t.Fatalf("stream status = %d, want 200", resp.StatusCode)
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "event: metrics\n" {
+		resp.Body.Close()
+		t.Fatalf("first stream line = %q, %v, want a metrics event", line, err)
+	}
+	return resp
+}
+
+func TestMetricsStreamClientLimit(t *testing.T) {
+	const maxClients = 3
+	s := newTestServer(t, WithMaxStreamClients(maxClients))
+	ts := httptest.NewServer(http.HandlerFunc(s.handleMetricsStream))
+	defer ts.Close()
+
+	streams := make([]*http.Response, 0, maxClients)
+	for i := 0; i < maxClients; i++ {
+		streams = append(streams, openStream(t, ts.URL))
+	}
+	defer func() {
+		for _, resp := range streams {
+			resp.Body.Close()
+		}
+	}()
+
+	// 第n+1个连接被拒绝
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("stream %d status = %d, want 503", maxClients+1, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "10" {
+		t.Errorf("Retry-After = %q, want 10", got)
+	}
+	if body := decodeError(t, resp); body.Error.Code != "service_unavailable" || !strings.Contains(body.Error.Message, "Too many stream clients") {
+		t.Errorf("error = %+v, want service_unavailable", body.Error)
+	}
+
+	// 断开一个连接后名额释放
+	streams[0].Body.Close()
+	streams = streams[1:]
+	deadline := time.Now().Add(5 * time.Second)
+	for s.streamClients.Load() >= maxClients {
+		if time.Now().After(deadline) {
+			t.Fatal("stream slot not released after the client disconnected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	streams = append(streams, openStream(t, ts.URL))
+}
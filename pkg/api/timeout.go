@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+// 默认的HTTP服务器超时
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 60 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+)
+
+// Timeouts HTTP服务器的超时设置，防止慢速客户端长期占用连接
+type Timeouts struct {
+	ReadHeader time.Duration // 读取请求头的最长时间
+	Read       time.Duration // 读取整个请求（包括请求体）的最长时间
+	Write      time.Duration // 从读完请求头到写完响应的最长时间，流式接口按每个事件单独计算
+	Idle       time.Duration // keep-alive连接等待下一个请求的最长时间
+}
+
+// DefaultTimeouts 返回默认的HTTP服务器超时
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		ReadHeader: DefaultReadHeaderTimeout,
+		Read:       DefaultReadTimeout,
+		Write:      DefaultWriteTimeout,
+		Idle:       DefaultIdleTimeout,
+	}
+}
+
+// WithTimeouts 设置HTTP服务器的超时，不大于0的字段保持默认值
+func WithTimeouts(t Timeouts) ServerOption {
+	return func(s *Server) {
+		if t.ReadHeader > 0 {
+			s.timeouts.ReadHeader = t.ReadHeader
+		}
+		if t.Read > 0 {
+			s.timeouts.Read = t.Read
+		}
+		if t.Write > 0 {
+			s.timeouts.Write = t.Write
+		}
+		if t.Idle > 0 {
+			s.timeouts.Idle = t.Idle
+		}
+	}
+}
+
+// extendWriteDeadline 将连接的写超时顺延为从现在开始的一个Write超时，用于持续写出的流式响应
+// 服务器级的WriteTimeout从读完请求头开始计算，不顺延会在超时后断开长期连接
+func (s *Server) extendWriteDeadline(w http.ResponseWriter) error {
+	return http.NewResponseController(w).SetWriteDeadline(time.Now().Add(s.timeouts.Write))
+}
+
+// applyTimeouts 将超时设置应用到HTTP服务器，流式接口在每个事件前顺延写超时，见extendWriteDeadline
+func (s *Server) applyTimeouts(hs *http.Server) {
+	hs.ReadHeaderTimeout = s.timeouts.ReadHeader
+	hs.ReadTimeout = s.timeouts.Read
+	hs.WriteTimeout = s.timeouts.Write
+	hs.IdleTimeout = s.timeouts.Idle
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTimeoutServer 返回应用了s的超时设置的httptest服务器
+func newTimeoutServer(t *testing.T, s *Server) *httptest.Server {
+	t.Helper()
+
+	ts := httptest.NewUnstartedServer(okHandler)
+	s.applyTimeouts(ts.Config)
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// waitClosed 读取连接直到服务器关闭连接，返回读到的数据和等待的时间
+func waitClosed(t *testing.T, conn net.Conn) (string, time.Duration) {
+	t.Helper()
+
+	start := time.Now()
+	conn.SetReadDeadline(start.Add(5 * time.Second))
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("connection was not closed by the server: %v", err)
+	}
+	return string(data), time.Since(start)
+}
+
+func TestSlowHeaderClientDisconnected(t *testing.T) {
+	const readHeader = 100 * time.Millisecond
+	s := newTestServer(t, WithTimeouts(Timeouts{ReadHeader: readHeader}))
+	ts := newTimeoutServer(t, s)
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 只发送部分请求头，之后不再发送
+	if _, err := io.WriteString(conn, "GET /api/v1/metrics HTTP/1.1\r\nHost: ioeye\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, elapsed := waitClosed(t, conn)
+	if elapsed < readHeader/2 || elapsed > readHeader+2*time.Second {
+		t.Errorf("connection closed after %v, want about the %v read header timeout", elapsed, readHeader)
+	}
+	// 服务器不会处理不完整的请求
+	if strings.Contains(data, "200 OK") {
+		t.Errorf("slow header request was served: %q", data)
+	}
+}
+
+func TestIdleKeepAliveConnectionClosed(t *testing.T) {
+	const idle = 100 * time.Millisecond
+	s := newTestServer(t, WithTimeouts(Timeouts{Idle: idle}))
+	ts := newTimeoutServer(t, s)
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 完整的请求正常处理，连接保持keep-alive
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: ioeye\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	// 空闲超过Idle超时后服务器关闭连接
+	start := time.Now()
+	conn.SetReadDeadline(start.Add(5 * time.Second))
+	if n, err := reader.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("read on idle connection = %d, %v, want EOF", n, err)
+	}
+	if elapsed := time.Since(start); elapsed > idle+2*time.Second {
+		t.Errorf("idle connection closed after %v, want about %v", elapsed, idle)
+	}
+}
+
+func TestWithTimeoutsKeepsDefaults(t *testing.T) {
+	s := newTestServer(t, WithTimeouts(Timeouts{ReadHeader: time.Second}))
+	hs := &http.Server{}
+	s.applyTimeouts(hs)
+
+	if hs.ReadHeaderTimeout != time.Second {
+		t.Errorf("ReadHeaderTimeout = %v, want 1s", hs.ReadHeaderTimeout)
+	}
+	if hs.ReadTimeout != DefaultReadTimeout || hs.WriteTimeout != DefaultWriteTimeout || hs.IdleTimeout != DefaultIdleTimeout {
+		t.Errorf("timeouts = read %v, write %v, idle %v, want the defaults", hs.ReadTimeout, hs.WriteTimeout, hs.IdleTimeout)
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sync"
+)
+
+// ServerOption 配置API服务器的选项
+type ServerOption func(*Server)
+
+// certReloader 持有当前证书，支持在不重启服务器的情况下重新加载
+type certReloader struct {
+	certFile string
+	keyFile  string
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+}
+
+// WithTLSFiles 使用证书和私钥文件启用HTTPS，文件可通过ReloadCertificate重新加载
+func WithTLSFiles(certFile, keyFile string) ServerOption {
+	return func(s *Server) {
+		if certFile != "" && keyFile != "" {
+			s.certReloader = &certReloader{certFile: certFile, keyFile: keyFile}
+		}
+	}
+}
+
+// WithTLSConfig 使用自定义TLS配置启用HTTPS
+func WithTLSConfig(config *tls.Config) ServerOption {
+	return func(s *Server) {
+		s.tlsConfig = config
+	}
+}
+
+// ReloadCertificate 重新从文件加载证书，用于证书轮换
+func (s *Server) ReloadCertificate() error {
+	if s.certReloader == nil {
+		return fmt.Errorf("TLS certificate files are not configured")
+	}
+	return s.certReloader.load()
+}
+
+// buildTLSConfig 返回HTTP服务器使用的TLS配置，未启用TLS时返回nil
+func (s *Server) buildTLSConfig() (*tls.Config, error) {
+	if s.certReloader == nil {
+		return s.tlsConfig, nil
+	}
+
+	if err := s.certReloader.load(); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.tlsConfig != nil {
+		config = s.tlsConfig.Clone()
+	}
+	config.GetCertificate = s.certReloader.getCertificate
+	return config, nil
+}
+
+// load 从文件加载证书并替换当前证书
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cert = &cert
+	return nil
+}
+
+// getCertificate 为每次TLS握手返回当前证书
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 按Pod跟踪的API路径
+const (
+	tracingPath    = "/api/v1/tracing"
+	tracingPodPath = "/api/v1/tracing/pod/"
+)
+
+// TracingStatusResponse 是按Pod跟踪状态的API响应格式
+type TracingStatusResponse struct {
+	Filtering  bool        `json:"filtering"` // 为false时跟踪所有Pod
+	SampleRate uint32      `json:"sample_rate" description:"内核程序每N个I/O记录1个，计数已按N放大，1表示记录所有I/O"`
+	TracedPods []TracedPod `json:"traced_pods"`
+	Timestamp  time.Time   `json:"timestamp"`
+}
+
+// TracedPod 是被跟踪Pod的API响应格式
+type TracedPod struct {
+	PodName   string   `json:"pod_name"`
+	Namespace string   `json:"namespace"`
+	PodUID    string   `json:"pod_uid"`
+	CgroupIDs []uint64 `json:"cgroup_ids"`
+}
+
+// handleTracing 处理跟踪状态请求：GET返回当前状态，DELETE恢复跟踪所有Pod
+func (s *Server) handleTracing(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		if err := s.storageMonitor.TraceAllPods(); err != nil {
+			writeJSONError(w, fmt.Sprintf("Failed to reset tracing: %v", err), http.StatusInternalServerError)
+			return
+		}
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.writeTracingStatus(w)
+}
+
+// handleTracingPod 处理单个Pod的跟踪开关：POST开始跟踪，DELETE停止跟踪，路径中为Pod UID或名称
+// 与指标接口一样按UID区分Pod，同名Pod只有一个时可以使用名称
+func (s *Server) handleTracingPod(w http.ResponseWriter, r *http.Request) {
+	podName := strings.TrimSuffix(r.URL.Path[len(tracingPodPath):], "/")
+	if podName == "" {
+		writeJSONError(w, "Pod name is required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		podKey, err := s.storageMonitor.ResolvePodKey(podName)
+		if err == nil {
+			err = s.storageMonitor.EnablePodTracing(podKey)
+		}
+		if err != nil {
+			writeJSONError(w, fmt.Sprintf("Failed to enable tracing for pod %s: %v", podName, err), http.StatusNotFound)
+			return
+		}
+	case http.MethodDelete:
+		// 被跟踪的Pod可能已被删除，由监控器在正在跟踪的Pod中按UID或名称查找
+		if err := s.storageMonitor.DisablePodTracing(podName); err != nil {
+			writeJSONError(w, fmt.Sprintf("Failed to disable tracing for pod %s: %v", podName, err), http.StatusNotFound)
+			return
+		}
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.writeTracingStatus(w)
+}
+
+// writeTracingStatus 返回当前的按Pod跟踪状态
+func (s *Server) writeTracingStatus(w http.ResponseWriter) {
+	status := s.storageMonitor.GetTracingStatus()
+
+	response := &TracingStatusResponse{
+		Filtering:  status.Filtering,
+		SampleRate: status.SampleRate,
+		TracedPods: make([]TracedPod, 0, len(status.Pods)),
+		Timestamp:  time.Now(),
+	}
+	for _, pod := range status.Pods {
+		response.TracedPods = append(response.TracedPods, TracedPod{
+			PodName:   pod.PodName,
+			Namespace: pod.Namespace,
+			PodUID:    pod.PodUID,
+			CgroupIDs: pod.CgroupIDs,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// unixSocketPrefix 以Unix domain socket监听的地址前缀，如unix:///var/run/ioeye.sock
+const unixSocketPrefix = "unix://"
+
+// unixSocketPath 返回地址中的socket文件路径，不是Unix socket地址时返回false
+func unixSocketPath(address string) (string, bool) {
+	if !strings.HasPrefix(address, unixSocketPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(address, unixSocketPrefix), true
+}
+
+// listenUnix 在path上监听Unix domain socket，先清理上次异常退出遗留的socket文件
+// 监听器关闭时会删除socket文件
+func listenUnix(path string) (net.Listener, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty unix socket path")
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on unix socket %s: %v", path, err)
+	}
+	return listener, nil
+}
+
+// removeStaleSocket 删除没有进程在监听的socket文件
+// 路径不是socket或仍有进程在监听时返回错误，避免误删其他文件或抢占正在运行的实例
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat unix socket %s: %v", path, err)
+	}
+	if info.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a unix socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("unix socket %s is already in use", path)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale unix socket %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unixClient 返回通过path上的Unix socket发送请求的HTTP客户端
+func unixClient(path string) *http.Client {
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		},
+	}
+}
+
+func TestServeOnUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ioeye.sock")
+	s := newTestServer(t)
+	s.address = unixSocketPrefix + path
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Start(ctx)
+	}()
+
+	client := unixClient(path)
+	var resp *http.Response
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var err error
+		resp, err = client.Get("http://ioeye" + healthPath)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatalf("GET %s over unix socket: %v", healthPath, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var health HealthResponse
+	err := json.NewDecoder(resp.Body).Decode(&health)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || err != nil || health.Status != "healthy" {
+		t.Errorf("health = %d %+v, %v, want 200 healthy", resp.StatusCode, health, err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+	// 关闭时删除socket文件
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("socket file still exists after shutdown: %v", err)
+	}
+}
+
+func TestListenUnixRemovesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ioeye.sock")
+
+	// 模拟异常退出遗留的socket文件
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := listenUnix(path)
+	if err != nil {
+		t.Fatalf("listenUnix() over a stale socket error = %v", err)
+	}
+	defer listener.Close()
+
+	// 仍有进程在监听时拒绝抢占
+	if _, err := listenUnix(path); err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Errorf("listenUnix() on a socket in use error = %v, want already in use", err)
+	}
+}
+
+func TestListenUnixRefusesRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ioeye.sock")
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := listenUnix(path); err == nil || !strings.Contains(err.Error(), "not a unix socket") {
+		t.Errorf("listenUnix() on a regular file error = %v, want not a unix socket", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("regular file was removed: %v", err)
+	}
+}
+
+func TestUnixSocketPath(t *testing.T) {
+	tests := []struct {
+		address string
+		path    string
+		ok      bool
+	}{
+		{"unix:///var/run/ioeye.sock", "/var/run/ioeye.sock", true},
+		{":8080", "", false},
+		{"127.0.0.1:8080", "", false},
+	}
+	for _, tt := range tests {
+		path, ok := unixSocketPath(tt.address)
+		if path != tt.path || ok != tt.ok {
+			t.Errorf("unixSocketPath(%q) = %q, %v, want %q, %v", tt.address, path, ok, tt.path, tt.ok)
+		}
+	}
+}
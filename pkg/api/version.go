@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+)
+
+// 版本信息的API路径
+const versionPath = "/api/v1/version"
+
+// BuildInfo 构建时通过-ldflags注入的版本信息
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	BuildDate string
+}
+
+// VersionResponse 是版本信息的API响应格式
+type VersionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version" description:"编译使用的Go版本"`
+}
+
+// WithBuildInfo 设置/api/v1/version返回的版本信息
+func WithBuildInfo(info BuildInfo) ServerOption {
+	return func(s *Server) {
+		s.buildInfo = info
+	}
+}
+
+// handleVersion 返回正在运行的构建的版本信息
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := &VersionResponse{
+		Version:   s.buildInfo.Version,
+		Commit:    s.buildInfo.Commit,
+		BuildDate: s.buildInfo.BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
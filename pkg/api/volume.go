@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// 按PVC聚合指标的API路径
+const volumeMetricsPath = "/api/v1/metrics/volume"
+
+// VolumeMetrics 是PVC聚合指标的API响应格式
+type VolumeMetrics struct {
+	Cluster       string   `json:"cluster,omitempty"`
+	Namespace     string   `json:"namespace"`
+	PVCName       string   `json:"pvc"`
+	PVName        string   `json:"pv"`
+	StorageClass  string   `json:"storage_class,omitempty"`
+	CSIDriver     string   `json:"csi_driver,omitempty"`
+	Ambiguous     bool     `json:"ambiguous" description:"有Pod同时挂载了其他PVC，其I/O计入了每个PVC，指标可能高估该PVC的负载"`
+	AmbiguousPods []string `json:"ambiguous_pods,omitempty" description:"同时挂载了其他PVC的Pod"`
+	AggregateMetrics
+}
+
+// VolumeMetricsResponse 是按PVC聚合指标的API响应格式
+type VolumeMetricsResponse struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Volumes   []*VolumeMetrics `json:"volumes"`
+}
+
+// handleGetVolumeMetrics 处理按PVC聚合指标的请求，支持?namespace=和?cluster=过滤
+func (s *Server) handleGetVolumeMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	cluster := r.URL.Query().Get("cluster")
+	volumes := make([]*VolumeMetrics, 0)
+	for _, volume := range s.storageMonitor.GetVolumeMetrics() {
+		if namespace != "" && volume.Namespace != namespace {
+			continue
+		}
+		if cluster != "" && volume.Cluster != cluster {
+			continue
+		}
+		volumes = append(volumes, &VolumeMetrics{
+			Cluster:          volume.Cluster,
+			Namespace:        volume.Namespace,
+			PVCName:          volume.PVCName,
+			PVName:           volume.PVName,
+			StorageClass:     volume.StorageClass,
+			CSIDriver:        volume.CSIDriver,
+			Ambiguous:        volume.Ambiguous,
+			AmbiguousPods:    volume.AmbiguousPods,
+			AggregateMetrics: convertToAggregateMetrics(volume.AggregateMetrics),
+		})
+	}
+
+	response := &VolumeMetricsResponse{
+		Timestamp: time.Now(),
+		Volumes:   volumes,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,531 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/analyzer"
+	"github.com/lizhongxuan/ioeye/pkg/api"
+	"github.com/lizhongxuan/ioeye/pkg/ebpf"
+	"github.com/lizhongxuan/ioeye/pkg/export/kafka"
+	"github.com/lizhongxuan/ioeye/pkg/export/statsd"
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// Config IOEye的运行配置，可从YAML文件加载
+type Config struct {
+	Kubeconfig string `yaml:"kubeconfig"`
+	// KubeContexts 要监控的集群在kubeconfig中的context，第一个为IOEye所在的集群，
+	// 其余集群只提供Pod拓扑信息；为空时只监控当前集群
+	KubeContexts      []string `yaml:"kube_contexts"`
+	Namespaces        []string `yaml:"namespaces"`         // 为空时监控所有命名空间
+	LabelSelector     string   `yaml:"label_selector"`     // 只监控符合选择器的Pod
+	ExcludeNamespaces []string `yaml:"exclude_namespaces"` // 不监控的命名空间，优先于namespaces
+	ExcludePods       []string `yaml:"exclude_pods"`       // 不监控的Pod名称，支持*、?等通配符
+	Interval          int      `yaml:"interval"`           // 采集周期（秒）
+	UseInformer       bool     `yaml:"use_informer"`
+	CgroupRoot        string   `yaml:"cgroup_root"`
+	// StalenessWindow Pod超过该时间没有新的eBPF数据时标记为过期
+	StalenessWindow time.Duration `yaml:"staleness_window"`
+	// RateWindow interval-averaged速率的窗口长度
+	RateWindow time.Duration `yaml:"rate_window"`
+	// SmoothingWindow 对最近N个采集周期的指标计算滑动平均，0表示不计算
+	SmoothingWindow int `yaml:"smoothing_window"`
+
+	BPF      BPFConfig      `yaml:"bpf"`
+	API      APIConfig      `yaml:"api"`
+	Analyzer AnalyzerConfig `yaml:"analyzer"`
+	History  HistoryConfig  `yaml:"history"`
+	Alert    AlertConfig    `yaml:"alert"`
+	OTLP     OTLPConfig     `yaml:"otlp"`
+	Kafka    KafkaConfig    `yaml:"kafka"`
+	StatsD   StatsDConfig   `yaml:"statsd"`
+	HA       HAConfig       `yaml:"ha"`
+	Debug    DebugConfig    `yaml:"debug"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+// BPFConfig eBPF子系统配置
+type BPFConfig struct {
+	Object     string         `yaml:"object"`      // 编译后的eBPF对象文件路径
+	MockData   bool           `yaml:"mock_data"`   // 使用内置模拟数据而不加载eBPF程序
+	Require    bool           `yaml:"require"`     // 块I/O跟踪程序附加失败时退出，为false时以降级模式继续运行
+	SampleRate uint           `yaml:"sample_rate"` // 内核程序每N个I/O记录1个，计数按N放大，1表示记录所有I/O
+	FSFilter   FSFilterConfig `yaml:"fs_filter"`   // 按文件系统类型和挂载点过滤VFS读写
+	// Probes 块I/O和VFS跟踪程序的探针目标，默认为ebpf.DefaultProbeTargets；
+	// 只替换其中出现的跟踪程序的探针，其余跟踪程序使用默认的探针
+	Probes []ProbeConfig `yaml:"probes"`
+}
+
+// ProbeConfig 一个探针的附加目标，字段与ebpf.ProbeTarget一一对应
+type ProbeConfig struct {
+	Tracer  string `yaml:"tracer"`  // block或filesystem
+	Type    string `yaml:"type"`    // kprobe、kretprobe或tracepoint
+	Symbol  string `yaml:"symbol"`  // kprobe为内核函数名，tracepoint为"子系统/名称"
+	Program string `yaml:"program"` // eBPF对象中的程序名
+}
+
+// ProbeTargets 返回转换为ebpf.ProbeTarget的探针目标
+func (c BPFConfig) ProbeTargets() []ebpf.ProbeTarget {
+	targets := make([]ebpf.ProbeTarget, 0, len(c.Probes))
+	for _, p := range c.Probes {
+		targets = append(targets, ebpf.ProbeTarget(p))
+	}
+	return targets
+}
+
+// defaultProbes 返回内置的探针目标
+func defaultProbes() []ProbeConfig {
+	defaults := ebpf.DefaultProbeTargets()
+	probes := make([]ProbeConfig, 0, len(defaults))
+	for _, t := range defaults {
+		probes = append(probes, ProbeConfig(t))
+	}
+	return probes
+}
+
+// FSFilterConfig 按文件系统类型和挂载点过滤VFS读写，字段与ebpf.FSFilter一一对应
+// 同一维度只能设置包含或排除之一，都为空时不按该维度过滤
+type FSFilterConfig struct {
+	IncludeTypes  []string `yaml:"include_types"`  // 只跟踪这些类型的文件系统，例如ext4、xfs
+	ExcludeTypes  []string `yaml:"exclude_types"`  // 不跟踪这些类型的文件系统，例如overlay、tmpfs
+	IncludeMounts []string `yaml:"include_mounts"` // 只跟踪这些挂载点所在的文件系统
+	ExcludeMounts []string `yaml:"exclude_mounts"` // 不跟踪这些挂载点所在的文件系统
+}
+
+// APIConfig API服务器配置
+type APIConfig struct {
+	Addr     string    `yaml:"addr"`
+	GRPCAddr string    `yaml:"grpc_addr"` // 为空时不启用gRPC
+	Token    string    `yaml:"token"`     // 为空时不启用认证
+	TLS      TLSConfig `yaml:"tls"`
+	// ShutdownTimeout 优雅关闭时等待进行中请求完成的最长时间
+	ShutdownTimeout time.Duration   `yaml:"shutdown_timeout"`
+	RateLimit       RateLimitConfig `yaml:"rate_limit"`
+	// AllowedOrigins 允许跨域访问API的源，为空时只允许同源访问
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	// Timeouts HTTP服务器的超时，防止慢速客户端长期占用连接
+	Timeouts TimeoutsConfig `yaml:"timeouts"`
+}
+
+// TimeoutsConfig API服务器的HTTP超时配置
+type TimeoutsConfig struct {
+	ReadHeader time.Duration `yaml:"read_header"` // 读取请求头的最长时间
+	Read       time.Duration `yaml:"read"`        // 读取整个请求的最长时间
+	Write      time.Duration `yaml:"write"`       // 写完响应的最长时间，流式接口按每个事件计算
+	Idle       time.Duration `yaml:"idle"`        // keep-alive连接空闲的最长时间
+}
+
+// RateLimitConfig API按客户端限流配置
+type RateLimitConfig struct {
+	RPS   float64 `yaml:"rps"`   // 每个客户端每秒允许的请求数，为0时不限流
+	Burst int     `yaml:"burst"` // 允许的突发请求数
+}
+
+// TLSConfig API服务器的证书配置，两项需同时设置
+type TLSConfig struct {
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+}
+
+// AnalyzerConfig 存储性能分析器配置
+type AnalyzerConfig struct {
+	MaxHistoryPerPod           int           `yaml:"max_history_per_pod"`
+	AnomalyThreshold           float64       `yaml:"anomaly_threshold"`         // 标准差倍数
+	SustainedAnomalySamples    int           `yaml:"sustained_anomaly_samples"` // 进入或解除异常所需的连续样本数
+	EWMAAlpha                  float64       `yaml:"ewma_alpha"`                // 异常检测基线的EWMA平滑系数，为0时使用简单平均
+	ReadLatencyThreshold       time.Duration `yaml:"read_latency_threshold"`
+	WriteLatencyThreshold      time.Duration `yaml:"write_latency_threshold"`
+	QueueLatencyThreshold      time.Duration `yaml:"queue_latency_threshold"`
+	ContentionIOPSThreshold    uint64        `yaml:"contention_iops_threshold"`    // 判定设备整体过载的读写IOPS之和
+	DeviceUtilizationThreshold float64       `yaml:"device_utilization_threshold"` // 判定块设备饱和的利用率（0-1]
+	SmallIOSizeThreshold       uint64        `yaml:"small_io_size_threshold"`      // 判定I/O过小的平均请求大小（字节）
+	TopSlowWindow              int           `yaml:"topslow_window"`               // 慢Pod排名使用的最近样本数，1表示只看最新样本
+	TopSlowStat                string        `yaml:"topslow_stat"`                 // mean或p95
+}
+
+// HistoryConfig 指标历史持久化配置
+type HistoryConfig struct {
+	Path      string        `yaml:"path"` // 为空时不持久化
+	Retention time.Duration `yaml:"retention"`
+}
+
+// AlertConfig 告警配置
+type AlertConfig struct {
+	Webhook  string        `yaml:"webhook"` // 为空时不发送webhook
+	Cooldown time.Duration `yaml:"cooldown"`
+	// HistorySize /api/v1/alerts保留的最近告警记录条数
+	HistorySize int `yaml:"history_size"`
+	// EscalationWindow 该时间内反复进入异常或瓶颈状态时告警升级为warning
+	EscalationWindow time.Duration `yaml:"escalation_window"`
+	// CriticalAfter 连续处于异常或瓶颈状态超过该时间时告警升级为critical
+	CriticalAfter time.Duration `yaml:"critical_after"`
+}
+
+// OTLPConfig OTLP指标导出配置
+type OTLPConfig struct {
+	Endpoint string `yaml:"endpoint"` // host:port，为空时不导出
+	Insecure bool   `yaml:"insecure"`
+}
+
+// KafkaConfig Kafka指标导出配置
+type KafkaConfig struct {
+	Brokers   []string `yaml:"brokers"` // host:port列表，为空时不导出
+	Topic     string   `yaml:"topic"`
+	QueueSize int      `yaml:"queue_size"` // 待发送消息队列长度，队列满时丢弃最旧的消息
+}
+
+// StatsDConfig StatsD指标导出配置
+type StatsDConfig struct {
+	Addr   string `yaml:"addr"`   // host:port，为空时不导出
+	Format string `yaml:"format"` // statsd或dogstatsd
+	Prefix string `yaml:"prefix"` // 指标名称前缀
+}
+
+// 实例角色
+const (
+	RoleActive  = "active"  // 加载eBPF探针、采集并导出指标；配置了租约时持有租约后才开始工作
+	RoleStandby = "standby" // 只读备用实例，不加载eBPF探针也不导出指标，读请求转发给持有租约的实例
+)
+
+// HAConfig 主备模式配置
+type HAConfig struct {
+	Role           string        `yaml:"role"`            // active或standby
+	LeaseName      string        `yaml:"lease_name"`      // 选举使用的Lease名称前缀，实际名称加上节点名称后缀；为空时不选举，active实例始终工作
+	LeaseNamespace string        `yaml:"lease_namespace"` // Lease对象所在的命名空间
+	LeaseDuration  time.Duration `yaml:"lease_duration"`  // 持有者超过该时间没有续约时其他实例接管
+	// AdvertiseURL 其他实例访问本实例API的地址，作为租约的持有者标识；为空时使用主机名，备用实例无法转发读请求
+	AdvertiseURL string `yaml:"advertise_url"`
+	// NodeName 本实例所在的节点，通常通过downward API设为spec.nodeName
+	// 每个实例只跟踪所在节点的I/O，同一节点上的实例才互为主备，因此每个节点使用各自的Lease
+	NodeName string `yaml:"node_name"`
+}
+
+// NodeLeaseName 返回本节点选举使用的Lease名称，即"<lease_name>-<node_name>"
+func (h HAConfig) NodeLeaseName() string {
+	return h.LeaseName + "-" + h.NodeName
+}
+
+// DebugConfig 调试配置
+type DebugConfig struct {
+	PprofAddr string `yaml:"pprof_addr"` // pprof监听地址，为空时不启用
+}
+
+// 日志输出格式
+const (
+	LogFormatConsole = "console" // 便于人阅读的文本格式
+	LogFormatJSON    = "json"    // 每行一个JSON对象，便于日志系统采集
+)
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Format string `yaml:"format"` // console或json
+	Level  string `yaml:"level"`  // debug、info、warn、error等zap日志级别
+}
+
+// Default 返回内置默认配置，API token默认取自环境变量IOEYE_API_TOKEN
+func Default() *Config {
+	return &Config{
+		Interval:        10,
+		CgroupRoot:      k8s.DefaultCgroupRoot,
+		StalenessWindow: monitor.DefaultStalenessWindow,
+		RateWindow:      monitor.DefaultRateWindow,
+		BPF: BPFConfig{
+			Object:     ebpf.DefaultObjectFile,
+			Require:    true,
+			SampleRate: 1,
+			Probes:     defaultProbes(),
+		},
+		API: APIConfig{
+			Addr:            ":8080",
+			Token:           os.Getenv("IOEYE_API_TOKEN"),
+			ShutdownTimeout: api.DefaultShutdownTimeout,
+			Timeouts: TimeoutsConfig{
+				ReadHeader: api.DefaultReadHeaderTimeout,
+				Read:       api.DefaultReadTimeout,
+				Write:      api.DefaultWriteTimeout,
+				Idle:       api.DefaultIdleTimeout,
+			},
+			RateLimit: RateLimitConfig{
+				Burst: 20,
+			},
+		},
+		Analyzer: AnalyzerConfig{
+			MaxHistoryPerPod:           100,
+			AnomalyThreshold:           2.0,
+			SustainedAnomalySamples:    1,
+			ReadLatencyThreshold:       analyzer.ReadLatencyThreshold,
+			WriteLatencyThreshold:      analyzer.WriteLatencyThreshold,
+			QueueLatencyThreshold:      analyzer.QueueLatencyThreshold,
+			ContentionIOPSThreshold:    analyzer.ContentionIOPSThreshold,
+			DeviceUtilizationThreshold: analyzer.DeviceUtilizationThreshold,
+			SmallIOSizeThreshold:       analyzer.SmallIOSizeThreshold,
+			TopSlowWindow:              1,
+			TopSlowStat:                string(analyzer.RankStatMean),
+		},
+		History: HistoryConfig{
+			Retention: 24 * time.Hour,
+		},
+		Alert: AlertConfig{
+			Cooldown:    5 * time.Minute,
+			HistorySize: analyzer.DefaultAlertHistorySize,
+
+			EscalationWindow: analyzer.DefaultAlertEscalationWindow,
+			CriticalAfter:    analyzer.DefaultAlertCriticalAfter,
+		},
+		Kafka: KafkaConfig{
+			Topic:     kafka.DefaultTopic,
+			QueueSize: kafka.DefaultQueueSize,
+		},
+		StatsD: StatsDConfig{
+			Format: statsd.FormatDogStatsD,
+			Prefix: statsd.DefaultPrefix,
+		},
+		HA: HAConfig{
+			Role:           RoleActive,
+			LeaseNamespace: "kube-system",
+			LeaseDuration:  15 * time.Second,
+		},
+		Log: LogConfig{
+			Format: LogFormatConsole,
+			Level:  "info",
+		},
+	}
+}
+
+// Load 从YAML文件加载配置，文件中未设置的字段使用默认值
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	cfg := Default()
+	if err := cfg.decode(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	return cfg, nil
+}
+
+// LoadWithFlags 从YAML文件加载配置到cfg，fs中已设置的参数重新应用到文件的值之上
+// fs中的参数需绑定到cfg的字段，并在ApplyEnv之后调用，优先级为命令行参数 > 环境变量 > 配置文件 > 默认值
+func LoadWithFlags(fs *flag.FlagSet, cfg *Config, path string) error {
+	// 命令行参数和ApplyEnv设置的参数都记录在fs中
+	overrides := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		overrides[f.Name] = f.Value.String()
+	})
+
+	fileCfg, err := Load(path)
+	if err != nil {
+		return err
+	}
+	*cfg = *fileCfg
+
+	for name, value := range overrides {
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("failed to reapply -%s over config file: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// decode 将YAML覆盖到当前配置上，未知字段视为错误以便发现拼写错误
+func (c *Config) decode(r io.Reader) error {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// Validate 校验配置的取值范围和字段组合
+func (c *Config) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be a positive number of seconds, got %d", c.Interval)
+	}
+	for _, ns := range c.Namespaces {
+		if ns == "" {
+			return fmt.Errorf("namespaces must not contain empty names")
+		}
+	}
+	for _, ns := range c.ExcludeNamespaces {
+		if ns == "" {
+			return fmt.Errorf("exclude_namespaces must not contain empty names")
+		}
+	}
+	if err := monitor.ValidateExcludePatterns(c.ExcludePods); err != nil {
+		return err
+	}
+	if c.API.Addr == "" {
+		return fmt.Errorf("api.addr is required")
+	}
+	if (c.API.TLS.Cert == "") != (c.API.TLS.Key == "") {
+		return fmt.Errorf("api.tls.cert and api.tls.key must be set together")
+	}
+	if c.Log.Format != LogFormatConsole && c.Log.Format != LogFormatJSON {
+		return fmt.Errorf("log.format must be %s or %s, got %q", LogFormatConsole, LogFormatJSON, c.Log.Format)
+	}
+	if _, err := zapcore.ParseLevel(c.Log.Level); err != nil {
+		return fmt.Errorf("invalid log.level: %v", err)
+	}
+	if c.StalenessWindow <= 0 {
+		return fmt.Errorf("staleness_window must be a positive duration, got %v", c.StalenessWindow)
+	}
+	if c.RateWindow <= 0 {
+		return fmt.Errorf("rate_window must be a positive duration, got %v", c.RateWindow)
+	}
+	if c.SmoothingWindow < 0 {
+		return fmt.Errorf("smoothing_window must not be negative, got %d", c.SmoothingWindow)
+	}
+	for i, kubeContext := range c.KubeContexts {
+		if kubeContext == "" {
+			return fmt.Errorf("kube_contexts must not contain empty names")
+		}
+		if slices.Contains(c.KubeContexts[:i], kubeContext) {
+			return fmt.Errorf("kube_contexts contains duplicate context %q", kubeContext)
+		}
+	}
+	if c.API.ShutdownTimeout <= 0 {
+		return fmt.Errorf("api.shutdown_timeout must be a positive duration, got %v", c.API.ShutdownTimeout)
+	}
+	for _, timeout := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"read_header", c.API.Timeouts.ReadHeader},
+		{"read", c.API.Timeouts.Read},
+		{"write", c.API.Timeouts.Write},
+		{"idle", c.API.Timeouts.Idle},
+	} {
+		if timeout.d <= 0 {
+			return fmt.Errorf("api.timeouts.%s must be a positive duration, got %v", timeout.name, timeout.d)
+		}
+	}
+	if c.API.RateLimit.RPS < 0 {
+		return fmt.Errorf("api.rate_limit.rps must not be negative, got %v", c.API.RateLimit.RPS)
+	}
+	if c.API.RateLimit.RPS > 0 && c.API.RateLimit.Burst <= 0 {
+		return fmt.Errorf("api.rate_limit.burst must be positive when api.rate_limit.rps is set, got %d", c.API.RateLimit.Burst)
+	}
+	if err := api.ValidateAllowedOrigins(c.API.AllowedOrigins); err != nil {
+		return fmt.Errorf("api.allowed_origins: %v", err)
+	}
+	if c.BPF.Object == "" && !c.BPF.MockData {
+		return fmt.Errorf("bpf.object is required unless bpf.mock_data is enabled")
+	}
+	if c.BPF.SampleRate < 1 || c.BPF.SampleRate > math.MaxUint32 {
+		return fmt.Errorf("bpf.sample_rate must be between 1 and %d, got %d", uint32(math.MaxUint32), c.BPF.SampleRate)
+	}
+	if err := ebpf.FSFilter(c.BPF.FSFilter).Validate(); err != nil {
+		return fmt.Errorf("bpf.fs_filter: %v", err)
+	}
+	if err := ebpf.ValidateProbeTargets(c.BPF.ProbeTargets()); err != nil {
+		return fmt.Errorf("bpf.probes: %v", err)
+	}
+	if c.Analyzer.MaxHistoryPerPod <= 0 {
+		return fmt.Errorf("analyzer.max_history_per_pod must be positive, got %d", c.Analyzer.MaxHistoryPerPod)
+	}
+	if c.Analyzer.AnomalyThreshold <= 0 {
+		return fmt.Errorf("analyzer.anomaly_threshold must be positive, got %v", c.Analyzer.AnomalyThreshold)
+	}
+	if c.Analyzer.SustainedAnomalySamples <= 0 {
+		return fmt.Errorf("analyzer.sustained_anomaly_samples must be positive, got %d", c.Analyzer.SustainedAnomalySamples)
+	}
+	if c.Analyzer.TopSlowWindow <= 0 {
+		return fmt.Errorf("analyzer.topslow_window must be positive, got %d", c.Analyzer.TopSlowWindow)
+	}
+	if !analyzer.RankStat(c.Analyzer.TopSlowStat).Valid() {
+		return fmt.Errorf("analyzer.topslow_stat must be %s or %s, got %q", analyzer.RankStatMean, analyzer.RankStatP95, c.Analyzer.TopSlowStat)
+	}
+	if c.Analyzer.EWMAAlpha < 0 || c.Analyzer.EWMAAlpha > 1 {
+		return fmt.Errorf("analyzer.ewma_alpha must be between 0 and 1, got %v", c.Analyzer.EWMAAlpha)
+	}
+	if c.Analyzer.ReadLatencyThreshold <= 0 || c.Analyzer.WriteLatencyThreshold <= 0 || c.Analyzer.QueueLatencyThreshold <= 0 {
+		return fmt.Errorf("analyzer latency thresholds must be positive durations")
+	}
+	if c.Analyzer.ContentionIOPSThreshold == 0 {
+		return fmt.Errorf("analyzer.contention_iops_threshold must be positive")
+	}
+	if c.Analyzer.DeviceUtilizationThreshold <= 0 || c.Analyzer.DeviceUtilizationThreshold > 1 {
+		return fmt.Errorf("analyzer.device_utilization_threshold must be in (0, 1], got %v", c.Analyzer.DeviceUtilizationThreshold)
+	}
+	if c.Analyzer.SmallIOSizeThreshold == 0 {
+		return fmt.Errorf("analyzer.small_io_size_threshold must be positive")
+	}
+	if c.History.Retention <= 0 {
+		return fmt.Errorf("history.retention must be a positive duration, got %v", c.History.Retention)
+	}
+	if c.Alert.Cooldown <= 0 {
+		return fmt.Errorf("alert.cooldown must be a positive duration, got %v", c.Alert.Cooldown)
+	}
+	if c.Alert.HistorySize <= 0 {
+		return fmt.Errorf("alert.history_size must be positive, got %d", c.Alert.HistorySize)
+	}
+	if c.Alert.EscalationWindow <= 0 {
+		return fmt.Errorf("alert.escalation_window must be a positive duration, got %v", c.Alert.EscalationWindow)
+	}
+	if c.Alert.CriticalAfter <= 0 {
+		return fmt.Errorf("alert.critical_after must be a positive duration, got %v", c.Alert.CriticalAfter)
+	}
+	for _, broker := range c.Kafka.Brokers {
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			return fmt.Errorf("kafka.brokers must be host:port addresses, got %q", broker)
+		}
+	}
+	if len(c.Kafka.Brokers) > 0 && c.Kafka.Topic == "" {
+		return fmt.Errorf("kafka.topic is required when kafka.brokers is set")
+	}
+	if c.Kafka.QueueSize <= 0 {
+		return fmt.Errorf("kafka.queue_size must be positive, got %d", c.Kafka.QueueSize)
+	}
+	if c.StatsD.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.StatsD.Addr); err != nil {
+			return fmt.Errorf("statsd.addr must be a host:port address, got %q", c.StatsD.Addr)
+		}
+	}
+	if c.StatsD.Format != statsd.FormatStatsD && c.StatsD.Format != statsd.FormatDogStatsD {
+		return fmt.Errorf("statsd.format must be %s or %s, got %q", statsd.FormatStatsD, statsd.FormatDogStatsD, c.StatsD.Format)
+	}
+	if c.StatsD.Prefix == "" {
+		return fmt.Errorf("statsd.prefix must not be empty")
+	}
+	if c.HA.Role != RoleActive && c.HA.Role != RoleStandby {
+		return fmt.Errorf("ha.role must be %s or %s, got %q", RoleActive, RoleStandby, c.HA.Role)
+	}
+	if c.HA.Role == RoleStandby && c.HA.LeaseName == "" {
+		return fmt.Errorf("ha.lease_name is required when ha.role is %s", RoleStandby)
+	}
+	if c.HA.LeaseName != "" && c.HA.LeaseNamespace == "" {
+		return fmt.Errorf("ha.lease_namespace is required when ha.lease_name is set")
+	}
+	// 全集群共用一个Lease时只有一个节点会被监控
+	if c.HA.LeaseName != "" && c.HA.NodeName == "" {
+		return fmt.Errorf("ha.node_name is required when ha.lease_name is set, each node elects its own active instance")
+	}
+	// 续约周期为租约时长的1/3，租约以整秒记录
+	if c.HA.LeaseDuration < 3*time.Second {
+		return fmt.Errorf("ha.lease_duration must be at least 3s, got %v", c.HA.LeaseDuration)
+	}
+	if c.HA.AdvertiseURL != "" {
+		if u, err := url.Parse(c.HA.AdvertiseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("ha.advertise_url must be an http or https URL, got %q", c.HA.AdvertiseURL)
+		}
+	}
+	return nil
+}
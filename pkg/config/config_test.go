@@ -0,0 +1,181 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig 将content写入临时目录中的name文件并返回路径
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkLoaded 检查示例配置中设置的字段，以及未设置的字段保留默认值
+func checkLoaded(t *testing.T, cfg *Config) {
+	t.Helper()
+
+	if want := []string{"prod", "staging"}; !reflect.DeepEqual(cfg.Namespaces, want) {
+		t.Errorf("Namespaces = %v, want %v", cfg.Namespaces, want)
+	}
+	if cfg.Interval != 5 {
+		t.Errorf("Interval = %d, want 5", cfg.Interval)
+	}
+	if cfg.API.Addr != ":9090" || cfg.API.TLS.Cert != "/etc/ioeye/tls.crt" || cfg.API.TLS.Key != "/etc/ioeye/tls.key" {
+		t.Errorf("API = %+v, want addr :9090 with TLS", cfg.API)
+	}
+	if cfg.Analyzer.ReadLatencyThreshold != 2*time.Millisecond || cfg.Analyzer.MaxHistoryPerPod != 500 {
+		t.Errorf("Analyzer = %+v, want read threshold 2ms and 500 samples", cfg.Analyzer)
+	}
+	if want := []string{"kafka-0:9092"}; !reflect.DeepEqual(cfg.Kafka.Brokers, want) {
+		t.Errorf("Kafka.Brokers = %v, want %v", cfg.Kafka.Brokers, want)
+	}
+
+	defaults := Default()
+	if cfg.Analyzer.WriteLatencyThreshold != defaults.Analyzer.WriteLatencyThreshold {
+		t.Errorf("WriteLatencyThreshold = %v, want default %v", cfg.Analyzer.WriteLatencyThreshold, defaults.Analyzer.WriteLatencyThreshold)
+	}
+	if cfg.API.Timeouts != defaults.API.Timeouts || cfg.Kafka.Topic != defaults.Kafka.Topic {
+		t.Errorf("unset fields changed: timeouts %+v, kafka topic %q", cfg.API.Timeouts, cfg.Kafka.Topic)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestLoadYAML(t *testing.T) {
+	path := writeConfig(t, "ioeye.yaml", `
+namespaces: [prod, staging]
+interval: 5
+api:
+  addr: ":9090"
+  tls:
+    cert: /etc/ioeye/tls.crt
+    key: /etc/ioeye/tls.key
+analyzer:
+  read_latency_threshold: 2ms
+  max_history_per_pod: 500
+kafka:
+  brokers:
+    - kafka-0:9092
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	checkLoaded(t, cfg)
+}
+
+func TestLoadJSON(t *testing.T) {
+	path := writeConfig(t, "ioeye.json", `{
+  "namespaces": ["prod", "staging"],
+  "interval": 5,
+  "api": {
+    "addr": ":9090",
+    "tls": {"cert": "/etc/ioeye/tls.crt", "key": "/etc/ioeye/tls.key"}
+  },
+  "analyzer": {"read_latency_threshold": "2ms", "max_history_per_pod": 500},
+  "kafka": {"brokers": ["kafka-0:9092"]}
+}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	checkLoaded(t, cfg)
+}
+
+func TestLoadEmptyFile(t *testing.T) {
+	cfg, err := Load(writeConfig(t, "ioeye.yaml", ""))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Load(empty file) = %+v, want the defaults", cfg)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown top-level field", "intervall: 5\n", "field intervall not found"},
+		{"unknown nested field", "api:\n  adress: :9090\n", "field adress not found"},
+		{"unknown JSON field", `{"interval": 5, "namespace": "prod"}`, "field namespace not found"},
+		{"wrong type", "interval: fast\n", "cannot unmarshal"},
+		{"bad duration", "analyzer:\n  read_latency_threshold: soon\n", "into time.Duration"},
+		{"malformed YAML", "api: [addr\n", "invalid config file"},
+		{"malformed JSON", `{"interval": 5,`, "invalid config file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, "ioeye.yaml", tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil || !strings.Contains(err.Error(), "failed to read config file") {
+		t.Errorf("Load(missing file) error = %v, want read error", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("Default().Validate() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"zero interval", func(c *Config) { c.Interval = 0 }, "interval must be a positive"},
+		{"empty namespace", func(c *Config) { c.Namespaces = []string{"prod", ""} }, "namespaces must not contain empty names"},
+		{"missing API address", func(c *Config) { c.API.Addr = "" }, "api.addr is required"},
+		{"TLS cert without key", func(c *Config) { c.API.TLS.Cert = "/etc/ioeye/tls.crt" }, "must be set together"},
+		{"unknown log format", func(c *Config) { c.Log.Format = "xml" }, "log.format"},
+		{"zero history", func(c *Config) { c.Analyzer.MaxHistoryPerPod = 0 }, "max_history_per_pod"},
+		{"zero latency threshold", func(c *Config) { c.Analyzer.QueueLatencyThreshold = 0 }, "latency thresholds must be positive"},
+		{"EWMA alpha above 1", func(c *Config) { c.Analyzer.EWMAAlpha = 1.5 }, "ewma_alpha"},
+		{"broker without port", func(c *Config) { c.Kafka.Brokers = []string{"kafka-0"} }, "kafka.brokers"},
+		{"standby without lease", func(c *Config) { c.HA.Role = RoleStandby }, "ha.lease_name is required"},
+		{"cluster-wide lease", func(c *Config) { c.HA.LeaseName = "ioeye-active" }, "ha.node_name is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(cfg)
+			if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeLeaseName(t *testing.T) {
+	cfg := Default()
+	cfg.HA.LeaseName = "ioeye-active"
+	cfg.HA.NodeName = "worker-1"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got := cfg.HA.NodeLeaseName(); got != "ioeye-active-worker-1" {
+		t.Errorf("NodeLeaseName() = %q, want ioeye-active-worker-1", got)
+	}
+}
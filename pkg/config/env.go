@@ -0,0 +1,43 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// EnvPrefix 命令行参数对应的环境变量名称前缀
+const EnvPrefix = "IOEYE_"
+
+// EnvName 返回命令行参数对应的环境变量名称，参数名转为大写、"-"替换为"_"并加上EnvPrefix，例如api-addr对应IOEYE_API_ADDR
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// ApplyEnv 用环境变量设置fs中没有在命令行上显式设置的参数，优先级为命令行参数 > 环境变量 > 默认值
+// 需在fs.Parse之后调用；值为空的环境变量视为未设置，skip中的参数不从环境变量读取
+// 集合参数的环境变量与命令行一样以逗号分隔多个值
+func ApplyEnv(fs *flag.FlagSet, skip ...string) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] || slices.Contains(skip, f.Name) {
+			return
+		}
+		name := EnvName(f.Name)
+		value := os.Getenv(name)
+		if value == "" {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for environment variable %s: %v", value, name, setErr)
+		}
+	})
+	return err
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newFlagSet 返回绑定到cfg部分字段的参数集合，与cmd/ioeye中的参数同名
+func newFlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("ioeye", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.IntVar(&cfg.Interval, "interval", cfg.Interval, "")
+	fs.StringVar(&cfg.API.Addr, "api-addr", cfg.API.Addr, "")
+	fs.StringVar(&cfg.Kubeconfig, "kubeconfig", cfg.Kubeconfig, "")
+	fs.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "")
+	fs.Float64Var(&cfg.Analyzer.AnomalyThreshold, "anomaly-threshold", cfg.Analyzer.AnomalyThreshold, "")
+	fs.DurationVar(&cfg.Analyzer.ReadLatencyThreshold, "read-latency-threshold", cfg.Analyzer.ReadLatencyThreshold, "")
+	fs.IntVar(&cfg.Analyzer.MaxHistoryPerPod, "max-history", cfg.Analyzer.MaxHistoryPerPod, "")
+	fs.Bool("validate", false, "")
+	return fs
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"interval":               "IOEYE_INTERVAL",
+		"api-addr":               "IOEYE_API_ADDR",
+		"read-latency-threshold": "IOEYE_READ_LATENCY_THRESHOLD",
+	}
+	for flagName, want := range tests {
+		if got := EnvName(flagName); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", flagName, got, want)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("IOEYE_INTERVAL", "30")
+	t.Setenv("IOEYE_API_ADDR", ":7070")
+	t.Setenv("IOEYE_KUBECONFIG", "/etc/ioeye/kubeconfig")
+	t.Setenv("IOEYE_READ_LATENCY_THRESHOLD", "3ms")
+	t.Setenv("IOEYE_LOG_LEVEL", "") // 空值视为未设置
+	t.Setenv("IOEYE_VALIDATE", "true")
+
+	cfg := Default()
+	fs := newFlagSet(cfg)
+	if err := fs.Parse([]string{"-interval=60"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyEnv(fs, "validate"); err != nil {
+		t.Fatalf("ApplyEnv() error = %v", err)
+	}
+
+	if cfg.Interval != 60 {
+		t.Errorf("Interval = %d, want 60 from the command line over the environment", cfg.Interval)
+	}
+	if cfg.API.Addr != ":7070" || cfg.Kubeconfig != "/etc/ioeye/kubeconfig" || cfg.Analyzer.ReadLatencyThreshold != 3*time.Millisecond {
+		t.Errorf("api addr %q, kubeconfig %q, read threshold %v, want the environment values",
+			cfg.API.Addr, cfg.Kubeconfig, cfg.Analyzer.ReadLatencyThreshold)
+	}
+	if cfg.Log.Level != "info" || cfg.Analyzer.MaxHistoryPerPod != 100 {
+		t.Errorf("log level %q, max history %d, want the defaults", cfg.Log.Level, cfg.Analyzer.MaxHistoryPerPod)
+	}
+	if fs.Lookup("validate").Value.String() != "false" {
+		t.Error("skipped flag was set from the environment")
+	}
+}
+
+func TestApplyEnvInvalidValue(t *testing.T) {
+	t.Setenv("IOEYE_INTERVAL", "often")
+
+	fs := newFlagSet(Default())
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	err := ApplyEnv(fs)
+	if err == nil || !strings.Contains(err.Error(), "IOEYE_INTERVAL") {
+		t.Errorf("ApplyEnv() error = %v, want it to name IOEYE_INTERVAL", err)
+	}
+}
+
+func TestFlagEnvFilePrecedence(t *testing.T) {
+	path := writeConfig(t, "ioeye.yaml", `
+interval: 5
+api:
+  addr: ":9090"
+log:
+  level: debug
+analyzer:
+  anomaly_threshold: 3
+`)
+	t.Setenv("IOEYE_INTERVAL", "30")
+	t.Setenv("IOEYE_API_ADDR", ":7070")
+
+	cfg := Default()
+	fs := newFlagSet(cfg)
+	if err := fs.Parse([]string{"-interval=60"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyEnv(fs); err != nil {
+		t.Fatalf("ApplyEnv() error = %v", err)
+	}
+	if err := LoadWithFlags(fs, cfg, path); err != nil {
+		t.Fatalf("LoadWithFlags() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"flag over env and file", cfg.Interval, 60},
+		{"env over file", cfg.API.Addr, ":7070"},
+		{"file over default", cfg.Log.Level, "debug"},
+		{"file over default", cfg.Analyzer.AnomalyThreshold, 3.0},
+		{"default", cfg.Analyzer.MaxHistoryPerPod, 100},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadWithFlagsInvalidFile(t *testing.T) {
+	cfg := Default()
+	fs := newFlagSet(cfg)
+	if err := fs.Parse([]string{"-interval=60"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadWithFlags(fs, cfg, writeConfig(t, "ioeye.yaml", "intervall: 5\n")); err == nil {
+		t.Error("LoadWithFlags() error = nil for an unknown field")
+	}
+}
@@ -0,0 +1,125 @@
+package ebpf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 跟踪程序名称，用于日志、AttachError和就绪检查
+const (
+	TracerBlock      = "block"      // 块I/O，提供所有基础指标
+	TracerNFS        = "nfs"        // NFS RPC往返延迟
+	TracerISCSI      = "iscsi"      // iSCSI命令往返延迟
+	TracerIOUring    = "io_uring"   // io_uring读写请求
+	TracerFilesystem = "filesystem" // 文件系统操作
+	TracerCSI        = "csi"        // CSI操作
+)
+
+// requiredTracers 失败后无法采集任何I/O指标的跟踪程序，其余跟踪程序失败只缺少部分指标
+var requiredTracers = map[string]bool{TracerBlock: true}
+
+// AttachError 列出Start时附加失败的跟踪程序，其余跟踪程序已正常附加
+type AttachError struct {
+	Failed map[string]error // 以跟踪程序名称为key的失败原因
+}
+
+// Error 按跟踪程序名称排序输出所有失败原因
+func (e *AttachError) Error() string {
+	names := make([]string, 0, len(e.Failed))
+	for name := range e.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s: %v", name, e.Failed[name]))
+	}
+	return "failed to attach eBPF tracers: " + strings.Join(parts, "; ")
+}
+
+// RequiredFailed 返回是否有必需的跟踪程序附加失败，此时监控器只能在降级模式下运行
+func (e *AttachError) RequiredFailed() bool {
+	for name := range e.Failed {
+		if requiredTracers[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// Degraded 返回必需的跟踪程序是否附加失败，降级模式下没有I/O指标，只有Kubernetes中的Pod信息
+func (m *Monitor) Degraded() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name := range m.tracerErrors {
+		if requiredTracers[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// FailedTracers 返回Start时附加失败的跟踪程序及原因，全部成功时为空
+func (m *Monitor) FailedTracers() map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	failed := make(map[string]string, len(m.tracerErrors))
+	for name, err := range m.tracerErrors {
+		failed[name] = err.Error()
+	}
+	return failed
+}
+
+// 探针类型
+const (
+	ProbeKprobe     = "kprobe"
+	ProbeKretprobe  = "kretprobe"
+	ProbeTracepoint = "tracepoint"
+)
+
+// ProbeStatus 一个kprobe或tracepoint的附加状态
+type ProbeStatus struct {
+	Tracer   string // 所属的跟踪程序
+	Type     string // kprobe、kretprobe或tracepoint
+	Symbol   string // kprobe为内核函数名，tracepoint为"子系统/名称"
+	Attached bool
+	Error    string // 附加失败或随跟踪程序一起卸载的原因
+}
+
+// GetAttachedProbes 返回Start时尝试附加的所有探针及其状态，按附加顺序排列
+// 跟踪程序在附加探针之前就失败时（例如内核模块未加载）没有对应的探针，原因见FailedTracers；模拟数据模式下为空
+func (m *Monitor) GetAttachedProbes() []ProbeStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]ProbeStatus(nil), m.probes...)
+}
+
+// recordProbe 记录一次探针附加的结果
+func (m *Monitor) recordProbe(tracer, probeType, symbol string, err error) {
+	status := ProbeStatus{Tracer: tracer, Type: probeType, Symbol: symbol, Attached: err == nil}
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.probes = append(m.probes, status)
+}
+
+// detachProbes 将跟踪程序已附加的探针标记为已卸载，用于跟踪程序的后续探针失败、已附加的探针随之关闭时
+func (m *Monitor) detachProbes(tracer string, cause error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.probes {
+		if m.probes[i].Tracer == tracer && m.probes[i].Attached {
+			m.probes[i].Attached = false
+			m.probes[i].Error = fmt.Sprintf("detached after another %s probe failed: %v", tracer, cause)
+		}
+	}
+}
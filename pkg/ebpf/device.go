@@ -0,0 +1,264 @@
+package ebpf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// procPartitionsPath 设备名称的来源，测试中可替换
+var procPartitionsPath = "/proc/partitions"
+
+// DeviceStats 单个块设备在最近一个统计窗口内的I/O统计
+// 只包含被跟踪的cgroup发起的I/O
+type DeviceStats struct {
+	Major           uint32
+	Minor           uint32
+	Name            string  // /proc/partitions中的设备名，无法识别时为空
+	ReadIOPS        uint64  // 每秒完成的读请求数
+	WriteIOPS       uint64  // 每秒完成的写请求数
+	ReadThroughput  uint64  // 每秒读取的字节数
+	WriteThroughput uint64  // 每秒写入的字节数
+	QueueLatencyNs  uint64  // 请求在调度队列中的平均等待时间（纳秒）
+	DiskLatencyNs   uint64  // 请求从下发到完成的平均设备耗时（纳秒）
+	Utilization     float64 // 窗口内设备至少有一个请求在处理的时间比例（0-1），即iostat的%util
+	LastUpdateTime  time.Time
+}
+
+// deviceAccumulator 统计窗口内单个设备的累计值
+type deviceAccumulator struct {
+	readOps        uint64
+	writeOps       uint64
+	readBytes      uint64
+	writeBytes     uint64
+	queueLatencyNs uint64 // 窗口内队列延迟总和
+	diskLatencyNs  uint64 // 窗口内设备耗时总和
+	busy           busyIntervals
+}
+
+// deviceTracker 维护设备名称，只在perf事件读取goroutine中使用
+type deviceTracker struct {
+	names map[uint32]string // 以内核dev_t为key的设备名
+}
+
+// busyIntervals 窗口内各请求从下发到完成的时间区间（内核单调时钟，纳秒）
+type busyIntervals [][2]uint64
+
+// add 记录一个请求的处理区间
+func (b *busyIntervals) add(start, end uint64) {
+	if end > start {
+		*b = append(*b, [2]uint64{start, end})
+	}
+}
+
+// total 返回区间并集的总长度，即至少有一个请求在处理的时间，会对区间原地排序
+func (b busyIntervals) total() uint64 {
+	sort.Slice(b, func(i, j int) bool { return b[i][0] < b[j][0] })
+
+	var busy, start, end uint64
+	for i, iv := range b {
+		if i == 0 || iv[0] > end {
+			busy += end - start
+			start, end = iv[0], iv[1]
+		} else if iv[1] > end {
+			end = iv[1]
+		}
+	}
+	return busy + end - start
+}
+
+// utilization 返回区间并集占窗口长度的比例
+// 窗口开始前下发的请求整段计入，因此结果最多截断为1
+func (b busyIntervals) utilization(elapsed time.Duration) float64 {
+	if elapsed <= 0 || len(b) == 0 {
+		return 0
+	}
+	return min(float64(b.total())/float64(elapsed.Nanoseconds()), 1)
+}
+
+// devMajor 从内核内部的dev_t编码中取出主设备号
+func devMajor(dev uint32) uint32 {
+	return dev >> 20
+}
+
+// devMinor 从内核内部的dev_t编码中取出次设备号
+func devMinor(dev uint32) uint32 {
+	return dev & (1<<20 - 1)
+}
+
+// mkdev 按内核内部的dev_t编码组合主次设备号
+func mkdev(major, minor uint32) uint32 {
+	return major<<20 | minor
+}
+
+// deviceKey 返回"major:minor"格式的设备标识
+func deviceKey(dev uint32) string {
+	return fmt.Sprintf("%d:%d", devMajor(dev), devMinor(dev))
+}
+
+// aggregateDeviceEvent 将块I/O事件累加到所在设备的统计中，其他来源的事件没有设备信息
+// weight为该事件代表的I/O数（采样率）
+func aggregateDeviceEvent(devices map[uint32]*deviceAccumulator, event *ioEvent, weight uint64) {
+	if event.Source != eventSourceBlock || event.Dev == 0 {
+		return
+	}
+
+	acc, ok := devices[event.Dev]
+	if !ok {
+		acc = &deviceAccumulator{}
+		devices[event.Dev] = acc
+	}
+
+	if event.Operation == 0 {
+		acc.readOps += weight
+		acc.readBytes += event.Bytes * weight
+	} else {
+		acc.writeOps += weight
+		acc.writeBytes += event.Bytes * weight
+	}
+	acc.queueLatencyNs += eventQueueLatency(event) * weight
+	acc.diskLatencyNs += eventDiskLatency(event) * weight
+	acc.busy.add(event.IOStart, event.IOEnd)
+}
+
+// eventQueueLatency 返回块I/O在调度队列中的等待时间，未经过调度队列时为0
+func eventQueueLatency(event *ioEvent) uint64 {
+	if event.QueueStart == 0 || event.IOStart <= event.QueueStart {
+		return 0
+	}
+	return event.IOStart - event.QueueStart
+}
+
+// eventDiskLatency 返回I/O从开始（块I/O为下发）到完成的耗时
+func eventDiskLatency(event *ioEvent) uint64 {
+	if event.IOEnd <= event.IOStart {
+		return 0
+	}
+	return event.IOEnd - event.IOStart
+}
+
+// buildDeviceStats 将窗口内的设备累计值转换为统计数据，elapsed为窗口长度，sampleRate为采样率
+func (t *deviceTracker) buildDeviceStats(devices map[uint32]*deviceAccumulator, elapsed time.Duration, now time.Time, sampleRate uint32) map[string]*DeviceStats {
+	// 出现新设备时重新读取设备名称，例如热插拔的磁盘
+	for dev := range devices {
+		if _, ok := t.names[dev]; !ok {
+			t.refreshNames()
+			break
+		}
+	}
+
+	seconds := elapsed.Seconds()
+	stats := make(map[string]*DeviceStats, len(devices))
+	for dev, acc := range devices {
+		s := &DeviceStats{
+			Major:          devMajor(dev),
+			Minor:          devMinor(dev),
+			Name:           t.names[dev],
+			Utilization:    sampledUtilization(acc.busy, elapsed, sampleRate),
+			LastUpdateTime: now,
+		}
+		if seconds > 0 {
+			s.ReadIOPS = uint64(float64(acc.readOps) / seconds)
+			s.WriteIOPS = uint64(float64(acc.writeOps) / seconds)
+			s.ReadThroughput = uint64(float64(acc.readBytes) / seconds)
+			s.WriteThroughput = uint64(float64(acc.writeBytes) / seconds)
+		}
+		if ops := acc.readOps + acc.writeOps; ops > 0 {
+			s.QueueLatencyNs = acc.queueLatencyNs / ops
+			s.DiskLatencyNs = acc.diskLatencyNs / ops
+		}
+		stats[deviceKey(dev)] = s
+	}
+	return stats
+}
+
+// refreshNames 从/proc/partitions重新读取设备名称，读取失败时保留已有的名称
+func (t *deviceTracker) refreshNames() {
+	names, err := readPartitions(procPartitionsPath)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", procPartitionsPath, err)
+		return
+	}
+	t.names = names
+}
+
+// readPartitions 解析/proc/partitions，返回以dev_t为key的设备名
+// 格式为表头和空行之后每行"major minor #blocks name"
+func readPartitions(path string) (map[uint32]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	names := make(map[uint32]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 {
+			continue
+		}
+		major, err1 := strconv.ParseUint(fields[0], 10, 32)
+		minor, err2 := strconv.ParseUint(fields[1], 10, 32)
+		if err1 != nil || err2 != nil {
+			continue // 表头
+		}
+		names[mkdev(uint32(major), uint32(minor))] = fields[3]
+	}
+	return names, scanner.Err()
+}
+
+// GetDeviceStats 获取最近一个统计窗口内各块设备的I/O统计，key为"major:minor"
+// 窗口内没有被跟踪的I/O的设备不会出现在结果中
+func (m *Monitor) GetDeviceStats() (map[string]*DeviceStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.mockData {
+		m.loadMockDeviceStats()
+	}
+
+	result := make(map[string]*DeviceStats, len(m.deviceStatsCache))
+	for key, stats := range m.deviceStatsCache {
+		statsCopy := *stats
+		result[key] = &statsCopy
+	}
+	return result, nil
+}
+
+// loadMockDeviceStats 用模拟数据填充设备统计缓存，调用方需持有m.mu
+func (m *Monitor) loadMockDeviceStats() {
+	now := m.clock()
+	m.deviceStatsCache = map[string]*DeviceStats{
+		"8:0": {
+			Major:           8,
+			Minor:           0,
+			Name:            "sda",
+			ReadIOPS:        3500,
+			WriteIOPS:       2500,
+			ReadThroughput:  7 * 1024 * 1024,
+			WriteThroughput: 4 * 1024 * 1024,
+			QueueLatencyNs:  600000,  // 0.6ms
+			DiskLatencyNs:   1300000, // 1.3ms
+			Utilization:     0.45,
+			LastUpdateTime:  now,
+		},
+		"259:0": {
+			Major:           259,
+			Minor:           0,
+			Name:            "nvme0n1",
+			ReadIOPS:        1500,
+			WriteIOPS:       500,
+			ReadThroughput:  2 * 1024 * 1024,
+			WriteThroughput: 500 * 1024,
+			QueueLatencyNs:  300000, // 0.3ms
+			DiskLatencyNs:   850000, // 0.85ms
+			Utilization:     0.12,
+			LastUpdateTime:  now,
+		},
+	}
+}
@@ -0,0 +1,241 @@
+package ebpf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+// fsTypes 常见文件系统的名称和超级块magic，与include/uapi/linux/magic.h一致
+// ext2、ext3和ext4使用相同的magic，统计时都归为ext4
+var fsTypes = []struct {
+	name      string
+	magic     uint64
+	ephemeral bool // 数据随容器或节点重启丢失
+}{
+	{"ext4", 0xef53, false},
+	{"ext3", 0xef53, false},
+	{"ext2", 0xef53, false},
+	{"xfs", 0x58465342, false},
+	{"btrfs", 0x9123683e, false},
+	{"f2fs", 0xf2f52010, false},
+	{"zfs", 0x2fc12fc1, false},
+	{"nfs", 0x6969, false},
+	{"ceph", 0x00c36400, false},
+	{"fuse", 0x65735546, false},
+	{"squashfs", 0x73717368, false},
+	{"overlay", 0x794c7630, true},
+	{"tmpfs", 0x01021994, true},
+	{"ramfs", 0x858458f6, true},
+	{"hugetlbfs", 0x958458f6, true},
+	{"proc", 0x9fa0, true},
+	{"sysfs", 0x62656572, true},
+	{"cgroup2", 0x63677270, true},
+	{"devpts", 0x1cd1, true},
+	{"pipefs", 0x50495045, true},
+	{"sockfs", 0x534f434b, true},
+}
+
+// FSTypeStats 单个文件系统类型在统计窗口内的VFS读写
+type FSTypeStats struct {
+	Ephemeral      bool   // 是否为overlay、tmpfs等数据不持久化的文件系统
+	ReadOps        uint64 // 读操作次数
+	WriteOps       uint64 // 写操作次数
+	ReadBytes      uint64 // 读取的字节数
+	WriteBytes     uint64 // 写入的字节数
+	ReadLatencyNs  uint64 // 平均读延迟（纳秒）
+	WriteLatencyNs uint64 // 平均写延迟（纳秒）
+}
+
+// fsAccumulator 统计窗口内单个文件系统类型的累计值
+type fsAccumulator struct {
+	stats          FSTypeStats
+	readLatencyNs  uint64 // 窗口内读延迟总和
+	writeLatencyNs uint64 // 窗口内写延迟总和
+}
+
+// FSTypeName 返回超级块magic对应的文件系统名称，未知的magic返回十六进制形式，例如"0x1234"
+func FSTypeName(magic uint64) string {
+	for _, fs := range fsTypes {
+		if fs.magic == magic {
+			return fs.name
+		}
+	}
+	return fmt.Sprintf("%#x", magic)
+}
+
+// FSTypes 返回可用于过滤的文件系统名称，按名称排序
+func FSTypes() []string {
+	names := make([]string, 0, len(fsTypes))
+	for _, fs := range fsTypes {
+		names = append(names, fs.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fsTypeMagic 返回文件系统名称对应的超级块magic
+func fsTypeMagic(name string) (uint64, bool) {
+	for _, fs := range fsTypes {
+		if fs.name == name {
+			return fs.magic, true
+		}
+	}
+	return 0, false
+}
+
+// isEphemeralFS 判断magic对应的文件系统是否不持久化数据，未知的文件系统视为持久化
+func isEphemeralFS(magic uint64) bool {
+	for _, fs := range fsTypes {
+		if fs.magic == magic {
+			return fs.ephemeral
+		}
+	}
+	return false
+}
+
+// addFSEvent 将VFS读写事件累加到所属文件系统类型的统计中，weight为该事件代表的I/O数
+func (acc *ioAccumulator) addFSEvent(event *ioEvent, weight uint64) {
+	name := FSTypeName(event.FSMagic)
+	if acc.fs == nil {
+		acc.fs = make(map[string]*fsAccumulator)
+	}
+	fs, ok := acc.fs[name]
+	if !ok {
+		fs = &fsAccumulator{stats: FSTypeStats{Ephemeral: isEphemeralFS(event.FSMagic)}}
+		acc.fs[name] = fs
+	}
+
+	latency := eventDiskLatency(event)
+	if event.Operation == 0 {
+		fs.stats.ReadOps += weight
+		fs.stats.ReadBytes += event.Bytes * weight
+		fs.readLatencyNs += latency * weight
+	} else {
+		fs.stats.WriteOps += weight
+		fs.stats.WriteBytes += event.Bytes * weight
+		fs.writeLatencyNs += latency * weight
+	}
+}
+
+// fsStats 计算窗口内各文件系统类型的平均延迟，没有VFS读写时返回nil
+func (acc *ioAccumulator) fsStats() map[string]FSTypeStats {
+	if len(acc.fs) == 0 {
+		return nil
+	}
+
+	result := make(map[string]FSTypeStats, len(acc.fs))
+	for name, fs := range acc.fs {
+		s := fs.stats
+		if s.ReadOps > 0 {
+			s.ReadLatencyNs = fs.readLatencyNs / s.ReadOps
+		}
+		if s.WriteOps > 0 {
+			s.WriteLatencyNs = fs.writeLatencyNs / s.WriteOps
+		}
+		result[name] = s
+	}
+	return result
+}
+
+// splitFilesystemPrograms 从spec中移除targets使用的VFS跟踪程序，返回只包含这些程序的spec副本
+// VFS程序通过CO-RE读取struct file，单独加载以免重定位失败时导致整个对象加载失败
+func splitFilesystemPrograms(spec *ebpf.CollectionSpec, targets []ProbeTarget) *ebpf.CollectionSpec {
+	fsSpec := spec.Copy()
+	fsSpec.Programs = make(map[string]*ebpf.ProgramSpec)
+	for _, name := range probePrograms(targets) {
+		if prog, ok := spec.Programs[name]; ok {
+			fsSpec.Programs[name] = prog
+			delete(spec.Programs, name)
+		}
+	}
+	return fsSpec
+}
+
+// attachFilesystemTracer 加载VFS跟踪程序并附加到探针目标（默认为vfs_read和vfs_write）上，按文件系统类型统计读写
+// 对象中没有对应程序、内核中没有对应函数或附加失败时返回错误，此时没有按文件系统类型的统计，其余跟踪程序照常工作
+func (m *Monitor) attachFilesystemTracer() error {
+	if m.mockData {
+		return nil
+	}
+	if m.fsSpec == nil {
+		return fmt.Errorf("eBPF object not loaded")
+	}
+
+	if err := m.loadFilesystemTracer(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.fsAttached = true
+	m.mu.Unlock()
+	return nil
+}
+
+// loadFilesystemTracer 复用已加载的映射加载VFS跟踪程序并附加探针，失败时释放已创建的资源
+func (m *Monitor) loadFilesystemTracer() error {
+	targets := m.tracerProbes(TracerFilesystem)
+	var missing []string
+	for _, name := range probePrograms(targets) {
+		if _, ok := m.fsSpec.Programs[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("VFS programs not found in eBPF object: %s", strings.Join(missing, ", "))
+	}
+	// 在加载程序之前检查，内核函数名不同时给出所有缺失的函数
+	if err := checkKernelSymbols(targets); err != nil {
+		return err
+	}
+
+	// 与块I/O跟踪程序共享events、过滤等映射
+	replacements := make(map[string]*ebpf.Map)
+	for name := range m.fsSpec.Maps {
+		if mp, ok := m.bpfMaps[name]; ok {
+			replacements[name] = mp
+		}
+	}
+
+	coll, err := ebpf.NewCollectionWithOptions(m.fsSpec, ebpf.CollectionOptions{MapReplacements: replacements})
+	if err != nil {
+		return fmt.Errorf("failed to load VFS programs: %v", err)
+	}
+	// 替换的映射是克隆出的文件描述符，程序加载后不再需要
+	for _, mp := range coll.Maps {
+		mp.Close()
+	}
+
+	var links []link.Link
+	for _, target := range targets {
+		l, err := attachProbe(target, coll.Programs[target.Program])
+		m.recordProbe(TracerFilesystem, target.Type, target.Symbol, err)
+		if err != nil {
+			for _, l := range links {
+				l.Close()
+			}
+			m.detachProbes(TracerFilesystem, err)
+			for _, prog := range coll.Programs {
+				prog.Close()
+			}
+			return fmt.Errorf("failed to attach %s %s: %v", target.Type, target.Symbol, err)
+		}
+		links = append(links, l)
+	}
+
+	for name, prog := range coll.Programs {
+		m.bpfPrograms[name] = prog
+	}
+	m.links = append(m.links, links...)
+	return nil
+}
+
+// FilesystemTracingEnabled 返回VFS跟踪程序是否已附加，模拟数据模式下始终为false
+func (m *Monitor) FilesystemTracingEnabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fsAttached
+}
@@ -0,0 +1,136 @@
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/cilium/ebpf"
+)
+
+// 按cgroup过滤I/O的映射在eBPF对象中的名称，与bpf/io_tracer.c一致
+const (
+	filterConfigMap  = "filter_config"
+	tracedCgroupsMap = "traced_cgroups"
+)
+
+// EnablePod 开始跟踪指定cgroup的I/O
+// 首次调用后内核程序只记录已启用的cgroup，调用TraceAllPods恢复跟踪所有cgroup
+// 跟踪程序尚未加载时只记录设置，加载后统一写入
+func (m *Monitor) EnablePod(cgroupID uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tracedCgroups[cgroupID] = true
+	if !m.filterEnabled {
+		m.filterEnabled = true
+		return m.applyFilter()
+	}
+
+	tracedMap, _, err := m.filterMaps()
+	if err != nil || tracedMap == nil {
+		return err
+	}
+	if err := tracedMap.Put(cgroupID, uint8(1)); err != nil {
+		return fmt.Errorf("failed to enable tracing for cgroup %d: %v", cgroupID, err)
+	}
+	return nil
+}
+
+// DisablePod 停止跟踪指定cgroup的I/O，只在已启用过滤时生效
+func (m *Monitor) DisablePod(cgroupID uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.tracedCgroups, cgroupID)
+
+	tracedMap, _, err := m.filterMaps()
+	if err != nil || tracedMap == nil {
+		return err
+	}
+	if err := tracedMap.Delete(cgroupID); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return fmt.Errorf("failed to disable tracing for cgroup %d: %v", cgroupID, err)
+	}
+	return nil
+}
+
+// TraceAllPods 关闭按cgroup过滤，恢复跟踪所有cgroup的I/O
+func (m *Monitor) TraceAllPods() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tracedCgroups = make(map[uint64]bool)
+	m.filterEnabled = false
+	return m.applyFilter()
+}
+
+// TracingFilter 返回是否启用了按cgroup过滤，以及当前跟踪的cgroup ID（升序）
+func (m *Monitor) TracingFilter() (enabled bool, cgroupIDs []uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cgroupIDs = make([]uint64, 0, len(m.tracedCgroups))
+	for id := range m.tracedCgroups {
+		cgroupIDs = append(cgroupIDs, id)
+	}
+	sort.Slice(cgroupIDs, func(i, j int) bool { return cgroupIDs[i] < cgroupIDs[j] })
+
+	return m.filterEnabled, cgroupIDs
+}
+
+// applyFilter 将完整的过滤设置写入内核映射，调用方需持有m.mu
+func (m *Monitor) applyFilter() error {
+	tracedMap, configMap, err := m.filterMaps()
+	if err != nil || tracedMap == nil {
+		return err
+	}
+
+	// 先同步cgroup集合再切换开关，避免启用过滤瞬间漏掉已选中的cgroup
+	var key uint64
+	var stale []uint64
+	iter := tracedMap.Iterate()
+	for iter.Next(&key, new(uint8)) {
+		if !m.tracedCgroups[key] {
+			stale = append(stale, key)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to iterate %s map: %v", tracedCgroupsMap, err)
+	}
+	for _, id := range stale {
+		if err := tracedMap.Delete(id); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return fmt.Errorf("failed to disable tracing for cgroup %d: %v", id, err)
+		}
+	}
+	for id := range m.tracedCgroups {
+		if err := tracedMap.Put(id, uint8(1)); err != nil {
+			return fmt.Errorf("failed to enable tracing for cgroup %d: %v", id, err)
+		}
+	}
+
+	var enabled uint8
+	if m.filterEnabled {
+		enabled = 1
+	}
+	if err := configMap.Put(uint32(0), enabled); err != nil {
+		return fmt.Errorf("failed to update %s map: %v", filterConfigMap, err)
+	}
+	return nil
+}
+
+// filterMaps 返回过滤相关的映射，模拟数据模式或跟踪程序尚未加载时返回nil
+func (m *Monitor) filterMaps() (traced, config *ebpf.Map, err error) {
+	if m.mockData || len(m.bpfMaps) == 0 {
+		return nil, nil, nil
+	}
+
+	traced, ok := m.bpfMaps[tracedCgroupsMap]
+	if !ok {
+		return nil, nil, fmt.Errorf("map %s not found in eBPF object", tracedCgroupsMap)
+	}
+	config, ok = m.bpfMaps[filterConfigMap]
+	if !ok {
+		return nil, nil, fmt.Errorf("map %s not found in eBPF object", filterConfigMap)
+	}
+	return traced, config, nil
+}
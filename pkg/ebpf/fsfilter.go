@@ -0,0 +1,190 @@
+package ebpf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 按文件系统过滤VFS读写的映射在eBPF对象中的名称，与bpf/io_tracer.c一致
+const (
+	fsFilterConfigMap = "fs_filter_config"
+	fsTypeFilterMap   = "fs_type_filter"
+	fsMountFilterMap  = "fs_mount_filter"
+)
+
+// 过滤模式，与bpf/io_tracer.c中的FS_FILTER_*一致
+const (
+	fsFilterOff     uint8 = 0
+	fsFilterInclude uint8 = 1
+	fsFilterExclude uint8 = 2
+)
+
+// fs_filter_config中的索引，与bpf/io_tracer.c中的FS_FILTER_BY_*一致
+const (
+	fsFilterByType  uint32 = 0
+	fsFilterByMount uint32 = 1
+)
+
+// procMountInfoPath 解析挂载点所在文件系统时读取的mountinfo，测试中可替换
+var procMountInfoPath = "/proc/self/mountinfo"
+
+// FSFilter 按文件系统类型和挂载点过滤VFS读写，两个维度都满足时才跟踪
+// 同一维度只能设置包含或排除之一，都为空时不按该维度过滤
+type FSFilter struct {
+	IncludeTypes  []string // 只跟踪这些类型的文件系统，名称见FSTypes
+	ExcludeTypes  []string // 不跟踪这些类型的文件系统，例如overlay、tmpfs
+	IncludeMounts []string // 只跟踪这些挂载点所在的文件系统
+	ExcludeMounts []string // 不跟踪这些挂载点所在的文件系统
+}
+
+// Validate 检查文件系统类型是否已知，以及同一维度是否同时设置了包含和排除
+func (f FSFilter) Validate() error {
+	if len(f.IncludeTypes) > 0 && len(f.ExcludeTypes) > 0 {
+		return fmt.Errorf("include and exclude filesystem types are mutually exclusive")
+	}
+	if len(f.IncludeMounts) > 0 && len(f.ExcludeMounts) > 0 {
+		return fmt.Errorf("include and exclude mount points are mutually exclusive")
+	}
+	_, types := filterMode(f.IncludeTypes, f.ExcludeTypes)
+	for _, name := range types {
+		if _, ok := fsTypeMagic(name); !ok {
+			return fmt.Errorf("unknown filesystem type %q, supported: %s", name, strings.Join(FSTypes(), ", "))
+		}
+	}
+	_, mounts := filterMode(f.IncludeMounts, f.ExcludeMounts)
+	for _, mount := range mounts {
+		if !strings.HasPrefix(mount, "/") {
+			return fmt.Errorf("mount point %q must be an absolute path", mount)
+		}
+	}
+	return nil
+}
+
+// WithFSFilter 设置按文件系统类型和挂载点过滤VFS读写，filter需先通过Validate检查
+// 挂载点在跟踪程序加载时从mountinfo解析为文件系统的设备号，之后新挂载的文件系统不会被匹配
+func WithFSFilter(filter FSFilter) MonitorOption {
+	return func(m *Monitor) {
+		m.fsFilter = filter
+	}
+}
+
+// applyFSFilter 将文件系统过滤设置写入内核映射，跟踪程序尚未加载时直接返回
+func (m *Monitor) applyFSFilter() error {
+	if m.mockData || len(m.bpfMaps) == 0 {
+		return nil
+	}
+
+	configMap, ok := m.bpfMaps[fsFilterConfigMap]
+	if !ok {
+		return fmt.Errorf("map %s not found in eBPF object", fsFilterConfigMap)
+	}
+	typeMap, ok := m.bpfMaps[fsTypeFilterMap]
+	if !ok {
+		return fmt.Errorf("map %s not found in eBPF object", fsTypeFilterMap)
+	}
+	mountMap, ok := m.bpfMaps[fsMountFilterMap]
+	if !ok {
+		return fmt.Errorf("map %s not found in eBPF object", fsMountFilterMap)
+	}
+
+	typeMode, types := filterMode(m.fsFilter.IncludeTypes, m.fsFilter.ExcludeTypes)
+	for _, name := range types {
+		magic, ok := fsTypeMagic(name)
+		if !ok {
+			return fmt.Errorf("unknown filesystem type %q", name)
+		}
+		if err := typeMap.Put(magic, uint8(1)); err != nil {
+			return fmt.Errorf("failed to update %s map: %v", fsTypeFilterMap, err)
+		}
+	}
+
+	mountMode, mounts := filterMode(m.fsFilter.IncludeMounts, m.fsFilter.ExcludeMounts)
+	if len(mounts) > 0 {
+		devs, err := readMountDevs(procMountInfoPath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", procMountInfoPath, err)
+		}
+		for _, mount := range mounts {
+			dev, ok := devs[mount]
+			if !ok {
+				fmt.Printf("Mount point %s not found in %s, ignoring it in the filesystem filter\n", mount, procMountInfoPath)
+				continue
+			}
+			if err := mountMap.Put(dev, uint8(1)); err != nil {
+				return fmt.Errorf("failed to update %s map: %v", fsMountFilterMap, err)
+			}
+		}
+	}
+
+	for index, mode := range map[uint32]uint8{fsFilterByType: typeMode, fsFilterByMount: mountMode} {
+		if err := configMap.Put(index, mode); err != nil {
+			return fmt.Errorf("failed to update %s map: %v", fsFilterConfigMap, err)
+		}
+	}
+	return nil
+}
+
+// filterMode 返回一个维度的过滤模式和对应的列表，两者都设置时包含优先
+func filterMode(include, exclude []string) (uint8, []string) {
+	switch {
+	case len(include) > 0:
+		return fsFilterInclude, include
+	case len(exclude) > 0:
+		return fsFilterExclude, exclude
+	default:
+		return fsFilterOff, nil
+	}
+}
+
+// readMountDevs 解析mountinfo，返回以挂载点为key的文件系统设备号（内核dev_t编码）
+// 每行第3个字段为"major:minor"，第5个字段为挂载点，挂载点中的空格等字符按八进制转义
+func readMountDevs(path string) (map[string]uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	devs := make(map[string]uint32)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		major, minor, ok := strings.Cut(fields[2], ":")
+		if !ok {
+			continue
+		}
+		maj, err1 := strconv.ParseUint(major, 10, 32)
+		mnr, err2 := strconv.ParseUint(minor, 10, 32)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		devs[unescapeMountPath(fields[4])] = mkdev(uint32(maj), uint32(mnr))
+	}
+	return devs, scanner.Err()
+}
+
+// unescapeMountPath 还原mountinfo中按"\ooo"八进制转义的字符
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+4 <= len(path) {
+			if c, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package ebpf
+
+import "math/bits"
+
+// LatencyHistBuckets 延迟直方图的桶数
+// 与biolatency一致按微秒取log2分桶：第0个桶统计[0, 2)微秒，第i个桶统计[2^i, 2^(i+1))微秒
+const LatencyHistBuckets = 27
+
+// LatencyHist log2延迟直方图，每个元素为落入对应桶的I/O次数
+type LatencyHist [LatencyHistBuckets]uint64
+
+// LatencyBucketBounds 返回每个桶的上界（纳秒，不含）
+func LatencyBucketBounds() []uint64 {
+	bounds := make([]uint64, LatencyHistBuckets)
+	for i := range bounds {
+		bounds[i] = (uint64(1) << (i + 1)) * 1000
+	}
+	return bounds
+}
+
+// Observe 记录一次I/O延迟（纳秒）
+func (h *LatencyHist) Observe(latencyNs uint64) {
+	h[latencySlot(latencyNs)]++
+}
+
+// ObserveN 记录n次相同的I/O延迟（纳秒），用于按采样率放大样本
+func (h *LatencyHist) ObserveN(latencyNs, n uint64) {
+	h[latencySlot(latencyNs)] += n
+}
+
+// Add 将另一个直方图累加到h
+func (h *LatencyHist) Add(other *LatencyHist) {
+	for i := range h {
+		h[i] += other[i]
+	}
+}
+
+// latencySlot 计算延迟所属的桶，超出范围的延迟计入最后一个桶
+func latencySlot(latencyNs uint64) int {
+	slot := bits.Len64(latencyNs/1000) - 1
+	if slot < 0 {
+		return 0
+	}
+	if slot >= LatencyHistBuckets {
+		return LatencyHistBuckets - 1
+	}
+	return slot
+}
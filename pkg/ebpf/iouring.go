@@ -0,0 +1,149 @@
+package ebpf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+// io_uring跟踪程序在eBPF对象中的名称
+const (
+	ioUringSubmitSQEProg = "trace_io_uring_submit_sqe"
+	ioUringSubmitReqProg = "trace_io_uring_submit_req"
+	ioUringCompleteProg  = "trace_io_uring_complete"
+)
+
+// ioUringSubmitTracepoints 提交tracepoint及对应的跟踪程序，6.4内核起io_uring_submit_sqe更名为io_uring_submit_req
+var ioUringSubmitTracepoints = []struct{ prog, name string }{
+	{ioUringSubmitReqProg, "io_uring_submit_req"},
+	{ioUringSubmitSQEProg, "io_uring_submit_sqe"},
+}
+
+// tracefsRoots tracefs可能的挂载点
+var tracefsRoots = []string{"/sys/kernel/tracing", "/sys/kernel/debug/tracing"}
+
+// splitIOUringPrograms 从spec中移除io_uring跟踪程序，返回只包含这些程序的spec副本
+// 两个提交程序分别依赖新旧内核的tracepoint结构，单独加载以免导致整个对象加载失败
+func splitIOUringPrograms(spec *ebpf.CollectionSpec) *ebpf.CollectionSpec {
+	uringSpec := spec.Copy()
+	uringSpec.Programs = make(map[string]*ebpf.ProgramSpec)
+	for _, name := range []string{ioUringSubmitSQEProg, ioUringSubmitReqProg, ioUringCompleteProg} {
+		if prog, ok := spec.Programs[name]; ok {
+			uringSpec.Programs[name] = prog
+			delete(spec.Programs, name)
+		}
+	}
+	return uringSpec
+}
+
+// attachIOUringTracer 加载io_uring跟踪程序并附加到提交和完成tracepoint上，测量请求从提交到完成的延迟
+// io_uring绕过了VFS读写路径，不跟踪时使用io_uring的负载只能看到块层I/O
+// 内核没有io_uring tracepoint或对象中没有对应程序时返回错误，其余跟踪程序照常工作
+func (m *Monitor) attachIOUringTracer() error {
+	if m.mockData {
+		return nil
+	}
+	if m.ioUringSpec == nil {
+		return fmt.Errorf("eBPF object not loaded")
+	}
+
+	if err := m.loadIOUringTracer(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.ioUringAttached = true
+	m.mu.Unlock()
+	return nil
+}
+
+// loadIOUringTracer 按内核支持的tracepoint选择提交程序，复用已加载的映射加载并附加，失败时释放已创建的资源
+func (m *Monitor) loadIOUringTracer() error {
+	submit, err := ioUringSubmitTracepoint()
+	if err != nil {
+		return err
+	}
+
+	spec := m.ioUringSpec.Copy()
+	for _, tp := range ioUringSubmitTracepoints {
+		if tp.prog != submit.prog {
+			delete(spec.Programs, tp.prog)
+		}
+	}
+	if _, ok := spec.Programs[submit.prog]; !ok {
+		return fmt.Errorf("program %s not found in eBPF object", submit.prog)
+	}
+	if _, ok := spec.Programs[ioUringCompleteProg]; !ok {
+		return fmt.Errorf("program %s not found in eBPF object", ioUringCompleteProg)
+	}
+
+	// 与块I/O跟踪程序共享events、过滤等映射
+	replacements := make(map[string]*ebpf.Map)
+	for name := range spec.Maps {
+		if mp, ok := m.bpfMaps[name]; ok {
+			replacements[name] = mp
+		}
+	}
+
+	coll, err := ebpf.NewCollectionWithOptions(spec, ebpf.CollectionOptions{MapReplacements: replacements})
+	if err != nil {
+		return fmt.Errorf("failed to load io_uring programs: %v", err)
+	}
+	// 替换的映射是克隆出的文件描述符，程序加载后不再需要
+	for _, mp := range coll.Maps {
+		mp.Close()
+	}
+
+	var links []link.Link
+	for _, tp := range []struct{ prog, name string }{
+		submit,
+		{ioUringCompleteProg, "io_uring_complete"},
+	} {
+		l, err := link.Tracepoint("io_uring", tp.name, coll.Programs[tp.prog], nil)
+		m.recordProbe(TracerIOUring, ProbeTracepoint, "io_uring/"+tp.name, err)
+		if err != nil {
+			for _, l := range links {
+				l.Close()
+			}
+			m.detachProbes(TracerIOUring, err)
+			for _, prog := range coll.Programs {
+				prog.Close()
+			}
+			return fmt.Errorf("failed to attach tracepoint io_uring/%s: %v", tp.name, err)
+		}
+		links = append(links, l)
+	}
+
+	for name, prog := range coll.Programs {
+		m.bpfPrograms[name] = prog
+	}
+	m.links = append(m.links, links...)
+	return nil
+}
+
+// ioUringSubmitTracepoint 检查tracefs中存在的io_uring tracepoint，返回应使用的提交tracepoint
+// 内核未启用io_uring或早于5.1时没有这些tracepoint
+func ioUringSubmitTracepoint() (struct{ prog, name string }, error) {
+	for _, root := range tracefsRoots {
+		events := filepath.Join(root, "events", "io_uring")
+		if _, err := os.Stat(filepath.Join(events, "io_uring_complete")); err != nil {
+			continue
+		}
+		for _, tp := range ioUringSubmitTracepoints {
+			if _, err := os.Stat(filepath.Join(events, tp.name)); err == nil {
+				return tp, nil
+			}
+		}
+	}
+	return struct{ prog, name string }{}, fmt.Errorf("io_uring tracepoints not available in tracefs")
+}
+
+// IOUringTracingEnabled 返回io_uring跟踪是否已附加，模拟数据模式下始终为false
+func (m *Monitor) IOUringTracingEnabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ioUringAttached
+}
@@ -0,0 +1,140 @@
+package ebpf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+// iSCSI跟踪程序在eBPF对象中的名称
+const (
+	iscsiQueueProg = "trace_iscsi_queuecommand"
+	iscsiDoneProg  = "trace_iscsi_cmd_done"
+)
+
+// iscsiQueueSymbol libiscsi中下发SCSI命令的函数，iscsi_tcp、iSER等initiator驱动都通过它下发命令
+const iscsiQueueSymbol = "iscsi_queuecommand"
+
+// iscsiDoneSymbols SCSI命令完成的内核函数，5.16内核起为导出的scsi_done，之前为scsi_mq_done
+var iscsiDoneSymbols = []string{"scsi_done", "scsi_mq_done"}
+
+// iscsiModule 提供iscsi_queuecommand的内核模块
+const iscsiModule = "libiscsi"
+
+// sysModuleRoot 已加载的内核模块在sysfs中的目录，测试中可替换
+var sysModuleRoot = "/sys/module"
+
+// splitISCSIPrograms 从spec中移除iSCSI跟踪程序，返回只包含这些程序的spec副本
+// iSCSI程序附加在libiscsi模块的函数上，单独加载以免影响没有iSCSI卷的节点
+func splitISCSIPrograms(spec *ebpf.CollectionSpec) *ebpf.CollectionSpec {
+	iscsiSpec := spec.Copy()
+	iscsiSpec.Programs = make(map[string]*ebpf.ProgramSpec)
+	for _, name := range []string{iscsiQueueProg, iscsiDoneProg} {
+		if prog, ok := spec.Programs[name]; ok {
+			iscsiSpec.Programs[name] = prog
+			delete(spec.Programs, name)
+		}
+	}
+	return iscsiSpec
+}
+
+// attachISCSITracer 加载iSCSI跟踪程序并附加到SCSI命令的下发和完成上，测量iSCSI target的往返延迟
+// 块I/O延迟中包含了网络往返，单独测量后计入网络延迟，用于区分网络和target端的慢
+// 内核未加载libiscsi模块或对象中没有对应程序时返回错误，此时不报告iSCSI的网络延迟，其余跟踪程序照常工作
+func (m *Monitor) attachISCSITracer() error {
+	if m.mockData {
+		return nil
+	}
+	if m.iscsiSpec == nil {
+		return fmt.Errorf("eBPF object not loaded")
+	}
+	if !kernelModuleLoaded(iscsiModule) {
+		return fmt.Errorf("kernel module %s not loaded, no iSCSI sessions on this node", iscsiModule)
+	}
+
+	if err := m.loadISCSITracer(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.iscsiAttached = true
+	m.mu.Unlock()
+	return nil
+}
+
+// loadISCSITracer 复用已加载的映射加载iSCSI跟踪程序并附加kprobe，失败时释放已创建的资源
+func (m *Monitor) loadISCSITracer() error {
+	if len(m.iscsiSpec.Programs) != 2 {
+		return fmt.Errorf("iSCSI programs not found in eBPF object")
+	}
+
+	// 与块I/O跟踪程序共享requests、events等映射，下发时从requests中取得块I/O请求的cgroup
+	replacements := make(map[string]*ebpf.Map)
+	for name := range m.iscsiSpec.Maps {
+		if mp, ok := m.bpfMaps[name]; ok {
+			replacements[name] = mp
+		}
+	}
+
+	coll, err := ebpf.NewCollectionWithOptions(m.iscsiSpec, ebpf.CollectionOptions{MapReplacements: replacements})
+	if err != nil {
+		return fmt.Errorf("failed to load iSCSI programs: %v", err)
+	}
+	// 替换的映射是克隆出的文件描述符，程序加载后不再需要
+	for _, mp := range coll.Maps {
+		mp.Close()
+	}
+
+	closeAll := func(links []link.Link) {
+		for _, l := range links {
+			l.Close()
+		}
+		for _, prog := range coll.Programs {
+			prog.Close()
+		}
+	}
+
+	queue, err := link.Kprobe(iscsiQueueSymbol, coll.Programs[iscsiQueueProg], nil)
+	m.recordProbe(TracerISCSI, ProbeKprobe, iscsiQueueSymbol, err)
+	if err != nil {
+		closeAll(nil)
+		return fmt.Errorf("failed to attach kprobe %s: %v", iscsiQueueSymbol, err)
+	}
+
+	// 按内核版本依次尝试完成函数
+	var done link.Link
+	for _, symbol := range iscsiDoneSymbols {
+		done, err = link.Kprobe(symbol, coll.Programs[iscsiDoneProg], nil)
+		m.recordProbe(TracerISCSI, ProbeKprobe, symbol, err)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		closeAll([]link.Link{queue})
+		m.detachProbes(TracerISCSI, err)
+		return fmt.Errorf("failed to attach kprobe on SCSI command completion (tried %v): %v", iscsiDoneSymbols, err)
+	}
+
+	for name, prog := range coll.Programs {
+		m.bpfPrograms[name] = prog
+	}
+	m.links = append(m.links, queue, done)
+	return nil
+}
+
+// kernelModuleLoaded 判断内核模块是否已加载，编译进内核且带参数的模块同样出现在sysfs中
+func kernelModuleLoaded(name string) bool {
+	_, err := os.Stat(filepath.Join(sysModuleRoot, name))
+	return err == nil
+}
+
+// ISCSITracingEnabled 返回iSCSI命令延迟跟踪是否已附加，模拟数据模式下始终为false
+func (m *Monitor) ISCSITracingEnabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.iscsiAttached
+}
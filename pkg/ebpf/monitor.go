@@ -2,10 +2,12 @@ package ebpf
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
 	"github.com/cilium/ebpf/rlimit"
 )
 
@@ -19,9 +21,21 @@ type IOStatsData struct {
 	WriteOps       uint64 // 写操作次数
 	ReadBytes      uint64 // 读取的字节数
 	WriteBytes     uint64 // 写入的字节数
-	QueueLatencyNs uint64 // 队列延迟（纳秒）
-	DiskLatencyNs  uint64 // 磁盘延迟（纳秒）
-	NetworkLatencyNs uint64 // 网络延迟（纳秒，仅对于网络存储有效）
+	QueueLatencyNs uint64 // 队列延迟（纳秒），取读写队列延迟中较高者
+	DiskLatencyNs  uint64 // 磁盘延迟（纳秒），取读写磁盘延迟中较高者
+	ReadQueueLatencyNs  uint64 // 读请求队列延迟（纳秒）
+	WriteQueueLatencyNs uint64 // 写请求队列延迟（纳秒）
+	ReadDiskLatencyNs   uint64 // 读请求磁盘延迟（纳秒）
+	WriteDiskLatencyNs  uint64 // 写请求磁盘延迟（纳秒）
+	NetworkLatencyNs uint64 // 网络延迟（纳秒，仅对于网络存储有效），即NFS RPC和iSCSI命令的平均往返延迟
+	NetworkOps       uint64 // NFS RPC和iSCSI命令次数
+	IOUringOps       uint64 // 经io_uring提交的读写次数，已计入ReadOps和WriteOps
+	Utilization      float64 // 窗口内至少有一个块I/O请求在设备上处理的时间比例（0-1）
+	ReadLatencyHist  LatencyHist // 读延迟log2直方图
+	WriteLatencyHist LatencyHist // 写延迟log2直方图
+	// FSStats 按文件系统类型统计的VFS读写，key为类型名（见FSTypeName），文件系统跟踪程序未附加时为空
+	// VFS读写包含命中页缓存的请求，不计入ReadOps、WriteOps等块I/O统计
+	FSStats        map[string]FSTypeStats
 	LastUpdateTime time.Time // 最后更新时间
 }
 
@@ -31,64 +45,190 @@ type BPFSpecs struct {
 	MapSpecs  map[string]*ebpf.MapSpec
 }
 
+// DefaultObjectFile 默认的eBPF对象文件路径
+const DefaultObjectFile = "/bpf/io_tracer.o"
+
+// MonitorOption 配置eBPF监控器的选项
+type MonitorOption func(*Monitor)
+
 // Monitor 存储性能eBPF监控
 type Monitor struct {
 	bpfPrograms    map[string]*ebpf.Program
 	bpfMaps        map[string]*ebpf.Map
 	links          []link.Link
+	mu             sync.Mutex
 	ioStatsCache   map[string]*IOStatsData // 缓存按Pod/容器组织的I/O统计数据
-	lastCollectTime time.Time               // 上次收集时间，用于计算IOPS和吞吐量
+	deviceStatsCache map[string]*DeviceStats // 缓存按块设备（major:minor）组织的I/O统计数据
+	lastCollectTime time.Time               // 上次原始数据采集时间，即ioTotals的更新时间
+	ioTotals       map[string]IOCounters   // 自监控开始以来各key累计的I/O计数
+	iopsRate       *rateTracker            // GetIOPS使用的速率状态
+	throughputRate *rateTracker            // GetThroughput使用的速率状态
+	clock          func() time.Time        // 时间来源，测试中可替换
+	statsWindow    time.Duration           // 统计窗口长度
+	objectFile     string                  // 编译后的eBPF对象文件
+	mockData       bool                    // 使用模拟数据，不加载eBPF程序
+	eventReader    *perf.Reader
+	readerDone     chan struct{}
+	attached       bool                    // 必需的跟踪程序是否已成功附加
+	tracerErrors   map[string]error        // Start时附加失败的跟踪程序及原因
+	probes         []ProbeStatus           // Start时尝试附加的探针，受mu保护
+	filterEnabled  bool                    // 是否只跟踪tracedCgroups中的cgroup
+	tracedCgroups  map[uint64]bool         // 启用过滤时跟踪的cgroup ID
+	nfsSpec        *ebpf.CollectionSpec    // 与块I/O跟踪程序分开加载的NFS跟踪程序
+	nfsAttached    bool                    // NFS RPC跟踪程序是否已附加
+	iscsiSpec      *ebpf.CollectionSpec    // 与块I/O跟踪程序分开加载的iSCSI跟踪程序
+	iscsiAttached  bool                    // iSCSI跟踪程序是否已附加
+	ioUringSpec    *ebpf.CollectionSpec    // 与块I/O跟踪程序分开加载的io_uring跟踪程序
+	ioUringAttached bool                   // io_uring跟踪程序是否已附加
+	sampleRate     uint32                  // 采样率，内核程序每N个I/O记录1个
+	fsFilter       FSFilter                // 按文件系统类型和挂载点过滤VFS读写
+	fsSpec         *ebpf.CollectionSpec    // 与块I/O跟踪程序分开加载的VFS跟踪程序
+	fsAttached     bool                    // VFS跟踪程序是否已附加
+	probeTargets   []ProbeTarget           // 块I/O和VFS跟踪程序的探针目标，为空时使用DefaultProbeTargets
+	pidKeys        bool                    // 以进程ID而不是cgroup ID作为统计数据的key
 }
 
-// NewMonitor 创建一个新的eBPF存储性能监控器
-func NewMonitor() (*Monitor, error) {
-	// 提高rlimit，以便能够加载eBPF程序
-	if err := rlimit.RemoveMemlock(); err != nil {
-		return nil, fmt.Errorf("failed to remove rlimit memlock: %v", err)
+// WithMockData 使用内置模拟数据，适用于无法加载eBPF的测试或CI环境
+func WithMockData() MonitorOption {
+	return func(m *Monitor) {
+		m.mockData = true
+	}
+}
+
+// WithObjectFile 设置编译后的eBPF对象文件路径
+func WithObjectFile(path string) MonitorOption {
+	return func(m *Monitor) {
+		if path != "" {
+			m.objectFile = path
+		}
+	}
+}
+
+// WithStatsWindow 设置I/O统计的聚合窗口
+func WithStatsWindow(window time.Duration) MonitorOption {
+	return func(m *Monitor) {
+		if window > 0 {
+			m.statsWindow = window
+		}
 	}
+}
 
-	// 在正式环境中，我们会使用上面的go:generate注释生成Go代码
-	// 此处为简化示例，我们将实现基本功能
+// WithPIDKeys 以进程ID而不是cgroup ID作为统计数据的key
+// 用于纯cgroup v1节点，这类节点上bpf_get_current_cgroup_id对所有进程返回同一个根cgroup，由上层按进程映射到Pod
+func WithPIDKeys() MonitorOption {
+	return func(m *Monitor) {
+		m.pidKeys = true
+	}
+}
 
+// NewMonitor 创建一个新的eBPF存储性能监控器
+func NewMonitor(opts ...MonitorOption) (*Monitor, error) {
 	// 创建eBPF监控实例
 	m := &Monitor{
 		bpfPrograms:    make(map[string]*ebpf.Program),
 		bpfMaps:        make(map[string]*ebpf.Map),
 		ioStatsCache:   make(map[string]*IOStatsData),
-		lastCollectTime: time.Now(),
+		deviceStatsCache: make(map[string]*DeviceStats),
+		ioTotals:       make(map[string]IOCounters),
+		clock:          time.Now,
+		statsWindow:    10 * time.Second, // 默认10秒
+		objectFile:     DefaultObjectFile,
+		readerDone:     make(chan struct{}),
+		tracedCgroups:  make(map[uint64]bool),
+		tracerErrors:   make(map[string]error),
+		sampleRate:     1, // 默认记录所有I/O
+	}
+
+	// 应用选项
+	for _, opt := range opts {
+		opt(m)
 	}
 
-	// 在实际实现中，我们会加载编译后的eBPF对象
-	// 此处仅作为示例代码框架
+	// 累计计数从此刻开始
+	m.lastCollectTime = m.clock()
+	m.iopsRate = newRateTracker(m.lastCollectTime)
+	m.throughputRate = newRateTracker(m.lastCollectTime)
 
 	return m, nil
 }
 
 // Start 启动eBPF监控
 func (m *Monitor) Start() error {
-	// 在这里我们会加载并附加eBPF程序到相应的钩子点
-	// 例如，attach到块I/O子系统、文件系统操作等
-
-	// 示例：跟踪块设备I/O
-	if err := m.attachBlockIOTracer(); err != nil {
-		return fmt.Errorf("failed to attach block I/O tracer: %v", err)
+	// 提高rlimit，以便能够加载eBPF程序，失败时所有跟踪程序都无法加载
+	var memlockErr error
+	if !m.mockData {
+		if err := rlimit.RemoveMemlock(); err != nil {
+			memlockErr = fmt.Errorf("failed to remove rlimit memlock: %v", err)
+		}
 	}
 
-	// 示例：跟踪文件系统操作
-	if err := m.attachFilesystemTracer(); err != nil {
-		return fmt.Errorf("failed to attach filesystem tracer: %v", err)
+	// 逐个附加跟踪程序，某个失败不影响其余跟踪程序
+	// NFS、iSCSI、io_uring和文件系统跟踪程序复用块I/O跟踪程序加载的映射，因此块I/O必须最先附加
+	tracers := []struct {
+		name   string
+		attach func() error
+	}{
+		{TracerBlock, m.attachBlockIOTracer},
+		{TracerNFS, m.attachNFSTracer},
+		{TracerISCSI, m.attachISCSITracer},
+		{TracerIOUring, m.attachIOUringTracer},
+		{TracerFilesystem, m.attachFilesystemTracer},
+		{TracerCSI, m.attachCSITracer},
 	}
 
-	// 示例：跟踪CSI操作
-	if err := m.attachCSITracer(); err != nil {
-		return fmt.Errorf("failed to attach CSI tracer: %v", err)
+	failed := make(map[string]error)
+	for _, tracer := range tracers {
+		err := memlockErr
+		if err == nil {
+			err = tracer.attach()
+		}
+		if err != nil {
+			fmt.Printf("eBPF tracer %s not attached: %v\n", tracer.name, err)
+			failed[tracer.name] = err
+			continue
+		}
+		fmt.Printf("eBPF tracer %s attached\n", tracer.name)
 	}
 
+	m.mu.Lock()
+	m.tracerErrors = failed
+	m.attached = failed[TracerBlock] == nil
+	m.mu.Unlock()
+
+	if len(failed) > 0 {
+		return &AttachError{Failed: failed}
+	}
 	return nil
 }
 
+// Attached 返回跟踪程序是否已附加且仍在读取事件，模拟数据模式下Start成功即视为已附加
+func (m *Monitor) Attached() bool {
+	m.mu.Lock()
+	attached := m.attached
+	m.mu.Unlock()
+	if !attached {
+		return false
+	}
+
+	// perf事件读取goroutine退出后不再有新数据
+	if m.eventReader != nil {
+		select {
+		case <-m.readerDone:
+			return false
+		default:
+		}
+	}
+	return true
+}
+
 // Close 关闭eBPF监控，释放资源
 func (m *Monitor) Close() error {
+	// 先停止perf事件读取
+	if m.eventReader != nil {
+		m.eventReader.Close()
+		<-m.readerDone
+	}
+
 	// 关闭所有links
 	for _, link := range m.links {
 		link.Close()
@@ -108,12 +248,17 @@ func (m *Monitor) Close() error {
 }
 
 // GetIOStatsData 获取完整的I/O统计数据
+// 真实模式下返回perf事件读取goroutine聚合的最近一个统计窗口；同一周期还需要其他数据时使用TakeSnapshot
 func (m *Monitor) GetIOStatsData() (map[string]*IOStatsData, error) {
-	now := time.Now()
-	
-	// 在实际实现中，这里应该从eBPF maps中读取原始数据并计算统计信息
-	// 这里是简化的模拟实现
-	
+	return m.TakeSnapshot().Stats, nil
+}
+
+// loadMockStats 用模拟数据填充缓存，调用方需持有m.mu
+// 模拟数据中的操作次数和字节数按每秒计，按距上次采集的时长累加到累计计数
+func (m *Monitor) loadMockStats() {
+	now := m.clock()
+	elapsed := now.Sub(m.lastCollectTime).Seconds()
+
 	// 示例Pod统计数据
 	podStats := map[string]*IOStatsData{
 		"pod1": {
@@ -123,8 +268,15 @@ func (m *Monitor) GetIOStatsData() (map[string]*IOStatsData, error) {
 			WriteOps:       2000,           // 2000次操作
 			ReadBytes:      5 * 1024 * 1024,  // 5MB
 			WriteBytes:     3 * 1024 * 1024,  // 3MB
-			QueueLatencyNs: 500000,         // 0.5ms
-			DiskLatencyNs:  1200000,        // 1.2ms
+			ReadQueueLatencyNs:  450000,  // 0.45ms
+			WriteQueueLatencyNs: 500000,  // 0.5ms
+			ReadDiskLatencyNs:   1200000, // 1.2ms
+			WriteDiskLatencyNs:  1000000, // 1.0ms
+			Utilization:         0.28,
+			FSStats: map[string]FSTypeStats{
+				"ext4":    {ReadOps: 2400, WriteOps: 1500, ReadBytes: 4 * 1024 * 1024, WriteBytes: 2 * 1024 * 1024, ReadLatencyNs: 40000, WriteLatencyNs: 90000},
+				"overlay": {Ephemeral: true, ReadOps: 600, WriteOps: 500, ReadBytes: 1024 * 1024, WriteBytes: 1024 * 1024, ReadLatencyNs: 15000, WriteLatencyNs: 30000},
+			},
 			LastUpdateTime: now,
 		},
 		"pod2": {
@@ -134,8 +286,11 @@ func (m *Monitor) GetIOStatsData() (map[string]*IOStatsData, error) {
 			WriteOps:       1000,           // 1000次操作
 			ReadBytes:      3 * 1024 * 1024,  // 3MB
 			WriteBytes:     1 * 1024 * 1024,  // 1MB
-			QueueLatencyNs: 700000,         // 0.7ms
-			DiskLatencyNs:  1500000,        // 1.5ms
+			ReadQueueLatencyNs:  700000,  // 0.7ms
+			WriteQueueLatencyNs: 600000,  // 0.6ms
+			ReadDiskLatencyNs:   1300000, // 1.3ms
+			WriteDiskLatencyNs:  1500000, // 1.5ms
+			Utilization:         0.22,
 			LastUpdateTime: now,
 		},
 		"pod3": {
@@ -145,157 +300,116 @@ func (m *Monitor) GetIOStatsData() (map[string]*IOStatsData, error) {
 			WriteOps:       500,            // 500次操作
 			ReadBytes:      2 * 1024 * 1024,  // 2MB
 			WriteBytes:     500 * 1024,     // 500KB
-			QueueLatencyNs: 400000,         // 0.4ms
-			DiskLatencyNs:  900000,         // 0.9ms
+			ReadQueueLatencyNs:  400000,  // 0.4ms
+			WriteQueueLatencyNs: 300000,  // 0.3ms
+			ReadDiskLatencyNs:   900000,  // 0.9ms
+			WriteDiskLatencyNs:  800000,  // 0.8ms
+			Utilization:         0.12,
+			NetworkLatencyNs:    1800000, // 1.8ms，模拟使用NFS卷的Pod
+			NetworkOps:          400,
 			LastUpdateTime: now,
 		},
 	}
 	
 	// 更新缓存
 	for podName, stats := range podStats {
+		stats.QueueLatencyNs = max(stats.ReadQueueLatencyNs, stats.WriteQueueLatencyNs)
+		stats.DiskLatencyNs = max(stats.ReadDiskLatencyNs, stats.WriteDiskLatencyNs)
+		stats.ReadLatencyHist = mockLatencyHist(stats.ReadLatencyNs, stats.ReadOps)
+		stats.WriteLatencyHist = mockLatencyHist(stats.WriteLatencyNs, stats.WriteOps)
 		m.ioStatsCache[podName] = stats
+
+		totals, ok := m.ioTotals[podName]
+		if !ok {
+			totals.Since = m.lastCollectTime
+		}
+		totals.ReadOps += uint64(float64(stats.ReadOps) * elapsed)
+		totals.WriteOps += uint64(float64(stats.WriteOps) * elapsed)
+		totals.ReadBytes += uint64(float64(stats.ReadBytes) * elapsed)
+		totals.WriteBytes += uint64(float64(stats.WriteBytes) * elapsed)
+		m.ioTotals[podName] = totals
 	}
 	
 	m.lastCollectTime = now
-	
-	// 返回缓存副本
-	result := make(map[string]*IOStatsData)
-	for podName, stats := range m.ioStatsCache {
-		statsCopy := *stats
-		result[podName] = &statsCopy
-	}
-	
-	return result, nil
 }
 
 // GetIOLatencyData 获取IO延迟数据
 func (m *Monitor) GetIOLatencyData() (map[string]map[string]uint64, error) {
-	// 从缓存或eBPF map中获取I/O延迟数据
-	ioStats, err := m.GetIOStatsData()
-	if err != nil {
-		return nil, err
-	}
-	
-	// 转换为所需格式
-	latencyData := make(map[string]map[string]uint64)
-	for podName, stats := range ioStats {
-		latencyData[podName] = map[string]uint64{
-			"read_latency_ns":  stats.ReadLatencyNs,
-			"write_latency_ns": stats.WriteLatencyNs,
-		}
-	}
-	
-	return latencyData, nil
+	return m.IOLatencyFromSnapshot(m.TakeSnapshot()), nil
 }
 
-// GetQueueLatencyData 获取IO队列延迟数据
+// GetQueueLatencyData 获取各块设备的IO队列延迟数据，key为"major:minor"
 func (m *Monitor) GetQueueLatencyData() (map[string]uint64, error) {
-	// 从缓存或eBPF map中获取队列延迟数据
-	ioStats, err := m.GetIOStatsData()
+	deviceStats, err := m.GetDeviceStats()
 	if err != nil {
 		return nil, err
 	}
 	
 	// 转换为所需格式
 	queueLatency := make(map[string]uint64)
-	for podName, stats := range ioStats {
-		// 这里我们使用podName作为键，在实际实现中应该使用设备ID
-		queueLatency[podName] = stats.QueueLatencyNs
+	for device, stats := range deviceStats {
+		queueLatency[device] = stats.QueueLatencyNs
 	}
 	
 	return queueLatency, nil
 }
 
-// GetDiskLatencyData 获取磁盘延迟数据
+// GetDiskLatencyData 获取各块设备的磁盘延迟数据，key为"major:minor"
 func (m *Monitor) GetDiskLatencyData() (map[string]uint64, error) {
-	// 从缓存或eBPF map中获取磁盘延迟数据
-	ioStats, err := m.GetIOStatsData()
+	deviceStats, err := m.GetDeviceStats()
 	if err != nil {
 		return nil, err
 	}
 	
 	// 转换为所需格式
 	diskLatency := make(map[string]uint64)
-	for podName, stats := range ioStats {
-		// 这里我们使用podName作为键，在实际实现中应该使用设备ID
-		diskLatency[podName] = stats.DiskLatencyNs
+	for device, stats := range deviceStats {
+		diskLatency[device] = stats.DiskLatencyNs
 	}
 	
 	return diskLatency, nil
 }
 
-// GetIOPS 获取IOPS数据
+// GetIOPS 获取IOPS数据，计算方式见IOPSFromSnapshot
 func (m *Monitor) GetIOPS() (map[string]map[string]uint64, error) {
-	// 从缓存获取I/O操作计数
-	ioStats, err := m.GetIOStatsData()
-	if err != nil {
-		return nil, err
-	}
-	
-	// 计算经过的时间（秒）
-	elapsedTime := time.Since(m.lastCollectTime).Seconds()
-	if elapsedTime < 0.001 { // 防止除以极小的数
-		elapsedTime = 1.0
-	}
-	
-	// 计算IOPS
-	iopsData := make(map[string]map[string]uint64)
-	for podName, stats := range ioStats {
-		readIOPS := uint64(float64(stats.ReadOps) / elapsedTime)
-		writeIOPS := uint64(float64(stats.WriteOps) / elapsedTime)
-		
-		iopsData[podName] = map[string]uint64{
-			"read_iops":  readIOPS,
-			"write_iops": writeIOPS,
-			"total_iops": readIOPS + writeIOPS,
-		}
-	}
-	
-	return iopsData, nil
+	return m.IOPSFromSnapshot(m.TakeSnapshot()), nil
 }
 
-// GetThroughput 获取吞吐量数据（字节/秒）
+// GetThroughput 获取吞吐量数据（字节/秒），计算方式见ThroughputFromSnapshot
 func (m *Monitor) GetThroughput() (map[string]map[string]uint64, error) {
-	// 从缓存获取I/O字节计数
-	ioStats, err := m.GetIOStatsData()
-	if err != nil {
-		return nil, err
+	return m.ThroughputFromSnapshot(m.TakeSnapshot()), nil
+}
+
+// mockLatencyHist 围绕平均延迟生成模拟直方图：20%落在低一个桶，70%落在所在桶，10%落在高一个桶
+func mockLatencyHist(avgLatencyNs, ops uint64) LatencyHist {
+	var hist LatencyHist
+	slot := latencySlot(avgLatencyNs)
+	low, high := ops*2/10, ops/10
+	if slot == 0 {
+		low = 0
 	}
-	
-	// 计算经过的时间（秒）
-	elapsedTime := time.Since(m.lastCollectTime).Seconds()
-	if elapsedTime < 0.001 { // 防止除以极小的数
-		elapsedTime = 1.0
+	if slot == LatencyHistBuckets-1 {
+		high = 0
 	}
-	
-	// 计算吞吐量
-	throughputData := make(map[string]map[string]uint64)
-	for podName, stats := range ioStats {
-		readThroughput := uint64(float64(stats.ReadBytes) / elapsedTime)
-		writeThroughput := uint64(float64(stats.WriteBytes) / elapsedTime)
-		
-		throughputData[podName] = map[string]uint64{
-			"read_throughput_bps":  readThroughput,
-			"write_throughput_bps": writeThroughput,
-			"total_throughput_bps": readThroughput + writeThroughput,
-		}
+	hist[slot] = ops - low - high
+	if low > 0 {
+		hist[slot-1] = low
 	}
-	
-	return throughputData, nil
+	if high > 0 {
+		hist[slot+1] = high
+	}
+	return hist
 }
 
 // 内部方法 - 附加不同类型的eBPF跟踪器
 
 func (m *Monitor) attachBlockIOTracer() error {
-	// 这里会实现块I/O跟踪
-	// 例如跟踪 block_rq_issue, block_rq_complete 等kprobes
-	return nil
-}
+	if m.mockData {
+		return nil
+	}
 
-func (m *Monitor) attachFilesystemTracer() error {
-	// 这里会实现文件系统操作跟踪
-	// 例如跟踪 vfs_read, vfs_write 等kprobes
-	return nil
+	// 跟踪 block_rq_issue, block_rq_complete tracepoint
+	return m.loadBlockIOTracer()
 }
 
 func (m *Monitor) attachCSITracer() error {
@@ -0,0 +1,102 @@
+package ebpf
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+// NFS RPC跟踪程序在eBPF对象中的名称和附加的内核函数
+var nfsKprobes = []struct{ prog, symbol string }{
+	{"trace_rpc_execute", "rpc_execute"},
+	{"trace_rpc_exit_task", "rpc_exit_task"},
+}
+
+// splitNFSPrograms 从spec中移除NFS跟踪程序，返回只包含这些程序的spec副本
+// NFS程序依赖sunrpc模块的BTF，单独加载以免在没有NFS客户端的内核上导致整个对象加载失败
+func splitNFSPrograms(spec *ebpf.CollectionSpec) *ebpf.CollectionSpec {
+	nfsSpec := spec.Copy()
+	nfsSpec.Programs = make(map[string]*ebpf.ProgramSpec)
+	for _, kp := range nfsKprobes {
+		if prog, ok := spec.Programs[kp.prog]; ok {
+			nfsSpec.Programs[kp.prog] = prog
+			delete(spec.Programs, kp.prog)
+		}
+	}
+	return nfsSpec
+}
+
+// attachNFSTracer 加载NFS跟踪程序并附加到RPC客户端的kprobe上，测量NFS RPC的往返延迟
+// 内核未加载NFS客户端模块或对象中没有对应程序时返回错误，此时不报告网络延迟，其余跟踪程序照常工作
+func (m *Monitor) attachNFSTracer() error {
+	if m.mockData {
+		return nil
+	}
+	if m.nfsSpec == nil {
+		return fmt.Errorf("eBPF object not loaded")
+	}
+
+	if err := m.loadNFSTracer(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.nfsAttached = true
+	m.mu.Unlock()
+	return nil
+}
+
+// loadNFSTracer 复用已加载的映射加载NFS跟踪程序并附加kprobe，失败时释放已创建的资源
+func (m *Monitor) loadNFSTracer() error {
+	if len(m.nfsSpec.Programs) != len(nfsKprobes) {
+		return fmt.Errorf("NFS programs not found in eBPF object")
+	}
+
+	// 与块I/O跟踪程序共享events、过滤等映射
+	replacements := make(map[string]*ebpf.Map)
+	for name := range m.nfsSpec.Maps {
+		if mp, ok := m.bpfMaps[name]; ok {
+			replacements[name] = mp
+		}
+	}
+
+	coll, err := ebpf.NewCollectionWithOptions(m.nfsSpec, ebpf.CollectionOptions{MapReplacements: replacements})
+	if err != nil {
+		return fmt.Errorf("failed to load NFS programs: %v", err)
+	}
+	// 替换的映射是克隆出的文件描述符，程序加载后不再需要
+	for _, mp := range coll.Maps {
+		mp.Close()
+	}
+
+	var links []link.Link
+	for _, kp := range nfsKprobes {
+		l, err := link.Kprobe(kp.symbol, coll.Programs[kp.prog], nil)
+		m.recordProbe(TracerNFS, ProbeKprobe, kp.symbol, err)
+		if err != nil {
+			for _, l := range links {
+				l.Close()
+			}
+			m.detachProbes(TracerNFS, err)
+			for _, prog := range coll.Programs {
+				prog.Close()
+			}
+			return fmt.Errorf("failed to attach kprobe %s: %v", kp.symbol, err)
+		}
+		links = append(links, l)
+	}
+
+	for name, prog := range coll.Programs {
+		m.bpfPrograms[name] = prog
+	}
+	m.links = append(m.links, links...)
+	return nil
+}
+
+// NFSTracingEnabled 返回NFS RPC延迟跟踪是否已附加，模拟数据模式下始终为false
+func (m *Monitor) NFSTracingEnabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.nfsAttached
+}
@@ -0,0 +1,162 @@
+package ebpf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+// kallsymsPath 检查kprobe符号是否存在时读取的内核符号表，测试中可替换
+var kallsymsPath = "/proc/kallsyms"
+
+// ProbeTarget 一个探针的附加目标，即把eBPF对象中的哪个程序附加到哪个内核函数或tracepoint上
+type ProbeTarget struct {
+	Tracer  string // 所属的跟踪程序，目前可配置TracerBlock和TracerFilesystem
+	Type    string // ProbeKprobe、ProbeKretprobe或ProbeTracepoint
+	Symbol  string // kprobe为内核函数名，tracepoint为"子系统/名称"
+	Program string // eBPF对象中的程序名
+}
+
+// configurableTracers 探针目标可以配置的跟踪程序
+var configurableTracers = []string{TracerBlock, TracerFilesystem}
+
+// DefaultProbeTargets 返回内置的探针目标：块I/O的block_rq_* tracepoint和VFS的vfs_read、vfs_write kprobe
+func DefaultProbeTargets() []ProbeTarget {
+	return []ProbeTarget{
+		{TracerBlock, ProbeTracepoint, "block/block_rq_insert", blockRqInsertProg},
+		{TracerBlock, ProbeTracepoint, "block/block_rq_issue", blockRqIssueProg},
+		{TracerBlock, ProbeTracepoint, "block/block_rq_complete", blockRqCompleteProg},
+		{TracerFilesystem, ProbeKprobe, "vfs_read", "trace_vfs_read_entry"},
+		{TracerFilesystem, ProbeKretprobe, "vfs_read", "trace_vfs_read_exit"},
+		{TracerFilesystem, ProbeKprobe, "vfs_write", "trace_vfs_write_entry"},
+		{TracerFilesystem, ProbeKretprobe, "vfs_write", "trace_vfs_write_exit"},
+	}
+}
+
+// ValidateProbeTargets 检查探针目标的跟踪程序、类型和符号格式，不检查符号在本机内核中是否存在
+func ValidateProbeTargets(targets []ProbeTarget) error {
+	for _, t := range targets {
+		if !slices.Contains(configurableTracers, t.Tracer) {
+			return fmt.Errorf("probe tracer must be one of %s, got %q", strings.Join(configurableTracers, ", "), t.Tracer)
+		}
+		switch t.Type {
+		case ProbeKprobe, ProbeKretprobe:
+			if t.Symbol == "" || strings.Contains(t.Symbol, "/") {
+				return fmt.Errorf("%s probe symbol must be a kernel function name, got %q", t.Type, t.Symbol)
+			}
+		case ProbeTracepoint:
+			if group, name, ok := strings.Cut(t.Symbol, "/"); !ok || group == "" || name == "" {
+				return fmt.Errorf("tracepoint probe symbol must be subsystem/name, got %q", t.Symbol)
+			}
+		default:
+			return fmt.Errorf("probe type must be %s, %s or %s, got %q", ProbeKprobe, ProbeKretprobe, ProbeTracepoint, t.Type)
+		}
+		if t.Program == "" {
+			return fmt.Errorf("probe program is required for %s %s", t.Type, t.Symbol)
+		}
+	}
+	return nil
+}
+
+// WithProbeTargets 设置块I/O和VFS跟踪程序的探针目标，用于适配内核函数名不同的内核，targets需先通过ValidateProbeTargets检查
+// 只替换targets中出现的跟踪程序的探针，其余跟踪程序使用DefaultProbeTargets中的探针
+func WithProbeTargets(targets []ProbeTarget) MonitorOption {
+	return func(m *Monitor) {
+		if len(targets) > 0 {
+			m.probeTargets = targets
+		}
+	}
+}
+
+// tracerProbes 返回跟踪程序的探针目标，未配置时使用内置的探针目标
+func (m *Monitor) tracerProbes(tracer string) []ProbeTarget {
+	for _, targets := range [][]ProbeTarget{m.probeTargets, DefaultProbeTargets()} {
+		var result []ProbeTarget
+		for _, t := range targets {
+			if t.Tracer == tracer {
+				result = append(result, t)
+			}
+		}
+		if len(result) > 0 {
+			return result
+		}
+	}
+	return nil
+}
+
+// probePrograms 返回探针目标使用的程序名，同一程序附加到多个符号时只出现一次
+func probePrograms(targets []ProbeTarget) []string {
+	var names []string
+	for _, t := range targets {
+		if !slices.Contains(names, t.Program) {
+			names = append(names, t.Program)
+		}
+	}
+	return names
+}
+
+// attachProbe 按探针类型把程序附加到目标上
+func attachProbe(target ProbeTarget, prog *ebpf.Program) (link.Link, error) {
+	switch target.Type {
+	case ProbeKretprobe:
+		return link.Kretprobe(target.Symbol, prog, nil)
+	case ProbeTracepoint:
+		group, name, _ := strings.Cut(target.Symbol, "/")
+		return link.Tracepoint(group, name, prog, nil)
+	default:
+		return link.Kprobe(target.Symbol, prog, nil)
+	}
+}
+
+// checkKernelSymbols 检查kprobe和kretprobe的符号是否都在/proc/kallsyms中，返回列出所有缺失符号的错误
+// 没有kprobe时不读取符号表；符号表无法读取时返回nil，由附加探针时报告错误
+func checkKernelSymbols(targets []ProbeTarget) error {
+	wanted := make(map[string]bool)
+	for _, t := range targets {
+		if t.Type == ProbeKprobe || t.Type == ProbeKretprobe {
+			wanted[t.Symbol] = false
+		}
+	}
+	if len(wanted) == 0 {
+		return nil
+	}
+
+	f, err := os.Open(kallsymsPath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	// 每行为"地址 类型 符号 [模块]"
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		if _, ok := wanted[fields[2]]; ok {
+			wanted[fields[2]] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil
+	}
+
+	var missing []string
+	for symbol, found := range wanted {
+		if !found {
+			missing = append(missing, symbol)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("kernel symbols not found in %s: %s", kallsymsPath, strings.Join(missing, ", "))
+}
@@ -0,0 +1,109 @@
+package ebpf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useKallsyms 将kallsymsPath指向内容为content的临时文件，测试结束后恢复
+func useKallsyms(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "kallsyms")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := kallsymsPath
+	kallsymsPath = path
+	t.Cleanup(func() { kallsymsPath = old })
+	return path
+}
+
+const testKallsyms = `ffffffff81400000 T vfs_read
+ffffffff81400100 T vfs_write
+ffffffffc0a01000 t nfs_file_read	[nfs]
+`
+
+func TestCheckKernelSymbols(t *testing.T) {
+	path := useKallsyms(t, testKallsyms)
+
+	tests := []struct {
+		name    string
+		targets []ProbeTarget
+		want    string
+	}{
+		{"defaults", DefaultProbeTargets(), ""},
+		{"module symbol", []ProbeTarget{{TracerFilesystem, ProbeKprobe, "nfs_file_read", "p"}}, ""},
+		{"tracepoints only", []ProbeTarget{{TracerBlock, ProbeTracepoint, "block/missing_tracepoint", "p"}}, ""},
+		{
+			"every missing symbol listed once in order",
+			[]ProbeTarget{
+				{TracerFilesystem, ProbeKprobe, "vfs_read", "p"},
+				{TracerFilesystem, ProbeKprobe, "ksys_write", "p"},
+				{TracerFilesystem, ProbeKretprobe, "ksys_write", "r"},
+				{TracerFilesystem, ProbeKprobe, "do_sys_read", "p"},
+				{TracerBlock, ProbeTracepoint, "block/block_rq_issue", "t"},
+			},
+			"kernel symbols not found in " + path + ": do_sys_read, ksys_write",
+		},
+	}
+	for _, tt := range tests {
+		err := checkKernelSymbols(tt.targets)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("%s: checkKernelSymbols() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckKernelSymbolsUnreadable(t *testing.T) {
+	old := kallsymsPath
+	kallsymsPath = filepath.Join(t.TempDir(), "missing")
+	defer func() { kallsymsPath = old }()
+
+	// 符号表无法读取时不报错，由附加探针时报告
+	if err := checkKernelSymbols([]ProbeTarget{{TracerFilesystem, ProbeKprobe, "ksys_write", "p"}}); err != nil {
+		t.Errorf("checkKernelSymbols() without kallsyms = %v, want nil", err)
+	}
+}
+
+func TestValidateProbeTargets(t *testing.T) {
+	tests := []struct {
+		name   string
+		target ProbeTarget
+		want   string // 错误信息中应包含的内容，为空表示合法
+	}{
+		{"kprobe", ProbeTarget{TracerFilesystem, ProbeKprobe, "ksys_read", "trace_vfs_read_entry"}, ""},
+		{"kretprobe", ProbeTarget{TracerFilesystem, ProbeKretprobe, "ksys_read", "trace_vfs_read_exit"}, ""},
+		{"tracepoint", ProbeTarget{TracerBlock, ProbeTracepoint, "block/block_rq_issue", blockRqIssueProg}, ""},
+		{"empty tracer", ProbeTarget{"", ProbeKprobe, "vfs_read", "p"}, "probe tracer must be one of"},
+		{"unconfigurable tracer", ProbeTarget{"nfs", ProbeKprobe, "nfs_file_read", "p"}, `got "nfs"`},
+		{"empty type", ProbeTarget{TracerFilesystem, "", "vfs_read", "p"}, "probe type must be"},
+		{"unknown type", ProbeTarget{TracerFilesystem, "uprobe", "vfs_read", "p"}, `got "uprobe"`},
+		{"empty kprobe symbol", ProbeTarget{TracerFilesystem, ProbeKprobe, "", "p"}, "must be a kernel function name"},
+		{"kprobe with tracepoint symbol", ProbeTarget{TracerFilesystem, ProbeKretprobe, "block/block_rq_issue", "p"}, "must be a kernel function name"},
+		{"tracepoint without subsystem", ProbeTarget{TracerBlock, ProbeTracepoint, "block_rq_issue", "p"}, "must be subsystem/name"},
+		{"tracepoint with empty subsystem", ProbeTarget{TracerBlock, ProbeTracepoint, "/block_rq_issue", "p"}, "must be subsystem/name"},
+		{"tracepoint with empty name", ProbeTarget{TracerBlock, ProbeTracepoint, "block/", "p"}, "must be subsystem/name"},
+		{"empty program", ProbeTarget{TracerFilesystem, ProbeKprobe, "vfs_read", ""}, "probe program is required"},
+	}
+	for _, tt := range tests {
+		// 合法的目标之后的非法目标同样被检查
+		err := ValidateProbeTargets([]ProbeTarget{DefaultProbeTargets()[0], tt.target})
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: ValidateProbeTargets() = %v, want nil", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: ValidateProbeTargets() = %v, want error containing %q", tt.name, err, tt.want)
+		}
+	}
+
+	if err := ValidateProbeTargets(DefaultProbeTargets()); err != nil {
+		t.Errorf("ValidateProbeTargets(DefaultProbeTargets()) = %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package ebpf
+
+import "time"
+
+// IOCounters 单个key自监控开始以来累计的I/O计数
+// 统计窗口内没有I/O的key会被清理，再次出现时从零重新累计，Since随之变化
+type IOCounters struct {
+	ReadOps    uint64
+	WriteOps   uint64
+	ReadBytes  uint64
+	WriteBytes uint64
+	Since      time.Time // 开始累计的时间，key被清理后重新出现时会变化
+}
+
+// add 累加一个统计窗口的计数
+func (c *IOCounters) add(stats *IOStatsData) {
+	c.ReadOps += stats.ReadOps
+	c.WriteOps += stats.WriteOps
+	c.ReadBytes += stats.ReadBytes
+	c.WriteBytes += stats.WriteBytes
+}
+
+// GetIOCounters 返回各key自监控开始以来累计的I/O计数，以及这些计数对应的原始采集时间
+// 原始采集时间只在统计窗口结束时变化，两次调用返回相同的时间说明没有新的数据
+func (m *Monitor) GetIOCounters() (map[string]IOCounters, time.Time) {
+	snap := m.TakeSnapshot()
+	return snap.Counters, snap.CollectTime
+}
+
+// counterRates 每秒速率
+type counterRates struct {
+	ReadOps    float64
+	WriteOps   float64
+	ReadBytes  float64
+	WriteBytes float64
+}
+
+// rateTracker 记录上次计算速率时的累计计数和对应的采集时间，按两次读取之间的差值计算速率
+// IOPS和吞吐量各自使用独立的rateTracker，互不影响对方的差值
+type rateTracker struct {
+	prev     map[string]IOCounters
+	prevTime time.Time // prev对应的原始采集时间，而非计算速率的时间
+	rates    map[string]counterRates
+}
+
+// newRateTracker 创建速率跟踪器，start为累计计数开始的时间
+func newRateTracker(start time.Time) *rateTracker {
+	return &rateTracker{
+		prev:     make(map[string]IOCounters),
+		prevTime: start,
+		rates:    make(map[string]counterRates),
+	}
+}
+
+// update 根据最新的累计计数计算速率
+// collectTime与上次相同说明没有新的原始数据，直接返回上次的速率
+func (t *rateTracker) update(totals map[string]IOCounters, collectTime time.Time) map[string]counterRates {
+	elapsed := collectTime.Sub(t.prevTime).Seconds()
+	if elapsed <= 0 {
+		return t.rates
+	}
+
+	rates := make(map[string]counterRates, len(totals))
+	for key, cur := range totals {
+		prev := t.prev[key]
+		// key在两次计算之间被清理后重新出现，之前的计数已不可比
+		if !prev.Since.Equal(cur.Since) {
+			prev = IOCounters{}
+		}
+		rates[key] = counterRates{
+			ReadOps:    counterDelta(cur.ReadOps, prev.ReadOps) / elapsed,
+			WriteOps:   counterDelta(cur.WriteOps, prev.WriteOps) / elapsed,
+			ReadBytes:  counterDelta(cur.ReadBytes, prev.ReadBytes) / elapsed,
+			WriteBytes: counterDelta(cur.WriteBytes, prev.WriteBytes) / elapsed,
+		}
+	}
+
+	// 只保留当前存在的key，消失的cgroup不再占用内存
+	t.prev = make(map[string]IOCounters, len(totals))
+	for key, cur := range totals {
+		t.prev[key] = cur
+	}
+	t.prevTime = collectTime
+	t.rates = rates
+
+	return rates
+}
+
+// counterDelta 计算累计计数的增量，计数不应变小，出现时按从零开始计算
+func counterDelta(cur, prev uint64) float64 {
+	if cur < prev {
+		return float64(cur)
+	}
+	return float64(cur - prev)
+}
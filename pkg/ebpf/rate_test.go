@@ -0,0 +1,127 @@
+package ebpf
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock 只在advance时前进的时间来源
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newFakeClockMonitor 返回使用模拟数据和fakeClock的监控器，累计计数从clock的当前时间开始
+func newFakeClockMonitor(t *testing.T) (*Monitor, *fakeClock) {
+	t.Helper()
+
+	m, err := NewMonitor(WithMockData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m.clock = clock.Now
+	m.lastCollectTime = clock.now
+	m.iopsRate = newRateTracker(clock.now)
+	m.throughputRate = newRateTracker(clock.now)
+	return m, clock
+}
+
+func TestGetIOPSWithFakeClock(t *testing.T) {
+	m, clock := newFakeClockMonitor(t)
+
+	// 模拟数据中pod1每秒读3000次、写2000次，读5MB、写3MB
+	for _, step := range []time.Duration{10 * time.Second, 5 * time.Second, time.Minute} {
+		clock.advance(step)
+
+		iops, err := m.GetIOPS()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := iops["pod1"]; got["read_iops"] != 3000 || got["write_iops"] != 2000 || got["total_iops"] != 5000 {
+			t.Errorf("after %v: pod1 IOPS = %v, want 3000 read and 2000 write", step, got)
+		}
+
+		// GetIOPS已读取过同一时刻的数据，吞吐量仍按自己上次计算以来的差值计算
+		throughput, err := m.GetThroughput()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := throughput["pod1"]; got["read_throughput_bps"] != 5*1024*1024 || got["write_throughput_bps"] != 3*1024*1024 {
+			t.Errorf("after %v: pod1 throughput = %v, want 5MiB/s read and 3MiB/s write", step, got)
+		}
+	}
+}
+
+func TestGetIOPSWithoutNewData(t *testing.T) {
+	m, clock := newFakeClockMonitor(t)
+	clock.advance(10 * time.Second)
+
+	first, err := m.GetIOPS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 时间没有前进，没有新的原始数据，返回上次的速率而不是0
+	second, err := m.GetIOPS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second["pod2"]["read_iops"] != first["pod2"]["read_iops"] || second["pod2"]["read_iops"] != 2000 {
+		t.Errorf("pod2 read IOPS = %d then %d, want 2000 both times", first["pod2"]["read_iops"], second["pod2"]["read_iops"])
+	}
+}
+
+func TestRateTrackerCounterReset(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := newRateTracker(start)
+
+	t1 := start.Add(10 * time.Second)
+	tracker.update(map[string]IOCounters{
+		"cgroup-a": {ReadOps: 1000, WriteOps: 500, Since: start},
+		"cgroup-b": {ReadOps: 1000, Since: start},
+	}, t1)
+
+	// cgroup-a在统计窗口内没有I/O被清理，5秒前重新出现并从零累计，新的计数大于上次的计数
+	// 只比较计数会得到(1500-1000)/10，应按Since识别出计数已重新开始
+	t2 := t1.Add(10 * time.Second)
+	rates := tracker.update(map[string]IOCounters{
+		"cgroup-a": {ReadOps: 1500, WriteOps: 100, Since: t1.Add(5 * time.Second)},
+		"cgroup-b": {ReadOps: 3000, Since: start},
+		"cgroup-c": {ReadOps: 200, Since: t1.Add(5 * time.Second)},
+	}, t2)
+
+	tests := []struct {
+		key      string
+		readOps  float64
+		writeOps float64
+	}{
+		{"cgroup-a", 150, 10},
+		{"cgroup-b", 200, 0},
+		{"cgroup-c", 20, 0},
+	}
+	for _, tt := range tests {
+		got := rates[tt.key]
+		if got.ReadOps != tt.readOps || got.WriteOps != tt.writeOps {
+			t.Errorf("%s rates = %+v, want %v read and %v write ops/s", tt.key, got, tt.readOps, tt.writeOps)
+		}
+	}
+
+	// 消失的key不再保留上次的计数
+	tracker.update(map[string]IOCounters{"cgroup-b": {ReadOps: 3000, Since: start}}, t2.Add(time.Second))
+	if _, ok := tracker.prev["cgroup-a"]; ok {
+		t.Error("rate state of a vanished key was kept")
+	}
+}
+
+func TestCounterDelta(t *testing.T) {
+	if got := counterDelta(1500, 1000); got != 500 {
+		t.Errorf("counterDelta(1500, 1000) = %v, want 500", got)
+	}
+	// 计数变小时按从零开始计算，而不是下溢为极大的值
+	if got := counterDelta(200, 1000); got != 200 {
+		t.Errorf("counterDelta(200, 1000) = %v, want 200", got)
+	}
+}
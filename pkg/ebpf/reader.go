@@ -0,0 +1,300 @@
+package ebpf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/perf"
+)
+
+// 块I/O跟踪程序和事件映射在eBPF对象中的名称
+const (
+	blockRqInsertProg   = "trace_block_rq_insert"
+	blockRqIssueProg    = "trace_block_rq_issue"
+	blockRqCompleteProg = "trace_block_rq_complete"
+	eventsMap           = "events"
+)
+
+// ioEvent 与bpf/io_tracer.c中的struct io_event_t内存布局一致
+type ioEvent struct {
+	Ts         uint64
+	Pid        uint32
+	Tid        uint32
+	CgroupID   uint64
+	IOStart    uint64
+	IOEnd      uint64
+	QueueStart uint64 // 块I/O进入调度队列的时间，未经过调度队列时为0
+	Bytes      uint64
+	Comm       [16]byte
+	Disk       [32]byte
+	Operation  uint8 // 0=read, 1=write
+	IOType     uint8 // 0=sync, 1=async
+	Source     uint8 // 事件来源，见eventSource*常量
+	_          [1]byte
+	Dev        uint32 // 块I/O所在设备的内核dev_t，VFS读写时为文件系统的s_dev
+	FSMagic    uint64 // VFS读写所在文件系统的超级块magic
+}
+
+// 事件来源，与bpf/io_tracer.c中的SOURCE_*一致
+const (
+	eventSourceBlock   = 0 // 块设备I/O
+	eventSourceNFS     = 1 // NFS RPC往返
+	eventSourceIOUring = 2 // io_uring读写请求
+	eventSourceVFS     = 3 // VFS读写
+	eventSourceISCSI   = 4 // iSCSI命令往返
+)
+
+// ioAccumulator 统计窗口内单个key的累计值
+type ioAccumulator struct {
+	stats               IOStatsData
+	readLatencyNs       uint64 // 窗口内读延迟总和
+	writeLatencyNs      uint64 // 窗口内写延迟总和
+	networkLatencyNs    uint64 // 窗口内NFS RPC和iSCSI命令往返延迟总和
+	blockReadOps        uint64 // 窗口内的块I/O读请求数，队列和磁盘延迟只来自块I/O
+	blockWriteOps       uint64 // 窗口内的块I/O写请求数
+	readQueueLatencyNs  uint64 // 窗口内块I/O读请求队列延迟总和
+	writeQueueLatencyNs uint64 // 窗口内块I/O写请求队列延迟总和
+	readDiskLatencyNs   uint64 // 窗口内块I/O读请求设备耗时总和
+	writeDiskLatencyNs  uint64 // 窗口内块I/O写请求设备耗时总和
+	busy                busyIntervals
+	fs                  map[string]*fsAccumulator // 按文件系统类型累计的VFS读写
+}
+
+// loadBlockIOTracer 加载eBPF对象，附加块I/O探针（默认为block_rq_* tracepoint）并打开perf事件缓冲区
+func (m *Monitor) loadBlockIOTracer() error {
+	spec, err := ebpf.LoadCollectionSpec(m.objectFile)
+	if err != nil {
+		return fmt.Errorf("failed to load eBPF object %s: %v", m.objectFile, err)
+	}
+	m.nfsSpec = splitNFSPrograms(spec)
+	m.iscsiSpec = splitISCSIPrograms(spec)
+	m.ioUringSpec = splitIOUringPrograms(spec)
+	m.fsSpec = splitFilesystemPrograms(spec, m.tracerProbes(TracerFilesystem))
+
+	coll, err := ebpf.NewCollection(spec)
+	if err != nil {
+		return fmt.Errorf("failed to create eBPF collection: %v", err)
+	}
+
+	// 由Monitor统一管理程序和映射的生命周期
+	for name, prog := range coll.Programs {
+		m.bpfPrograms[name] = prog
+	}
+	for name, mp := range coll.Maps {
+		m.bpfMaps[name] = mp
+	}
+
+	// 写入加载前通过EnablePod设置的过滤条件
+	m.mu.Lock()
+	err = m.applyFilter()
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := m.applySampleRate(); err != nil {
+		return err
+	}
+	if err := m.applyFSFilter(); err != nil {
+		return err
+	}
+
+	targets := m.tracerProbes(TracerBlock)
+	if err := checkKernelSymbols(targets); err != nil {
+		return err
+	}
+	for _, target := range targets {
+		prog, ok := m.bpfPrograms[target.Program]
+		if !ok {
+			return fmt.Errorf("program %s not found in eBPF object", target.Program)
+		}
+		l, err := attachProbe(target, prog)
+		m.recordProbe(TracerBlock, target.Type, target.Symbol, err)
+		if err != nil {
+			return fmt.Errorf("failed to attach %s %s: %v", target.Type, target.Symbol, err)
+		}
+		m.links = append(m.links, l)
+	}
+
+	events, ok := m.bpfMaps[eventsMap]
+	if !ok {
+		return fmt.Errorf("map %s not found in eBPF object", eventsMap)
+	}
+
+	reader, err := perf.NewReader(events, os.Getpagesize()*64)
+	if err != nil {
+		return fmt.Errorf("failed to open perf event reader: %v", err)
+	}
+	m.eventReader = reader
+
+	go m.readEvents()
+
+	return nil
+}
+
+// readEvents 持续读取perf事件并按统计窗口聚合到ioStatsCache
+func (m *Monitor) readEvents() {
+	defer close(m.readerDone)
+
+	pending := make(map[string]*ioAccumulator)
+	devices := make(map[uint32]*deviceAccumulator)
+	tracker := &deviceTracker{}
+	tracker.refreshNames()
+	windowStart := time.Now()
+	// 每个样本代表sampleRate个I/O
+	weight := uint64(m.sampleRate)
+
+	for {
+		// 借助读超时按窗口周期切换统计数据
+		m.eventReader.SetDeadline(windowStart.Add(m.statsWindow))
+
+		record, err := m.eventReader.Read()
+		switch {
+		case errors.Is(err, perf.ErrClosed):
+			return
+		case errors.Is(err, os.ErrDeadlineExceeded):
+		case err != nil:
+			fmt.Printf("Error reading perf event: %v\n", err)
+		case record.LostSamples > 0:
+			fmt.Printf("Lost %d perf events\n", record.LostSamples)
+		default:
+			var event ioEvent
+			if err := binary.Read(bytes.NewReader(record.RawSample), binary.NativeEndian, &event); err != nil {
+				fmt.Printf("Error decoding perf event: %v\n", err)
+				break
+			}
+			aggregateEvent(pending, m.eventKey(&event), &event, weight)
+			aggregateDeviceEvent(devices, &event, weight)
+		}
+
+		if now := time.Now(); now.Sub(windowStart) >= m.statsWindow {
+			elapsed := now.Sub(windowStart)
+			m.flushWindow(pending, tracker.buildDeviceStats(devices, elapsed, now, m.sampleRate), elapsed, now)
+			pending = make(map[string]*ioAccumulator)
+			devices = make(map[uint32]*deviceAccumulator)
+			windowStart = now
+		}
+	}
+}
+
+// eventKey 返回事件所属统计数据的key，默认为cgroup ID，启用WithPIDKeys时为进程ID，均由上层映射回Pod
+func (m *Monitor) eventKey(event *ioEvent) string {
+	if m.pidKeys {
+		return strconv.FormatUint(uint64(event.Pid), 10)
+	}
+	return strconv.FormatUint(event.CgroupID, 10)
+}
+
+// aggregateEvent 将单个I/O事件累加到key的统计中，weight为该事件代表的I/O数（采样率）
+// 延迟总和同样按weight累加，除以放大后的次数得到的平均值不变
+func aggregateEvent(pending map[string]*ioAccumulator, key string, event *ioEvent, weight uint64) {
+	acc, ok := pending[key]
+	if !ok {
+		acc = &ioAccumulator{}
+		pending[key] = acc
+	}
+
+	// 块I/O的总延迟包含调度队列中的等待时间
+	queueLatency := eventQueueLatency(event)
+	diskLatency := eventDiskLatency(event)
+	latency := queueLatency + diskLatency
+
+	// VFS读写只计入按文件系统类型的统计，其中命中页缓存的请求不会到达块层
+	if event.Source == eventSourceVFS {
+		acc.addFSEvent(event, weight)
+		return
+	}
+
+	// NFS RPC和iSCSI命令只计入网络延迟，读写次数和字节数仍以块I/O为准
+	if event.Source == eventSourceNFS || event.Source == eventSourceISCSI {
+		acc.stats.NetworkOps += weight
+		acc.networkLatencyNs += latency * weight
+		return
+	}
+
+	// io_uring请求与块I/O一样计入读写统计，另外记录次数
+	if event.Source == eventSourceIOUring {
+		acc.stats.IOUringOps += weight
+	}
+
+	if event.Source == eventSourceBlock {
+		acc.busy.add(event.IOStart, event.IOEnd)
+		if event.Operation == 0 {
+			acc.blockReadOps += weight
+			acc.readQueueLatencyNs += queueLatency * weight
+			acc.readDiskLatencyNs += diskLatency * weight
+		} else {
+			acc.blockWriteOps += weight
+			acc.writeQueueLatencyNs += queueLatency * weight
+			acc.writeDiskLatencyNs += diskLatency * weight
+		}
+	}
+
+	if event.Operation == 0 {
+		acc.stats.ReadOps += weight
+		acc.stats.ReadBytes += event.Bytes * weight
+		acc.readLatencyNs += latency * weight
+		acc.stats.ReadLatencyHist.ObserveN(latency, weight)
+	} else {
+		acc.stats.WriteOps += weight
+		acc.stats.WriteBytes += event.Bytes * weight
+		acc.writeLatencyNs += latency * weight
+		acc.stats.WriteLatencyHist.ObserveN(latency, weight)
+	}
+}
+
+// flushWindow 用刚结束的统计窗口替换ioStatsCache和deviceStatsCache，elapsed为窗口长度
+func (m *Monitor) flushWindow(pending map[string]*ioAccumulator, devices map[string]*DeviceStats, elapsed time.Duration, now time.Time) {
+	stats := make(map[string]*IOStatsData, len(pending))
+	for key, acc := range pending {
+		s := acc.stats
+		if s.ReadOps > 0 {
+			s.ReadLatencyNs = acc.readLatencyNs / s.ReadOps
+		}
+		if s.WriteOps > 0 {
+			s.WriteLatencyNs = acc.writeLatencyNs / s.WriteOps
+		}
+		if s.NetworkOps > 0 {
+			s.NetworkLatencyNs = acc.networkLatencyNs / s.NetworkOps
+		}
+		// 队列延迟为进入调度队列到下发的时间，磁盘延迟为下发到完成的时间
+		if acc.blockReadOps > 0 {
+			s.ReadQueueLatencyNs = acc.readQueueLatencyNs / acc.blockReadOps
+			s.ReadDiskLatencyNs = acc.readDiskLatencyNs / acc.blockReadOps
+		}
+		if acc.blockWriteOps > 0 {
+			s.WriteQueueLatencyNs = acc.writeQueueLatencyNs / acc.blockWriteOps
+			s.WriteDiskLatencyNs = acc.writeDiskLatencyNs / acc.blockWriteOps
+		}
+		s.QueueLatencyNs = max(s.ReadQueueLatencyNs, s.WriteQueueLatencyNs)
+		s.DiskLatencyNs = max(s.ReadDiskLatencyNs, s.WriteDiskLatencyNs)
+		s.Utilization = sampledUtilization(acc.busy, elapsed, m.sampleRate)
+		s.FSStats = acc.fsStats()
+		s.LastUpdateTime = now
+		stats[key] = &s
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ioStatsCache = stats
+	m.deviceStatsCache = devices
+
+	// 累加窗口计数，本窗口没有I/O的key不再保留
+	totals := make(map[string]IOCounters, len(stats))
+	for key, s := range stats {
+		counters, ok := m.ioTotals[key]
+		if !ok {
+			counters.Since = now
+		}
+		counters.add(s)
+		totals[key] = counters
+	}
+	m.ioTotals = totals
+	m.lastCollectTime = now
+}
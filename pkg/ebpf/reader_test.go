@@ -0,0 +1,24 @@
+package ebpf
+
+import "testing"
+
+func TestEventKey(t *testing.T) {
+	event := &ioEvent{Pid: 4242, CgroupID: 10423}
+
+	m, err := NewMonitor(WithMockData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.eventKey(event); got != "10423" {
+		t.Errorf("eventKey() = %q, want the cgroup ID", got)
+	}
+
+	// 纯cgroup v1节点上所有进程的cgroup ID相同，改以进程ID区分
+	m, err = NewMonitor(WithMockData(), WithPIDKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.eventKey(event); got != "4242" {
+		t.Errorf("eventKey() with WithPIDKeys = %q, want the pid", got)
+	}
+}
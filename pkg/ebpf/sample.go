@@ -0,0 +1,47 @@
+package ebpf
+
+import (
+	"fmt"
+	"time"
+)
+
+// 采样率映射在eBPF对象中的名称，与bpf/io_tracer.c一致
+const sampleConfigMap = "sample_config"
+
+// WithSampleRate 设置采样率，内核程序对每个I/O以1/n的概率记录，n为0或1时记录所有I/O
+// 被采样的事件按n放大计数，IOPS和吞吐量保持近似正确；平均延迟和延迟分布来自样本，
+// 采样后I/O较少的Pod误差较大
+func WithSampleRate(n uint32) MonitorOption {
+	return func(m *Monitor) {
+		if n > 0 {
+			m.sampleRate = n
+		}
+	}
+}
+
+// SampleRate 返回生效的采样率N，即每N个I/O记录1个，1表示记录所有I/O
+func (m *Monitor) SampleRate() uint32 {
+	return m.sampleRate
+}
+
+// applySampleRate 将采样率写入内核映射，跟踪程序尚未加载时直接返回
+func (m *Monitor) applySampleRate() error {
+	if m.mockData || len(m.bpfMaps) == 0 {
+		return nil
+	}
+
+	configMap, ok := m.bpfMaps[sampleConfigMap]
+	if !ok {
+		return fmt.Errorf("map %s not found in eBPF object", sampleConfigMap)
+	}
+	if err := configMap.Put(uint32(0), m.sampleRate); err != nil {
+		return fmt.Errorf("failed to update %s map: %v", sampleConfigMap, err)
+	}
+	return nil
+}
+
+// sampledUtilization 将样本请求的繁忙时间比例按采样率放大，不超过1
+// 请求并发较高时样本区间相互重叠的比例与全部请求不同，结果只是近似值
+func sampledUtilization(busy busyIntervals, elapsed time.Duration, rate uint32) float64 {
+	return min(busy.utilization(elapsed)*float64(rate), 1)
+}
@@ -0,0 +1,89 @@
+package ebpf
+
+import "time"
+
+// IOSnapshot 在同一时刻取得的原始I/O数据，IOPS、吞吐量和延迟都从同一份快照计算，彼此一致
+// 模拟数据模式下每取一次快照生成一次模拟数据
+type IOSnapshot struct {
+	Stats       map[string]*IOStatsData // 最近一个统计窗口的I/O统计，与GetIOStatsData相同
+	Counters    map[string]IOCounters   // 自监控开始以来的累计计数，与GetIOCounters相同
+	CollectTime time.Time               // 原始数据的采集时间，即Counters的更新时间
+}
+
+// TakeSnapshot 在一次加锁内取得I/O统计和累计计数的副本，一个采集周期只需读取一次原始数据
+func (m *Monitor) TakeSnapshot() *IOSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.mockData {
+		m.loadMockStats()
+	}
+
+	snap := &IOSnapshot{
+		Stats:       make(map[string]*IOStatsData, len(m.ioStatsCache)),
+		Counters:    make(map[string]IOCounters, len(m.ioTotals)),
+		CollectTime: m.lastCollectTime,
+	}
+	for key, stats := range m.ioStatsCache {
+		statsCopy := *stats
+		snap.Stats[key] = &statsCopy
+	}
+	for key, c := range m.ioTotals {
+		snap.Counters[key] = c
+	}
+	return snap
+}
+
+// IOLatencyFromSnapshot 从快照计算各key的读写延迟，格式与GetIOLatencyData相同
+func (m *Monitor) IOLatencyFromSnapshot(snap *IOSnapshot) map[string]map[string]uint64 {
+	latencyData := make(map[string]map[string]uint64, len(snap.Stats))
+	for key, stats := range snap.Stats {
+		latencyData[key] = map[string]uint64{
+			"read_latency_ns":  stats.ReadLatencyNs,
+			"write_latency_ns": stats.WriteLatencyNs,
+		}
+	}
+	return latencyData
+}
+
+// IOPSFromSnapshot 从快照的累计计数计算IOPS，格式与GetIOPS相同
+// 速率为上次计算以来累计操作次数的增量除以两次原始数据采集的时间间隔，期间没有新数据时返回上次的结果
+func (m *Monitor) IOPSFromSnapshot(snap *IOSnapshot) map[string]map[string]uint64 {
+	m.mu.Lock()
+	rates := m.iopsRate.update(snap.Counters, snap.CollectTime)
+	m.mu.Unlock()
+
+	iopsData := make(map[string]map[string]uint64, len(rates))
+	for key, rate := range rates {
+		readIOPS := uint64(rate.ReadOps)
+		writeIOPS := uint64(rate.WriteOps)
+
+		iopsData[key] = map[string]uint64{
+			"read_iops":  readIOPS,
+			"write_iops": writeIOPS,
+			"total_iops": readIOPS + writeIOPS,
+		}
+	}
+	return iopsData
+}
+
+// ThroughputFromSnapshot 从快照的累计计数计算吞吐量（字节/秒），格式与GetThroughput相同
+// 与IOPSFromSnapshot使用独立的速率状态，调用顺序不影响彼此的结果
+func (m *Monitor) ThroughputFromSnapshot(snap *IOSnapshot) map[string]map[string]uint64 {
+	m.mu.Lock()
+	rates := m.throughputRate.update(snap.Counters, snap.CollectTime)
+	m.mu.Unlock()
+
+	throughputData := make(map[string]map[string]uint64, len(rates))
+	for key, rate := range rates {
+		readThroughput := uint64(rate.ReadBytes)
+		writeThroughput := uint64(rate.WriteBytes)
+
+		throughputData[key] = map[string]uint64{
+			"read_throughput_bps":  readThroughput,
+			"write_throughput_bps": writeThroughput,
+			"total_throughput_bps": readThroughput + writeThroughput,
+		}
+	}
+	return throughputData
+}
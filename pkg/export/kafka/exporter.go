@@ -0,0 +1,288 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// DefaultTopic 默认写入的topic
+const DefaultTopic = "ioeye-metrics"
+
+// DefaultQueueSize 默认的待发送消息队列长度
+const DefaultQueueSize = 10000
+
+// maxBatchRecords 单个Produce请求最多包含的消息数，避免超过broker的消息大小上限
+const maxBatchRecords = 500
+
+// Option 配置Kafka导出器的选项
+type Option func(*Exporter)
+
+// Exporter 每个采集周期将各Pod的指标以JSON消息写入Kafka，消息key为Pod UID
+// 采集和发送互相独立：发送跟不上时消息在有界队列中缓冲，队列满时丢弃最旧的消息
+type Exporter struct {
+	storageMonitor *monitor.StorageMonitor
+	producer       *producer
+	interval       time.Duration
+	queueSize      int
+	errorHandler   func(error)
+	mu             sync.Mutex
+	queue          []record      // 待发送的消息，受mu保护
+	inflight       int           // 队首正在发送的消息数，受mu保护
+	dropped        uint64        // 因队列满丢弃的消息数，受mu保护
+	notify         chan struct{} // 通知发送goroutine有新消息
+	stopChan       chan struct{}
+	done           chan struct{}
+	stopOnce       sync.Once
+}
+
+// Message 写入Kafka的消息内容
+type Message struct {
+	PodName         string    `json:"pod_name"`
+	Namespace       string    `json:"namespace"`
+	PodUID          string    `json:"pod_uid,omitempty"`
+	NodeName        string    `json:"node,omitempty"`
+	WorkloadKind    string    `json:"workload_kind,omitempty"`
+	WorkloadName    string    `json:"workload_name,omitempty"`
+	ReadLatency     uint64    `json:"read_latency_ns"`
+	WriteLatency    uint64    `json:"write_latency_ns"`
+	QueueLatency    uint64    `json:"queue_latency_ns"`
+	DiskLatency     uint64    `json:"disk_latency_ns"`
+	NetworkLatency  uint64    `json:"network_latency_ns"`
+	ReadIOPS        uint64    `json:"read_iops"`
+	WriteIOPS       uint64    `json:"write_iops"`
+	ReadThroughput  uint64    `json:"read_throughput_bps"`
+	WriteThroughput uint64    `json:"write_throughput_bps"`
+	Utilization     float64   `json:"utilization"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// WithTopic 设置写入的topic
+func WithTopic(topic string) Option {
+	return func(e *Exporter) {
+		if topic != "" {
+			e.producer.topic = topic
+		}
+	}
+}
+
+// WithInterval 设置读取指标的周期，应与采集周期一致
+func WithInterval(interval time.Duration) Option {
+	return func(e *Exporter) {
+		if interval > 0 {
+			e.interval = interval
+		}
+	}
+}
+
+// WithQueueSize 设置待发送消息队列的长度，broker不可用时最多缓冲这么多条消息
+func WithQueueSize(size int) Option {
+	return func(e *Exporter) {
+		if size > 0 {
+			e.queueSize = size
+		}
+	}
+}
+
+// WithErrorHandler 设置发送失败和丢弃消息时的回调，默认打印到标准输出
+// 回调在发送goroutine中调用，不应阻塞
+func WithErrorHandler(handler func(error)) Option {
+	return func(e *Exporter) {
+		if handler != nil {
+			e.errorHandler = handler
+		}
+	}
+}
+
+// NewExporter 创建Kafka指标导出器，brokers为引导broker的host:port列表
+func NewExporter(storageMonitor *monitor.StorageMonitor, brokers []string, opts ...Option) (*Exporter, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("at least one Kafka broker is required")
+	}
+
+	e := &Exporter{
+		storageMonitor: storageMonitor,
+		producer:       newProducer(brokers, DefaultTopic, "ioeye", 10*time.Second),
+		interval:       10 * time.Second, // 默认10秒读取一次
+		queueSize:      DefaultQueueSize,
+		errorHandler: func(err error) {
+			fmt.Printf("Kafka export error: %v\n", err)
+		},
+		notify:   make(chan struct{}, 1),
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	// 应用选项
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	go e.sendLoop()
+
+	return e, nil
+}
+
+// Start 每个周期将新采集的指标放入发送队列，直到上下文取消或调用Stop
+func (e *Exporter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	var lastCollection time.Time
+	for {
+		select {
+		case <-ticker.C:
+			// 暂停采集期间没有新数据，不重复发送
+			collected := e.storageMonitor.LastCollectionTime()
+			if collected.IsZero() || !collected.After(lastCollection) {
+				continue
+			}
+			lastCollection = collected
+			e.enqueue(e.storageMonitor.GetAllMetrics())
+		case <-ctx.Done():
+			return e.Stop()
+		case <-e.stopChan:
+			return nil
+		}
+	}
+}
+
+// Stop 尽量发送队列中剩余的消息后关闭连接，可重复调用
+func (e *Exporter) Stop() error {
+	e.stopOnce.Do(func() {
+		close(e.stopChan)
+		<-e.done
+		e.producer.close()
+	})
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if remaining := len(e.queue); remaining > 0 {
+		return fmt.Errorf("%d Kafka messages were not sent before shutdown", remaining)
+	}
+	return nil
+}
+
+// enqueue 将一个周期的Pod指标编码为消息放入队列，队列满时丢弃最旧的消息
+func (e *Exporter) enqueue(allMetrics map[string]*monitor.PodStorageMetrics) {
+	records := make([]record, 0, len(allMetrics))
+	for _, metrics := range allMetrics {
+		value, err := json.Marshal(newMessage(metrics))
+		if err != nil {
+			e.errorHandler(fmt.Errorf("failed to encode metrics of pod %s: %v", metrics.PodName, err))
+			continue
+		}
+		records = append(records, record{key: messageKey(metrics), value: value, ts: metrics.Timestamp})
+	}
+
+	e.mu.Lock()
+	e.queue = append(e.queue, records...)
+	if overflow := len(e.queue) - e.queueSize; overflow > 0 {
+		e.queue = append([]record(nil), e.queue[overflow:]...)
+		e.inflight = max(e.inflight-overflow, 0)
+		e.dropped += uint64(overflow)
+	}
+	e.mu.Unlock()
+
+	select {
+	case e.notify <- struct{}{}:
+	default:
+	}
+}
+
+// sendLoop 持续发送队列中的消息，发送失败的消息留在队列中下次重试
+func (e *Exporter) sendLoop() {
+	defer close(e.done)
+
+	for {
+		select {
+		case <-e.notify:
+		case <-e.stopChan:
+			e.drain()
+			return
+		}
+
+		e.drain()
+	}
+}
+
+// drain 分批发送队列中的消息，直到队列为空或发送失败
+func (e *Exporter) drain() {
+	for {
+		e.mu.Lock()
+		if dropped := e.dropped; dropped > 0 {
+			e.dropped = 0
+			e.mu.Unlock()
+			e.errorHandler(fmt.Errorf("dropped %d messages because the send queue is full", dropped))
+			e.mu.Lock()
+		}
+		n := min(len(e.queue), maxBatchRecords)
+		batch := e.queue[:n:n]
+		e.inflight = n
+		e.mu.Unlock()
+
+		if n == 0 {
+			return
+		}
+
+		failed, err := e.producer.send(batch)
+
+		// 发送期间队首的消息可能因队列满被丢弃，只移除仍在队列中的部分
+		// 已被broker接受的消息出队，失败的消息留在队首下次重试，避免重复写入成功的分区
+		e.mu.Lock()
+		dropped := n - e.inflight
+		var retry []record
+		for _, i := range failed {
+			if i >= dropped {
+				retry = append(retry, batch[i])
+			}
+		}
+		if len(retry) == 0 {
+			e.queue = e.queue[e.inflight:]
+		} else {
+			e.queue = append(retry, e.queue[e.inflight:]...)
+		}
+		e.inflight = 0
+		e.mu.Unlock()
+
+		if err != nil {
+			e.errorHandler(fmt.Errorf("failed to produce %d of %d messages to topic %s: %v", len(failed), n, e.producer.topic, err))
+			return
+		}
+	}
+}
+
+// newMessage 将Pod指标转换为消息
+func newMessage(metrics *monitor.PodStorageMetrics) *Message {
+	return &Message{
+		PodName:         metrics.PodName,
+		Namespace:       metrics.Namespace,
+		PodUID:          metrics.PodUID,
+		NodeName:        metrics.NodeName,
+		WorkloadKind:    metrics.WorkloadKind,
+		WorkloadName:    metrics.WorkloadName,
+		ReadLatency:     metrics.ReadLatency,
+		WriteLatency:    metrics.WriteLatency,
+		QueueLatency:    metrics.QueueLatency,
+		DiskLatency:     metrics.DiskLatency,
+		NetworkLatency:  metrics.NetworkLatency,
+		ReadIOPS:        metrics.ReadIOPS,
+		WriteIOPS:       metrics.WriteIOPS,
+		ReadThroughput:  metrics.ReadThroughput,
+		WriteThroughput: metrics.WriteThroughput,
+		Utilization:     metrics.Utilization,
+		Timestamp:       metrics.Timestamp,
+	}
+}
+
+// messageKey 以Pod UID作为消息key，使同一Pod的消息写入同一分区；没有UID时（如模拟数据）使用"命名空间/Pod名称"
+func messageKey(metrics *monitor.PodStorageMetrics) []byte {
+	if metrics.PodUID != "" {
+		return []byte(metrics.PodUID)
+	}
+	return []byte(metrics.Namespace + "/" + metrics.PodName)
+}
@@ -0,0 +1,238 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxResponseSize 单个响应允许的最大长度，防止异常数据导致过量分配
+const maxResponseSize = 16 << 20
+
+// producer 最小化的Kafka生产者，只实现按key分区、acks=1、不压缩的同步发送
+// 连接和元数据在首次发送时建立，出错时丢弃以便下次重新获取
+type producer struct {
+	brokers       []string // 引导broker地址
+	topic         string
+	clientID      string
+	timeout       time.Duration
+	mu            sync.Mutex
+	correlationID int32
+	conns         map[string]net.Conn // 以broker地址为key的连接
+	meta          *topicMetadata
+	nodes         map[int32]string // 节点ID到broker地址
+}
+
+// newProducer 创建生产者，不会立即连接broker
+func newProducer(brokers []string, topic, clientID string, timeout time.Duration) *producer {
+	return &producer{
+		brokers:  brokers,
+		topic:    topic,
+		clientID: clientID,
+		timeout:  timeout,
+		conns:    make(map[string]net.Conn),
+	}
+}
+
+// send 按key分区发送一批消息，返回未被broker接受的消息在records中的下标（升序）
+// 元数据过期导致的错误会在刷新元数据后只重试失败的分区一次，已写入的分区不会重复发送
+func (p *producer) send(records []record) ([]int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pending := make([]int, len(records))
+	for i := range pending {
+		pending[i] = i
+	}
+	failed, err := p.sendLocked(records, pending)
+	if err == nil {
+		return nil, nil
+	}
+
+	// 分区leader变化或连接断开后重新获取元数据再试一次
+	p.meta = nil
+	if ke, ok := err.(kafkaError); ok && !ke.retriable() {
+		return failed, err
+	}
+	return p.sendLocked(records, failed)
+}
+
+// sendLocked 发送records中下标为pending的消息，返回未被接受的消息下标和遇到的第一个错误
+// 一个leader或分区失败不影响其他leader和分区的发送，调用方需持有p.mu
+func (p *producer) sendLocked(records []record, pending []int) ([]int, error) {
+	if p.meta == nil {
+		if err := p.refreshMetadata(); err != nil {
+			return pending, err
+		}
+	}
+
+	var failed []int
+	var firstErr error
+	fail := func(indexes []int, err error) {
+		failed = append(failed, indexes...)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// 按分区leader分组，同一分区内保持消息顺序
+	byLeader := make(map[int32]map[int32][]int)
+	for _, i := range pending {
+		partition := partitionFor(records[i].key, p.meta.partitions)
+		leader := p.meta.leaders[partition]
+		if _, ok := p.nodes[leader]; leader < 0 || !ok {
+			fail([]int{i}, kafkaError(errLeaderNotAvailable))
+			continue
+		}
+		if byLeader[leader] == nil {
+			byLeader[leader] = make(map[int32][]int)
+		}
+		byLeader[leader][partition] = append(byLeader[leader][partition], i)
+	}
+
+	for leader, partitions := range byLeader {
+		addr := p.nodes[leader]
+		batches := make(map[int32][]record, len(partitions))
+		for partition, indexes := range partitions {
+			for _, i := range indexes {
+				batches[partition] = append(batches[partition], records[i])
+			}
+		}
+
+		body := encodeProduceRequest(p.topic, 1, p.timeout, batches)
+		resp, err := p.roundTrip(addr, apiKeyProduce, produceVersion, body)
+		var results map[int32]int16
+		if err == nil {
+			if results, err = decodeProduceResponse(resp); err != nil {
+				p.closeConn(addr)
+				err = fmt.Errorf("failed to decode produce response from %s: %v", addr, err)
+			}
+		}
+		if err != nil {
+			// 请求失败时无法确定broker是否已写入，该leader的所有分区都按失败处理
+			for _, indexes := range partitions {
+				fail(indexes, err)
+			}
+			continue
+		}
+
+		for partition, indexes := range partitions {
+			code, ok := results[partition]
+			switch {
+			case !ok:
+				fail(indexes, fmt.Errorf("no produce result for partition %d from %s", partition, addr))
+			case code != 0:
+				fail(indexes, kafkaError(code))
+			}
+		}
+	}
+
+	sort.Ints(failed)
+	return failed, firstErr
+}
+
+// refreshMetadata 依次向引导broker查询topic的分区和leader，调用方需持有p.mu
+func (p *producer) refreshMetadata() error {
+	var lastErr error
+	for _, addr := range p.brokers {
+		resp, err := p.roundTrip(addr, apiKeyMetadata, metadataVersion, encodeMetadataRequest(p.topic))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		meta, err := decodeMetadataResponse(resp, p.topic)
+		if err != nil {
+			p.closeConn(addr)
+			lastErr = fmt.Errorf("failed to decode metadata response from %s: %v", addr, err)
+			continue
+		}
+		if meta.err != 0 {
+			return fmt.Errorf("failed to get metadata of topic %s: %v", p.topic, kafkaError(meta.err))
+		}
+		if len(meta.partitions) == 0 {
+			return fmt.Errorf("topic %s has no partitions", p.topic)
+		}
+
+		p.meta = meta
+		p.nodes = make(map[int32]string, len(meta.brokers))
+		for _, b := range meta.brokers {
+			p.nodes[b.nodeID] = b.addr
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to get metadata from any broker: %v", lastErr)
+}
+
+// roundTrip 向broker发送一个请求并读取响应体，出错时关闭连接，调用方需持有p.mu
+func (p *producer) roundTrip(addr string, apiKey, apiVersion int16, body []byte) ([]byte, error) {
+	conn, err := p.conn(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	p.correlationID++
+	correlationID := p.correlationID
+
+	conn.SetDeadline(time.Now().Add(p.timeout))
+	if _, err := conn.Write(encodeRequest(apiKey, apiVersion, correlationID, p.clientID, body)); err != nil {
+		p.closeConn(addr)
+		return nil, fmt.Errorf("failed to write request to %s: %v", addr, err)
+	}
+
+	var header [8]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		p.closeConn(addr)
+		return nil, fmt.Errorf("failed to read response from %s: %v", addr, err)
+	}
+	size := int32(binary.BigEndian.Uint32(header[:4]))
+	if size < 4 || size > maxResponseSize {
+		p.closeConn(addr)
+		return nil, fmt.Errorf("invalid response size %d from %s", size, addr)
+	}
+	if got := int32(binary.BigEndian.Uint32(header[4:])); got != correlationID {
+		p.closeConn(addr)
+		return nil, fmt.Errorf("unexpected correlation id %d from %s, want %d", got, addr, correlationID)
+	}
+
+	resp := make([]byte, size-4)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		p.closeConn(addr)
+		return nil, fmt.Errorf("failed to read response from %s: %v", addr, err)
+	}
+	return resp, nil
+}
+
+// conn 返回到broker的连接，不存在时建立，调用方需持有p.mu
+func (p *producer) conn(addr string) (net.Conn, error) {
+	if conn, ok := p.conns[addr]; ok {
+		return conn, nil
+	}
+	conn, err := net.DialTimeout("tcp", addr, p.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to broker %s: %v", addr, err)
+	}
+	p.conns[addr] = conn
+	return conn, nil
+}
+
+// closeConn 关闭并丢弃到broker的连接，调用方需持有p.mu
+func (p *producer) closeConn(addr string) {
+	if conn, ok := p.conns[addr]; ok {
+		conn.Close()
+		delete(p.conns, addr)
+	}
+}
+
+// close 关闭所有连接
+func (p *producer) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for addr := range p.conns {
+		p.closeConn(addr)
+	}
+}
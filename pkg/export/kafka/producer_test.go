@@ -0,0 +1,396 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testTopic = "ioeye-metrics"
+
+// errMessageTooLarge 不可重试的错误码MESSAGE_TOO_LARGE
+const errMessageTooLarge int16 = 10
+
+// fakeCluster 在本地监听的Kafka broker集合，只实现Metadata v1和Produce v3
+// 分区i的leader为节点i%len(brokers)
+type fakeCluster struct {
+	listeners  []net.Listener
+	partitions int32
+
+	mu           sync.Mutex
+	metadataReqs int
+	produceErrs  map[int32][]int16      // 各分区依次返回的错误码，用完后返回成功
+	received     map[int32][]string     // 各分区已接受的消息value
+	receivedBy   map[int32]map[int]bool // 各分区收到Produce请求的节点
+}
+
+// newFakeCluster 启动brokers个broker，topic有partitions个分区
+func newFakeCluster(t *testing.T, brokers int, partitions int32) *fakeCluster {
+	t.Helper()
+
+	c := &fakeCluster{
+		partitions:  partitions,
+		produceErrs: make(map[int32][]int16),
+		received:    make(map[int32][]string),
+		receivedBy:  make(map[int32]map[int]bool),
+	}
+	for node := 0; node < brokers; node++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { ln.Close() })
+		c.listeners = append(c.listeners, ln)
+		go c.serve(ln, node)
+	}
+	return c
+}
+
+// newProducer 返回以第一个broker为引导地址的生产者
+func (c *fakeCluster) newProducer(t *testing.T) *producer {
+	p := newProducer([]string{c.listeners[0].Addr().String()}, testTopic, "ioeye-test", 5*time.Second)
+	t.Cleanup(p.close)
+	return p
+}
+
+// failNext 让partition接下来的Produce依次返回codes中的错误码
+func (c *fakeCluster) failNext(partition int32, codes ...int16) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.produceErrs[partition] = append(c.produceErrs[partition], codes...)
+}
+
+// receivedValues 返回所有分区已接受的消息value，排序后返回
+func (c *fakeCluster) receivedValues() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var values []string
+	for _, v := range c.received {
+		values = append(values, v...)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func (c *fakeCluster) serve(ln net.Listener, node int) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			for c.handle(conn, node) {
+			}
+		}()
+	}
+}
+
+// handle 处理连接上的一个请求，连接关闭或请求无法解析时返回false
+func (c *fakeCluster) handle(conn net.Conn, node int) bool {
+	var size [4]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return false
+	}
+	req := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return false
+	}
+
+	d := &decoder{buf: req}
+	apiKey := d.int16()
+	d.int16() // api_version
+	correlationID := d.int32()
+	d.string() // client_id
+
+	var body []byte
+	switch apiKey {
+	case apiKeyMetadata:
+		body = c.metadataResponse()
+	case apiKeyProduce:
+		body = c.produceResponse(d, node)
+	default:
+		return false
+	}
+	if d.err != nil {
+		return false
+	}
+
+	resp := &encoder{}
+	resp.int32(int32(4 + len(body)))
+	resp.int32(correlationID)
+	resp.buf = append(resp.buf, body...)
+	_, err := conn.Write(resp.buf)
+	return err == nil
+}
+
+// metadataResponse 编码Metadata v1响应
+func (c *fakeCluster) metadataResponse() []byte {
+	c.mu.Lock()
+	c.metadataReqs++
+	c.mu.Unlock()
+
+	e := &encoder{}
+	e.int32(int32(len(c.listeners)))
+	for node, ln := range c.listeners {
+		host, port, _ := net.SplitHostPort(ln.Addr().String())
+		portNum, _ := strconv.Atoi(port)
+		e.int32(int32(node))
+		e.string(host)
+		e.int32(int32(portNum))
+		e.int16(-1) // rack null
+	}
+	e.int32(0) // controller_id
+
+	e.int32(1)
+	e.int16(0)
+	e.string(testTopic)
+	e.int8(0) // is_internal
+	e.int32(c.partitions)
+	for partition := int32(0); partition < c.partitions; partition++ {
+		leader := partition % int32(len(c.listeners))
+		e.int16(0)
+		e.int32(partition)
+		e.int32(leader)
+		e.int32(1) // replicas
+		e.int32(leader)
+		e.int32(1) // isr
+		e.int32(leader)
+	}
+	return e.buf
+}
+
+// produceResponse 解码Produce v3请求体，记录接受的消息并编码响应
+func (c *fakeCluster) produceResponse(d *decoder, node int) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d.string() // transactional_id
+	d.int16()  // acks
+	d.int32()  // timeout_ms
+
+	e := &encoder{}
+	topics := d.arrayLen()
+	e.int32(int32(topics))
+	for i := 0; i < topics; i++ {
+		e.string(d.string())
+		partitions := d.arrayLen()
+		e.int32(int32(partitions))
+		for j := 0; j < partitions; j++ {
+			partition := d.int32()
+			batch := d.take(int(d.int32()))
+
+			if c.receivedBy[partition] == nil {
+				c.receivedBy[partition] = make(map[int]bool)
+			}
+			c.receivedBy[partition][node] = true
+
+			var code int16
+			if codes := c.produceErrs[partition]; len(codes) > 0 {
+				code, c.produceErrs[partition] = codes[0], codes[1:]
+			}
+			if code == 0 {
+				c.received[partition] = append(c.received[partition], batchValues(batch)...)
+			}
+
+			e.int32(partition)
+			e.int16(code)
+			e.int64(0)  // base_offset
+			e.int64(-1) // log_append_time
+		}
+	}
+	e.int32(0) // throttle_time_ms
+	return e.buf
+}
+
+// batchValues 解析RecordBatch中各消息的value
+func batchValues(batch []byte) []string {
+	// base_offset到base_sequence共57字节，之后是消息数
+	const recordsOffset = 57
+	if len(batch) < recordsOffset+4 {
+		return nil
+	}
+	n := int(binary.BigEndian.Uint32(batch[recordsOffset:]))
+	buf := batch[recordsOffset+4:]
+
+	varint := func() int64 {
+		v, size := binary.Varint(buf)
+		buf = buf[size:]
+		return v
+	}
+	var values []string
+	for i := 0; i < n; i++ {
+		varint()      // length
+		buf = buf[1:] // attributes
+		varint()      // timestamp_delta
+		varint()      // offset_delta
+		if keyLen := varint(); keyLen > 0 {
+			buf = buf[keyLen:]
+		}
+		valueLen := varint()
+		values = append(values, string(buf[:valueLen]))
+		buf = buf[valueLen:]
+		varint() // headers
+	}
+	return values
+}
+
+// testRecords 返回n条key和value不同的消息
+func testRecords(n int) []record {
+	records := make([]record, n)
+	for i := range records {
+		key := fmt.Sprintf("pod-%d", i)
+		records[i] = record{key: []byte(key), value: []byte("value-" + key), ts: time.UnixMilli(1700000000000)}
+	}
+	return records
+}
+
+// recordValues 返回records的value，排序后返回
+func recordValues(records []record) []string {
+	values := make([]string, len(records))
+	for i, r := range records {
+		values[i] = string(r.value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func TestProducerSendRoutesToLeaders(t *testing.T) {
+	c := newFakeCluster(t, 2, 4)
+	p := c.newProducer(t)
+	records := testRecords(20)
+
+	if failed, err := p.send(records); len(failed) != 0 || err != nil {
+		t.Fatalf("send() = %v, %v, want all records accepted", failed, err)
+	}
+
+	if got, want := c.receivedValues(), recordValues(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("received %v, want %v", got, want)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 每条消息写入按key计算的分区，且请求发往该分区的leader
+	for partition, values := range c.received {
+		for _, v := range values {
+			key := []byte(v[len("value-"):])
+			if want := partitionFor(key, []int32{0, 1, 2, 3}); want != partition {
+				t.Errorf("record %s written to partition %d, want %d", v, partition, want)
+			}
+		}
+		if leader := int(partition) % 2; len(c.receivedBy[partition]) != 1 || !c.receivedBy[partition][leader] {
+			t.Errorf("partition %d produced to nodes %v, want only leader %d", partition, c.receivedBy[partition], leader)
+		}
+	}
+	if c.metadataReqs != 1 {
+		t.Errorf("metadata requested %d times, want 1", c.metadataReqs)
+	}
+}
+
+func TestProducerRetriesOnlyFailedPartitions(t *testing.T) {
+	c := newFakeCluster(t, 2, 4)
+	p := c.newProducer(t)
+	records := testRecords(20)
+
+	// 一个分区的leader刚刚变化，其他分区第一次就写入成功
+	failing := partitionFor(records[0].key, []int32{0, 1, 2, 3})
+	c.failNext(failing, errNotLeaderForPartition)
+
+	if failed, err := p.send(records); len(failed) != 0 || err != nil {
+		t.Fatalf("send() = %v, %v, want all records accepted after the retry", failed, err)
+	}
+
+	// 已成功的分区不会重复写入
+	if got, want := c.receivedValues(), recordValues(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("received %v, want each record exactly once %v", got, want)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.metadataReqs != 2 {
+		t.Errorf("metadata requested %d times, want 2 after a retriable error", c.metadataReqs)
+	}
+}
+
+func TestProducerReturnsFailedRecords(t *testing.T) {
+	c := newFakeCluster(t, 2, 4)
+	p := c.newProducer(t)
+	records := testRecords(20)
+
+	// 不可重试的错误不刷新元数据重试，只返回该分区的消息
+	failing := partitionFor(records[0].key, []int32{0, 1, 2, 3})
+	c.failNext(failing, errMessageTooLarge)
+
+	failed, err := p.send(records)
+	if err != kafkaError(errMessageTooLarge) {
+		t.Errorf("send() error = %v, want %v", err, kafkaError(errMessageTooLarge))
+	}
+	var want []int
+	for i, r := range records {
+		if partitionFor(r.key, []int32{0, 1, 2, 3}) == failing {
+			want = append(want, i)
+		}
+	}
+	if !reflect.DeepEqual(failed, want) {
+		t.Errorf("failed = %v, want the records of partition %d %v", failed, failing, want)
+	}
+	if n := len(c.receivedValues()); n != len(records)-len(want) {
+		t.Errorf("received %d records, want %d", n, len(records)-len(want))
+	}
+}
+
+func TestProducerBrokerUnreachable(t *testing.T) {
+	c := newFakeCluster(t, 1, 1)
+	p := c.newProducer(t)
+	c.listeners[0].Close()
+
+	records := testRecords(3)
+	failed, err := p.send(records)
+	if err == nil {
+		t.Fatal("send() to an unreachable broker succeeded")
+	}
+	if !reflect.DeepEqual(failed, []int{0, 1, 2}) {
+		t.Errorf("failed = %v, want all records", failed)
+	}
+}
+
+func TestExporterDrainRequeuesOnlyFailedRecords(t *testing.T) {
+	c := newFakeCluster(t, 2, 4)
+	records := testRecords(20)
+	var errs []error
+	e := &Exporter{
+		producer:     c.newProducer(t),
+		queueSize:    DefaultQueueSize,
+		queue:        append([]record(nil), records...),
+		errorHandler: func(err error) { errs = append(errs, err) },
+	}
+
+	failing := partitionFor(records[0].key, []int32{0, 1, 2, 3})
+	c.failNext(failing, errMessageTooLarge)
+
+	// 失败分区的消息留在队列中，已接受的消息出队
+	e.drain()
+	if len(errs) != 1 {
+		t.Fatalf("errors = %v, want one produce error", errs)
+	}
+	for _, r := range e.queue {
+		if partitionFor(r.key, []int32{0, 1, 2, 3}) != failing {
+			t.Errorf("accepted record %s left in the queue", r.value)
+		}
+	}
+
+	// 再次发送后每条消息恰好写入一次
+	e.drain()
+	if len(e.queue) != 0 {
+		t.Errorf("%d records left in the queue", len(e.queue))
+	}
+	if got, want := c.receivedValues(), recordValues(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("received %v, want each record exactly once %v", got, want)
+	}
+}
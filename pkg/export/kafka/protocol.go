@@ -0,0 +1,370 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"time"
+)
+
+// 用到的Kafka API，选用Kafka 0.11起支持的版本
+const (
+	apiKeyProduce   int16 = 0
+	apiKeyMetadata  int16 = 3
+	produceVersion  int16 = 3 // 支持RecordBatch（magic 2）的最低版本
+	metadataVersion int16 = 1
+)
+
+// Kafka错误码中需要刷新元数据后重试的部分
+const (
+	errUnknownTopicOrPartition int16 = 3
+	errLeaderNotAvailable      int16 = 5
+	errNotLeaderForPartition   int16 = 6
+)
+
+// castagnoli RecordBatch使用的CRC-32C校验表
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// errShortBuffer 响应长度不足
+var errShortBuffer = errors.New("kafka: malformed response")
+
+// kafkaError Kafka broker返回的错误码
+type kafkaError int16
+
+// Error 实现error接口
+func (e kafkaError) Error() string {
+	return fmt.Sprintf("kafka error code %d", int16(e))
+}
+
+// retriable 判断是否为刷新元数据后可以重试的错误
+func (e kafkaError) retriable() bool {
+	switch int16(e) {
+	case errUnknownTopicOrPartition, errLeaderNotAvailable, errNotLeaderForPartition:
+		return true
+	}
+	return false
+}
+
+// record 一条待发送的消息
+type record struct {
+	key   []byte
+	value []byte
+	ts    time.Time
+}
+
+// encoder 按Kafka协议的大端格式追加编码
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) int8(v int8)   { e.buf = append(e.buf, byte(v)) }
+func (e *encoder) int16(v int16) { e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(v)) }
+func (e *encoder) int32(v int32) { e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(v)) }
+func (e *encoder) int64(v int64) { e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v)) }
+
+// varint 编码zigzag变长整数，用于RecordBatch中的record
+func (e *encoder) varint(v int64) { e.buf = binary.AppendVarint(e.buf, v) }
+
+func (e *encoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// nullableString 空字符串编码为null
+func (e *encoder) nullableString(s string) {
+	if s == "" {
+		e.int16(-1)
+		return
+	}
+	e.string(s)
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.int32(int32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// varintBytes record中以变长整数为长度前缀的字节串，nil编码为-1
+func (e *encoder) varintBytes(b []byte) {
+	if b == nil {
+		e.varint(-1)
+		return
+	}
+	e.varint(int64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// decoder 按Kafka协议的大端格式顺序解码，出错后的读取均返回零值
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil || n < 0 || len(d.buf) < n {
+		d.err = errShortBuffer
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) int8() int8 {
+	if b := d.take(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *decoder) int16() int16 {
+	if b := d.take(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *decoder) int32() int32 {
+	if b := d.take(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *decoder) int64() int64 {
+	if b := d.take(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+// string 解码字符串，null返回空字符串
+func (d *decoder) string() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.take(int(n)))
+}
+
+// arrayLen 解码数组长度，null数组视为空数组
+func (d *decoder) arrayLen() int {
+	n := d.int32()
+	if n < 0 {
+		return 0
+	}
+	// 每个元素至少占1字节，避免恶意长度导致过量分配
+	if int(n) > len(d.buf) {
+		d.err = errShortBuffer
+		return 0
+	}
+	return int(n)
+}
+
+// encodeRequest 编码带长度前缀的请求，请求头为v1格式
+func encodeRequest(apiKey, apiVersion int16, correlationID int32, clientID string, body []byte) []byte {
+	e := &encoder{buf: make([]byte, 4, 4+14+len(clientID)+len(body))}
+	e.int16(apiKey)
+	e.int16(apiVersion)
+	e.int32(correlationID)
+	e.nullableString(clientID)
+	e.buf = append(e.buf, body...)
+	binary.BigEndian.PutUint32(e.buf, uint32(len(e.buf)-4))
+	return e.buf
+}
+
+// encodeMetadataRequest 编码只查询topic的Metadata v1请求体
+func encodeMetadataRequest(topic string) []byte {
+	e := &encoder{}
+	e.int32(1)
+	e.string(topic)
+	return e.buf
+}
+
+// brokerInfo Metadata响应中的broker地址
+type brokerInfo struct {
+	nodeID int32
+	addr   string
+}
+
+// topicMetadata Metadata响应中一个topic的分区信息
+type topicMetadata struct {
+	brokers    []brokerInfo
+	err        int16
+	partitions []int32         // 按分区号升序
+	leaders    map[int32]int32 // 分区号到leader节点，无leader时为-1
+}
+
+// decodeMetadataResponse 解码Metadata v1响应，只保留topic对应的元数据
+func decodeMetadataResponse(body []byte, topic string) (*topicMetadata, error) {
+	d := &decoder{buf: body}
+	meta := &topicMetadata{leaders: make(map[int32]int32), err: errUnknownTopicOrPartition}
+
+	for i, n := 0, d.arrayLen(); i < n; i++ {
+		nodeID := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		meta.brokers = append(meta.brokers, brokerInfo{nodeID: nodeID, addr: fmt.Sprintf("%s:%d", host, port)})
+	}
+	d.int32() // controller_id
+
+	for i, n := 0, d.arrayLen(); i < n; i++ {
+		errCode := d.int16()
+		name := d.string()
+		d.int8() // is_internal
+
+		var partitions []int32
+		leaders := make(map[int32]int32)
+		for j, m := 0, d.arrayLen(); j < m; j++ {
+			d.int16() // 分区错误码，leader为-1时即可判断不可用
+			partition := d.int32()
+			leader := d.int32()
+			for k, r := 0, d.arrayLen(); k < r; k++ {
+				d.int32() // replicas
+			}
+			for k, r := 0, d.arrayLen(); k < r; k++ {
+				d.int32() // isr
+			}
+			partitions = append(partitions, partition)
+			leaders[partition] = leader
+		}
+
+		if name == topic {
+			meta.err = errCode
+			meta.partitions = partitions
+			meta.leaders = leaders
+		}
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	sort.Slice(meta.partitions, func(i, j int) bool { return meta.partitions[i] < meta.partitions[j] })
+	return meta, nil
+}
+
+// encodeProduceRequest 编码Produce v3请求体，batches为各分区待发送的消息
+func encodeProduceRequest(topic string, acks int16, timeout time.Duration, batches map[int32][]record) []byte {
+	e := &encoder{}
+	e.nullableString("") // transactional_id
+	e.int16(acks)
+	e.int32(int32(timeout / time.Millisecond))
+	e.int32(1)
+	e.string(topic)
+	e.int32(int32(len(batches)))
+	for partition, records := range batches {
+		e.int32(partition)
+		e.bytes(encodeRecordBatch(records))
+	}
+	return e.buf
+}
+
+// encodeRecordBatch 编码不压缩的RecordBatch（magic 2）
+func encodeRecordBatch(records []record) []byte {
+	firstTs := records[0].ts.UnixMilli()
+	maxTs := firstTs
+
+	body := &encoder{}
+	for i, r := range records {
+		ts := r.ts.UnixMilli()
+		maxTs = max(maxTs, ts)
+
+		rec := &encoder{}
+		rec.int8(0) // attributes
+		rec.varint(ts - firstTs)
+		rec.varint(int64(i))
+		rec.varintBytes(r.key)
+		rec.varintBytes(r.value)
+		rec.varint(0) // headers
+
+		body.varint(int64(len(rec.buf)))
+		body.buf = append(body.buf, rec.buf...)
+	}
+
+	// CRC覆盖attributes到末尾的部分
+	crcPart := &encoder{}
+	crcPart.int16(0) // attributes：不压缩、CreateTime
+	crcPart.int32(int32(len(records) - 1))
+	crcPart.int64(firstTs)
+	crcPart.int64(maxTs)
+	crcPart.int64(-1) // producer_id
+	crcPart.int16(-1) // producer_epoch
+	crcPart.int32(-1) // base_sequence
+	crcPart.int32(int32(len(records)))
+	crcPart.buf = append(crcPart.buf, body.buf...)
+
+	batch := &encoder{}
+	batch.int64(0) // base_offset，由broker分配
+	batch.int32(int32(4 + 1 + 4 + len(crcPart.buf)))
+	batch.int32(-1) // partition_leader_epoch
+	batch.int8(2)   // magic
+	batch.buf = binary.BigEndian.AppendUint32(batch.buf, crc32.Checksum(crcPart.buf, castagnoli))
+	batch.buf = append(batch.buf, crcPart.buf...)
+	return batch.buf
+}
+
+// decodeProduceResponse 解码Produce v3响应，返回各分区的错误码
+func decodeProduceResponse(body []byte) (map[int32]int16, error) {
+	d := &decoder{buf: body}
+	result := make(map[int32]int16)
+	for i, n := 0, d.arrayLen(); i < n; i++ {
+		d.string() // topic
+		for j, m := 0, d.arrayLen(); j < m; j++ {
+			partition := d.int32()
+			result[partition] = d.int16()
+			d.int64() // base_offset
+			d.int64() // log_append_time
+		}
+	}
+	d.int32() // throttle_time_ms
+	if d.err != nil {
+		return nil, d.err
+	}
+	return result, nil
+}
+
+// murmur2 与Kafka Java客户端默认分区器一致的哈希，保证同一key与其他客户端写入相同分区
+func murmur2(data []byte) int32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+
+	length := len(data)
+	h := seed ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := data[length&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// partitionFor 按key选择分区
+func partitionFor(key []byte, partitions []int32) int32 {
+	return partitions[int(murmur2(key)&0x7fffffff)%len(partitions)]
+}
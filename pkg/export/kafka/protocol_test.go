@@ -0,0 +1,161 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash/crc32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// golden 将按字段分行、带注释的十六进制字符串转换为字节，注释以#开头
+func golden(t *testing.T, s string) []byte {
+	t.Helper()
+
+	var digits strings.Builder
+	for _, line := range strings.Split(s, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		digits.WriteString(strings.Join(strings.Fields(line), ""))
+	}
+	b, err := hex.DecodeString(digits.String())
+	if err != nil {
+		t.Fatalf("invalid golden hex: %v", err)
+	}
+	return b
+}
+
+// 以下字节按Kafka协议文档的RecordBatch v2和Produce v3格式逐字段编写，CRC-32C由独立实现计算
+var (
+	goldenBaseTime = time.UnixMilli(1700000000000)
+
+	goldenRecords = []record{
+		{key: []byte("k1"), value: []byte("v1"), ts: goldenBaseTime},
+		{key: nil, value: []byte("v2"), ts: goldenBaseTime.Add(5 * time.Millisecond)},
+	}
+
+	goldenRecordBatch = `
+		0000000000000000  # base_offset
+		00000045          # batch_length 69
+		ffffffff          # partition_leader_epoch -1
+		02                # magic 2
+		bdf15f45          # crc32c
+		0000              # attributes：不压缩、CreateTime
+		00000001          # last_offset_delta 1
+		0000018bcfe56800  # base_timestamp 1700000000000
+		0000018bcfe56805  # max_timestamp 1700000000005
+		ffffffffffffffff  # producer_id -1
+		ffff              # producer_epoch -1
+		ffffffff          # base_sequence -1
+		00000002          # records 2
+		14                # record 0: length 10
+		00                #   attributes
+		00                #   timestamp_delta 0
+		00                #   offset_delta 0
+		04 6b31           #   key "k1"
+		04 7631           #   value "v1"
+		00                #   headers 0
+		10                # record 1: length 8
+		00                #   attributes
+		0a                #   timestamp_delta 5
+		02                #   offset_delta 1
+		01                #   key null
+		04 7632           #   value "v2"
+		00                #   headers 0
+	`
+)
+
+func TestEncodeRecordBatch(t *testing.T) {
+	want := golden(t, goldenRecordBatch)
+	if got := encodeRecordBatch(goldenRecords); !bytes.Equal(got, want) {
+		t.Errorf("encodeRecordBatch() =\n%s\nwant\n%s", hex.Dump(got), hex.Dump(want))
+	}
+}
+
+func TestEncodeProduceRequest(t *testing.T) {
+	want := golden(t, `
+		ffff                                # transactional_id null
+		0001                                # acks 1
+		000005dc                            # timeout_ms 1500
+		00000001                            # topics 1
+		000d 696f6579652d6d657472696373     # topic "ioeye-metrics"
+		00000001                            # partitions 1
+		00000002                            # partition 2
+		00000051                            # record_set 81 bytes
+	`)
+	want = append(want, golden(t, goldenRecordBatch)...)
+
+	got := encodeProduceRequest("ioeye-metrics", 1, 1500*time.Millisecond, map[int32][]record{2: goldenRecords})
+	if !bytes.Equal(got, want) {
+		t.Errorf("encodeProduceRequest() =\n%s\nwant\n%s", hex.Dump(got), hex.Dump(want))
+	}
+}
+
+func TestEncodeRequest(t *testing.T) {
+	want := golden(t, `
+		00000011          # size 17
+		0000              # api_key Produce
+		0003              # api_version 3
+		00000007          # correlation_id 7
+		0005 696f657965   # client_id "ioeye"
+		0102              # body
+	`)
+	if got := encodeRequest(apiKeyProduce, produceVersion, 7, "ioeye", []byte{1, 2}); !bytes.Equal(got, want) {
+		t.Errorf("encodeRequest() = %x, want %x", got, want)
+	}
+}
+
+func TestCastagnoliCheckValue(t *testing.T) {
+	// CRC-32C的标准校验值
+	if got := crc32.Checksum([]byte("123456789"), castagnoli); got != 0xe3069283 {
+		t.Errorf("crc32c(123456789) = %#x, want 0xe3069283", got)
+	}
+}
+
+// Kafka Java客户端UtilsTest.testMurmur2中的测试向量
+var murmur2Vectors = []struct {
+	key  string
+	hash int32
+}{
+	{"21", -973932308},
+	{"foobar", -790332482},
+	{"a-little-bit-long-string", -985981536},
+	{"a-little-bit-longer-string", -1486304829},
+	{"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8", -58897971},
+	{"abc", 479470107},
+}
+
+func TestMurmur2(t *testing.T) {
+	for _, tt := range murmur2Vectors {
+		if got := murmur2([]byte(tt.key)); got != tt.hash {
+			t.Errorf("murmur2(%q) = %d, want %d", tt.key, got, tt.hash)
+		}
+	}
+}
+
+func TestPartitionFor(t *testing.T) {
+	// 期望值按Java默认分区器toPositive(murmur2(key)) % numPartitions计算
+	tests := []struct {
+		key        string
+		partitions int
+		want       int32
+	}{
+		{"21", 3, 0},
+		{"foobar", 3, 0},
+		{"a-little-bit-long-string", 3, 2},
+		{"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8", 3, 2},
+		{"foobar", 12, 6},
+		{"a-little-bit-long-string", 12, 8},
+		{"a-little-bit-longer-string", 12, 11},
+		{"abc", 12, 3},
+	}
+	for _, tt := range tests {
+		partitions := make([]int32, tt.partitions)
+		for i := range partitions {
+			partitions[i] = int32(i)
+		}
+		if got := partitionFor([]byte(tt.key), partitions); got != tt.want {
+			t.Errorf("partitionFor(%q, %d partitions) = %d, want %d", tt.key, tt.partitions, got, tt.want)
+		}
+	}
+}
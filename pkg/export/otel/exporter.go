@@ -0,0 +1,192 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// meterName IOEye注册的OTel Meter名称
+const meterName = "github.com/lizhongxuan/ioeye"
+
+// Option 配置OTLP导出器的选项
+type Option func(*Exporter)
+
+// Exporter 通过OTLP推送Pod存储性能指标
+type Exporter struct {
+	storageMonitor *monitor.StorageMonitor
+	endpoint       string
+	interval       time.Duration
+	insecure       bool
+	provider       *sdkmetric.MeterProvider
+	registration   metric.Registration
+	stopOnce       sync.Once
+}
+
+// podGauge 描述如何从Pod指标中提取一个OTel gauge值
+type podGauge struct {
+	name  string
+	unit  string
+	help  string
+	value func(m *monitor.PodStorageMetrics) uint64
+}
+
+// podGauges 按Pod导出的OTel gauge指标
+var podGauges = []podGauge{
+	{"ioeye.pod.read_latency", "ns", "Average read latency of the pod.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.ReadLatency }},
+	{"ioeye.pod.write_latency", "ns", "Average write latency of the pod.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.WriteLatency }},
+	{"ioeye.pod.queue_latency", "ns", "Average I/O queue latency of the pod.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.QueueLatency }},
+	{"ioeye.pod.disk_latency", "ns", "Average disk latency of the pod.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.DiskLatency }},
+	{"ioeye.pod.network_latency", "ns", "Average network storage latency of the pod.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.NetworkLatency }},
+	{"ioeye.pod.read_iops", "{operation}/s", "Read operations per second of the pod.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.ReadIOPS }},
+	{"ioeye.pod.write_iops", "{operation}/s", "Write operations per second of the pod.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.WriteIOPS }},
+	{"ioeye.pod.read_throughput", "By/s", "Read throughput of the pod.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.ReadThroughput }},
+	{"ioeye.pod.write_throughput", "By/s", "Write throughput of the pod.",
+		func(m *monitor.PodStorageMetrics) uint64 { return m.WriteThroughput }},
+}
+
+// WithInterval 设置指标推送周期
+func WithInterval(interval time.Duration) Option {
+	return func(e *Exporter) {
+		if interval > 0 {
+			e.interval = interval
+		}
+	}
+}
+
+// WithInsecure 使用HTTP而非HTTPS连接OTLP端点
+func WithInsecure() Option {
+	return func(e *Exporter) {
+		e.insecure = true
+	}
+}
+
+// NewExporter 创建OTLP指标导出器，endpoint格式为host:port
+func NewExporter(ctx context.Context, storageMonitor *monitor.StorageMonitor, endpoint string, opts ...Option) (*Exporter, error) {
+	e := &Exporter{
+		storageMonitor: storageMonitor,
+		endpoint:       endpoint,
+		interval:       10 * time.Second, // 默认10秒推送一次
+	}
+
+	// 应用选项
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	clientOpts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(e.endpoint)}
+	if e.insecure {
+		clientOpts = append(clientOpts, otlpmetrichttp.WithInsecure())
+	}
+
+	exporter, err := otlpmetrichttp.New(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %v", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName("ioeye"),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTel resource: %v", err)
+	}
+
+	if err := e.init(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(e.interval)), res); err != nil {
+		e.provider.Shutdown(ctx)
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// init 创建使用reader的MeterProvider并注册gauge
+func (e *Exporter) init(reader sdkmetric.Reader, res *resource.Resource) error {
+	e.provider = sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(reader),
+	)
+	return e.registerGauges()
+}
+
+// Start 等待上下文取消后刷新并关闭导出器
+func (e *Exporter) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return e.Stop()
+}
+
+// Stop 刷新尚未推送的指标并关闭导出器，可重复调用
+func (e *Exporter) Stop() error {
+	var err error
+	e.stopOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// 先刷新再注销回调，最后一次采集仍需通过回调读取Pod指标
+		if flushErr := e.provider.ForceFlush(ctx); flushErr != nil {
+			err = fmt.Errorf("failed to flush OTLP metrics: %v", flushErr)
+		}
+		if e.registration != nil {
+			e.registration.Unregister()
+		}
+		if shutdownErr := e.provider.Shutdown(ctx); shutdownErr != nil && err == nil {
+			err = fmt.Errorf("failed to shut down OTLP exporter: %v", shutdownErr)
+		}
+	})
+	return err
+}
+
+// registerGauges 创建异步gauge，并注册一次采集中读取所有Pod指标的回调
+func (e *Exporter) registerGauges() error {
+	meter := e.provider.Meter(meterName)
+
+	gauges := make([]metric.Int64ObservableGauge, len(podGauges))
+	observables := make([]metric.Observable, len(podGauges))
+	for i, g := range podGauges {
+		gauge, err := meter.Int64ObservableGauge(g.name,
+			metric.WithUnit(g.unit),
+			metric.WithDescription(g.help),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create gauge %s: %v", g.name, err)
+		}
+		gauges[i] = gauge
+		observables[i] = gauge
+	}
+
+	registration, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for _, metrics := range e.storageMonitor.GetAllMetrics() {
+			attrs := metric.WithAttributes(
+				attribute.String("pod", metrics.PodName),
+				attribute.String("namespace", metrics.Namespace),
+			)
+			for i, g := range podGauges {
+				o.ObserveInt64(gauges[i], int64(g.value(metrics)), attrs)
+			}
+		}
+		return nil
+	}, observables...)
+	if err != nil {
+		return fmt.Errorf("failed to register gauge callback: %v", err)
+	}
+	e.registration = registration
+
+	return nil
+}
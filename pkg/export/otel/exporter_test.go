@@ -0,0 +1,136 @@
+package otel
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/ebpf"
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// memoryExporter 在内存中记录导出的gauge值，以"指标名/Pod名"为key
+// SDK在Export返回后会复用ResourceMetrics，因此在Export中复制需要的值
+type memoryExporter struct {
+	mu      sync.Mutex
+	exports int
+	values  map[string]int64
+}
+
+func (e *memoryExporter) Temporality(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+	return sdkmetric.DefaultTemporalitySelector(kind)
+}
+
+func (e *memoryExporter) Aggregation(kind sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(kind)
+}
+
+func (e *memoryExporter) Export(_ context.Context, rm *metricdata.ResourceMetrics) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.exports++
+	if e.values == nil {
+		e.values = make(map[string]int64)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			gauge, ok := m.Data.(metricdata.Gauge[int64])
+			if !ok {
+				continue
+			}
+			for _, dp := range gauge.DataPoints {
+				if pod, ok := dp.Attributes.Value(attribute.Key("pod")); ok {
+					e.values[m.Name+"/"+pod.AsString()] = dp.Value
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (e *memoryExporter) ForceFlush(context.Context) error { return nil }
+
+func (e *memoryExporter) Shutdown(context.Context) error { return nil }
+
+// gaugeValue 返回导出的名为name的gauge对pod的值
+func (e *memoryExporter) gaugeValue(name, pod string) (int64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	v, ok := e.values[name+"/"+pod]
+	return v, ok
+}
+
+// newTestMonitor 返回已完成一次采集的监控器，Pod pod1使用模拟eBPF数据
+func newTestMonitor(t *testing.T) *monitor.StorageMonitor {
+	t.Helper()
+
+	clientset := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid-1"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	})
+	bpfMonitor, err := ebpf.NewMonitor(ebpf.WithMockData())
+	if err != nil {
+		t.Fatalf("NewMonitor() error = %v", err)
+	}
+	storageMonitor := monitor.NewStorageMonitor(bpfMonitor, k8s.NewClientForClientset(clientset), monitor.WithInterval(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	snapshots := storageMonitor.Subscribe()
+	if err := storageMonitor.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	select {
+	case <-snapshots:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no metrics collected")
+	}
+	storageMonitor.Unsubscribe(snapshots)
+	return storageMonitor
+}
+
+func TestStopFlushesLastValues(t *testing.T) {
+	storageMonitor := newTestMonitor(t)
+	want, err := storageMonitor.GetPodMetrics("pod1")
+	if err != nil {
+		t.Fatalf("GetPodMetrics() error = %v", err)
+	}
+
+	// 推送周期远大于测试时间，导出的指标只能来自Stop时的刷新
+	memory := &memoryExporter{}
+	e := &Exporter{storageMonitor: storageMonitor}
+	if err := e.init(sdkmetric.NewPeriodicReader(memory, sdkmetric.WithInterval(time.Hour)), resource.Empty()); err != nil {
+		t.Fatalf("init() error = %v", err)
+	}
+
+	if err := e.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	got, ok := memory.gaugeValue("ioeye.pod.read_latency", "pod1")
+	if !ok {
+		t.Fatal("read latency of pod1 was not exported on Stop")
+	}
+	if got != int64(want.ReadLatency) {
+		t.Errorf("exported read latency = %d, want %d", got, want.ReadLatency)
+	}
+
+	// 重复调用不再导出
+	exports := memory.exports
+	if err := e.Stop(); err != nil {
+		t.Errorf("second Stop() error = %v", err)
+	}
+	if memory.exports != exports {
+		t.Errorf("second Stop() exported again")
+	}
+}
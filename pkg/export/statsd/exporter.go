@@ -0,0 +1,250 @@
+package statsd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/monitor"
+)
+
+// 标签格式
+const (
+	FormatStatsD    = "statsd"    // 原始StatsD不支持标签，命名空间和Pod名称拼接在指标名称中
+	FormatDogStatsD = "dogstatsd" // DogStatsD扩展格式，命名空间和Pod名称作为标签
+)
+
+// DefaultPrefix 默认的指标名称前缀
+const DefaultPrefix = "ioeye"
+
+// maxPacketSize 单个UDP数据报的最大长度，按以太网MTU 1500扣除IP和UDP头部计算，避免IP分片
+const maxPacketSize = 1432
+
+// Option 配置StatsD导出器的选项
+type Option func(*Exporter)
+
+// Exporter 每个采集周期将各Pod的指标以gauge形式通过UDP发送到StatsD服务
+// UDP发送不等待对端确认，服务不可用时不会阻塞采集，发送失败只通过错误回调报告
+type Exporter struct {
+	storageMonitor *monitor.StorageMonitor
+	addr           string
+	conn           net.Conn
+	format         string
+	prefix         string
+	interval       time.Duration
+	errorHandler   func(error)
+	stopChan       chan struct{}
+	stopOnce       sync.Once
+}
+
+// podGauge 描述如何从Pod指标中提取一个gauge值
+type podGauge struct {
+	name  string
+	value func(m *monitor.PodStorageMetrics) uint64
+}
+
+// podGauges 按Pod发送的gauge指标，名称不含前缀
+var podGauges = []podGauge{
+	{"read_latency_ns", func(m *monitor.PodStorageMetrics) uint64 { return m.ReadLatency }},
+	{"write_latency_ns", func(m *monitor.PodStorageMetrics) uint64 { return m.WriteLatency }},
+	{"queue_latency_ns", func(m *monitor.PodStorageMetrics) uint64 { return m.QueueLatency }},
+	{"disk_latency_ns", func(m *monitor.PodStorageMetrics) uint64 { return m.DiskLatency }},
+	{"network_latency_ns", func(m *monitor.PodStorageMetrics) uint64 { return m.NetworkLatency }},
+	{"read_iops", func(m *monitor.PodStorageMetrics) uint64 { return m.ReadIOPS }},
+	{"write_iops", func(m *monitor.PodStorageMetrics) uint64 { return m.WriteIOPS }},
+	{"read_throughput_bps", func(m *monitor.PodStorageMetrics) uint64 { return m.ReadThroughput }},
+	{"write_throughput_bps", func(m *monitor.PodStorageMetrics) uint64 { return m.WriteThroughput }},
+}
+
+// WithFormat 设置标签格式，FormatStatsD或FormatDogStatsD
+func WithFormat(format string) Option {
+	return func(e *Exporter) {
+		if format != "" {
+			e.format = format
+		}
+	}
+}
+
+// WithPrefix 设置指标名称前缀，为空时使用DefaultPrefix
+func WithPrefix(prefix string) Option {
+	return func(e *Exporter) {
+		if prefix != "" {
+			e.prefix = prefix
+		}
+	}
+}
+
+// WithInterval 设置读取指标的周期，应与采集周期一致
+func WithInterval(interval time.Duration) Option {
+	return func(e *Exporter) {
+		if interval > 0 {
+			e.interval = interval
+		}
+	}
+}
+
+// WithErrorHandler 设置发送失败时的回调，默认打印到标准输出
+func WithErrorHandler(handler func(error)) Option {
+	return func(e *Exporter) {
+		if handler != nil {
+			e.errorHandler = handler
+		}
+	}
+}
+
+// NewExporter 创建StatsD指标导出器，addr为StatsD服务的host:port
+func NewExporter(storageMonitor *monitor.StorageMonitor, addr string, opts ...Option) (*Exporter, error) {
+	e := &Exporter{
+		storageMonitor: storageMonitor,
+		addr:           addr,
+		format:         FormatDogStatsD,
+		prefix:         DefaultPrefix,
+		interval:       10 * time.Second, // 默认10秒读取一次
+		errorHandler: func(err error) {
+			fmt.Printf("StatsD export error: %v\n", err)
+		},
+		stopChan: make(chan struct{}),
+	}
+
+	// 应用选项
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if e.format != FormatStatsD && e.format != FormatDogStatsD {
+		return nil, fmt.Errorf("unknown StatsD format %q, must be %s or %s", e.format, FormatStatsD, FormatDogStatsD)
+	}
+
+	// UDP没有连接过程，这里只解析地址，StatsD服务未启动也能创建成功
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve StatsD address %s: %v", addr, err)
+	}
+	e.conn = conn
+
+	return e, nil
+}
+
+// Start 每个周期发送新采集的指标，直到上下文取消或调用Stop
+func (e *Exporter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	var lastCollection time.Time
+	for {
+		select {
+		case <-ticker.C:
+			// 暂停采集期间没有新数据，不重复发送
+			collected := e.storageMonitor.LastCollectionTime()
+			if collected.IsZero() || !collected.After(lastCollection) {
+				continue
+			}
+			lastCollection = collected
+			e.send(e.storageMonitor.GetAllMetrics())
+		case <-ctx.Done():
+			return e.Stop()
+		case <-e.stopChan:
+			return nil
+		}
+	}
+}
+
+// Stop 关闭UDP套接字，可重复调用
+func (e *Exporter) Stop() error {
+	var err error
+	e.stopOnce.Do(func() {
+		close(e.stopChan)
+		if closeErr := e.conn.Close(); closeErr != nil {
+			err = fmt.Errorf("failed to close StatsD connection: %v", closeErr)
+		}
+	})
+	return err
+}
+
+// send 将一个周期的Pod指标按行编码，多行合并到不超过maxPacketSize的数据报中发送
+func (e *Exporter) send(allMetrics map[string]*monitor.PodStorageMetrics) {
+	packet := make([]byte, 0, maxPacketSize)
+	var failed int
+	var lastErr error
+	flush := func() {
+		if len(packet) == 0 {
+			return
+		}
+		if _, err := e.conn.Write(packet); err != nil {
+			failed++
+			lastErr = err
+		}
+		packet = packet[:0]
+	}
+
+	for _, metrics := range allMetrics {
+		for _, g := range podGauges {
+			line := e.formatLine(metrics, g.name, g.value(metrics))
+			if len(packet) > 0 && len(packet)+1+len(line) > maxPacketSize {
+				flush()
+			}
+			if len(packet) > 0 {
+				packet = append(packet, '\n')
+			}
+			packet = append(packet, line...)
+		}
+	}
+	flush()
+
+	// 同一周期的失败原因通常相同（如对端端口未监听），只报告一次
+	if failed > 0 {
+		e.errorHandler(fmt.Errorf("failed to send %d StatsD packets to %s: %v", failed, e.addr, lastErr))
+	}
+}
+
+// formatLine 按标签格式编码一个gauge
+// DogStatsD: <prefix>.pod.<name>:<value>|g|#namespace:<namespace>,pod:<pod>
+// StatsD:    <prefix>.pod.<namespace>.<pod>.<name>:<value>|g
+func (e *Exporter) formatLine(m *monitor.PodStorageMetrics, name string, value uint64) []byte {
+	var b strings.Builder
+	b.WriteString(e.prefix)
+	b.WriteString(".pod.")
+	if e.format == FormatStatsD {
+		b.WriteString(sanitizeName(m.Namespace))
+		b.WriteByte('.')
+		b.WriteString(sanitizeName(m.PodName))
+		b.WriteByte('.')
+	}
+	b.WriteString(name)
+	b.WriteByte(':')
+	b.WriteString(strconv.FormatUint(value, 10))
+	b.WriteString("|g")
+	if e.format == FormatDogStatsD {
+		b.WriteString("|#namespace:")
+		b.WriteString(sanitizeTag(m.Namespace))
+		b.WriteString(",pod:")
+		b.WriteString(sanitizeTag(m.PodName))
+	}
+	return []byte(b.String())
+}
+
+// sanitizeName 替换指标名称中StatsD用作分隔符的字符，Pod名称中的"."会被当作名称层级
+func sanitizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', ':', '|', '@', '#', ',', ' ', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// sanitizeTag 替换标签值中DogStatsD用作分隔符的字符
+func sanitizeTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', ' ', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,305 @@
+package k8s
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// DefaultCgroupRoot 默认的cgroup文件系统挂载点
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// DefaultProcRoot 默认的proc文件系统挂载点，纯cgroup v1节点从中读取进程所属的cgroup
+const DefaultProcRoot = "/proc"
+
+// CgroupResolver 将eBPF上报的cgroup ID映射回所属Pod
+// cgroup v2中cgroup ID即cgroup目录的inode号；纯cgroup v1节点上eBPF数据改以进程ID为key，
+// 见PIDBased，此时ID为进程ID，按/proc/<pid>/cgroup中的路径映射到Pod
+type CgroupResolver struct {
+	root       string
+	procRoot   string
+	mu         sync.RWMutex
+	byID       map[uint64]PodRef
+	containers map[uint64]string // 容器cgroup ID到容器名称，不含Pod级cgroup
+}
+
+// NewCgroupResolver 创建一个新的cgroup解析器，root为cgroup文件系统挂载点
+func NewCgroupResolver(root string) *CgroupResolver {
+	if root == "" {
+		root = DefaultCgroupRoot
+	}
+
+	return &CgroupResolver{
+		root:       root,
+		procRoot:   DefaultProcRoot,
+		byID:       make(map[uint64]PodRef),
+		containers: make(map[uint64]string),
+	}
+}
+
+// Refresh 遍历cgroup层级，按Pod UID将Pod及其容器的cgroup目录关联到pods中的Pod
+// 纯cgroup v1节点上改为遍历进程，见refreshProcs
+func (r *CgroupResolver) Refresh(pods []PodRef) error {
+	podsByUID := make(map[string]PodRef, len(pods))
+	for _, pod := range pods {
+		podsByUID[pod.UID] = pod
+	}
+
+	hierarchy, ok := r.hierarchy()
+	if !ok {
+		return r.refreshProcs(podsByUID)
+	}
+
+	byID := make(map[uint64]PodRef)
+	containers := make(map[uint64]string)
+	err := filepath.WalkDir(hierarchy, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 遍历期间cgroup可能被删除，跳过即可
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		// 只遍历kubelet创建的kubepods层级
+		if filepath.Dir(path) == hierarchy && !strings.HasPrefix(d.Name(), "kubepods") {
+			return filepath.SkipDir
+		}
+
+		uid, ok := podUIDFromCgroupPath(path)
+		if !ok {
+			return nil
+		}
+		pod, ok := podsByUID[uid]
+		if !ok {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			byID[stat.Ino] = pod
+			if name, ok := containerName(pod, d.Name()); ok {
+				containers[stat.Ino] = name
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk cgroup hierarchy %s: %v", hierarchy, err)
+	}
+
+	r.setMapping(byID, containers)
+	return nil
+}
+
+// refreshProcs 纯cgroup v1节点上按进程关联Pod：读取每个进程的/proc/<pid>/cgroup，
+// 路径属于pods中的Pod时以进程ID为key记录；两次刷新之间启动并退出的进程无法关联
+func (r *CgroupResolver) refreshProcs(podsByUID map[string]PodRef) error {
+	entries, err := os.ReadDir(r.procRoot)
+	if err != nil {
+		return fmt.Errorf("failed to read processes from %s: %v", r.procRoot, err)
+	}
+
+	byID := make(map[uint64]PodRef)
+	containers := make(map[uint64]string)
+	for _, entry := range entries {
+		pid, err := strconv.ParseUint(entry.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		// 进程可能已退出
+		data, err := os.ReadFile(filepath.Join(r.procRoot, entry.Name(), "cgroup"))
+		if err != nil {
+			continue
+		}
+		path, ok := podCgroupFromProc(data)
+		if !ok {
+			continue
+		}
+		uid, _ := podUIDFromCgroupPath(path)
+		pod, ok := podsByUID[uid]
+		if !ok {
+			continue
+		}
+
+		byID[pid] = pod
+		if name, ok := containerName(pod, filepath.Base(path)); ok {
+			containers[pid] = name
+		}
+	}
+
+	r.setMapping(byID, containers)
+	return nil
+}
+
+// setMapping 替换映射结果
+func (r *CgroupResolver) setMapping(byID map[uint64]PodRef, containers map[uint64]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.byID = byID
+	r.containers = containers
+}
+
+// PIDBased 返回节点是否为纯cgroup v1，此时eBPF数据需以进程ID为key，Resolve等方法的ID均为进程ID
+// 纯cgroup v1节点上bpf_get_current_cgroup_id对所有进程返回同一个根cgroup，无法区分Pod
+func (r *CgroupResolver) PIDBased() bool {
+	_, ok := r.hierarchy()
+	return !ok
+}
+
+// Resolve 返回cgroup ID对应的Pod
+func (r *CgroupResolver) Resolve(cgroupID uint64) (PodRef, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pod, ok := r.byID[cgroupID]
+	return pod, ok
+}
+
+// ResolveContainer 返回cgroup ID对应的Pod和容器名称
+// cgroup属于Pod级目录或pause容器等无法对应到容器时，ok为true但容器名称为空
+func (r *CgroupResolver) ResolveContainer(cgroupID uint64) (pod PodRef, container string, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pod, ok = r.byID[cgroupID]
+	return pod, r.containers[cgroupID], ok
+}
+
+// CgroupIDs 返回UID为podUID的Pod及其容器的cgroup ID（升序），Pod不在上次Refresh的结果中时返回空
+// 按UID而不是名称匹配，不同命名空间中的同名Pod互不影响；按进程ID解析时没有可用于过滤的cgroup ID，总是返回空
+func (r *CgroupResolver) CgroupIDs(podUID string) []uint64 {
+	if r.PIDBased() {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []uint64
+	for id, pod := range r.byID {
+		if pod.UID == podUID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// hierarchy 返回需要遍历的cgroup层级目录
+// eBPF程序通过bpf_get_current_cgroup_id取得的总是cgroup v2（unified）层级中的ID：
+// cgroup v2直接使用挂载点，cgroup v1使用混合模式下的unified层级；
+// 纯cgroup v1节点没有unified层级，blkio等v1层级的目录inode不会与eBPF上报的ID匹配，ok为false，改按进程解析
+func (r *CgroupResolver) hierarchy() (string, bool) {
+	if _, err := os.Stat(filepath.Join(r.root, "cgroup.controllers")); err == nil {
+		return r.root, true
+	}
+
+	unified := filepath.Join(r.root, "unified")
+	if info, err := os.Stat(unified); err == nil && info.IsDir() {
+		return unified, true
+	}
+
+	return "", false
+}
+
+// podCgroupFromProc 从/proc/<pid>/cgroup的内容中找出属于Pod的cgroup路径，优先使用blkio层级
+// 每行格式为"层级ID:控制器列表:路径"，例如"4:blkio:/kubepods/burstable/pod<uid>/<container>"
+func podCgroupFromProc(data []byte) (string, bool) {
+	var found string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if _, ok := podUIDFromCgroupPath(fields[2]); !ok {
+			continue
+		}
+		if slices.Contains(strings.Split(fields[1], ","), "blkio") {
+			return fields[2], true
+		}
+		if found == "" {
+			found = fields[2]
+		}
+	}
+	return found, found != ""
+}
+
+// podUIDFromCgroupPath 从cgroup路径中提取Pod UID，支持cgroupfs和systemd两种驱动的命名：
+//
+//	kubepods/burstable/pod<uid>/<container>
+//	kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod<uid>.slice/cri-containerd-<id>.scope
+func podUIDFromCgroupPath(path string) (string, bool) {
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		segment := strings.TrimSuffix(segments[i], ".slice")
+
+		idx := strings.LastIndex(segment, "pod")
+		if idx < 0 {
+			continue
+		}
+		uid := segment[idx+len("pod"):]
+		// systemd驱动中UID的"-"被替换为"_"
+		uid = strings.ReplaceAll(uid, "_", "-")
+		if isPodUID(uid) {
+			return uid, true
+		}
+	}
+	return "", false
+}
+
+// containerName 按cgroup目录名中的容器ID查找Pod中的容器名称，支持cgroupfs和systemd两种驱动的命名：
+//
+//	<id>
+//	cri-containerd-<id>.scope、crio-<id>.scope、docker-<id>.scope
+func containerName(pod PodRef, dirName string) (string, bool) {
+	id := strings.TrimSuffix(dirName, ".scope")
+	if idx := strings.LastIndex(id, "-"); idx >= 0 {
+		id = id[idx+1:]
+	}
+	if id == "" {
+		return "", false
+	}
+	for _, container := range pod.Containers {
+		if container.ID == id {
+			return container.Name, true
+		}
+	}
+	return "", false
+}
+
+// isPodUID 判断字符串是否为UUID格式的Pod UID
+func isPodUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdef", c) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,294 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+const (
+	testPodUID1 = "1b4e28ba-2fa1-11d2-883f-0016d3cca427"
+	testPodUID2 = "6fa459ea-ee8a-3ca4-894e-db77e160355e"
+)
+
+// makeCgroupDirs 在root下创建dirs中的cgroup目录，返回各目录的inode号
+func makeCgroupDirs(t *testing.T, root string, dirs ...string) map[string]uint64 {
+	t.Helper()
+
+	inodes := make(map[string]uint64, len(dirs))
+	for _, dir := range dirs {
+		path := filepath.Join(root, dir)
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inodes[dir] = info.Sys().(*syscall.Stat_t).Ino
+	}
+	return inodes
+}
+
+// newUnifiedRoot 创建cgroup v2挂载点的目录结构
+func newUnifiedRoot(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("io memory\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// systemdPodDir 返回systemd驱动下burstable Pod的cgroup目录
+func systemdPodDir(uid string) string {
+	return "kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + strings.ReplaceAll(uid, "-", "_") + ".slice"
+}
+
+func TestCgroupIDsMatchesPodUID(t *testing.T) {
+	root := newUnifiedRoot(t)
+	pod1Dir := systemdPodDir(testPodUID1)
+	pod2Dir := systemdPodDir(testPodUID2)
+	inodes := makeCgroupDirs(t, root,
+		pod1Dir,
+		pod1Dir+"/cri-containerd-aaa.scope",
+		pod2Dir,
+		pod2Dir+"/cri-containerd-bbb.scope",
+	)
+
+	// 不同命名空间中的同名Pod
+	pods := []PodRef{
+		{Name: "db-0", Namespace: "team-a", UID: testPodUID1, Containers: []ContainerRef{{Name: "db", ID: "aaa"}}},
+		{Name: "db-0", Namespace: "team-b", UID: testPodUID2, Containers: []ContainerRef{{Name: "db", ID: "bbb"}}},
+	}
+	resolver := NewCgroupResolver(root)
+	if err := resolver.Refresh(pods); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	want := []uint64{inodes[pod1Dir], inodes[pod1Dir+"/cri-containerd-aaa.scope"]}
+	sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+	if got := resolver.CgroupIDs(testPodUID1); !reflect.DeepEqual(got, want) {
+		t.Errorf("CgroupIDs(%s) = %v, want %v", testPodUID1, got, want)
+	}
+	if got := resolver.CgroupIDs("db-0"); len(got) != 0 {
+		t.Errorf("CgroupIDs(pod name) = %v, want none", got)
+	}
+
+	pod, container, ok := resolver.ResolveContainer(inodes[pod2Dir+"/cri-containerd-bbb.scope"])
+	if !ok || pod.Namespace != "team-b" || container != "db" {
+		t.Errorf("ResolveContainer() = %+v, %q, %v, want team-b/db-0 container db", pod, container, ok)
+	}
+}
+
+func TestPodUIDFromCgroupPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		uid  string
+		ok   bool
+	}{
+		{"cgroupfs pod", "/sys/fs/cgroup/kubepods/burstable/pod" + testPodUID1, testPodUID1, true},
+		{"cgroupfs container", "/sys/fs/cgroup/kubepods/burstable/pod" + testPodUID1 + "/0a1b2c3d4e5f", testPodUID1, true},
+		{"cgroupfs guaranteed", "/sys/fs/cgroup/kubepods/pod" + testPodUID2 + "/0a1b2c3d4e5f", testPodUID2, true},
+		{"systemd pod", "/sys/fs/cgroup/" + systemdPodDir(testPodUID1), testPodUID1, true},
+		{"systemd container", "/sys/fs/cgroup/" + systemdPodDir(testPodUID1) + "/cri-containerd-0a1b2c3d4e5f.scope", testPodUID1, true},
+		{"systemd besteffort", "/sys/fs/cgroup/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod" +
+			strings.ReplaceAll(testPodUID2, "-", "_") + ".slice/crio-0a1b2c3d4e5f.scope", testPodUID2, true},
+		{"qos level", "/sys/fs/cgroup/kubepods.slice/kubepods-burstable.slice", "", false},
+		{"kubepods root", "/sys/fs/cgroup/kubepods", "", false},
+		{"not a uid", "/sys/fs/cgroup/kubepods/burstable/podnotauid", "", false},
+		{"system slice", "/sys/fs/cgroup/system.slice/containerd.service", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uid, ok := podUIDFromCgroupPath(tt.path)
+			if uid != tt.uid || ok != tt.ok {
+				t.Errorf("podUIDFromCgroupPath(%q) = %q, %v, want %q, %v", tt.path, uid, ok, tt.uid, tt.ok)
+			}
+		})
+	}
+}
+
+func TestContainerName(t *testing.T) {
+	pod := PodRef{Containers: []ContainerRef{
+		{Name: "app", ID: "0a1b2c3d4e5f"},
+		{Name: "sidecar", ID: "9f8e7d6c5b4a"},
+	}}
+
+	tests := []struct {
+		dirName string
+		name    string
+		ok      bool
+	}{
+		{"0a1b2c3d4e5f", "app", true},
+		{"cri-containerd-0a1b2c3d4e5f.scope", "app", true},
+		{"crio-9f8e7d6c5b4a.scope", "sidecar", true},
+		{"docker-9f8e7d6c5b4a.scope", "sidecar", true},
+		{"cri-containerd-ffffffffffff.scope", "", false}, // pause容器等不在Pod状态中的容器
+		{"kubepods-burstable-pod" + strings.ReplaceAll(testPodUID1, "-", "_") + ".slice", "", false},
+		{"cri-containerd-.scope", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dirName, func(t *testing.T) {
+			name, ok := containerName(pod, tt.dirName)
+			if name != tt.name || ok != tt.ok {
+				t.Errorf("containerName(%q) = %q, %v, want %q, %v", tt.dirName, name, ok, tt.name, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRefreshCgroupfsLayout(t *testing.T) {
+	root := newUnifiedRoot(t)
+	podDir := "kubepods/burstable/pod" + testPodUID1
+	inodes := makeCgroupDirs(t, root,
+		podDir,
+		podDir+"/0a1b2c3d4e5f",
+		podDir+"/ffffffffffff",               // pause容器
+		"kubepods/burstable/pod"+testPodUID2, // 不在pods中的Pod
+		"system.slice/kubelet.service",
+	)
+
+	pod := PodRef{Name: "web", Namespace: "default", UID: testPodUID1, Containers: []ContainerRef{{Name: "app", ID: "0a1b2c3d4e5f"}}}
+	resolver := NewCgroupResolver(root)
+	if err := resolver.Refresh([]PodRef{pod}); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	tests := []struct {
+		dir       string
+		ok        bool
+		container string
+	}{
+		{podDir, true, ""},
+		{podDir + "/0a1b2c3d4e5f", true, "app"},
+		{podDir + "/ffffffffffff", true, ""},
+		{"kubepods/burstable/pod" + testPodUID2, false, ""},
+		{"system.slice/kubelet.service", false, ""},
+	}
+	for _, tt := range tests {
+		got, container, ok := resolver.ResolveContainer(inodes[tt.dir])
+		if ok != tt.ok || container != tt.container || (ok && got.UID != testPodUID1) {
+			t.Errorf("ResolveContainer(%s) = %s, %q, %v, want ok=%v container %q", tt.dir, got.UID, container, ok, tt.ok, tt.container)
+		}
+	}
+}
+
+func TestCgroupHierarchy(t *testing.T) {
+	t.Run("cgroup v2", func(t *testing.T) {
+		root := newUnifiedRoot(t)
+		resolver := NewCgroupResolver(root)
+		got, ok := resolver.hierarchy()
+		if !ok || got != root || resolver.PIDBased() {
+			t.Errorf("hierarchy() = %q, %v, pid based %v, want %q", got, ok, resolver.PIDBased(), root)
+		}
+	})
+
+	t.Run("hybrid", func(t *testing.T) {
+		root := t.TempDir()
+		makeCgroupDirs(t, root, "unified", "blkio")
+		resolver := NewCgroupResolver(root)
+		got, ok := resolver.hierarchy()
+		if want := filepath.Join(root, "unified"); !ok || got != want || resolver.PIDBased() {
+			t.Errorf("hierarchy() = %q, %v, pid based %v, want %q", got, ok, resolver.PIDBased(), want)
+		}
+	})
+
+	t.Run("cgroup v1 only", func(t *testing.T) {
+		root := t.TempDir()
+		podDir := "kubepods/burstable/pod" + testPodUID1
+		makeCgroupDirs(t, root, "blkio/"+podDir+"/0a1b2c3d4e5f", "memory/"+podDir+"/0a1b2c3d4e5f")
+
+		// 纯cgroup v1节点按/proc/<pid>/cgroup关联进程和Pod
+		procRoot := t.TempDir()
+		writeProcCgroup(t, procRoot, "100", "12:memory:/"+podDir+"/0a1b2c3d4e5f\n4:blkio:/"+podDir+"/0a1b2c3d4e5f\n1:name=systemd:/"+podDir+"/0a1b2c3d4e5f\n")
+		writeProcCgroup(t, procRoot, "101", "4:blkio:/"+podDir+"\n")
+		writeProcCgroup(t, procRoot, "200", "4:blkio:/system.slice/containerd.service\n")
+		writeProcCgroup(t, procRoot, "300", "4:blkio:/kubepods/burstable/pod"+testPodUID2+"/ffffffffffff\n")
+		writeProcCgroup(t, procRoot, "self", "4:blkio:/"+podDir+"\n")
+
+		resolver := NewCgroupResolver(root)
+		resolver.procRoot = procRoot
+		if _, ok := resolver.hierarchy(); ok || !resolver.PIDBased() {
+			t.Fatal("cgroup v1 only node not detected as pid based")
+		}
+
+		pod := PodRef{Name: "web", Namespace: "default", UID: testPodUID1, Containers: []ContainerRef{{Name: "app", ID: "0a1b2c3d4e5f"}}}
+		if err := resolver.Refresh([]PodRef{pod}); err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+
+		tests := []struct {
+			pid       uint64
+			ok        bool
+			container string
+		}{
+			{100, true, "app"},
+			{101, true, ""},  // Pod级cgroup中的进程
+			{200, false, ""}, // 不属于Pod的进程
+			{300, false, ""}, // 不在pods中的Pod
+			{999, false, ""}, // 已退出的进程
+		}
+		for _, tt := range tests {
+			got, container, ok := resolver.ResolveContainer(tt.pid)
+			if ok != tt.ok || container != tt.container || (ok && got.UID != testPodUID1) {
+				t.Errorf("ResolveContainer(%d) = %s, %q, %v, want ok=%v container %q", tt.pid, got.UID, container, ok, tt.ok, tt.container)
+			}
+		}
+
+		// 进程ID不能用于按cgroup过滤
+		if ids := resolver.CgroupIDs(testPodUID1); len(ids) != 0 {
+			t.Errorf("CgroupIDs() = %v on a pid based node, want none", ids)
+		}
+	})
+
+	t.Run("cgroup v1 only without proc", func(t *testing.T) {
+		resolver := NewCgroupResolver(t.TempDir())
+		resolver.procRoot = filepath.Join(t.TempDir(), "missing")
+		if err := resolver.Refresh(nil); err == nil {
+			t.Error("Refresh() error = nil without a readable proc filesystem")
+		}
+	})
+}
+
+// writeProcCgroup 在procRoot下写入进程pid的cgroup文件
+func writeProcCgroup(t *testing.T, procRoot, pid, content string) {
+	t.Helper()
+
+	dir := filepath.Join(procRoot, pid)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPodCgroupFromProc(t *testing.T) {
+	podPath := "/kubepods/burstable/pod" + testPodUID1 + "/0a1b2c3d4e5f"
+	tests := []struct {
+		name    string
+		content string
+		path    string
+		ok      bool
+	}{
+		{"prefers blkio", "12:memory:/kubepods/burstable/pod" + testPodUID2 + "\n4:blkio:" + podPath + "\n", podPath, true},
+		{"combined controllers", "3:cpu,cpuacct:" + podPath + "\n", podPath, true},
+		{"systemd driver", "4:blkio:/" + systemdPodDir(testPodUID1) + "/cri-containerd-0a1b2c3d4e5f.scope\n",
+			"/" + systemdPodDir(testPodUID1) + "/cri-containerd-0a1b2c3d4e5f.scope", true},
+		{"not in a pod", "4:blkio:/system.slice/kubelet.service\n0::/\n", "", false},
+		{"malformed", "garbage\n", "", false},
+	}
+	for _, tt := range tests {
+		path, ok := podCgroupFromProc([]byte(tt.content))
+		if path != tt.path || ok != tt.ok {
+			t.Errorf("%s: podCgroupFromProc() = %q, %v, want %q, %v", tt.name, path, ok, tt.path, tt.ok)
+		}
+	}
+}
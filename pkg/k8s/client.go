@@ -5,20 +5,104 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 // Client 封装Kubernetes客户端
 type Client struct {
-	clientset *kubernetes.Clientset
+	clientset   kubernetes.Interface
+	cluster     string                    // 集群名称，即kubeconfig中的context名称，单集群时为空
+	podLister   corelisters.PodLister     // 启用informer时的Pod本地缓存
+	podInformer cache.SharedIndexInformer // 启用informer时的Pod informer
+	eventsMu    sync.RWMutex
+	events      *eventStore // 调用WatchStorageEvents后记录的存储相关事件
+	listMu      sync.RWMutex
+	lastList    time.Time // 上次成功列出Pod的时间，受listMu保护
 }
 
 // NewClient 创建一个新的Kubernetes客户端
 func NewClient(kubeconfigPath string) (*Client, error) {
+	return NewClientForContext(kubeconfigPath, "")
+}
+
+// NewClientForContext 使用kubeconfig中的指定context创建客户端，列出的Pod以context名称作为集群名称
+// kubeContext为空时与NewClient相同
+func NewClientForContext(kubeconfigPath, kubeContext string) (*Client, error) {
+	config, err := buildConfig(kubeconfigPath, kubeContext)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := newClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	c.cluster = kubeContext
+	return c, nil
+}
+
+// NewClientFromBytes 使用内存中的kubeconfig内容创建Kubernetes客户端，无需先写入文件
+func NewClientFromBytes(kubeconfig []byte) (*Client, error) {
+	if len(kubeconfig) == 0 {
+		return nil, fmt.Errorf("kubeconfig is empty")
+	}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kubeconfig: %v", err)
+	}
+
+	return newClientForConfig(config)
+}
+
+// newClientForConfig 根据REST配置创建直接访问API server的客户端
+func newClientForConfig(config *rest.Config) (*Client, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+
+	return NewClientForClientset(clientset), nil
+}
+
+// NewClientForClientset 使用已创建的clientset构造直接访问API server的客户端，
+// 用于嵌入IOEye的程序复用自己的clientset，或在测试中使用fake clientset
+func NewClientForClientset(clientset kubernetes.Interface) *Client {
+	return &Client{
+		clientset: clientset,
+	}
+}
+
+// Cluster 返回客户端所属集群的名称，单集群时为空
+func (c *Client) Cluster() string {
+	return c.cluster
+}
+
+// buildConfig 根据kubeconfig路径构建REST配置，路径为空时优先使用集群内配置
+// 指定kubeContext时使用kubeconfig中的该context，路径为空时按KUBECONFIG环境变量和默认位置查找kubeconfig
+func buildConfig(kubeconfigPath, kubeContext string) (*rest.Config, error) {
+	if kubeContext != "" {
+		rules := clientcmd.NewDefaultClientConfigLoadingRules()
+		rules.ExplicitPath = kubeconfigPath
+		config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules,
+			&clientcmd.ConfigOverrides{CurrentContext: kubeContext}).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build kubeconfig for context %q: %v", kubeContext, err)
+		}
+		return config, nil
+	}
+
 	var config *rest.Config
 	var err error
 
@@ -40,43 +124,117 @@ func NewClient(kubeconfigPath string) (*Client, error) {
 		}
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
-	}
+	return config, nil
+}
 
-	return &Client{
-		clientset: clientset,
-	}, nil
+// PodRef 标识一个Pod
+type PodRef struct {
+	Cluster    string // Pod所在集群的名称，单集群时为空
+	Name       string
+	Namespace  string
+	UID        string
+	NodeName   string
+	Labels     map[string]string
+	Workload   WorkloadRef
+	PVCs       []string       // Pod挂载的PersistentVolumeClaim名称
+	Containers []ContainerRef // 已启动的容器，用于将容器的cgroup关联到容器名称
 }
 
-// ListPods 列出特定命名空间中的所有Pod
-func (c *Client) ListPods(namespace string) ([]string, error) {
-	var podNames []string
+// ContainerRef 标识Pod中的一个容器
+type ContainerRef struct {
+	Name string
+	ID   string // 容器运行时中的容器ID，不含"containerd://"等前缀
+}
 
-	// 如果namespace为空，则列出所有命名空间的Pod
-	ns := namespace
-	if ns == "" {
-		ns = metav1.NamespaceAll
+// ListPods 列出namespaces中符合opts.LabelSelector的运行中的Pod，namespaces为空时列出所有命名空间
+// 指定多个命名空间时逐个查询并合并结果；直接查询API和从informer缓存读取时都按isPodRunning过滤
+func (c *Client) ListPods(ctx context.Context, namespaces []string, opts metav1.ListOptions) ([]PodRef, error) {
+	if c.podLister != nil {
+		selector, err := labels.Parse(opts.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector %q: %v", opts.LabelSelector, err)
+		}
+		podRefs, err := c.listCachedPods(namespaces, selector)
+		if err == nil {
+			c.recordList()
+		}
+		return podRefs, err
 	}
 
-	pods, err := c.clientset.CoreV1().Pods(ns).List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list pods: %v", err)
+	var podRefs []PodRef
+
+	// 如果未指定命名空间，则列出所有命名空间的Pod
+	for _, ns := range queryNamespaces(namespaces) {
+		pods, err := c.clientset.CoreV1().Pods(ns).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods in namespace %q: %v", ns, err)
+		}
+
+		for i := range pods.Items {
+			if !isPodRunning(&pods.Items[i]) {
+				continue
+			}
+			podRefs = append(podRefs, c.newPodRef(&pods.Items[i]))
+		}
+	}
+
+	c.recordList()
+	return podRefs, nil
+}
+
+// queryNamespaces 返回需要逐个查询的命名空间，去除重复项，为空时返回metav1.NamespaceAll
+func queryNamespaces(namespaces []string) []string {
+	if len(namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+
+	seen := make(map[string]bool, len(namespaces))
+	result := make([]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		if seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		result = append(result, ns)
 	}
+	return result
+}
 
-	for _, pod := range pods.Items {
-		podNames = append(podNames, pod.Name)
+// newPodRef 从Pod对象构造PodRef
+func (c *Client) newPodRef(pod *corev1.Pod) PodRef {
+	return PodRef{
+		Cluster:    c.cluster,
+		Name:       pod.Name,
+		Namespace:  pod.Namespace,
+		UID:        string(pod.UID),
+		NodeName:   pod.Spec.NodeName,
+		Labels:     pod.Labels,
+		Workload:   podWorkload(pod),
+		PVCs:       podPVCs(pod),
+		Containers: podContainers(pod),
 	}
+}
 
-	return podNames, nil
+// podContainers 从Pod状态中返回已分配容器ID的容器，包括初始化容器
+func podContainers(pod *corev1.Pod) []ContainerRef {
+	var containers []ContainerRef
+	statuses := append(append([]corev1.ContainerStatus(nil), pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		// ContainerID格式为"<运行时>://<ID>"，容器尚未创建时为空
+		_, id, ok := strings.Cut(status.ContainerID, "://")
+		if !ok || id == "" {
+			continue
+		}
+		containers = append(containers, ContainerRef{Name: status.Name, ID: id})
+	}
+	return containers
 }
 
 // GetPodVolumes 获取特定Pod的卷信息
-func (c *Client) GetPodVolumes(namespace, podName string) ([]string, error) {
+func (c *Client) GetPodVolumes(ctx context.Context, namespace, podName string) ([]string, error) {
 	var volumeNames []string
 
-	pod, err := c.clientset.CoreV1().Pods(namespace).Get(context.Background(), podName, metav1.GetOptions{})
+	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pod %s: %v", podName, err)
 	}
@@ -88,18 +246,12 @@ func (c *Client) GetPodVolumes(namespace, podName string) ([]string, error) {
 	return volumeNames, nil
 }
 
-// GetCSIDrivers 返回集群中所有的CSI驱动
-func (c *Client) GetCSIDrivers() ([]string, error) {
-	var driverNames []string
-
-	// 需要使用CSI API获取驱动列表
-	// 此处为简化示例，仅返回常见的一些CSI驱动名称
-	driverNames = []string{
-		"csi.aws.ebs.com",
-		"pd.csi.storage.gke.io",
-		"disk.csi.azure.com",
-		"cinder.csi.openstack.org",
+// GetPodPVCs 获取特定Pod挂载的PersistentVolumeClaim名称
+func (c *Client) GetPodPVCs(ctx context.Context, namespace, podName string) ([]string, error) {
+	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod %s: %v", podName, err)
 	}
 
-	return driverNames, nil
+	return podPVCs(pod), nil
 }
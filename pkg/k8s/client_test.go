@@ -0,0 +1,140 @@
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// runningPod 返回处于运行状态的Pod
+func runningPod(namespace, name string, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			UID:       types.UID(namespace + "-" + name),
+			Labels:    labels,
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+// podNames 返回排序后的"命名空间/名称"列表
+func podNames(pods []PodRef) []string {
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Namespace+"/"+pod.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestListPodsNamespaces(t *testing.T) {
+	// 未运行和正在删除的Pod在两种查询方式下都不返回
+	pending := runningPod("team-a", "web-1", map[string]string{"app": "web"})
+	pending.Status.Phase = corev1.PodPending
+	completed := runningPod("team-b", "job-0", nil)
+	completed.Status.Phase = corev1.PodSucceeded
+	deleting := runningPod("team-c", "db-1", map[string]string{"app": "db"})
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	deleting.Finalizers = []string{"test"}
+
+	clientset := fake.NewSimpleClientset(
+		runningPod("team-a", "web-0", map[string]string{"app": "web"}),
+		runningPod("team-a", "db-0", map[string]string{"app": "db"}),
+		runningPod("team-b", "web-0", map[string]string{"app": "web"}),
+		runningPod("team-c", "db-0", map[string]string{"app": "db"}),
+		pending, completed, deleting,
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	informerClient, err := newInformerClient(ctx, clientset, "")
+	if err != nil {
+		t.Fatalf("newInformerClient() error = %v", err)
+	}
+	clients := map[string]*Client{
+		"list":     NewClientForClientset(clientset),
+		"informer": informerClient,
+	}
+
+	tests := []struct {
+		name       string
+		namespaces []string
+		selector   string
+		want       []string
+	}{
+		{
+			name:       "single namespace",
+			namespaces: []string{"team-a"},
+			want:       []string{"team-a/db-0", "team-a/web-0"},
+		},
+		{
+			name:       "multiple namespaces",
+			namespaces: []string{"team-a", "team-c"},
+			want:       []string{"team-a/db-0", "team-a/web-0", "team-c/db-0"},
+		},
+		{
+			name:       "duplicate namespaces",
+			namespaces: []string{"team-b", "team-b"},
+			want:       []string{"team-b/web-0"},
+		},
+		{
+			name: "all namespaces",
+			want: []string{"team-a/db-0", "team-a/web-0", "team-b/web-0", "team-c/db-0"},
+		},
+		{
+			name:       "namespace without pods",
+			namespaces: []string{"team-d"},
+			want:       []string{},
+		},
+		{
+			name:       "multiple namespaces with label selector",
+			namespaces: []string{"team-a", "team-b"},
+			selector:   "app=web",
+			want:       []string{"team-a/web-0", "team-b/web-0"},
+		},
+	}
+
+	for clientName, client := range clients {
+		for _, tt := range tests {
+			t.Run(clientName+"/"+tt.name, func(t *testing.T) {
+				pods, err := client.ListPods(ctx, tt.namespaces, metav1.ListOptions{LabelSelector: tt.selector})
+				if err != nil {
+					t.Fatalf("ListPods() error = %v", err)
+				}
+				if got := podNames(pods); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("ListPods(%v) = %v, want %v", tt.namespaces, got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestQueryNamespaces(t *testing.T) {
+	tests := []struct {
+		name       string
+		namespaces []string
+		want       []string
+	}{
+		{"all", nil, []string{metav1.NamespaceAll}},
+		{"single", []string{"team-a"}, []string{"team-a"}},
+		{"multiple keep order", []string{"team-c", "team-a"}, []string{"team-c", "team-a"}},
+		{"duplicates removed", []string{"team-a", "team-b", "team-a"}, []string{"team-a", "team-b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := queryNamespaces(tt.namespaces); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queryNamespaces(%v) = %v, want %v", tt.namespaces, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CSIDriverInfo 描述集群中安装的CSI驱动
+type CSIDriverInfo struct {
+	Name string
+	// AttachRequired 驱动是否需要ControllerPublishVolume（attach）操作，未声明时Kubernetes默认为true
+	AttachRequired bool
+	// VolumeExpansion 是否有使用该驱动的StorageClass允许卷扩容
+	// CSIDriver对象本身不声明扩容能力，扩容由StorageClass的allowVolumeExpansion控制
+	VolumeExpansion bool
+}
+
+// GetCSIDrivers 返回集群中安装的CSI驱动，按名称排序
+// 集群不提供storage.k8s.io/v1 CSIDriver API时返回空列表并打印警告
+func (c *Client) GetCSIDrivers(ctx context.Context) ([]CSIDriverInfo, error) {
+	drivers, err := c.clientset.StorageV1().CSIDrivers().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			fmt.Printf("Warning: CSIDriver API is not available in this cluster: %v\n", err)
+			return []CSIDriverInfo{}, nil
+		}
+		return nil, fmt.Errorf("failed to list CSI drivers: %v", err)
+	}
+
+	// 统计允许扩容的StorageClass所使用的provisioner
+	expandable := make(map[string]bool)
+	classes, err := c.clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		fmt.Printf("Warning: failed to list storage classes, volume expansion support is unknown: %v\n", err)
+	} else {
+		for _, class := range classes.Items {
+			if class.AllowVolumeExpansion != nil && *class.AllowVolumeExpansion {
+				expandable[class.Provisioner] = true
+			}
+		}
+	}
+
+	result := make([]CSIDriverInfo, 0, len(drivers.Items))
+	for _, driver := range drivers.Items {
+		attachRequired := true
+		if driver.Spec.AttachRequired != nil {
+			attachRequired = *driver.Spec.AttachRequired
+		}
+		result = append(result, CSIDriverInfo{
+			Name:            driver.Name,
+			AttachRequired:  attachRequired,
+			VolumeExpansion: expandable[driver.Name],
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result, nil
+}
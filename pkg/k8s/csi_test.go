@@ -0,0 +1,80 @@
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestGetCSIDrivers(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&storagev1.CSIDriver{
+			ObjectMeta: metav1.ObjectMeta{Name: "rbd.csi.ceph.com"},
+			Spec:       storagev1.CSIDriverSpec{AttachRequired: boolPtr(false)},
+		},
+		&storagev1.CSIDriver{
+			ObjectMeta: metav1.ObjectMeta{Name: "ebs.csi.aws.com"},
+		},
+		&storagev1.StorageClass{
+			ObjectMeta:           metav1.ObjectMeta{Name: "gp3"},
+			Provisioner:          "ebs.csi.aws.com",
+			AllowVolumeExpansion: boolPtr(true),
+		},
+		&storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "ceph-rbd"},
+			Provisioner: "rbd.csi.ceph.com",
+		},
+	)
+
+	drivers, err := NewClientForClientset(clientset).GetCSIDrivers(context.Background())
+	if err != nil {
+		t.Fatalf("GetCSIDrivers() error = %v", err)
+	}
+
+	// 按名称排序；未声明attachRequired时默认为true
+	want := []CSIDriverInfo{
+		{Name: "ebs.csi.aws.com", AttachRequired: true, VolumeExpansion: true},
+		{Name: "rbd.csi.ceph.com", AttachRequired: false, VolumeExpansion: false},
+	}
+	if !reflect.DeepEqual(drivers, want) {
+		t.Errorf("GetCSIDrivers() = %+v, want %+v", drivers, want)
+	}
+}
+
+func TestGetCSIDriversAPIUnavailable(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("list", "csidrivers", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(schema.GroupResource{Group: "storage.k8s.io", Resource: "csidrivers"}, "")
+	})
+
+	drivers, err := NewClientForClientset(clientset).GetCSIDrivers(context.Background())
+	if err != nil {
+		t.Fatalf("GetCSIDrivers() error = %v, want nil when the API group is unavailable", err)
+	}
+	if drivers == nil || len(drivers) != 0 {
+		t.Errorf("GetCSIDrivers() = %#v, want an empty list", drivers)
+	}
+}
+
+func TestGetCSIDriversError(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("list", "csidrivers", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "storage.k8s.io", Resource: "csidrivers"}, "", nil)
+	})
+
+	if _, err := NewClientForClientset(clientset).GetCSIDrivers(context.Background()); err == nil {
+		t.Error("GetCSIDrivers() error = nil, want an error for a forbidden list")
+	}
+}
@@ -0,0 +1,175 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// 存储相关事件的保留时间和每个对象最多保留的事件数
+const (
+	storageEventRetention = time.Hour
+	maxEventsPerObject    = 50
+)
+
+// storageEventReasons 与存储相关的Kubernetes事件原因
+var storageEventReasons = map[string]bool{
+	"FailedMount":            true,
+	"FailedAttachVolume":     true,
+	"FailedMapVolume":        true,
+	"VolumeResizeFailed":     true,
+	"FileSystemResizeFailed": true,
+	"NodeHasDiskPressure":    true,
+	"FreeDiskSpaceFailed":    true,
+	"EvictionThresholdMet":   true,
+}
+
+// StorageEvent 与Pod或其所在节点相关的存储事件
+type StorageEvent struct {
+	Kind      string // Pod或Node
+	Name      string
+	Reason    string
+	Message   string
+	Type      string // Normal或Warning
+	Count     int32  // 事件重复发生的次数
+	Timestamp time.Time
+	uid       string
+}
+
+// eventStore 按对象保存最近的存储事件
+type eventStore struct {
+	mu     sync.RWMutex
+	events map[string][]StorageEvent // key见eventKey
+}
+
+// WatchStorageEvents 开始监听集群中与存储相关的事件，ctx取消时停止
+// 不等待首次同步完成，缺少events的list/watch权限时只是没有事件，不影响其他功能
+func (c *Client) WatchStorageEvents(ctx context.Context) error {
+	store := &eventStore{events: make(map[string][]StorageEvent)}
+
+	factory := informers.NewSharedInformerFactory(c.clientset, informerResyncPeriod)
+	informer := factory.Core().V1().Events().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if event, ok := obj.(*corev1.Event); ok {
+				store.record(event)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if event, ok := newObj.(*corev1.Event); ok {
+				store.record(event)
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add event handler: %v", err)
+	}
+
+	c.eventsMu.Lock()
+	c.events = store
+	c.eventsMu.Unlock()
+
+	factory.Start(ctx.Done())
+	return nil
+}
+
+// StorageEvents 返回since之后与Pod及其所在节点相关的存储事件，按时间排序
+// 未调用WatchStorageEvents时返回nil
+func (c *Client) StorageEvents(namespace, podName, nodeName string, since time.Time) []StorageEvent {
+	c.eventsMu.RLock()
+	store := c.events
+	c.eventsMu.RUnlock()
+	if store == nil {
+		return nil
+	}
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var result []StorageEvent
+	keys := []string{eventKey("Pod", namespace, podName)}
+	if nodeName != "" {
+		keys = append(keys, eventKey("Node", "", nodeName))
+	}
+	for _, key := range keys {
+		for _, event := range store.events[key] {
+			if !event.Timestamp.Before(since) {
+				result = append(result, event)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Timestamp.Before(result[j].Timestamp) })
+	return result
+}
+
+// record 保存存储相关的Pod或节点事件，同一事件重复发生时更新已有记录
+func (s *eventStore) record(event *corev1.Event) {
+	if !storageEventReasons[event.Reason] {
+		return
+	}
+
+	obj := event.InvolvedObject
+	var key string
+	switch obj.Kind {
+	case "Pod":
+		key = eventKey("Pod", obj.Namespace, obj.Name)
+	case "Node":
+		// 节点事件的命名空间不固定，只按节点名称区分
+		key = eventKey("Node", "", obj.Name)
+	default:
+		return
+	}
+
+	recorded := StorageEvent{
+		Kind:      obj.Kind,
+		Name:      obj.Name,
+		Reason:    event.Reason,
+		Message:   event.Message,
+		Type:      event.Type,
+		Count:     event.Count,
+		Timestamp: eventTime(event),
+		uid:       string(event.UID),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 顺便清理过期事件
+	cutoff := time.Now().Add(-storageEventRetention)
+	events := s.events[key][:0]
+	for _, e := range s.events[key] {
+		if e.uid != recorded.uid && e.Timestamp.After(cutoff) {
+			events = append(events, e)
+		}
+	}
+	events = append(events, recorded)
+	if len(events) > maxEventsPerObject {
+		events = events[len(events)-maxEventsPerObject:]
+	}
+	s.events[key] = events
+}
+
+// eventKey 返回事件所属对象的key
+func eventKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// eventTime 返回事件最近一次发生的时间，兼容只设置了EventTime的新版事件
+func eventTime(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
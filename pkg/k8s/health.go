@@ -0,0 +1,30 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Ping 请求API server的/version检查集群连接是否可用，开销很小，适合在就绪检查中调用
+// 启用informer时ListPods读取本地缓存，与API server断开后仍能成功，需要通过Ping发现连接问题
+func (c *Client) Ping(ctx context.Context) error {
+	if err := c.clientset.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Error(); err != nil {
+		return fmt.Errorf("failed to reach Kubernetes API server: %v", err)
+	}
+	return nil
+}
+
+// LastListTime 返回上次成功列出Pod的时间，尚未成功列出时为零值
+func (c *Client) LastListTime() time.Time {
+	c.listMu.RLock()
+	defer c.listMu.RUnlock()
+	return c.lastList
+}
+
+// recordList 记录一次成功的Pod列表请求
+func (c *Client) recordList() {
+	c.listMu.Lock()
+	defer c.listMu.Unlock()
+	c.lastList = time.Now()
+}
@@ -0,0 +1,165 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// informerResyncPeriod informer的全量重新同步周期
+const informerResyncPeriod = 10 * time.Minute
+
+// PodEventHandler 处理Pod新增和删除事件
+type PodEventHandler struct {
+	OnAdd    func(pod PodRef)
+	OnDelete func(pod PodRef)
+}
+
+// NewClientWithInformer 创建一个基于informer的Kubernetes客户端
+// 客户端在本地缓存中维护运行中的Pod，ctx取消时informer停止
+func NewClientWithInformer(ctx context.Context, kubeconfigPath string) (*Client, error) {
+	return NewClientWithInformerForContext(ctx, kubeconfigPath, "")
+}
+
+// NewClientWithInformerForContext 使用kubeconfig中的指定context创建基于informer的客户端
+// kubeContext为空时与NewClientWithInformer相同
+func NewClientWithInformerForContext(ctx context.Context, kubeconfigPath, kubeContext string) (*Client, error) {
+	config, err := buildConfig(kubeconfigPath, kubeContext)
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+
+	return newInformerClient(ctx, clientset, kubeContext)
+}
+
+// newInformerClient 启动clientset的Pod informer并等待首次同步，列出的Pod以cluster作为集群名称
+func newInformerClient(ctx context.Context, clientset kubernetes.Interface, cluster string) (*Client, error) {
+	factory := informers.NewSharedInformerFactory(clientset, informerResyncPeriod)
+	podInformer := factory.Core().V1().Pods()
+
+	c := &Client{
+		clientset:   clientset,
+		cluster:     cluster,
+		podLister:   podInformer.Lister(),
+		podInformer: podInformer.Informer(),
+	}
+
+	factory.Start(ctx.Done())
+
+	// 等待本地缓存完成首次同步
+	if !cache.WaitForCacheSync(ctx.Done(), c.podInformer.HasSynced) {
+		return nil, fmt.Errorf("failed to sync pod informer cache")
+	}
+
+	return c, nil
+}
+
+// InformerEnabled 返回客户端是否使用informer缓存
+func (c *Client) InformerEnabled() bool {
+	return c.podInformer != nil
+}
+
+// WatchPods 注册Pod事件处理器，Pod进入运行状态时触发OnAdd，Pod删除或结束时触发OnDelete
+func (c *Client) WatchPods(handler PodEventHandler) error {
+	if c.podInformer == nil {
+		return fmt.Errorf("pod informer is not enabled")
+	}
+
+	_, err := c.podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok || !isPodRunning(pod) {
+				return
+			}
+			if handler.OnAdd != nil {
+				handler.OnAdd(c.newPodRef(pod))
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, ok := oldObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			newPod, ok := newObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+
+			// 只关注运行状态的变化
+			switch {
+			case !isPodRunning(oldPod) && isPodRunning(newPod):
+				if handler.OnAdd != nil {
+					handler.OnAdd(c.newPodRef(newPod))
+				}
+			case isPodRunning(oldPod) && !isPodRunning(newPod):
+				if handler.OnDelete != nil {
+					handler.OnDelete(c.newPodRef(newPod))
+				}
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			// 删除事件可能携带墓碑对象
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			pod, ok := obj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			if handler.OnDelete != nil {
+				handler.OnDelete(c.newPodRef(pod))
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add pod event handler: %v", err)
+	}
+
+	return nil
+}
+
+// listCachedPods 从informer缓存中列出namespaces中符合selector的运行中的Pod，namespaces为空时列出所有命名空间
+func (c *Client) listCachedPods(namespaces []string, selector labels.Selector) ([]PodRef, error) {
+	var pods []*corev1.Pod
+
+	for _, ns := range queryNamespaces(namespaces) {
+		var nsPods []*corev1.Pod
+		var err error
+		if ns == metav1.NamespaceAll {
+			nsPods, err = c.podLister.List(selector)
+		} else {
+			nsPods, err = c.podLister.Pods(ns).List(selector)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list cached pods: %v", err)
+		}
+		pods = append(pods, nsPods...)
+	}
+
+	var podRefs []PodRef
+	for _, pod := range pods {
+		if !isPodRunning(pod) {
+			continue
+		}
+		podRefs = append(podRefs, c.newPodRef(pod))
+	}
+
+	return podRefs, nil
+}
+
+// isPodRunning 判断Pod是否处于运行状态，Pending、已结束和正在删除的Pod没有需要跟踪的I/O
+func isPodRunning(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil
+}
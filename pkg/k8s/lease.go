@@ -0,0 +1,266 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LeaseElector 通过coordination.k8s.io/v1的Lease对象在多个实例中选出唯一的活动实例
+// 租约过期按本地观察到租约最后一次变化的时间计算，不依赖各节点时钟一致
+type LeaseElector struct {
+	client    *Client
+	namespace string
+	name      string
+	identity  string        // 本实例的持有者标识
+	duration  time.Duration // 持有者超过该时间没有续约时其他实例可以接管
+	retry     time.Duration // 获取或续约的周期
+
+	mu           sync.Mutex
+	holder       string    // 最近观察到的持有者，受mu保护
+	leading      bool      // 本实例是否持有租约，受mu保护
+	observed     string    // 最近观察到的租约内容（持有者和续约时间），用于判断租约是否仍在续约
+	observedTime time.Time // observed最后一次变化的本地时间
+	lastRenew    time.Time // 本实例最后一次成功获取或续约的时间
+	acquired     chan struct{}
+	lost         chan struct{}
+}
+
+// NewLeaseElector 创建租约选举器，identity为本实例的持有者标识，duration为租约时长
+// 使用Run参与选举，或使用Observe只跟踪当前的持有者
+func (c *Client) NewLeaseElector(namespace, name, identity string, duration time.Duration) *LeaseElector {
+	return &LeaseElector{
+		client:    c,
+		namespace: namespace,
+		name:      name,
+		identity:  identity,
+		duration:  duration,
+		retry:     duration / 3,
+		acquired:  make(chan struct{}),
+		lost:      make(chan struct{}),
+	}
+}
+
+// Run 每隔租约时长的1/3尝试获取或续约租约，直到ctx取消或失去租约
+// 获得租约时关闭Acquired返回的通道；之后超过租约时长的2/3仍未能续约时视为失去租约，关闭Lost返回的通道并返回
+// ctx取消时如果持有租约则主动释放，其他实例无需等待租约过期即可接管
+func (e *LeaseElector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.retry)
+	defer ticker.Stop()
+
+	for {
+		ok, err := e.tryAcquireOrRenew(ctx)
+		if err != nil && ctx.Err() == nil {
+			fmt.Printf("Error updating lease %s/%s: %v\n", e.namespace, e.name, err)
+		}
+
+		e.mu.Lock()
+		switch {
+		case ok && !e.leading:
+			e.leading = true
+			close(e.acquired)
+		case !ok && e.leading && (e.holder != e.identity || time.Since(e.lastRenew) > e.duration*2/3):
+			// 租约已被其他实例接管，或超过续约期限；续约期限早于租约过期，其他实例接管前本实例已停止工作
+			e.leading = false
+			close(e.lost)
+			e.mu.Unlock()
+			return
+		}
+		e.mu.Unlock()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			e.release()
+			return
+		}
+	}
+}
+
+// Observe 每隔租约时长的1/3读取一次租约，只跟踪当前的持有者而不参与选举，直到ctx取消
+func (e *LeaseElector) Observe(ctx context.Context) {
+	ticker := time.NewTicker(e.retry)
+	defer ticker.Stop()
+
+	for {
+		if _, err := e.getLease(ctx); err != nil && !apierrors.IsNotFound(err) && ctx.Err() == nil {
+			fmt.Printf("Error reading lease %s/%s: %v\n", e.namespace, e.name, err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Acquired 返回在本实例第一次获得租约时关闭的通道
+func (e *LeaseElector) Acquired() <-chan struct{} {
+	return e.acquired
+}
+
+// Lost 返回在本实例获得租约后又失去租约时关闭的通道
+func (e *LeaseElector) Lost() <-chan struct{} {
+	return e.lost
+}
+
+// Leading 返回本实例当前是否持有租约
+func (e *LeaseElector) Leading() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leading
+}
+
+// Holder 返回最近观察到的租约持有者标识，租约不存在或已被释放时为空
+func (e *LeaseElector) Holder() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.holder
+}
+
+// getLease 读取租约并记录观察到的持有者
+func (e *LeaseElector) getLease(ctx context.Context) (*coordinationv1.Lease, error) {
+	lease, err := e.client.clientset.CoordinationV1().Leases(e.namespace).Get(ctx, e.name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			e.mu.Lock()
+			e.holder = ""
+			e.mu.Unlock()
+		}
+		return nil, err
+	}
+
+	holder := ""
+	if lease.Spec.HolderIdentity != nil {
+		holder = *lease.Spec.HolderIdentity
+	}
+	observed := holder
+	if lease.Spec.RenewTime != nil {
+		observed += "@" + lease.Spec.RenewTime.UTC().Format(time.RFC3339Nano)
+	}
+
+	e.mu.Lock()
+	e.holder = holder
+	if observed != e.observed {
+		e.observed = observed
+		e.observedTime = time.Now()
+	}
+	e.mu.Unlock()
+
+	return lease, nil
+}
+
+// tryAcquireOrRenew 租约不存在、已过期、已释放或由本实例持有时写入本实例为持有者
+// 并发写入由resourceVersion保证只有一个实例成功，返回本实例是否持有租约
+func (e *LeaseElector) tryAcquireOrRenew(ctx context.Context) (bool, error) {
+	leases := e.client.clientset.CoordinationV1().Leases(e.namespace)
+	now := metav1.NewMicroTime(time.Now())
+	durationSeconds := int32(e.duration.Seconds())
+
+	lease, err := e.getLease(ctx)
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: e.name, Namespace: e.namespace},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &e.identity,
+				LeaseDurationSeconds: &durationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		if _, err := leases.Create(ctx, lease, metav1.CreateOptions{}); err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to create lease: %v", err)
+		}
+		e.recordRenew()
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get lease: %v", err)
+	}
+
+	e.mu.Lock()
+	holder, observedTime := e.holder, e.observedTime
+	e.mu.Unlock()
+
+	if holder != e.identity {
+		// 以租约上记录的时长为准，持有者释放租约时将其设为1秒
+		duration := e.duration
+		if lease.Spec.LeaseDurationSeconds != nil {
+			duration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+		}
+		if holder != "" && time.Since(observedTime) < duration {
+			return false, nil
+		}
+		lease.Spec.AcquireTime = &now
+		transitions := int32(1)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions = *lease.Spec.LeaseTransitions + 1
+		}
+		lease.Spec.LeaseTransitions = &transitions
+	}
+	lease.Spec.HolderIdentity = &e.identity
+	lease.Spec.LeaseDurationSeconds = &durationSeconds
+	lease.Spec.RenewTime = &now
+
+	if _, err := leases.Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+		if apierrors.IsConflict(err) {
+			// 其他实例刚刚更新了租约
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to update lease: %v", err)
+	}
+	e.recordRenew()
+	return true, nil
+}
+
+// recordRenew 记录一次成功的获取或续约
+func (e *LeaseElector) recordRenew() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.holder = e.identity
+	e.lastRenew = time.Now()
+}
+
+// release 清空持有者并将租约时长设为1秒，使其他实例可以立即接管，本实例未持有租约时不做任何事
+func (e *LeaseElector) release() {
+	e.mu.Lock()
+	leading := e.leading
+	e.leading = false
+	e.mu.Unlock()
+	if !leading {
+		return
+	}
+
+	// 调用方的ctx已取消，使用独立的超时
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	leases := e.client.clientset.CoordinationV1().Leases(e.namespace)
+	lease, err := leases.Get(ctx, e.name, metav1.GetOptions{})
+	if err != nil {
+		fmt.Printf("Error releasing lease %s/%s: %v\n", e.namespace, e.name, err)
+		return
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != e.identity {
+		return
+	}
+
+	empty := ""
+	oneSecond := int32(1)
+	now := metav1.NewMicroTime(time.Now())
+	lease.Spec.HolderIdentity = &empty
+	lease.Spec.LeaseDurationSeconds = &oneSecond
+	lease.Spec.RenewTime = &now
+	if _, err := leases.Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+		fmt.Printf("Error releasing lease %s/%s: %v\n", e.namespace, e.name, err)
+	}
+}
@@ -0,0 +1,227 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const (
+	testLeaseNamespace = "kube-system"
+	testLeaseName      = "ioeye-active-worker-1"
+)
+
+// newTestElector 返回以identity参与选举、使用clientset的租约选举器
+func newTestElector(clientset *fake.Clientset, identity string, duration time.Duration) *LeaseElector {
+	return NewClientForClientset(clientset).NewLeaseElector(testLeaseNamespace, testLeaseName, identity, duration)
+}
+
+// getTestLease 读取测试使用的Lease
+func getTestLease(t *testing.T, clientset *fake.Clientset) *coordinationv1.Lease {
+	t.Helper()
+
+	lease, err := clientset.CoordinationV1().Leases(testLeaseNamespace).Get(context.Background(), testLeaseName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get lease: %v", err)
+	}
+	return lease
+}
+
+// leaseHeldBy 返回由holder持有、刚刚续约的Lease
+func leaseHeldBy(holder string, durationSeconds int32) *coordinationv1.Lease {
+	now := metav1.NewMicroTime(time.Now())
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: testLeaseName, Namespace: testLeaseNamespace},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &durationSeconds,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	}
+}
+
+func TestLeaseAcquireAndRenew(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	e := newTestElector(clientset, "a", 15*time.Second)
+	ctx := context.Background()
+
+	// Lease不存在时创建
+	if ok, err := e.tryAcquireOrRenew(ctx); !ok || err != nil {
+		t.Fatalf("tryAcquireOrRenew() on a missing lease = %v, %v, want acquired", ok, err)
+	}
+	lease := getTestLease(t, clientset)
+	if *lease.Spec.HolderIdentity != "a" || *lease.Spec.LeaseDurationSeconds != 15 {
+		t.Errorf("created lease holder %q duration %d, want a 15", *lease.Spec.HolderIdentity, *lease.Spec.LeaseDurationSeconds)
+	}
+	firstRenew := lease.Spec.RenewTime.Time
+
+	// 持有者续约只更新续约时间，不计为一次转移
+	time.Sleep(time.Millisecond)
+	if ok, err := e.tryAcquireOrRenew(ctx); !ok || err != nil {
+		t.Fatalf("tryAcquireOrRenew() by the holder = %v, %v, want renewed", ok, err)
+	}
+	lease = getTestLease(t, clientset)
+	if !lease.Spec.RenewTime.After(firstRenew) {
+		t.Errorf("renew time %v not advanced from %v", lease.Spec.RenewTime.Time, firstRenew)
+	}
+	if lease.Spec.LeaseTransitions != nil {
+		t.Errorf("lease transitions = %d after a renewal, want unset", *lease.Spec.LeaseTransitions)
+	}
+	if e.Holder() != "a" {
+		t.Errorf("Holder() = %q, want a", e.Holder())
+	}
+}
+
+func TestLeaseHeldByAnotherInstance(t *testing.T) {
+	clientset := fake.NewSimpleClientset(leaseHeldBy("b", 15))
+	e := newTestElector(clientset, "a", 15*time.Second)
+
+	// 持有者仍在续约期内，不接管
+	if ok, err := e.tryAcquireOrRenew(context.Background()); ok || err != nil {
+		t.Fatalf("tryAcquireOrRenew() on a held lease = %v, %v, want not acquired", ok, err)
+	}
+	if holder := *getTestLease(t, clientset).Spec.HolderIdentity; holder != "b" {
+		t.Errorf("lease holder = %q, want b unchanged", holder)
+	}
+	if e.Holder() != "b" {
+		t.Errorf("Holder() = %q, want b", e.Holder())
+	}
+}
+
+func TestLeaseTakeoverAfterExpiry(t *testing.T) {
+	clientset := fake.NewSimpleClientset(leaseHeldBy("b", 15))
+	e := newTestElector(clientset, "a", 15*time.Second)
+	ctx := context.Background()
+
+	if ok, _ := e.tryAcquireOrRenew(ctx); ok {
+		t.Fatal("took over a lease that is still being renewed")
+	}
+
+	// 模拟本地观察到租约内容已超过租约时长没有变化，不依赖租约上记录的时间
+	e.mu.Lock()
+	e.observedTime = time.Now().Add(-16 * time.Second)
+	e.mu.Unlock()
+
+	if ok, err := e.tryAcquireOrRenew(ctx); !ok || err != nil {
+		t.Fatalf("tryAcquireOrRenew() on an expired lease = %v, %v, want taken over", ok, err)
+	}
+	lease := getTestLease(t, clientset)
+	if *lease.Spec.HolderIdentity != "a" {
+		t.Errorf("lease holder = %q, want a", *lease.Spec.HolderIdentity)
+	}
+	if lease.Spec.LeaseTransitions == nil || *lease.Spec.LeaseTransitions != 1 {
+		t.Errorf("lease transitions = %v, want 1", lease.Spec.LeaseTransitions)
+	}
+}
+
+func TestLeaseTakeoverAfterRelease(t *testing.T) {
+	clientset := fake.NewSimpleClientset(leaseHeldBy("", 1))
+	e := newTestElector(clientset, "a", 15*time.Second)
+
+	// 已释放的租约没有持有者，立即接管
+	if ok, err := e.tryAcquireOrRenew(context.Background()); !ok || err != nil {
+		t.Fatalf("tryAcquireOrRenew() on a released lease = %v, %v, want acquired", ok, err)
+	}
+}
+
+func TestLeaseUpdateConflict(t *testing.T) {
+	clientset := fake.NewSimpleClientset(leaseHeldBy("", 1))
+	// 其他实例在读取和写入之间更新了租约
+	clientset.PrependReactor("update", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewConflict(schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"}, testLeaseName, nil)
+	})
+	e := newTestElector(clientset, "a", 15*time.Second)
+
+	if ok, err := e.tryAcquireOrRenew(context.Background()); ok || err != nil {
+		t.Errorf("tryAcquireOrRenew() on a conflict = %v, %v, want not acquired without error", ok, err)
+	}
+}
+
+func TestLeaseCreateRace(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	// 另一个实例先创建了租约
+	clientset.PrependReactor("create", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewAlreadyExists(schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"}, testLeaseName)
+	})
+	e := newTestElector(clientset, "a", 15*time.Second)
+
+	if ok, err := e.tryAcquireOrRenew(context.Background()); ok || err != nil {
+		t.Errorf("tryAcquireOrRenew() when the create races = %v, %v, want not acquired without error", ok, err)
+	}
+}
+
+func TestLeaseRunReleasesOnCancel(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	e := newTestElector(clientset, "a", 300*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-e.Acquired():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lease not acquired")
+	}
+	if !e.Leading() {
+		t.Error("Leading() = false after acquiring the lease")
+	}
+
+	cancel()
+	<-done
+
+	// 释放后其他实例无需等待租约过期
+	lease := getTestLease(t, clientset)
+	if *lease.Spec.HolderIdentity != "" || *lease.Spec.LeaseDurationSeconds != 1 {
+		t.Errorf("released lease holder %q duration %d, want empty holder and 1s", *lease.Spec.HolderIdentity, *lease.Spec.LeaseDurationSeconds)
+	}
+	if e.Leading() {
+		t.Error("Leading() = true after releasing the lease")
+	}
+	select {
+	case <-e.Lost():
+		t.Error("Lost closed by a voluntary release")
+	default:
+	}
+}
+
+func TestLeaseRunLosesToAnotherInstance(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	e := newTestElector(clientset, "a", 300*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Run(ctx)
+
+	select {
+	case <-e.Acquired():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lease not acquired")
+	}
+
+	// 其他实例接管了租约
+	if _, err := clientset.CoordinationV1().Leases(testLeaseNamespace).Update(context.Background(), leaseHeldBy("b", 15), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-e.Lost():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Lost not closed after another instance took over the lease")
+	}
+	if e.Leading() {
+		t.Error("Leading() = true after losing the lease")
+	}
+}
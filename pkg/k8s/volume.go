@@ -0,0 +1,70 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VolumeInfo 描述Pod使用的PVC及其绑定的PV
+type VolumeInfo struct {
+	PVCName      string
+	PVName       string // PVC尚未绑定时为空
+	StorageClass string
+	CSIDriver    string // 非CSI卷（如in-tree插件或hostPath）为空
+}
+
+// Bound 返回PVC是否已绑定到PV
+func (v VolumeInfo) Bound() bool {
+	return v.PVName != ""
+}
+
+// GetPVForPVC 获取PVC绑定的PV信息，PVC尚未绑定时只返回PVC上声明的存储类
+func (c *Client) GetPVForPVC(ctx context.Context, namespace, pvcName string) (*VolumeInfo, error) {
+	pvc, err := c.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, pvcName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PVC %s/%s: %v", namespace, pvcName, err)
+	}
+
+	info := &VolumeInfo{PVCName: pvcName}
+	if pvc.Spec.StorageClassName != nil {
+		info.StorageClass = *pvc.Spec.StorageClassName
+	}
+
+	if pvc.Spec.VolumeName == "" {
+		return info, nil
+	}
+
+	pv, err := c.clientset.CoreV1().PersistentVolumes().Get(ctx, pvc.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PV %s for PVC %s/%s: %v", pvc.Spec.VolumeName, namespace, pvcName, err)
+	}
+
+	info.PVName = pv.Name
+	// 以PV上的存储类为准，静态创建的PV可能与PVC声明不同
+	if pv.Spec.StorageClassName != "" {
+		info.StorageClass = pv.Spec.StorageClassName
+	}
+	if pv.Spec.CSI != nil {
+		info.CSIDriver = pv.Spec.CSI.Driver
+	}
+
+	return info, nil
+}
+
+// podPVCs 返回Pod规格中引用的PVC名称
+// 通用临时卷的PVC由Kubernetes以"<Pod名称>-<卷名称>"创建，同样计入
+func podPVCs(pod *corev1.Pod) []string {
+	var pvcs []string
+	for _, volume := range pod.Spec.Volumes {
+		switch {
+		case volume.PersistentVolumeClaim != nil:
+			pvcs = append(pvcs, volume.PersistentVolumeClaim.ClaimName)
+		case volume.Ephemeral != nil:
+			pvcs = append(pvcs, pod.Name+"-"+volume.Name)
+		}
+	}
+	return pvcs
+}
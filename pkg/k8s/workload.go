@@ -0,0 +1,44 @@
+package k8s
+
+import (
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// 工作负载类型
+const (
+	WorkloadKindDeployment  = "Deployment"
+	WorkloadKindStatefulSet = "StatefulSet"
+	WorkloadKindDaemonSet   = "DaemonSet"
+	WorkloadKindReplicaSet  = "ReplicaSet"
+	// WorkloadKindStandalone 没有控制器的裸Pod
+	WorkloadKindStandalone = "standalone"
+)
+
+// WorkloadRef 标识Pod所属的工作负载
+type WorkloadRef struct {
+	Kind string
+	Name string
+}
+
+// podWorkload 根据Pod的控制器owner reference确定其所属工作负载
+// ReplicaSet名称由Deployment名称加pod-template-hash构成，据此还原Deployment而无需额外请求API Server
+// 没有控制器的Pod归入standalone
+func podWorkload(pod *corev1.Pod) WorkloadRef {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return WorkloadRef{Kind: WorkloadKindStandalone}
+	}
+
+	if owner.Kind == WorkloadKindReplicaSet {
+		hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+		if name, ok := strings.CutSuffix(owner.Name, "-"+hash); ok && hash != "" {
+			return WorkloadRef{Kind: WorkloadKindDeployment, Name: name}
+		}
+	}
+
+	return WorkloadRef{Kind: owner.Kind, Name: owner.Name}
+}
@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+)
+
+// AggregateMetrics 一组Pod的聚合存储性能指标
+// IOPS和吞吐量为各Pod之和，延迟为按操作次数加权的平均值
+type AggregateMetrics struct {
+	Pods            []string
+	ReadLatency     uint64 // 纳秒
+	WriteLatency    uint64 // 纳秒
+	ReadIOPS        uint64
+	WriteIOPS       uint64
+	ReadThroughput  uint64 // 字节/秒
+	WriteThroughput uint64 // 字节/秒
+	QueueLatency    uint64 // 纳秒
+	DiskLatency     uint64 // 纳秒
+	NetworkLatency  uint64 // 纳秒
+	Timestamp       time.Time
+}
+
+// aggregatePods 合并一组Pod的指标，Pods按名称排序
+func aggregatePods(pods []PodStorageMetrics) AggregateMetrics {
+	sort.Slice(pods, func(i, j int) bool { return pods[i].PodName < pods[j].PodName })
+
+	agg := AggregateMetrics{Pods: make([]string, 0, len(pods))}
+
+	var readLatency, writeLatency, queueLatency, diskLatency, networkLatency weightedMean
+	for _, pod := range pods {
+		agg.Pods = append(agg.Pods, pod.PodName)
+		agg.ReadIOPS += pod.ReadIOPS
+		agg.WriteIOPS += pod.WriteIOPS
+		agg.ReadThroughput += pod.ReadThroughput
+		agg.WriteThroughput += pod.WriteThroughput
+		if pod.Timestamp.After(agg.Timestamp) {
+			agg.Timestamp = pod.Timestamp
+		}
+
+		readLatency.add(pod.ReadLatency, pod.ReadIOPS)
+		writeLatency.add(pod.WriteLatency, pod.WriteIOPS)
+		totalIOPS := pod.ReadIOPS + pod.WriteIOPS
+		queueLatency.add(pod.QueueLatency, totalIOPS)
+		diskLatency.add(pod.DiskLatency, totalIOPS)
+		networkLatency.add(pod.NetworkLatency, totalIOPS)
+	}
+
+	agg.ReadLatency = readLatency.value()
+	agg.WriteLatency = writeLatency.value()
+	agg.QueueLatency = queueLatency.value()
+	agg.DiskLatency = diskLatency.value()
+	agg.NetworkLatency = networkLatency.value()
+
+	return agg
+}
+
+// weightedMean 按权重累计平均值，所有权重为0时退化为算术平均
+type weightedMean struct {
+	weightedSum float64
+	weight      float64
+	sum         float64
+	count       int
+}
+
+// add 累加一个取值及其权重
+func (m *weightedMean) add(v, weight uint64) {
+	m.weightedSum += float64(v) * float64(weight)
+	m.weight += float64(weight)
+	m.sum += float64(v)
+	m.count++
+}
+
+// value 返回当前平均值
+func (m *weightedMean) value() uint64 {
+	if m.weight > 0 {
+		return uint64(m.weightedSum / m.weight)
+	}
+	if m.count == 0 {
+		return 0
+	}
+	return uint64(m.sum / float64(m.count))
+}
@@ -0,0 +1,111 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+)
+
+// WithRemoteClusters 同时监控其他集群中的Pod，每个客户端对应一个集群，集群名称取自k8s.Client.Cluster
+// eBPF程序只跟踪本节点的I/O，这些集群中的Pod只有命名空间、工作负载、节点和卷等拓扑信息，
+// 没有I/O指标；不同集群中同名的Pod会相互覆盖，需保证Pod名称在所有集群中唯一
+func WithRemoteClusters(clients ...*k8s.Client) StorageMonitorOption {
+	return func(sm *StorageMonitor) {
+		sm.remoteClusters = clients
+	}
+}
+
+// clusterClients 返回本集群和所有远程集群的客户端
+func (sm *StorageMonitor) clusterClients() []*k8s.Client {
+	return append([]*k8s.Client{sm.k8sClient}, sm.remoteClusters...)
+}
+
+// clientFor 返回集群对应的客户端，未知的集群返回本集群的客户端
+func (sm *StorageMonitor) clientFor(cluster string) *k8s.Client {
+	for _, client := range sm.remoteClusters {
+		if client.Cluster() == cluster {
+			return client
+		}
+	}
+	return sm.k8sClient
+}
+
+// isLocal 判断Pod是否属于运行eBPF程序的本集群
+func (sm *StorageMonitor) isLocal(pod k8s.PodRef) bool {
+	return pod.Cluster == sm.k8sClient.Cluster()
+}
+
+// listPods 列出所有集群中的Pod，同时返回列出失败的远程集群
+// 本集群列出失败时返回错误；远程集群不可达时只记录错误并跳过，不影响其他集群的采集
+func (sm *StorageMonitor) listPods(ctx context.Context) ([]k8s.PodRef, map[string]bool, error) {
+	opts := metav1.ListOptions{LabelSelector: sm.labelSelector}
+	pods, err := sm.k8sClient.ListPods(ctx, sm.namespaces, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unreachable := make(map[string]bool)
+	for _, client := range sm.remoteClusters {
+		remotePods, err := client.ListPods(ctx, sm.namespaces, opts)
+		if err != nil {
+			fmt.Printf("Error listing pods in cluster %s: %v\n", client.Cluster(), err)
+			sm.collectionStats.recordError(CollectStageListPods)
+			unreachable[client.Cluster()] = true
+			continue
+		}
+		pods = append(pods, remotePods...)
+	}
+	return pods, unreachable, nil
+}
+
+// localPods 返回属于本集群的Pod，只有这些Pod的cgroup在本节点上
+func (sm *StorageMonitor) localPods(pods []k8s.PodRef) []k8s.PodRef {
+	if len(sm.remoteClusters) == 0 {
+		return pods
+	}
+
+	local := make([]k8s.PodRef, 0, len(pods))
+	for _, pod := range pods {
+		if sm.isLocal(pod) {
+			local = append(local, pod)
+		}
+	}
+	return local
+}
+
+// ClusterHealth 集群API server的连接状态
+type ClusterHealth struct {
+	Cluster     string    // 集群名称，单集群时为空
+	Err         error     // API server不可达的原因，连接正常时为nil
+	LastPodList time.Time // 上次成功列出Pod的时间，尚未成功列出时为零值
+}
+
+// CheckClusters 并发检查本集群和所有远程集群的API server连接，第一个元素为本集群，没有Kubernetes客户端时返回nil
+// 一个集群超时不会占用其他集群的检查时间
+func (sm *StorageMonitor) CheckClusters(ctx context.Context) []ClusterHealth {
+	if sm.k8sClient == nil {
+		return nil
+	}
+
+	clients := sm.clusterClients()
+	result := make([]ClusterHealth, len(clients))
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(i int, client *k8s.Client) {
+			defer wg.Done()
+			result[i] = ClusterHealth{
+				Cluster:     client.Cluster(),
+				Err:         client.Ping(ctx),
+				LastPodList: client.LastListTime(),
+			}
+		}(i, client)
+	}
+	wg.Wait()
+	return result
+}
@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// 采集的各个阶段，采集失败时按阶段计数
+const (
+	CollectStageListPods   = "list_pods"
+	CollectStageIOStats    = "iostats"
+	CollectStageIOPS       = "iops"
+	CollectStageThroughput = "throughput"
+	CollectStageCgroups    = "cgroups"
+)
+
+// CollectStages 所有采集阶段，按执行顺序排列
+var CollectStages = []string{
+	CollectStageListPods,
+	CollectStageIOStats,
+	CollectStageIOPS,
+	CollectStageThroughput,
+	CollectStageCgroups,
+}
+
+// CollectionDurationBuckets 采集耗时直方图各桶的上界（秒），另有一个+Inf桶
+var CollectionDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// CollectionStats IOEye自身采集过程的统计，自启动以来累计
+type CollectionStats struct {
+	DurationCounts []uint64          // 落在CollectionDurationBuckets各桶中的采集次数（非累计），最后一个元素为+Inf桶
+	DurationSum    float64           // 所有采集耗时之和（秒）
+	DurationCount  uint64            // 采集次数，包括失败的采集
+	Errors         map[string]uint64 // 各阶段的失败次数，包含所有阶段
+	LastPodsCount  int               // 最近一次成功采集处理的Pod数
+}
+
+// collectionStats 记录采集耗时和失败次数
+type collectionStats struct {
+	mu             sync.Mutex
+	durationCounts []uint64
+	durationSum    float64
+	durationCount  uint64
+	errors         map[string]uint64
+	lastPodsCount  int
+}
+
+// newCollectionStats 创建空的采集统计
+func newCollectionStats() *collectionStats {
+	return &collectionStats{
+		durationCounts: make([]uint64, len(CollectionDurationBuckets)+1),
+		errors:         make(map[string]uint64),
+	}
+}
+
+// observe 记录一次采集的耗时，podsCount小于0表示采集失败
+func (s *collectionStats) observe(duration time.Duration, podsCount int) {
+	seconds := duration.Seconds()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket := len(CollectionDurationBuckets)
+	for i, bound := range CollectionDurationBuckets {
+		if seconds <= bound {
+			bucket = i
+			break
+		}
+	}
+	s.durationCounts[bucket]++
+	s.durationSum += seconds
+	s.durationCount++
+	if podsCount >= 0 {
+		s.lastPodsCount = podsCount
+	}
+}
+
+// recordError 记录采集在stage阶段失败
+func (s *collectionStats) recordError(stage string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors[stage]++
+}
+
+// GetCollectionStats 返回自启动以来的采集耗时、失败次数和最近一次处理的Pod数
+func (sm *StorageMonitor) GetCollectionStats() CollectionStats {
+	s := sm.collectionStats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	errors := make(map[string]uint64, len(CollectStages))
+	for _, stage := range CollectStages {
+		errors[stage] = s.errors[stage]
+	}
+
+	return CollectionStats{
+		DurationCounts: append([]uint64(nil), s.durationCounts...),
+		DurationSum:    s.durationSum,
+		DurationCount:  s.durationCount,
+		Errors:         errors,
+		LastPodsCount:  s.lastPodsCount,
+	}
+}
+
+// collectFailed 记录采集在stage阶段失败并原样返回err
+func (sm *StorageMonitor) collectFailed(stage string, err error) error {
+	sm.collectionStats.recordError(stage)
+	return err
+}
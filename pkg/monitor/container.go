@@ -0,0 +1,116 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/lizhongxuan/ioeye/pkg/ebpf"
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+)
+
+// ContainerStorageMetrics Pod中单个容器的存储性能指标
+type ContainerStorageMetrics struct {
+	ContainerName   string
+	ReadLatency     uint64 // 纳秒
+	WriteLatency    uint64 // 纳秒
+	ReadIOPS        uint64
+	WriteIOPS       uint64
+	ReadThroughput  uint64 // 字节/秒
+	WriteThroughput uint64 // 字节/秒
+	QueueLatency    uint64 // 纳秒，取读写队列延迟中较高者
+	DiskLatency     uint64 // 纳秒，取读写磁盘延迟中较高者
+	NetworkLatency  uint64 // 纳秒
+}
+
+// GetContainerMetrics 获取Pod中各容器的存储指标，按容器名称排序，id为Pod UID或名称，namespace为空时不校验命名空间
+// 无法按容器归属I/O（如使用模拟数据）时返回空切片，此时只有Pod级指标
+func (sm *StorageMonitor) GetContainerMetrics(namespace, id string) ([]*ContainerStorageMetrics, error) {
+	sm.metricsMutex.RLock()
+	defer sm.metricsMutex.RUnlock()
+
+	key, err := sm.resolvePodKey(id)
+	if err != nil {
+		return nil, err
+	}
+	metrics := sm.metrics[key]
+	if namespace != "" && metrics.Namespace != namespace {
+		return nil, fmt.Errorf("no metrics found for pod %s", id)
+	}
+
+	result := make([]*ContainerStorageMetrics, 0, len(metrics.Containers))
+	for _, container := range metrics.Containers {
+		containerCopy := *container
+		result = append(result, &containerCopy)
+	}
+	return result, nil
+}
+
+// resolveContainerKeys 将以cgroup ID为key的数据按podKey和容器名称分组
+// 同一容器的多个cgroup通过merge合并，Pod级cgroup和无法解析的cgroup被丢弃
+func resolveContainerKeys[V any](resolver *k8s.CgroupResolver, data map[string]V, merge func(a, b V) V) map[string]map[string]V {
+	result := make(map[string]map[string]V)
+	for key, value := range data {
+		cgroupID, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			continue
+		}
+		pod, container, ok := resolver.ResolveContainer(cgroupID)
+		if !ok || container == "" {
+			continue
+		}
+		containers, ok := result[podKey(pod)]
+		if !ok {
+			containers = make(map[string]V)
+			result[podKey(pod)] = containers
+		}
+		if existing, ok := containers[container]; ok {
+			value = merge(existing, value)
+		}
+		containers[container] = value
+	}
+	return result
+}
+
+// buildContainerMetrics 生成一个Pod中各容器的指标，按容器名称排序，没有容器数据时返回nil
+func buildContainerMetrics(ioStats map[string]*ebpf.IOStatsData, iops, throughput map[string]map[string]uint64) []*ContainerStorageMetrics {
+	names := make(map[string]bool)
+	for name := range ioStats {
+		names[name] = true
+	}
+	for name := range iops {
+		names[name] = true
+	}
+	for name := range throughput {
+		names[name] = true
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	containers := make([]*ContainerStorageMetrics, 0, len(names))
+	for name := range names {
+		container := &ContainerStorageMetrics{ContainerName: name}
+		if stats, ok := ioStats[name]; ok {
+			container.ReadLatency = stats.ReadLatencyNs
+			container.WriteLatency = stats.WriteLatencyNs
+			container.QueueLatency = max(stats.ReadQueueLatencyNs, stats.WriteQueueLatencyNs)
+			container.DiskLatency = max(stats.ReadDiskLatencyNs, stats.WriteDiskLatencyNs)
+			container.NetworkLatency = stats.NetworkLatencyNs
+		}
+		if counters, ok := iops[name]; ok {
+			container.ReadIOPS = counters["read_iops"]
+			container.WriteIOPS = counters["write_iops"]
+		}
+		if counters, ok := throughput[name]; ok {
+			container.ReadThroughput = counters["read_throughput_bps"]
+			container.WriteThroughput = counters["write_throughput_bps"]
+		}
+		containers = append(containers, container)
+	}
+
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].ContainerName < containers[j].ContainerName
+	})
+	return containers
+}
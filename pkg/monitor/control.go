@@ -0,0 +1,45 @@
+package monitor
+
+import "time"
+
+// PauseStatus 监控的暂停状态
+type PauseStatus struct {
+	Paused   bool
+	PausedAt time.Time // 未暂停时为零值
+}
+
+// Pause 暂停采集，暂停期间采集goroutine跳过每次tick，不再请求API Server和读取eBPF数据
+// 已采集的指标保留并继续对外提供，进行中的采集会正常完成。重复调用不会改变暂停时间
+func (sm *StorageMonitor) Pause() PauseStatus {
+	sm.pauseMutex.Lock()
+	defer sm.pauseMutex.Unlock()
+
+	if !sm.paused {
+		sm.paused = true
+		sm.pausedAt = time.Now()
+	}
+	return PauseStatus{Paused: sm.paused, PausedAt: sm.pausedAt}
+}
+
+// Resume 恢复采集，从下一次tick开始重新采集
+func (sm *StorageMonitor) Resume() PauseStatus {
+	sm.pauseMutex.Lock()
+	defer sm.pauseMutex.Unlock()
+
+	sm.paused = false
+	sm.pausedAt = time.Time{}
+	return PauseStatus{}
+}
+
+// GetPauseStatus 获取监控的暂停状态
+func (sm *StorageMonitor) GetPauseStatus() PauseStatus {
+	sm.pauseMutex.Lock()
+	defer sm.pauseMutex.Unlock()
+
+	return PauseStatus{Paused: sm.paused, PausedAt: sm.pausedAt}
+}
+
+// Paused 返回监控是否处于暂停状态
+func (sm *StorageMonitor) Paused() bool {
+	return sm.GetPauseStatus().Paused
+}
@@ -0,0 +1,58 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DeviceMetrics 单个块设备的存储性能指标，用于发现被多个Pod共享的故障磁盘
+type DeviceMetrics struct {
+	Device          string // "major:minor"
+	Major           uint32
+	Minor           uint32
+	Name            string // 设备名，如sda、nvme0n1，无法识别时为空
+	ReadIOPS        uint64
+	WriteIOPS       uint64
+	ReadThroughput  uint64  // 字节/秒
+	WriteThroughput uint64  // 字节/秒
+	QueueLatency    uint64  // 纳秒
+	DiskLatency     uint64  // 纳秒
+	Utilization     float64 // 设备有I/O在处理的时间比例（0-1）
+	Timestamp       time.Time
+}
+
+// GetDeviceMetrics 获取最近一个统计窗口内各块设备的指标，按主次设备号排序
+func (sm *StorageMonitor) GetDeviceMetrics() ([]*DeviceMetrics, error) {
+	deviceStats, err := sm.bpfMonitor.GetDeviceStats()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device stats: %v", err)
+	}
+
+	devices := make([]*DeviceMetrics, 0, len(deviceStats))
+	for key, stats := range deviceStats {
+		devices = append(devices, &DeviceMetrics{
+			Device:          key,
+			Major:           stats.Major,
+			Minor:           stats.Minor,
+			Name:            stats.Name,
+			ReadIOPS:        stats.ReadIOPS,
+			WriteIOPS:       stats.WriteIOPS,
+			ReadThroughput:  stats.ReadThroughput,
+			WriteThroughput: stats.WriteThroughput,
+			QueueLatency:    stats.QueueLatencyNs,
+			DiskLatency:     stats.DiskLatencyNs,
+			Utilization:     stats.Utilization,
+			Timestamp:       stats.LastUpdateTime,
+		})
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].Major != devices[j].Major {
+			return devices[i].Major < devices[j].Major
+		}
+		return devices[i].Minor < devices[j].Minor
+	})
+
+	return devices, nil
+}
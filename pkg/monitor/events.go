@@ -0,0 +1,20 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+)
+
+// GetStorageEvents 返回since之后与Pod及其所在节点相关的存储事件，按时间排序
+// 例如挂载失败、卷扩容失败和节点磁盘压力，用于解释Pod存储性能的变化，id为Pod UID或名称
+func (sm *StorageMonitor) GetStorageEvents(id string, since time.Time) ([]k8s.StorageEvent, error) {
+	metrics, err := sm.GetPodMetrics(id)
+	if err != nil {
+		return nil, err
+	}
+	if sm.k8sClient == nil {
+		return nil, nil
+	}
+	return sm.clientFor(metrics.Cluster).StorageEvents(metrics.Namespace, metrics.PodName, metrics.NodeName, since), nil
+}
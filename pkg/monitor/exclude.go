@@ -0,0 +1,62 @@
+package monitor
+
+import (
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+)
+
+// WithExcludeNamespaces 设置不监控的命名空间，优先于WithNamespaces
+func WithExcludeNamespaces(namespaces []string) StorageMonitorOption {
+	return func(sm *StorageMonitor) {
+		sm.excludeNamespaces = namespaces
+	}
+}
+
+// WithExcludePods 设置不监控的Pod名称，支持path.Match的通配符，例如"ioeye-*"
+func WithExcludePods(patterns []string) StorageMonitorOption {
+	return func(sm *StorageMonitor) {
+		sm.excludePods = patterns
+	}
+}
+
+// ValidateExcludePatterns 校验Pod排除模式的语法
+func ValidateExcludePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pod exclude pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// isExcluded 判断Pod是否被排除
+func (sm *StorageMonitor) isExcluded(pod k8s.PodRef) bool {
+	if slices.Contains(sm.excludeNamespaces, pod.Namespace) {
+		return true
+	}
+	for _, pattern := range sm.excludePods {
+		// 模式已在Start时校验，这里忽略错误
+		if matched, _ := path.Match(pattern, pod.Name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// filterExcluded 移除被排除的Pod，被排除的Pod不会被采集，也不会出现在分析器的历史中
+func (sm *StorageMonitor) filterExcluded(pods []k8s.PodRef) []k8s.PodRef {
+	if len(sm.excludeNamespaces) == 0 && len(sm.excludePods) == 0 {
+		return pods
+	}
+
+	filtered := pods[:0]
+	for _, pod := range pods {
+		if !sm.isExcluded(pod) {
+			filtered = append(filtered, pod)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+)
+
+func TestIsExcluded(t *testing.T) {
+	sm := NewStorageMonitor(nil, nil,
+		WithExcludeNamespaces([]string{"kube-system"}),
+		WithExcludePods([]string{"etcd-0", "ioeye-*", "db-?"}),
+	)
+
+	tests := []struct {
+		name string
+		pod  k8s.PodRef
+		want bool
+	}{
+		{"excluded namespace", k8s.PodRef{Namespace: "kube-system", Name: "coredns"}, true},
+		{"namespace is not a prefix match", k8s.PodRef{Namespace: "kube-system-extra", Name: "coredns"}, false},
+		{"exact name", k8s.PodRef{Namespace: "default", Name: "etcd-0"}, true},
+		{"exact name does not match a longer name", k8s.PodRef{Namespace: "default", Name: "etcd-01"}, false},
+		{"glob star", k8s.PodRef{Namespace: "default", Name: "ioeye-agent-x7k2p"}, true},
+		{"glob star matches an empty suffix", k8s.PodRef{Namespace: "default", Name: "ioeye-"}, true},
+		{"glob is anchored", k8s.PodRef{Namespace: "default", Name: "my-ioeye-agent"}, false},
+		{"glob question mark", k8s.PodRef{Namespace: "default", Name: "db-1"}, true},
+		{"glob question mark matches one character", k8s.PodRef{Namespace: "default", Name: "db-10"}, false},
+		{"not excluded", k8s.PodRef{Namespace: "default", Name: "web-0"}, false},
+	}
+	for _, tt := range tests {
+		if got := sm.isExcluded(tt.pod); got != tt.want {
+			t.Errorf("%s: isExcluded(%s/%s) = %v, want %v", tt.name, tt.pod.Namespace, tt.pod.Name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterExcluded(t *testing.T) {
+	pods := []k8s.PodRef{
+		{Namespace: "default", Name: "web-0"},
+		{Namespace: "kube-system", Name: "coredns"},
+		{Namespace: "default", Name: "ioeye-agent"},
+		{Namespace: "default", Name: "db-0"},
+	}
+
+	// 没有排除规则时原样返回
+	sm := NewStorageMonitor(nil, nil)
+	if got := sm.filterExcluded(pods); len(got) != len(pods) {
+		t.Errorf("filterExcluded() without rules returned %d pods, want %d", len(got), len(pods))
+	}
+
+	sm = NewStorageMonitor(nil, nil,
+		WithExcludeNamespaces([]string{"kube-system"}),
+		WithExcludePods([]string{"ioeye-*"}),
+	)
+	var names []string
+	for _, pod := range sm.filterExcluded(pods) {
+		names = append(names, pod.Name)
+	}
+	if want := []string{"web-0", "db-0"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("filterExcluded() = %v, want %v", names, want)
+	}
+}
+
+func TestValidateExcludePatterns(t *testing.T) {
+	if err := ValidateExcludePatterns([]string{"etcd-0", "ioeye-*", "db-[0-9]"}); err != nil {
+		t.Errorf("ValidateExcludePatterns() error = %v", err)
+	}
+	if err := ValidateExcludePatterns([]string{"ioeye-*", "db-[0-9"}); err == nil {
+		t.Error("ValidateExcludePatterns() accepted an unterminated character class")
+	}
+}
@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"sort"
+
+	"github.com/lizhongxuan/ioeye/pkg/ebpf"
+)
+
+// FileSystemMetrics Pod在单个文件系统类型上的VFS读写，次数和字节数为最近一个统计窗口内的累计值
+type FileSystemMetrics struct {
+	Type         string // 文件系统类型，例如ext4、overlay，未知类型为十六进制的超级块magic
+	Ephemeral    bool   // 是否为overlay、tmpfs等数据不持久化的文件系统
+	ReadOps      uint64
+	WriteOps     uint64
+	ReadBytes    uint64
+	WriteBytes   uint64
+	ReadLatency  uint64 // 纳秒
+	WriteLatency uint64 // 纳秒
+}
+
+// EphemeralRatio 返回VFS读写中落在不持久化文件系统上的比例（0-1），没有VFS读写时为0
+// 比例较高说明Pod的I/O主要是容器可写层、tmpfs等临时数据，而不是持久卷
+func (m *PodStorageMetrics) EphemeralRatio() float64 {
+	var ephemeral, total uint64
+	for _, fs := range m.FileSystems {
+		ops := fs.ReadOps + fs.WriteOps
+		total += ops
+		if fs.Ephemeral {
+			ephemeral += ops
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(ephemeral) / float64(total)
+}
+
+// buildFileSystemMetrics 将eBPF按文件系统类型的统计转换为按类型排序的列表，没有数据时返回nil
+func buildFileSystemMetrics(stats map[string]ebpf.FSTypeStats) []FileSystemMetrics {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	result := make([]FileSystemMetrics, 0, len(stats))
+	for name, s := range stats {
+		result = append(result, FileSystemMetrics{
+			Type:         name,
+			Ephemeral:    s.Ephemeral,
+			ReadOps:      s.ReadOps,
+			WriteOps:     s.WriteOps,
+			ReadBytes:    s.ReadBytes,
+			WriteBytes:   s.WriteBytes,
+			ReadLatency:  s.ReadLatencyNs,
+			WriteLatency: s.WriteLatencyNs,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Type < result[j].Type })
+	return result
+}
+
+// mergeFSStats 合并两个cgroup按文件系统类型的统计，延迟按操作次数加权平均
+func mergeFSStats(a, b map[string]ebpf.FSTypeStats) map[string]ebpf.FSTypeStats {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]ebpf.FSTypeStats, len(a)+len(b))
+	for name, s := range a {
+		merged[name] = s
+	}
+	for name, y := range b {
+		x, ok := merged[name]
+		if !ok {
+			merged[name] = y
+			continue
+		}
+		s := ebpf.FSTypeStats{
+			Ephemeral:  x.Ephemeral,
+			ReadOps:    x.ReadOps + y.ReadOps,
+			WriteOps:   x.WriteOps + y.WriteOps,
+			ReadBytes:  x.ReadBytes + y.ReadBytes,
+			WriteBytes: x.WriteBytes + y.WriteBytes,
+		}
+		if s.ReadOps > 0 {
+			s.ReadLatencyNs = (x.ReadLatencyNs*x.ReadOps + y.ReadLatencyNs*y.ReadOps) / s.ReadOps
+		}
+		if s.WriteOps > 0 {
+			s.WriteLatencyNs = (x.WriteLatencyNs*x.WriteOps + y.WriteLatencyNs*y.WriteOps) / s.WriteOps
+		}
+		merged[name] = s
+	}
+	return merged
+}
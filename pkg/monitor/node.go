@@ -0,0 +1,55 @@
+package monitor
+
+import "sort"
+
+// DefaultSaturationQueueLatency 默认的节点饱和队列延迟阈值（纳秒）
+const DefaultSaturationQueueLatency = 5 * 1000 * 1000 // 5ms
+
+// NodeMetrics 同一节点上所有Pod的聚合存储性能指标
+type NodeMetrics struct {
+	Cluster  string // 单集群时为空
+	NodeName string
+	// Saturated 聚合队列延迟超过阈值，说明I/O请求在设备队列中大量堆积，设备本身可能已饱和
+	Saturated bool
+	AggregateMetrics
+}
+
+// nodeKey 标识集群中的一个节点
+type nodeKey struct {
+	cluster string
+	name    string
+}
+
+// GetNodeMetrics 按所在节点聚合Pod的指标，忽略尚未调度的Pod，结果按集群和节点名称排序
+func (sm *StorageMonitor) GetNodeMetrics() []*NodeMetrics {
+	sm.metricsMutex.RLock()
+	groups := make(map[nodeKey][]PodStorageMetrics)
+	for _, metrics := range sm.metrics {
+		if metrics.NodeName == "" {
+			continue
+		}
+		key := nodeKey{cluster: metrics.Cluster, name: metrics.NodeName}
+		groups[key] = append(groups[key], *metrics)
+	}
+	sm.metricsMutex.RUnlock()
+
+	nodes := make([]*NodeMetrics, 0, len(groups))
+	for key, pods := range groups {
+		agg := aggregatePods(pods)
+		nodes = append(nodes, &NodeMetrics{
+			Cluster:          key.cluster,
+			NodeName:         key.name,
+			Saturated:        agg.QueueLatency >= sm.nodeSaturation,
+			AggregateMetrics: agg,
+		})
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Cluster != nodes[j].Cluster {
+			return nodes[i].Cluster < nodes[j].Cluster
+		}
+		return nodes[i].NodeName < nodes[j].NodeName
+	})
+
+	return nodes
+}
@@ -0,0 +1,70 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+)
+
+// podKey 返回Pod在内部映射中的key，优先使用UID
+// 同名Pod删除后重建时UID不同，新旧Pod的指标和历史不会混在一起；模拟数据等没有UID时使用Pod名称
+func podKey(pod k8s.PodRef) string {
+	if pod.UID != "" {
+		return pod.UID
+	}
+	return pod.Name
+}
+
+// Key 返回指标在GetAllMetrics结果和分析器中使用的key，与Pod的UID一致，没有UID时为Pod名称
+func (m *PodStorageMetrics) Key() string {
+	if m.PodUID != "" {
+		return m.PodUID
+	}
+	return m.PodName
+}
+
+// ResolvePodKey 将Pod UID或名称解析为GetAllMetrics结果和分析器中使用的key
+// 先按key匹配，再按名称匹配；多个命名空间或集群中有同名Pod时返回错误，需改用UID查询
+func (sm *StorageMonitor) ResolvePodKey(id string) (string, error) {
+	sm.metricsMutex.RLock()
+	defer sm.metricsMutex.RUnlock()
+
+	return sm.resolvePodKey(id)
+}
+
+// resolvePodKey 同ResolvePodKey，调用方需持有metricsMutex
+func (sm *StorageMonitor) resolvePodKey(id string) (string, error) {
+	if _, ok := sm.metrics[id]; ok {
+		return id, nil
+	}
+
+	var matches []string
+	for key, metrics := range sm.metrics {
+		if metrics.PodName == id {
+			matches = append(matches, key)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no metrics found for pod %s", id)
+	case 1:
+		return matches[0], nil
+	default:
+		sort.Strings(matches)
+		return "", fmt.Errorf("pod name %s is ambiguous, use one of the pod UIDs %s", id, strings.Join(matches, ", "))
+	}
+}
+
+// rekeyByName 将以Pod名称为key的数据转换为以podKey为key，不属于pods的数据被丢弃
+// 用于没有cgroup映射、eBPF数据直接以Pod名称为key的模拟数据模式
+func rekeyByName[V any](data map[string]V, pods []k8s.PodRef) map[string]V {
+	result := make(map[string]V, len(data))
+	for _, pod := range pods {
+		if value, ok := data[pod.Name]; ok {
+			result[podKey(pod)] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,197 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/ebpf"
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+)
+
+// RateMode 表示GetPodRates的计算方式
+type RateMode string
+
+const (
+	// RateModeInstant 最近两个原始样本之间的速率，反映最新的变化，类似PromQL的irate
+	RateModeInstant RateMode = "instantaneous"
+	// RateModeAverage 速率窗口内最早和最新样本之间的平均速率，更平滑，类似PromQL的rate
+	RateModeAverage RateMode = "interval-averaged"
+)
+
+// DefaultRateWindow interval-averaged速率的默认窗口长度
+const DefaultRateWindow = time.Minute
+
+// Valid 判断是否为支持的计算方式
+func (m RateMode) Valid() bool {
+	return m == RateModeInstant || m == RateModeAverage
+}
+
+// PodRates Pod按原始样本时间差计算的每秒I/O速率
+type PodRates struct {
+	PodName         string
+	Mode            RateMode
+	ReadIOPS        float64
+	WriteIOPS       float64
+	ReadThroughput  float64       // 字节/秒
+	WriteThroughput float64       // 字节/秒
+	Window          time.Duration // 计算速率的两个样本之间的时间差
+	Timestamp       time.Time     // 最新样本的原始采集时间
+}
+
+// rateCounters Pod的累计I/O计数
+type rateCounters struct {
+	readOps, writeOps, readBytes, writeBytes uint64
+}
+
+// rateSample 某个原始采集时间的Pod累计计数
+type rateSample struct {
+	counters rateCounters
+	time     time.Time
+}
+
+// rateHistory 按原始采集时间保存各Pod的累计计数样本
+// 内核侧的累计计数按cgroup保存，窗口内没有I/O的cgroup会被清理后重新计数，不能直接按Pod相加；
+// 这里按cgroup计算两次原始采集之间的增量，再累加到单调递增的Pod计数上
+type rateHistory struct {
+	window   time.Duration
+	prev     map[string]ebpf.IOCounters // 上次原始采集时各key的累计计数
+	prevTime time.Time                  // prev对应的原始采集时间
+	totals   map[string]rateCounters    // 各Pod的累计计数
+	samples  map[string][]rateSample    // 各Pod按时间升序的样本
+}
+
+// WithRateWindow 设置interval-averaged速率的窗口长度，window不大于0时不生效
+// 窗口内至少保留最近两个样本，窗口短于采集周期时两种计算方式的结果相同
+func WithRateWindow(window time.Duration) StorageMonitorOption {
+	return func(sm *StorageMonitor) {
+		if window > 0 {
+			sm.rates.window = window
+		}
+	}
+}
+
+// newRateHistory 创建按window保留样本的速率历史
+func newRateHistory(window time.Duration) *rateHistory {
+	return &rateHistory{
+		window:  window,
+		prev:    make(map[string]ebpf.IOCounters),
+		totals:  make(map[string]rateCounters),
+		samples: make(map[string][]rateSample),
+	}
+}
+
+// GetPodRates 返回Pod按原始样本时间差计算的每秒IOPS和吞吐量
+// 与PodStorageMetrics中的速率不同，分母是两个样本的原始采集时间之差，而不是采集周期或调用间隔
+// id为Pod UID或名称，Pod不存在或样本不足两个时返回错误
+func (sm *StorageMonitor) GetPodRates(id string, mode RateMode) (*PodRates, error) {
+	if !mode.Valid() {
+		return nil, fmt.Errorf("unsupported rate mode %q, must be %s or %s", mode, RateModeInstant, RateModeAverage)
+	}
+
+	sm.metricsMutex.RLock()
+	defer sm.metricsMutex.RUnlock()
+
+	key, err := sm.resolvePodKey(id)
+	if err != nil {
+		return nil, err
+	}
+	samples := sm.rates.samples[key]
+	if len(samples) < 2 {
+		return nil, fmt.Errorf("insufficient samples for pod %s: %d samples, need 2", id, len(samples))
+	}
+
+	last := samples[len(samples)-1]
+	first := samples[len(samples)-2]
+	if mode == RateModeAverage {
+		first = samples[0]
+	}
+
+	elapsed := last.time.Sub(first.time)
+	seconds := elapsed.Seconds()
+	return &PodRates{
+		PodName:         sm.metrics[key].PodName,
+		Mode:            mode,
+		ReadIOPS:        float64(last.counters.readOps-first.counters.readOps) / seconds,
+		WriteIOPS:       float64(last.counters.writeOps-first.counters.writeOps) / seconds,
+		ReadThroughput:  float64(last.counters.readBytes-first.counters.readBytes) / seconds,
+		WriteThroughput: float64(last.counters.writeBytes-first.counters.writeBytes) / seconds,
+		Window:          elapsed,
+		Timestamp:       last.time,
+	}, nil
+}
+
+// record 用最新的累计计数为pods追加样本，collectTime与上次相同说明没有新的原始数据，直接返回
+// 第一次调用只记录基线；pods和resolver解析出的key均为podKey；调用方需持有metricsMutex写锁
+func (h *rateHistory) record(counters map[string]ebpf.IOCounters, collectTime time.Time, resolver *k8s.CgroupResolver, pods []string) {
+	if !collectTime.After(h.prevTime) {
+		return
+	}
+	first := h.prevTime.IsZero()
+
+	for key, cur := range counters {
+		pod, ok := resolveRateKey(resolver, key)
+		if first || !ok {
+			continue
+		}
+		// key被清理后重新出现时，之前的计数已不可比，新的计数都产生于上次采集之后
+		prev, ok := h.prev[key]
+		if !ok || !prev.Since.Equal(cur.Since) {
+			prev = ebpf.IOCounters{}
+		}
+
+		total := h.totals[pod]
+		total.readOps += counterIncrease(cur.ReadOps, prev.ReadOps)
+		total.writeOps += counterIncrease(cur.WriteOps, prev.WriteOps)
+		total.readBytes += counterIncrease(cur.ReadBytes, prev.ReadBytes)
+		total.writeBytes += counterIncrease(cur.WriteBytes, prev.WriteBytes)
+		h.totals[pod] = total
+	}
+	h.prev = counters
+	h.prevTime = collectTime
+
+	// 本次没有I/O的Pod同样追加样本，速率随之降为0；已消失的Pod不再保留
+	samples := make(map[string][]rateSample, len(pods))
+	totals := make(map[string]rateCounters, len(pods))
+	for _, pod := range pods {
+		total := h.totals[pod]
+		totals[pod] = total
+		samples[pod] = h.trim(append(h.samples[pod], rateSample{counters: total, time: collectTime}))
+	}
+	h.totals = totals
+	h.samples = samples
+}
+
+// trim 丢弃早于窗口的样本，至少保留最近两个
+func (h *rateHistory) trim(samples []rateSample) []rateSample {
+	cutoff := samples[len(samples)-1].time.Add(-h.window)
+	drop := 0
+	for drop < len(samples)-2 && samples[drop].time.Before(cutoff) {
+		drop++
+	}
+	return samples[drop:]
+}
+
+// resolveRateKey 将eBPF数据的key转换为podKey，resolver为nil时key已经是podKey
+func resolveRateKey(resolver *k8s.CgroupResolver, key string) (string, bool) {
+	if resolver == nil {
+		return key, true
+	}
+	cgroupID, err := strconv.ParseUint(key, 10, 64)
+	if err != nil {
+		return "", false
+	}
+	pod, ok := resolver.Resolve(cgroupID)
+	if !ok {
+		return "", false
+	}
+	return podKey(pod), true
+}
+
+// counterIncrease 计算累计计数的增量，计数变小时视为从零开始
+func counterIncrease(cur, prev uint64) uint64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
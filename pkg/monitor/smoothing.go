@@ -0,0 +1,102 @@
+package monitor
+
+// SmoothedMetrics Pod最近若干个采集周期指标的滑动平均，用于平滑仪表盘上每个周期的抖动
+// 延迟按对应的IOPS加权平均，窗口内都没有I/O时为简单平均；IOPS和吞吐量为简单平均
+type SmoothedMetrics struct {
+	Samples         int    // 参与平均的采集周期数，窗口未填满时小于窗口大小
+	ReadLatency     uint64 // 纳秒，按读IOPS加权
+	WriteLatency    uint64 // 纳秒，按写IOPS加权
+	QueueLatency    uint64 // 纳秒，按读写IOPS之和加权
+	DiskLatency     uint64 // 纳秒，按读写IOPS之和加权
+	NetworkLatency  uint64 // 纳秒，按读写IOPS之和加权
+	ReadIOPS        uint64
+	WriteIOPS       uint64
+	ReadThroughput  uint64 // 字节/秒
+	WriteThroughput uint64 // 字节/秒
+}
+
+// smoothingRing Pod最近n个采集周期的指标，写满后覆盖最旧的周期
+type smoothingRing struct {
+	samples []PodStorageMetrics
+	next    int // 下一个写入的位置
+	size    int
+}
+
+// WithSmoothingWindow 为每个Pod计算最近n个采集周期的滑动平均，结果保存在PodStorageMetrics.Smoothed中
+// n不大于1时不计算，默认不计算
+func WithSmoothingWindow(n int) StorageMonitorOption {
+	return func(sm *StorageMonitor) {
+		if n > 1 {
+			sm.smoothingWindow = n
+		}
+	}
+}
+
+// smooth 将Pod本周期的指标加入滑动窗口，返回窗口内的平均值，调用方需持有metricsMutex写锁
+func (sm *StorageMonitor) smooth(key string, metrics *PodStorageMetrics) *SmoothedMetrics {
+	ring, ok := sm.smoothing[key]
+	if !ok {
+		ring = &smoothingRing{samples: make([]PodStorageMetrics, sm.smoothingWindow)}
+		sm.smoothing[key] = ring
+	}
+	ring.samples[ring.next] = *metrics
+	ring.next = (ring.next + 1) % len(ring.samples)
+	ring.size = min(ring.size+1, len(ring.samples))
+
+	return ring.average()
+}
+
+// average 计算窗口内已有周期的平均值
+func (r *smoothingRing) average() *SmoothedMetrics {
+	var readLatency, writeLatency weightedSum
+	var queueLatency, diskLatency, networkLatency weightedSum
+	result := &SmoothedMetrics{Samples: r.size}
+	for _, s := range r.samples[:r.size] {
+		readLatency.add(s.ReadLatency, s.ReadIOPS)
+		writeLatency.add(s.WriteLatency, s.WriteIOPS)
+		queueLatency.add(s.QueueLatency, s.ReadIOPS+s.WriteIOPS)
+		diskLatency.add(s.DiskLatency, s.ReadIOPS+s.WriteIOPS)
+		networkLatency.add(s.NetworkLatency, s.ReadIOPS+s.WriteIOPS)
+		result.ReadIOPS += s.ReadIOPS
+		result.WriteIOPS += s.WriteIOPS
+		result.ReadThroughput += s.ReadThroughput
+		result.WriteThroughput += s.WriteThroughput
+	}
+
+	n := uint64(r.size)
+	result.ReadLatency = readLatency.average()
+	result.WriteLatency = writeLatency.average()
+	result.QueueLatency = queueLatency.average()
+	result.DiskLatency = diskLatency.average()
+	result.NetworkLatency = networkLatency.average()
+	result.ReadIOPS /= n
+	result.WriteIOPS /= n
+	result.ReadThroughput /= n
+	result.WriteThroughput /= n
+	return result
+}
+
+// weightedSum 累计加权平均所需的和，权重都为0时退化为简单平均
+type weightedSum struct {
+	weighted, weights, plain, count uint64
+}
+
+// add 加入一个值及其权重
+func (w *weightedSum) add(value, weight uint64) {
+	w.weighted += value * weight
+	w.weights += weight
+	w.plain += value
+	w.count++
+}
+
+// average 返回加权平均值，没有值时为0
+func (w *weightedSum) average() uint64 {
+	switch {
+	case w.weights > 0:
+		return w.weighted / w.weights
+	case w.count > 0:
+		return w.plain / w.count
+	default:
+		return 0
+	}
+}
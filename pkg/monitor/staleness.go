@@ -0,0 +1,22 @@
+package monitor
+
+import "time"
+
+// DefaultStalenessWindow 默认的指标过期时间，Pod超过该时间没有新的eBPF数据时视为过期
+const DefaultStalenessWindow = time.Minute
+
+// WithStalenessWindow 设置指标过期时间
+// Pod的eBPF数据超过该时间没有更新（例如跟踪程序已分离、cgroup已消失或Pod一直空闲）时，
+// 保留最后一次的指标但标记为过期
+func WithStalenessWindow(window time.Duration) StorageMonitorOption {
+	return func(sm *StorageMonitor) {
+		if window > 0 {
+			sm.stalenessWindow = window
+		}
+	}
+}
+
+// isStale 判断最后一次eBPF数据是否已过期，从未收到过数据的Pod没有可过期的指标
+func (sm *StorageMonitor) isStale(lastDataTime, now time.Time) bool {
+	return !lastDataTime.IsZero() && now.Sub(lastDataTime) > sm.stalenessWindow
+}
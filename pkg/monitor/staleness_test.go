@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lizhongxuan/ioeye/pkg/ebpf"
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStaleWhenRawDataStops(t *testing.T) {
+	const window = 50 * time.Millisecond
+
+	clientset := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid-1"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	})
+	bpfMonitor, err := ebpf.NewMonitor(ebpf.WithMockData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm := NewStorageMonitor(bpfMonitor, k8s.NewClientForClientset(clientset), WithStalenessWindow(window))
+	ctx := context.Background()
+
+	if err := sm.collectMetrics(ctx); err != nil {
+		t.Fatalf("collectMetrics() error = %v", err)
+	}
+	fresh, err := sm.GetPodMetrics("uid-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.Stale || fresh.LastDataTime.IsZero() {
+		t.Fatalf("metrics after the first collection: stale %v, last data %v, want fresh data", fresh.Stale, fresh.LastDataTime)
+	}
+
+	// 切换到cgroup映射后模拟数据的key无法解析，相当于Pod的cgroup已消失，不再有新的eBPF数据
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "cgroup.controllers"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sm.cgroupResolver = k8s.NewCgroupResolver(root)
+
+	// 仍在过期时间内
+	if err := sm.collectMetrics(ctx); err != nil {
+		t.Fatalf("collectMetrics() error = %v", err)
+	}
+	if metrics, _ := sm.GetPodMetrics("uid-1"); metrics.Stale {
+		t.Error("metrics marked stale within the staleness window")
+	}
+
+	time.Sleep(2 * window)
+	if err := sm.collectMetrics(ctx); err != nil {
+		t.Fatalf("collectMetrics() error = %v", err)
+	}
+	stale, err := sm.GetPodMetrics("uid-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stale.Stale {
+		t.Errorf("metrics not marked stale %v after the last eBPF data", time.Since(stale.LastDataTime))
+	}
+	// 保留最后一次收到数据时的值
+	if !stale.LastDataTime.Equal(fresh.LastDataTime) || stale.ReadLatency != fresh.ReadLatency {
+		t.Errorf("stale metrics = last data %v, read latency %d, want the last received values %v, %d",
+			stale.LastDataTime, stale.ReadLatency, fresh.LastDataTime, fresh.ReadLatency)
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	sm := NewStorageMonitor(nil, nil, WithStalenessWindow(time.Minute))
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		lastData time.Time
+		want     bool
+	}{
+		{"never received data", time.Time{}, false},
+		{"recent data", now.Add(-30 * time.Second), false},
+		{"exactly the window", now.Add(-time.Minute), false},
+		{"older than the window", now.Add(-time.Minute - time.Second), true},
+	}
+	for _, tt := range tests {
+		if got := sm.isStale(tt.lastData, now); got != tt.want {
+			t.Errorf("%s: isStale() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
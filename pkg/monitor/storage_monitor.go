@@ -3,11 +3,14 @@ package monitor
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/lizhongxuan/ioeye/pkg/ebpf"
 	"github.com/lizhongxuan/ioeye/pkg/k8s"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // StorageMonitorOption 配置存储监控器的选项
@@ -15,53 +18,153 @@ type StorageMonitorOption func(*StorageMonitor)
 
 // StorageMonitor 存储性能监控器
 type StorageMonitor struct {
-	bpfMonitor    *ebpf.Monitor
-	k8sClient     *k8s.Client
-	namespace     string
-	interval      int
-	metrics       map[string]*PodStorageMetrics
-	metricsMutex  sync.RWMutex
-	stopChan      chan struct{}
+	bpfMonitor     *ebpf.Monitor
+	k8sClient      *k8s.Client
+	remoteClusters []*k8s.Client // 只提供拓扑信息的其他集群
+	namespaces     []string // 为空时监控所有命名空间
+	labelSelector  string
+	excludeNamespaces []string // 不监控的命名空间
+	excludePods       []string // 不监控的Pod名称模式
+	interval       time.Duration // 采集周期，受intervalMutex保护
+	intervalMutex  sync.Mutex
+	intervalChan   chan struct{} // 通知采集goroutine采集周期已变化
+	metrics        map[string]*PodStorageMetrics // 以podKey为key，同名Pod重建后不会沿用旧Pod的数据
+	histograms     map[string]*LatencyHistogram
+	metricsMutex   sync.RWMutex
+	stopChan       chan struct{}
+	cgroupResolver *k8s.CgroupResolver       // 非空时eBPF数据以cgroup ID（纯cgroup v1节点上为进程ID）为key
+	cgroupRefreshFailing bool                // 上次刷新cgroup映射是否失败，只由采集goroutine访问
+	volumeCache    map[string]k8s.VolumeInfo // 以"集群/命名空间/PVC名称"为key的已绑定卷信息
+	lastCollection time.Time                 // 上次成功采集的时间，受metricsMutex保护
+	tracedPods     map[string]*tracedPod     // 按需跟踪的Pod，以Pod UID为key
+	tracingMutex   sync.Mutex
+	nodeSaturation uint64                    // 节点聚合队列延迟超过该值（纳秒）时视为设备饱和
+	stalenessWindow time.Duration            // Pod的eBPF数据超过该时间没有更新时标记为过期
+	collectionStats *collectionStats         // 采集耗时和失败次数
+	rates          *rateHistory              // GetPodRates使用的累计计数样本，受metricsMutex保护
+	smoothingWindow int                      // 滑动平均的采集周期数，0表示不计算
+	smoothing      map[string]*smoothingRing // 各Pod最近若干周期的指标，受metricsMutex保护
+	paused         bool                      // 暂停期间跳过采集，受pauseMutex保护
+	pausedAt       time.Time
+	pauseMutex     sync.Mutex
+	subs           subscribers               // Subscribe注册的订阅者
 }
 
 // PodStorageMetrics Pod存储性能指标
 type PodStorageMetrics struct {
-	PodName         string
-	Namespace       string
-	ReadLatency     uint64 // 纳秒
-	WriteLatency    uint64 // 纳秒
-	ReadIOPS        uint64
-	WriteIOPS       uint64
-	ReadThroughput  uint64 // 字节/秒
-	WriteThroughput uint64 // 字节/秒
-	QueueLatency    uint64 // 纳秒
-	DiskLatency     uint64 // 纳秒
-	NetworkLatency  uint64 // 纳秒
-	Timestamp       time.Time
-}
-
-// WithNamespace 设置要监控的命名空间
-func WithNamespace(namespace string) StorageMonitorOption {
+	Cluster           string // Pod所在集群的名称，单集群时为空
+	PodName           string
+	Namespace         string
+	PodUID            string           // Pod的UID，模拟数据等无法获取时为空
+	WorkloadKind      string           // 所属工作负载类型，裸Pod为standalone
+	WorkloadName      string           // 所属工作负载名称
+	NodeName          string           // 所在节点，未调度的Pod为空
+	Volumes           []k8s.VolumeInfo // 挂载的PVC及其绑定的PV
+	ReadLatency       uint64           // 纳秒
+	WriteLatency      uint64           // 纳秒
+	ReadIOPS          uint64
+	WriteIOPS         uint64
+	ReadThroughput    uint64 // 字节/秒
+	WriteThroughput   uint64 // 字节/秒
+	QueueLatency      uint64 // 纳秒，取读写队列延迟中较高者
+	DiskLatency       uint64 // 纳秒，取读写磁盘延迟中较高者
+	ReadQueueLatency  uint64 // 纳秒
+	WriteQueueLatency uint64 // 纳秒
+	ReadDiskLatency   uint64 // 纳秒
+	WriteDiskLatency  uint64 // 纳秒
+	NetworkLatency    uint64 // 纳秒
+	AvgReadSize       uint64  // 平均每次读的字节数，没有读操作时为0
+	AvgWriteSize      uint64  // 平均每次写的字节数，没有写操作时为0
+	ReadRatio         float64 // 读操作占读写操作总数的比例（0-1），没有读写操作时为0
+	// Utilization 窗口内Pod至少有一个块I/O请求在设备上处理的时间比例（0-1），
+	// 多个容器cgroup的值相加后截断为1
+	Utilization       float64
+	// 自监控开始以来的累计读写次数和字节数，单调递增，IOEye重启后从0开始，供需要自行计算速率的工具使用
+	ReadOpsTotal      uint64
+	WriteOpsTotal     uint64
+	ReadBytesTotal    uint64
+	WriteBytesTotal   uint64
+	// Containers 各容器的指标，按容器名称排序，无法按容器归属时为空
+	// Pod级指标是所有容器及Pod级cgroup的合计，不依赖于该字段
+	Containers        []*ContainerStorageMetrics
+	// Smoothed 最近若干个采集周期的滑动平均，未通过WithSmoothingWindow启用时为nil
+	Smoothed          *SmoothedMetrics
+	// FileSystems 最近一个统计窗口内按文件系统类型的VFS读写，按类型排序，文件系统跟踪程序未附加时为空
+	FileSystems       []FileSystemMetrics
+	// LastDataTime 最后一次收到该Pod的eBPF数据的时间，从未收到时为零值
+	LastDataTime      time.Time
+	// Stale 超过过期时间没有新的eBPF数据，其余字段是最后一次收到数据时的值
+	Stale             bool
+	Timestamp         time.Time
+}
+
+
+// LatencyHistogram Pod最近一个统计窗口的读写延迟log2直方图
+type LatencyHistogram struct {
+	PodName     string
+	Bounds      []uint64 // 各桶上界（纳秒，不含）
+	ReadCounts  []uint64 // 各桶读I/O次数
+	WriteCounts []uint64 // 各桶写I/O次数
+	Timestamp   time.Time
+}
+
+// MinInterval 允许的最小采集周期，避免过于频繁地请求API Server
+const MinInterval = time.Second
+
+// WithNamespaces 设置要监控的命名空间，未设置或为空时监控所有命名空间
+func WithNamespaces(namespaces ...string) StorageMonitorOption {
 	return func(sm *StorageMonitor) {
-		sm.namespace = namespace
+		sm.namespaces = namespaces
+	}
+}
+
+// WithLabelSelector 只监控符合标签选择器的Pod，例如"app=mysql,tier in (db)"
+func WithLabelSelector(selector string) StorageMonitorOption {
+	return func(sm *StorageMonitor) {
+		sm.labelSelector = selector
 	}
 }
 
 // WithInterval 设置监控间隔（秒）
 func WithInterval(interval int) StorageMonitorOption {
 	return func(sm *StorageMonitor) {
-		sm.interval = interval
+		sm.interval = time.Duration(interval) * time.Second
+	}
+}
+
+// WithCgroupResolver 设置cgroup解析器，用于将eBPF上报的cgroup ID映射到Pod
+func WithCgroupResolver(resolver *k8s.CgroupResolver) StorageMonitorOption {
+	return func(sm *StorageMonitor) {
+		sm.cgroupResolver = resolver
+	}
+}
+
+// WithSaturationQueueLatency 设置判定节点存储设备饱和的聚合队列延迟阈值（纳秒）
+func WithSaturationQueueLatency(threshold uint64) StorageMonitorOption {
+	return func(sm *StorageMonitor) {
+		if threshold > 0 {
+			sm.nodeSaturation = threshold
+		}
 	}
 }
 
 // NewStorageMonitor 创建新的存储性能监控器
 func NewStorageMonitor(bpfMonitor *ebpf.Monitor, k8sClient *k8s.Client, opts ...StorageMonitorOption) *StorageMonitor {
 	sm := &StorageMonitor{
-		bpfMonitor: bpfMonitor,
-		k8sClient:  k8sClient,
-		interval:   10, // 默认10秒
-		metrics:    make(map[string]*PodStorageMetrics),
-		stopChan:   make(chan struct{}),
+		bpfMonitor:     bpfMonitor,
+		k8sClient:      k8sClient,
+		interval:       10 * time.Second, // 默认10秒
+		intervalChan:   make(chan struct{}, 1),
+		metrics:        make(map[string]*PodStorageMetrics),
+		histograms:     make(map[string]*LatencyHistogram),
+		stopChan:       make(chan struct{}),
+		volumeCache:    make(map[string]k8s.VolumeInfo),
+		tracedPods:     make(map[string]*tracedPod),
+		nodeSaturation: DefaultSaturationQueueLatency,
+		stalenessWindow: DefaultStalenessWindow,
+		collectionStats: newCollectionStats(),
+		rates:           newRateHistory(DefaultRateWindow),
+		smoothing:       make(map[string]*smoothingRing),
 	}
 
 	// 应用选项
@@ -74,21 +177,58 @@ func NewStorageMonitor(bpfMonitor *ebpf.Monitor, k8sClient *k8s.Client, opts ...
 
 // Start 启动存储性能监控
 func (sm *StorageMonitor) Start(ctx context.Context) error {
+	// 启动前校验标签选择器，避免格式错误的选择器静默匹配不到任何Pod
+	if _, err := labels.Parse(sm.labelSelector); err != nil {
+		return fmt.Errorf("invalid label selector %q: %v", sm.labelSelector, err)
+	}
+	if err := ValidateExcludePatterns(sm.excludePods); err != nil {
+		return err
+	}
+
 	// 创建一个新的context，接收外部取消信号
 	monitorCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
+
+	// 启用informer时，Pod删除后立即清理其指标
+	for _, client := range sm.clusterClients() {
+		if !client.InformerEnabled() {
+			continue
+		}
+		if err := client.WatchPods(k8s.PodEventHandler{
+			OnDelete: sm.removePod,
+		}); err != nil {
+			cancel()
+			return fmt.Errorf("failed to watch pods: %v", err)
+		}
+	}
 
 	// 启动监控goroutine
+	// monitorCtx随监控goroutine退出而取消，从而中止进行中的API请求
 	go func() {
-		ticker := time.NewTicker(time.Duration(sm.interval) * time.Second)
+		defer cancel()
+		defer sm.closeSubscribers()
+
+		ticker := time.NewTicker(sm.Interval())
 		defer ticker.Stop()
 
 		for {
 			select {
 			case <-ticker.C:
-				if err := sm.collectMetrics(); err != nil {
+				if sm.Paused() {
+					continue
+				}
+
+				// 每次采集以监控间隔为截止时间，避免慢速API Server阻塞采集
+				collectCtx, collectCancel := context.WithTimeout(monitorCtx, sm.Interval())
+				err := sm.collectMetrics(collectCtx)
+				collectCancel()
+				if err != nil {
 					fmt.Printf("Error collecting metrics: %v\n", err)
+					continue
 				}
+				sm.publish()
+			case <-sm.intervalChan:
+				// 重置ticker而不是重建goroutine，下一次采集在新周期后进行
+				ticker.Reset(sm.Interval())
 			case <-monitorCtx.Done():
 				return
 			case <-sm.stopChan:
@@ -100,27 +240,53 @@ func (sm *StorageMonitor) Start(ctx context.Context) error {
 	return nil
 }
 
+// Interval 返回当前的采集周期
+func (sm *StorageMonitor) Interval() time.Duration {
+	sm.intervalMutex.Lock()
+	defer sm.intervalMutex.Unlock()
+	return sm.interval
+}
+
+// SetInterval 在运行时修改采集周期，周期不能小于MinInterval
+// 可以在Start之前或之后调用，并发调用时以最后一次为准
+func (sm *StorageMonitor) SetInterval(interval time.Duration) error {
+	if interval < MinInterval {
+		return fmt.Errorf("interval must be at least %v, got %v", MinInterval, interval)
+	}
+
+	sm.intervalMutex.Lock()
+	sm.interval = interval
+	sm.intervalMutex.Unlock()
+
+	// 通道已有未处理的通知时无需重复发送，采集goroutine会读取最新的周期
+	select {
+	case sm.intervalChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
 // Stop 停止监控
 func (sm *StorageMonitor) Stop() {
 	close(sm.stopChan)
 }
 
-// GetPodMetrics 获取特定Pod的存储指标
-func (sm *StorageMonitor) GetPodMetrics(podName string) (*PodStorageMetrics, error) {
+// GetPodMetrics 获取特定Pod的存储指标，id为Pod UID或名称
+func (sm *StorageMonitor) GetPodMetrics(id string) (*PodStorageMetrics, error) {
 	sm.metricsMutex.RLock()
 	defer sm.metricsMutex.RUnlock()
 	
-	metrics, ok := sm.metrics[podName]
-	if !ok {
-		return nil, fmt.Errorf("no metrics found for pod %s", podName)
+	key, err := sm.resolvePodKey(id)
+	if err != nil {
+		return nil, err
 	}
 	
 	// 返回副本而非原始对象
-	metricsCopy := *metrics
+	metricsCopy := *sm.metrics[key]
 	return &metricsCopy, nil
 }
 
-// GetAllMetrics 获取所有Pod的存储指标
+// GetAllMetrics 获取所有Pod的存储指标，以Pod UID为key，没有UID时为Pod名称
 func (sm *StorageMonitor) GetAllMetrics() map[string]*PodStorageMetrics {
 	sm.metricsMutex.RLock()
 	defer sm.metricsMutex.RUnlock()
@@ -134,98 +300,356 @@ func (sm *StorageMonitor) GetAllMetrics() map[string]*PodStorageMetrics {
 	return result
 }
 
-// 内部方法
+// LastCollectionTime 返回上次成功采集的时间，尚未成功采集时为零值
+func (sm *StorageMonitor) LastCollectionTime() time.Time {
+	sm.metricsMutex.RLock()
+	defer sm.metricsMutex.RUnlock()
+	return sm.lastCollection
+}
+
+// TracersAttached 返回eBPF跟踪程序是否已附加
+func (sm *StorageMonitor) TracersAttached() bool {
+	return sm.bpfMonitor.Attached()
+}
+
+// TracersDegraded 返回是否因块I/O跟踪程序附加失败而在降级模式下运行，此时只有Kubernetes中的Pod信息
+func (sm *StorageMonitor) TracersDegraded() bool {
+	return sm.bpfMonitor.Degraded()
+}
+
+// FailedTracers 返回附加失败的eBPF跟踪程序及原因
+func (sm *StorageMonitor) FailedTracers() map[string]string {
+	return sm.bpfMonitor.FailedTracers()
+}
+
+// GetAttachedProbes 返回eBPF跟踪程序尝试附加的kprobe和tracepoint及其状态
+func (sm *StorageMonitor) GetAttachedProbes() []ebpf.ProbeStatus {
+	return sm.bpfMonitor.GetAttachedProbes()
+}
 
-// collectMetrics 收集所有存储性能指标
-func (sm *StorageMonitor) collectMetrics() error {
-	// 从K8s获取Pod列表
-	pods, err := sm.k8sClient.ListPods(sm.namespace)
+// GetLatencyHistogram 获取特定Pod的读写延迟直方图，id为Pod UID或名称
+func (sm *StorageMonitor) GetLatencyHistogram(id string) (*LatencyHistogram, error) {
+	sm.metricsMutex.RLock()
+	defer sm.metricsMutex.RUnlock()
+
+	key, err := sm.resolvePodKey(id)
 	if err != nil {
-		return fmt.Errorf("failed to list pods: %v", err)
+		return nil, err
+	}
+	hist, ok := sm.histograms[key]
+	if !ok {
+		return nil, fmt.Errorf("no latency histogram found for pod %s", id)
 	}
 
-	// 从eBPF获取基础I/O统计数据
-	ioStatsData, err := sm.bpfMonitor.GetIOStatsData()
-	if err != nil {
-		return fmt.Errorf("failed to get I/O stats data: %v", err)
+	// 返回副本而非原始对象
+	return &LatencyHistogram{
+		PodName:     hist.PodName,
+		Bounds:      append([]uint64(nil), hist.Bounds...),
+		ReadCounts:  append([]uint64(nil), hist.ReadCounts...),
+		WriteCounts: append([]uint64(nil), hist.WriteCounts...),
+		Timestamp:   hist.Timestamp,
+	}, nil
+}
+
+// 内部方法
+
+// removePod 删除已不存在的Pod的指标
+func (sm *StorageMonitor) removePod(pod k8s.PodRef) {
+	// informer监听所有命名空间，忽略未监控命名空间中的同名Pod
+	if !sm.watchesNamespace(pod.Namespace) {
+		return
 	}
-	
-	// 获取IOPS数据
-	iopsData, err := sm.bpfMonitor.GetIOPS()
-	if err != nil {
-		return fmt.Errorf("failed to get IOPS data: %v", err)
+
+	sm.metricsMutex.Lock()
+	defer sm.metricsMutex.Unlock()
+
+	key := podKey(pod)
+	if metrics, ok := sm.metrics[key]; ok && (metrics.Namespace != pod.Namespace || metrics.Cluster != pod.Cluster) {
+		return
 	}
-	
-	// 获取吞吐量数据
-	throughputData, err := sm.bpfMonitor.GetThroughput()
-	if err != nil {
-		return fmt.Errorf("failed to get throughput data: %v", err)
+	delete(sm.metrics, key)
+	delete(sm.histograms, key)
+	delete(sm.smoothing, key)
+}
+
+// watchesNamespace 判断命名空间是否在监控范围内
+func (sm *StorageMonitor) watchesNamespace(namespace string) bool {
+	if len(sm.namespaces) == 0 {
+		return true
 	}
-	
-	// 获取磁盘延迟数据
-	diskLatencyData, err := sm.bpfMonitor.GetDiskLatencyData()
-	if err != nil {
-		return fmt.Errorf("failed to get disk latency data: %v", err)
+	for _, ns := range sm.namespaces {
+		if ns == namespace {
+			return true
+		}
 	}
+	return false
+}
 
-	// 获取队列延迟数据
-	queueLatencyData, err := sm.bpfMonitor.GetQueueLatencyData()
+// collectMetrics 收集所有存储性能指标，并记录采集耗时和失败的阶段
+func (sm *StorageMonitor) collectMetrics(ctx context.Context) error {
+	start := time.Now()
+	podsCount := -1
+	defer func() {
+		sm.collectionStats.observe(time.Since(start), podsCount)
+	}()
+
+	// 从K8s获取所有集群的Pod列表
+	pods, unreachable, err := sm.listPods(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get queue latency data: %v", err)
+		return sm.collectFailed(CollectStageListPods, fmt.Errorf("failed to list pods: %v", err))
 	}
+	pods = sm.filterExcluded(pods)
+
+	// 从eBPF读取一次原始数据，I/O统计、IOPS、吞吐量和累计计数都来自同一时刻
+	snap := sm.bpfMonitor.TakeSnapshot()
+	ioStatsData := snap.Stats
+	iopsData := sm.bpfMonitor.IOPSFromSnapshot(snap)
+	throughputData := sm.bpfMonitor.ThroughputFromSnapshot(snap)
+
+	// 累计计数和原始采集时间，用于按样本时间差计算速率
+	ioCounters, collectTime := snap.Counters, snap.CollectTime
+	
+	// 将以cgroup ID为key的eBPF数据转换为以podKey为key
+	var containerIOStats map[string]map[string]*ebpf.IOStatsData
+	var containerIOPS, containerThroughput map[string]map[string]map[string]uint64
+	if sm.cgroupResolver != nil {
+		// 刷新失败时不放弃整次采集，沿用上次的映射，从未成功时只有Kubernetes中的Pod信息而没有I/O数据
+		// 只在开始失败和恢复时打印，避免每个采集周期重复输出
+		if err := sm.cgroupResolver.Refresh(sm.localPods(pods)); err != nil {
+			sm.collectionStats.recordError(CollectStageCgroups)
+			if !sm.cgroupRefreshFailing {
+				fmt.Printf("Warning: failed to refresh cgroup mapping, I/O data of new pods is unavailable until it recovers: %v\n", err)
+			}
+			sm.cgroupRefreshFailing = true
+		} else if sm.cgroupRefreshFailing {
+			fmt.Printf("Cgroup mapping refreshed again after previous failures\n")
+			sm.cgroupRefreshFailing = false
+		}
+		sm.syncPodTracing()
+		// 容器级数据需在合并为Pod级之前拆分
+		containerIOStats = resolveContainerKeys(sm.cgroupResolver, ioStatsData, mergeIOStats)
+		containerIOPS = resolveContainerKeys(sm.cgroupResolver, iopsData, sumCounters)
+		containerThroughput = resolveContainerKeys(sm.cgroupResolver, throughputData, sumCounters)
+		ioStatsData = resolvePodKeys(sm.cgroupResolver, ioStatsData, mergeIOStats)
+		iopsData = resolvePodKeys(sm.cgroupResolver, iopsData, sumCounters)
+		throughputData = resolvePodKeys(sm.cgroupResolver, throughputData, sumCounters)
+	} else {
+		// 模拟数据直接以Pod名称为key
+		ioStatsData = rekeyByName(ioStatsData, pods)
+		iopsData = rekeyByName(iopsData, pods)
+		throughputData = rekeyByName(throughputData, pods)
+		ioCounters = rekeyByName(ioCounters, pods)
+	}
+
+	// 关联Pod使用的PVC和PV，在获取锁之前完成以免API请求阻塞查询
+	volumes := sm.resolveVolumes(ctx, pods)
 
 	// 在更新指标前获取锁
 	sm.metricsMutex.Lock()
 	defer sm.metricsMutex.Unlock()
 
+	// 清理已不存在或不再匹配选择器的Pod，暂时不可达的集群中的Pod保留上次的指标
+	// 同名Pod重建后UID不同，旧Pod的指标同样在这里被清理
+	listed := make(map[string]bool, len(pods))
+	podKeys := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		listed[podKey(pod)] = true
+		podKeys = append(podKeys, podKey(pod))
+	}
+	sm.rates.record(ioCounters, collectTime, sm.cgroupResolver, podKeys)
+	for key, metrics := range sm.metrics {
+		if !listed[key] && !unreachable[metrics.Cluster] {
+			delete(sm.metrics, key)
+			delete(sm.histograms, key)
+			delete(sm.smoothing, key)
+		}
+	}
+
 	// 生成指标
 	now := time.Now()
-	for _, podName := range pods {
+	for _, pod := range pods {
+		podName := pod.Name
+		key := podKey(pod)
+
 		// 为每个Pod创建或更新指标对象
-		metrics, ok := sm.metrics[podName]
+		metrics, ok := sm.metrics[key]
 		if !ok {
 			metrics = &PodStorageMetrics{
-				PodName:   podName,
-				Namespace: sm.namespace,
+				PodName: podName,
 			}
-			sm.metrics[podName] = metrics
+			sm.metrics[key] = metrics
 		}
 		
+		// 使用Pod实际所在的集群、命名空间和所属工作负载
+		metrics.Cluster = pod.Cluster
+		metrics.Namespace = pod.Namespace
+		metrics.PodUID = pod.UID
+		metrics.WorkloadKind = pod.Workload.Kind
+		metrics.WorkloadName = pod.Workload.Name
+		metrics.NodeName = pod.NodeName
+		metrics.Volumes = volumes[key]
+		metrics.Containers = buildContainerMetrics(containerIOStats[key], containerIOPS[key], containerThroughput[key])
+
 		// 更新时间戳
 		metrics.Timestamp = now
 		
 		// 填充基础I/O统计数据
-		if ioStats, ok := ioStatsData[podName]; ok {
+		if ioStats, ok := ioStatsData[key]; ok {
+			metrics.LastDataTime = ioStats.LastUpdateTime
 			metrics.ReadLatency = ioStats.ReadLatencyNs
 			metrics.WriteLatency = ioStats.WriteLatencyNs
+
+			// 分别记录读写方向的队列和磁盘延迟，汇总值取两者中较高者
+			metrics.ReadQueueLatency = ioStats.ReadQueueLatencyNs
+			metrics.WriteQueueLatency = ioStats.WriteQueueLatencyNs
+			metrics.ReadDiskLatency = ioStats.ReadDiskLatencyNs
+			metrics.WriteDiskLatency = ioStats.WriteDiskLatencyNs
+			metrics.QueueLatency = max(metrics.ReadQueueLatency, metrics.WriteQueueLatency)
+			metrics.DiskLatency = max(metrics.ReadDiskLatency, metrics.WriteDiskLatency)
+
+			// 只有使用NFS卷的Pod才有网络延迟
+			metrics.NetworkLatency = ioStats.NetworkLatencyNs
+			metrics.Utilization = ioStats.Utilization
+
+			// 区分小的随机I/O和大的顺序I/O
+			metrics.AvgReadSize = avgIOSize(ioStats.ReadBytes, ioStats.ReadOps)
+			metrics.AvgWriteSize = avgIOSize(ioStats.WriteBytes, ioStats.WriteOps)
+			metrics.ReadRatio = readRatio(ioStats.ReadOps, ioStats.WriteOps)
+			metrics.FileSystems = buildFileSystemMetrics(ioStats.FSStats)
+
+			sm.histograms[key] = &LatencyHistogram{
+				PodName:     podName,
+				Bounds:      ebpf.LatencyBucketBounds(),
+				ReadCounts:  append([]uint64(nil), ioStats.ReadLatencyHist[:]...),
+				WriteCounts: append([]uint64(nil), ioStats.WriteLatencyHist[:]...),
+				Timestamp:   now,
+			}
 		}
 		
 		// 填充IOPS数据
-		if iops, ok := iopsData[podName]; ok {
+		if iops, ok := iopsData[key]; ok {
 			metrics.ReadIOPS = iops["read_iops"]
 			metrics.WriteIOPS = iops["write_iops"]
 		}
 		
 		// 填充吞吐量数据
-		if throughput, ok := throughputData[podName]; ok {
+		if throughput, ok := throughputData[key]; ok {
 			metrics.ReadThroughput = throughput["read_throughput_bps"]
 			metrics.WriteThroughput = throughput["write_throughput_bps"]
 		}
-		
-		// 填充磁盘延迟数据
-		if diskLatency, ok := diskLatencyData[podName]; ok {
-			metrics.DiskLatency = diskLatency
-		}
-		
-		// 填充队列延迟数据
-		if queueLatency, ok := queueLatencyData[podName]; ok {
-			metrics.QueueLatency = queueLatency
+
+		// 填充累计计数，与GetPodRates使用相同的按cgroup增量累加的计数
+		total := sm.rates.totals[key]
+		metrics.ReadOpsTotal = total.readOps
+		metrics.WriteOpsTotal = total.writeOps
+		metrics.ReadBytesTotal = total.readBytes
+		metrics.WriteBytesTotal = total.writeBytes
+
+		if sm.smoothingWindow > 0 {
+			metrics.Smoothed = sm.smooth(key, metrics)
 		}
+
+		// 时间戳随每次采集更新，需根据eBPF数据本身的时间判断是否仍在产生新数据
+		metrics.Stale = sm.isStale(metrics.LastDataTime, now)
 	}
 
+	sm.lastCollection = now
+	podsCount = len(pods)
+
 	return nil
 }
 
+// resolvePodKeys 将以cgroup ID为key的数据转换为以podKey为key
+// 同一Pod的多个容器cgroup通过merge合并，无法解析的cgroup被丢弃
+func resolvePodKeys[V any](resolver *k8s.CgroupResolver, data map[string]V, merge func(a, b V) V) map[string]V {
+	result := make(map[string]V, len(data))
+	for key, value := range data {
+		cgroupID, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			continue
+		}
+		pod, ok := resolver.Resolve(cgroupID)
+		if !ok {
+			continue
+		}
+		key := podKey(pod)
+		if existing, ok := result[key]; ok {
+			value = merge(existing, value)
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// mergeIOStats 合并两个cgroup的I/O统计，延迟按操作次数加权平均
+// 已无法得知两个cgroup的请求区间是否重叠，利用率按不重叠相加
+func mergeIOStats(a, b *ebpf.IOStatsData) *ebpf.IOStatsData {
+	merged := &ebpf.IOStatsData{
+		ReadOps:          a.ReadOps + b.ReadOps,
+		WriteOps:         a.WriteOps + b.WriteOps,
+		ReadBytes:        a.ReadBytes + b.ReadBytes,
+		WriteBytes:       a.WriteBytes + b.WriteBytes,
+		NetworkOps:       a.NetworkOps + b.NetworkOps,
+		IOUringOps:       a.IOUringOps + b.IOUringOps,
+		Utilization:      min(a.Utilization+b.Utilization, 1),
+		ReadLatencyHist:  a.ReadLatencyHist,
+		WriteLatencyHist: a.WriteLatencyHist,
+		LastUpdateTime:   a.LastUpdateTime,
+	}
+	merged.ReadLatencyHist.Add(&b.ReadLatencyHist)
+	merged.WriteLatencyHist.Add(&b.WriteLatencyHist)
+	if b.LastUpdateTime.After(merged.LastUpdateTime) {
+		merged.LastUpdateTime = b.LastUpdateTime
+	}
+
+	weighted := func(x, xOps, y, yOps uint64) uint64 {
+		if xOps+yOps == 0 {
+			return 0
+		}
+		return (x*xOps + y*yOps) / (xOps + yOps)
+	}
+	merged.ReadLatencyNs = weighted(a.ReadLatencyNs, a.ReadOps, b.ReadLatencyNs, b.ReadOps)
+	merged.WriteLatencyNs = weighted(a.WriteLatencyNs, a.WriteOps, b.WriteLatencyNs, b.WriteOps)
+	merged.ReadQueueLatencyNs = weighted(a.ReadQueueLatencyNs, a.ReadOps, b.ReadQueueLatencyNs, b.ReadOps)
+	merged.WriteQueueLatencyNs = weighted(a.WriteQueueLatencyNs, a.WriteOps, b.WriteQueueLatencyNs, b.WriteOps)
+	merged.ReadDiskLatencyNs = weighted(a.ReadDiskLatencyNs, a.ReadOps, b.ReadDiskLatencyNs, b.ReadOps)
+	merged.WriteDiskLatencyNs = weighted(a.WriteDiskLatencyNs, a.WriteOps, b.WriteDiskLatencyNs, b.WriteOps)
+	merged.QueueLatencyNs = max(merged.ReadQueueLatencyNs, merged.WriteQueueLatencyNs)
+	merged.DiskLatencyNs = max(merged.ReadDiskLatencyNs, merged.WriteDiskLatencyNs)
+	merged.NetworkLatencyNs = weighted(a.NetworkLatencyNs, a.NetworkOps, b.NetworkLatencyNs, b.NetworkOps)
+	merged.FSStats = mergeFSStats(a.FSStats, b.FSStats)
+
+	return merged
+}
+
+// avgIOSize 返回平均每次操作的字节数，没有操作时为0
+func avgIOSize(bytes, ops uint64) uint64 {
+	if ops == 0 {
+		return 0
+	}
+	return bytes / ops
+}
+
+// readRatio 返回读操作占读写操作总数的比例，没有操作时为0
+func readRatio(readOps, writeOps uint64) float64 {
+	if readOps+writeOps == 0 {
+		return 0
+	}
+	return float64(readOps) / float64(readOps+writeOps)
+}
+
+// sumCounters 按字段累加两个cgroup的速率数据
+func sumCounters(a, b map[string]uint64) map[string]uint64 {
+	sum := make(map[string]uint64, len(a))
+	for k, v := range a {
+		sum[k] = v
+	}
+	for k, v := range b {
+		sum[k] += v
+	}
+	return sum
+}
+
 // GetPodIOPS 获取特定Pod的IOPS指标
 func (sm *StorageMonitor) GetPodIOPS(podName string) (readIOPS, writeIOPS uint64, err error) {
 	metrics, err := sm.GetPodMetrics(podName)
@@ -258,60 +682,42 @@ func (sm *StorageMonitor) GetPodLatency(podName string) (readLatency, writeLaten
 
 // GetTopIOPSPods 获取IOPS最高的N个Pod
 func (sm *StorageMonitor) GetTopIOPSPods(n int) []*PodStorageMetrics {
-	sm.metricsMutex.RLock()
-	defer sm.metricsMutex.RUnlock()
-	
-	// 创建一个Pod指标的切片
-	pods := make([]*PodStorageMetrics, 0, len(sm.metrics))
-	for _, metrics := range sm.metrics {
-		podCopy := *metrics
-		pods = append(pods, &podCopy)
-	}
-	
-	// 按总IOPS（读+写）排序
-	// 降序排列，最高的在前面
-	for i := 0; i < len(pods)-1; i++ {
-		for j := i + 1; j < len(pods); j++ {
-			if (pods[i].ReadIOPS + pods[i].WriteIOPS) < (pods[j].ReadIOPS + pods[j].WriteIOPS) {
-				pods[i], pods[j] = pods[j], pods[i]
-			}
-		}
-	}
-	
-	// 返回前N个
-	if n > len(pods) {
-		n = len(pods)
-	}
-	
-	return pods[:n]
+	// 按总IOPS（读+写）降序排列
+	return sm.topNPods(n, func(m *PodStorageMetrics) uint64 {
+		return m.ReadIOPS + m.WriteIOPS
+	})
 }
 
 // GetTopThroughputPods 获取吞吐量最高的N个Pod
 func (sm *StorageMonitor) GetTopThroughputPods(n int) []*PodStorageMetrics {
+	// 按总吞吐量（读+写）降序排列
+	return sm.topNPods(n, func(m *PodStorageMetrics) uint64 {
+		return m.ReadThroughput + m.WriteThroughput
+	})
+}
+
+// topNPods 按key降序返回前N个Pod指标的副本
+func (sm *StorageMonitor) topNPods(n int, key func(*PodStorageMetrics) uint64) []*PodStorageMetrics {
+	// 仅在拷贝期间持有读锁，排序在锁外进行
 	sm.metricsMutex.RLock()
-	defer sm.metricsMutex.RUnlock()
-	
-	// 创建一个Pod指标的切片
 	pods := make([]*PodStorageMetrics, 0, len(sm.metrics))
 	for _, metrics := range sm.metrics {
 		podCopy := *metrics
 		pods = append(pods, &podCopy)
 	}
-	
-	// 按总吞吐量（读+写）排序
-	// 降序排列，最高的在前面
-	for i := 0; i < len(pods)-1; i++ {
-		for j := i + 1; j < len(pods); j++ {
-			if (pods[i].ReadThroughput + pods[i].WriteThroughput) < (pods[j].ReadThroughput + pods[j].WriteThroughput) {
-				pods[i], pods[j] = pods[j], pods[i]
-			}
-		}
-	}
-	
+	sm.metricsMutex.RUnlock()
+
+	sort.Slice(pods, func(i, j int) bool {
+		return key(pods[i]) > key(pods[j])
+	})
+
 	// 返回前N个
+	if n < 0 {
+		n = 0
+	}
 	if n > len(pods) {
 		n = len(pods)
 	}
-	
+
 	return pods[:n]
 }
@@ -0,0 +1,111 @@
+package monitor
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// newTopNMonitor 返回持有n个Pod指标的监控器，IOPS和吞吐量随机分布
+func newTopNMonitor(n int) *StorageMonitor {
+	sm := NewStorageMonitor(nil, nil)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < n; i++ {
+		uid := fmt.Sprintf("uid-%d", i)
+		sm.metrics[uid] = &PodStorageMetrics{
+			PodName:         fmt.Sprintf("pod-%d", i),
+			Namespace:       "default",
+			PodUID:          uid,
+			ReadIOPS:        uint64(rng.Intn(10000)),
+			WriteIOPS:       uint64(rng.Intn(10000)),
+			ReadThroughput:  uint64(rng.Intn(1 << 30)),
+			WriteThroughput: uint64(rng.Intn(1 << 30)),
+		}
+	}
+	return sm
+}
+
+// bubbleSortTopIOPS 是改用sort.Slice之前的实现，在锁内对全部Pod做冒泡排序，仅作为基准对照
+func bubbleSortTopIOPS(sm *StorageMonitor, n int) []*PodStorageMetrics {
+	sm.metricsMutex.RLock()
+	defer sm.metricsMutex.RUnlock()
+
+	pods := make([]*PodStorageMetrics, 0, len(sm.metrics))
+	for _, metrics := range sm.metrics {
+		podCopy := *metrics
+		pods = append(pods, &podCopy)
+	}
+	for i := 0; i < len(pods); i++ {
+		for j := i + 1; j < len(pods); j++ {
+			if pods[i].ReadIOPS+pods[i].WriteIOPS < pods[j].ReadIOPS+pods[j].WriteIOPS {
+				pods[i], pods[j] = pods[j], pods[i]
+			}
+		}
+	}
+	if n > len(pods) {
+		n = len(pods)
+	}
+	return pods[:n]
+}
+
+func TestGetTopIOPSPods(t *testing.T) {
+	sm := newTopNMonitor(500)
+
+	top := sm.GetTopIOPSPods(10)
+	if len(top) != 10 {
+		t.Fatalf("GetTopIOPSPods(10) returned %d pods", len(top))
+	}
+	want := bubbleSortTopIOPS(sm, 10)
+	for i := range top {
+		if got, exp := top[i].ReadIOPS+top[i].WriteIOPS, want[i].ReadIOPS+want[i].WriteIOPS; got != exp {
+			t.Errorf("pod %d IOPS = %d, want %d", i, got, exp)
+		}
+	}
+
+	// 返回的是副本，修改不影响监控器中的数据
+	top[0].ReadIOPS++
+	if sm.metrics[top[0].PodUID].ReadIOPS == top[0].ReadIOPS {
+		t.Error("GetTopIOPSPods returned the stored metrics instead of a copy")
+	}
+
+	if got := sm.GetTopIOPSPods(1000); len(got) != 500 {
+		t.Errorf("GetTopIOPSPods(1000) returned %d pods, want all 500", len(got))
+	}
+	if got := sm.GetTopIOPSPods(-1); len(got) != 0 {
+		t.Errorf("GetTopIOPSPods(-1) returned %d pods, want none", len(got))
+	}
+}
+
+func TestGetTopThroughputPods(t *testing.T) {
+	sm := newTopNMonitor(500)
+
+	top := sm.GetTopThroughputPods(20)
+	for i := 1; i < len(top); i++ {
+		if top[i-1].ReadThroughput+top[i-1].WriteThroughput < top[i].ReadThroughput+top[i].WriteThroughput {
+			t.Fatalf("GetTopThroughputPods not sorted in descending order at %d", i)
+		}
+	}
+}
+
+func BenchmarkGetTopIOPSPods(b *testing.B) {
+	sm := newTopNMonitor(5000)
+
+	b.Run("sort.Slice", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sm.GetTopIOPSPods(10)
+		}
+	})
+	b.Run("bubble", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			bubbleSortTopIOPS(sm, 10)
+		}
+	})
+}
+
+func BenchmarkGetTopThroughputPods(b *testing.B) {
+	sm := newTopNMonitor(5000)
+
+	for i := 0; i < b.N; i++ {
+		sm.GetTopThroughputPods(10)
+	}
+}
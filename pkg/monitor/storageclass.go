@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+)
+
+// StorageClassMetrics 使用同一存储类的Pod的聚合存储性能指标
+// eBPF数据按Pod而非按卷采集，挂载多个存储类PVC的Pod会计入每个存储类
+type StorageClassMetrics struct {
+	Cluster      string   // 单集群时为空
+	StorageClass string   // 未指定存储类的静态PV为空
+	CSIDrivers   []string // 该存储类下PV使用的CSI驱动
+	AggregateMetrics
+}
+
+// storageClassKey 标识集群中的一个存储类，不同集群中的同名存储类可能对应不同的后端
+type storageClassKey struct {
+	cluster string
+	name    string
+}
+
+// GetStorageClassMetrics 按存储类聚合挂载了已绑定PVC的Pod的指标，结果按集群和存储类名称排序
+func (sm *StorageMonitor) GetStorageClassMetrics() []*StorageClassMetrics {
+	sm.metricsMutex.RLock()
+	groups := make(map[storageClassKey][]PodStorageMetrics)
+	drivers := make(map[storageClassKey]map[string]bool)
+	for _, metrics := range sm.metrics {
+		seen := make(map[storageClassKey]bool)
+		for _, volume := range metrics.Volumes {
+			if !volume.Bound() {
+				continue
+			}
+			class := storageClassKey{cluster: metrics.Cluster, name: volume.StorageClass}
+			if drivers[class] == nil {
+				drivers[class] = make(map[string]bool)
+			}
+			if volume.CSIDriver != "" {
+				drivers[class][volume.CSIDriver] = true
+			}
+			if !seen[class] {
+				seen[class] = true
+				groups[class] = append(groups[class], *metrics)
+			}
+		}
+	}
+	sm.metricsMutex.RUnlock()
+
+	classes := make([]*StorageClassMetrics, 0, len(groups))
+	for class, pods := range groups {
+		classMetrics := &StorageClassMetrics{
+			Cluster:          class.cluster,
+			StorageClass:     class.name,
+			CSIDrivers:       make([]string, 0, len(drivers[class])),
+			AggregateMetrics: aggregatePods(pods),
+		}
+		for driver := range drivers[class] {
+			classMetrics.CSIDrivers = append(classMetrics.CSIDrivers, driver)
+		}
+		sort.Strings(classMetrics.CSIDrivers)
+		classes = append(classes, classMetrics)
+	}
+
+	sort.Slice(classes, func(i, j int) bool {
+		if classes[i].Cluster != classes[j].Cluster {
+			return classes[i].Cluster < classes[j].Cluster
+		}
+		return classes[i].StorageClass < classes[j].StorageClass
+	})
+
+	return classes
+}
+
+// resolveVolumes 查询各Pod挂载的PVC及其PV，返回以podKey为key的卷信息
+// PVC与PV的绑定关系不会改变，已绑定的结果缓存在volumeCache中，未绑定的PVC在下次采集时重新查询
+// 仅由采集goroutine调用
+func (sm *StorageMonitor) resolveVolumes(ctx context.Context, pods []k8s.PodRef) map[string][]k8s.VolumeInfo {
+	result := make(map[string][]k8s.VolumeInfo, len(pods))
+	referenced := make(map[string]bool)
+
+	for _, pod := range pods {
+		for _, pvcName := range pod.PVCs {
+			key := pod.Cluster + "/" + pod.Namespace + "/" + pvcName
+			referenced[key] = true
+
+			info, ok := sm.volumeCache[key]
+			if !ok {
+				resolved, err := sm.clientFor(pod.Cluster).GetPVForPVC(ctx, pod.Namespace, pvcName)
+				if err != nil {
+					fmt.Printf("Error resolving volume for pod %s: %v\n", pod.Name, err)
+					continue
+				}
+				info = *resolved
+				if info.Bound() {
+					sm.volumeCache[key] = info
+				}
+			}
+			result[podKey(pod)] = append(result[podKey(pod)], info)
+		}
+	}
+
+	// 清理不再被任何Pod引用的PVC
+	for key := range sm.volumeCache {
+		if !referenced[key] {
+			delete(sm.volumeCache, key)
+		}
+	}
+
+	return result
+}
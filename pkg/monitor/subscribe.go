@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SubscriberBuffer 每个订阅者最多缓冲的快照数，订阅者处理跟不上、缓冲已满时丢弃新的快照
+const SubscriberBuffer = 4
+
+// subscribers 订阅者列表，采集goroutine在每次成功采集后向所有订阅者推送快照
+type subscribers struct {
+	mu     sync.Mutex
+	list   []*subscriber
+	nextID int
+	closed bool // 监控已停止，之后的订阅立即得到已关闭的通道
+}
+
+// subscriber 一个订阅者的通道和丢弃快照的状态，受subscribers.mu保护
+type subscriber struct {
+	id      int // 订阅顺序编号，只用于日志，取消其他订阅后不变
+	ch      chan map[string]*PodStorageMetrics
+	dropped uint64 // 本轮缓冲已满以来丢弃的快照数，为0表示没有在丢弃
+}
+
+// Subscribe 返回一个通道，每次成功采集后尝试推送一份所有Pod指标的快照，格式与GetAllMetrics相同
+// 每个订阅者得到独立的快照副本，暂停期间没有采集也就没有推送；
+// 推送不等待订阅者：缓冲的SubscriberBuffer个快照未取走时，该订阅者会错过这次采集的快照，因此不保证收到每次采集的结果，
+// 开始丢弃和恢复接收时各打印一次日志。订阅者应在Start之前订阅以免错过第一次采集，处理时间应短于采集周期
+// 监控停止后通道被关闭，可以用range读取直到监控停止
+func (sm *StorageMonitor) Subscribe() <-chan map[string]*PodStorageMetrics {
+	ch := make(chan map[string]*PodStorageMetrics, SubscriberBuffer)
+
+	sm.subs.mu.Lock()
+	defer sm.subs.mu.Unlock()
+	if sm.subs.closed {
+		close(ch)
+		return ch
+	}
+	sm.subs.nextID++
+	sm.subs.list = append(sm.subs.list, &subscriber{id: sm.subs.nextID, ch: ch})
+	return ch
+}
+
+// Unsubscribe 取消订阅并关闭通道，ch为Subscribe返回的通道，已取消或监控已停止时不做任何事
+func (sm *StorageMonitor) Unsubscribe(ch <-chan map[string]*PodStorageMetrics) {
+	sm.subs.mu.Lock()
+	defer sm.subs.mu.Unlock()
+	for i, sub := range sm.subs.list {
+		if sub.ch == ch {
+			close(sub.ch)
+			sm.subs.list = append(sm.subs.list[:i], sm.subs.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// publish 向所有订阅者推送当前的指标快照，不等待处理跟不上的订阅者
+// 仅由采集goroutine在成功采集后调用；publish是唯一的发送方，有空位的通道在发送前不会被填满，
+// 因此先检查缓冲再复制，缓冲已满的订阅者不会产生快照副本
+// 订阅者持续跟不上时只在开始丢弃时打印一次，恢复接收时打印期间丢弃的快照数，避免每个采集周期都输出日志
+func (sm *StorageMonitor) publish() {
+	sm.subs.mu.Lock()
+	defer sm.subs.mu.Unlock()
+
+	for _, sub := range sm.subs.list {
+		if len(sub.ch) == cap(sub.ch) {
+			if sub.dropped == 0 {
+				fmt.Printf("Subscriber %d is not keeping up, dropping metrics snapshots until its %d buffered snapshots are consumed\n", sub.id, SubscriberBuffer)
+			}
+			sub.dropped++
+			continue
+		}
+		if sub.dropped > 0 {
+			fmt.Printf("Subscriber %d resumed receiving metrics snapshots after %d were dropped\n", sub.id, sub.dropped)
+			sub.dropped = 0
+		}
+		sub.ch <- sm.GetAllMetrics()
+	}
+}
+
+// closeSubscribers 关闭所有订阅者的通道，仅在采集goroutine退出时调用
+func (sm *StorageMonitor) closeSubscribers() {
+	sm.subs.mu.Lock()
+	defer sm.subs.mu.Unlock()
+
+	for _, sub := range sm.subs.list {
+		close(sub.ch)
+	}
+	sm.subs.list = nil
+	sm.subs.closed = true
+}
@@ -0,0 +1,120 @@
+package monitor
+
+import (
+	"testing"
+)
+
+// drain 取走通道中已缓冲的所有快照
+func drain(ch <-chan map[string]*PodStorageMetrics) []map[string]*PodStorageMetrics {
+	var snapshots []map[string]*PodStorageMetrics
+	for {
+		select {
+		case snapshot := <-ch:
+			snapshots = append(snapshots, snapshot)
+		default:
+			return snapshots
+		}
+	}
+}
+
+func TestPublishMultipleSubscribers(t *testing.T) {
+	sm := newTopNMonitor(3)
+	subs := []<-chan map[string]*PodStorageMetrics{sm.Subscribe(), sm.Subscribe(), sm.Subscribe()}
+
+	sm.publish()
+
+	// 每个订阅者恰好收到一份快照，且是互相独立的副本
+	var snapshots []map[string]*PodStorageMetrics
+	for i, ch := range subs {
+		got := drain(ch)
+		if len(got) != 1 {
+			t.Fatalf("subscriber %d received %d snapshots, want exactly 1", i, len(got))
+		}
+		if len(got[0]) != 3 {
+			t.Errorf("subscriber %d snapshot has %d pods, want 3", i, len(got[0]))
+		}
+		snapshots = append(snapshots, got[0])
+	}
+	snapshots[0]["uid-0"].ReadIOPS = 1 << 40
+	if snapshots[1]["uid-0"].ReadIOPS == 1<<40 || sm.metrics["uid-0"].ReadIOPS == 1<<40 {
+		t.Error("subscribers share the same snapshot")
+	}
+}
+
+func TestPublishSkipsFullSubscriber(t *testing.T) {
+	sm := newTopNMonitor(3)
+	slow := sm.Subscribe()
+	fast := sm.Subscribe()
+
+	const publishes = SubscriberBuffer + 3
+	received := 0
+	for i := 0; i < publishes; i++ {
+		sm.publish()
+		received += len(drain(fast))
+	}
+
+	// 处理跟不上的订阅者只保留缓冲内的快照，不影响其他订阅者
+	if n := len(drain(slow)); n != SubscriberBuffer {
+		t.Errorf("slow subscriber received %d snapshots, want %d", n, SubscriberBuffer)
+	}
+	if received != publishes {
+		t.Errorf("fast subscriber received %d snapshots, want %d", received, publishes)
+	}
+
+	// 丢弃期间记录丢弃数，取走缓冲后恢复接收并清零
+	if dropped := sm.subs.list[0].dropped; dropped != publishes-SubscriberBuffer {
+		t.Errorf("slow subscriber dropped = %d, want %d", dropped, publishes-SubscriberBuffer)
+	}
+	if dropped := sm.subs.list[1].dropped; dropped != 0 {
+		t.Errorf("fast subscriber dropped = %d, want 0", dropped)
+	}
+	sm.publish()
+	if n := len(drain(slow)); n != 1 {
+		t.Errorf("slow subscriber received %d snapshots after catching up, want 1", n)
+	}
+	if dropped := sm.subs.list[0].dropped; dropped != 0 {
+		t.Errorf("slow subscriber dropped = %d after recovering, want 0", dropped)
+	}
+}
+
+func TestPublishDoesNotCopyForFullSubscriber(t *testing.T) {
+	const pods = 1000
+	sm := newTopNMonitor(pods)
+	ch := sm.Subscribe()
+	for i := 0; i < SubscriberBuffer; i++ {
+		sm.publish()
+	}
+
+	// 复制快照需要为每个Pod分配一次，缓冲已满时不应复制
+	if allocs := testing.AllocsPerRun(10, sm.publish); allocs >= pods {
+		t.Errorf("publish to a full subscriber made %.0f allocations, want no snapshot copy", allocs)
+	}
+	if n := len(drain(ch)); n != SubscriberBuffer {
+		t.Errorf("subscriber has %d snapshots, want %d", n, SubscriberBuffer)
+	}
+}
+
+func TestUnsubscribeStopsDelivery(t *testing.T) {
+	sm := newTopNMonitor(1)
+	kept := sm.Subscribe()
+	removed := sm.Subscribe()
+
+	sm.Unsubscribe(removed)
+	sm.publish()
+
+	if _, ok := <-removed; ok {
+		t.Error("unsubscribed channel received a snapshot, want closed")
+	}
+	if n := len(drain(kept)); n != 1 {
+		t.Errorf("remaining subscriber received %d snapshots, want 1", n)
+	}
+
+	// 停止后的订阅立即得到已关闭的通道
+	sm.closeSubscribers()
+	if _, ok := <-kept; ok {
+		t.Error("channel not closed after closeSubscribers")
+	}
+	if _, ok := <-sm.Subscribe(); ok {
+		t.Error("Subscribe after stop returned an open channel")
+	}
+}
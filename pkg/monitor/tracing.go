@@ -0,0 +1,186 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PodTracingStatus 按需跟踪的Pod及其当前跟踪的cgroup
+type PodTracingStatus struct {
+	PodName   string
+	Namespace string
+	PodUID    string
+	CgroupIDs []uint64 // 为空表示Pod当前没有可跟踪的cgroup，例如Pod已被删除
+}
+
+// TracingStatus 按Pod跟踪的状态
+type TracingStatus struct {
+	Filtering  bool   // 为false时跟踪所有Pod
+	SampleRate uint32 // 内核程序每N个I/O记录1个，1表示记录所有I/O
+	Pods       []PodTracingStatus
+}
+
+// tracedPod 按需跟踪的一个Pod，Pod被删除后仍保留名称以便显示和停止跟踪
+type tracedPod struct {
+	podName   string
+	namespace string
+	cgroupIDs []uint64
+}
+
+// EnablePodTracing 开始跟踪指定Pod的I/O，id为Pod UID或名称，按ResolvePodKey解析
+// 首次调用后只跟踪通过该方法启用的Pod，调用TraceAllPods恢复跟踪所有Pod
+// Pod的cgroup在每次采集时按UID重新解析，容器重启后仍会被跟踪，同名Pod重建后不会被跟踪
+func (sm *StorageMonitor) EnablePodTracing(id string) error {
+	if sm.cgroupResolver == nil {
+		return fmt.Errorf("per-pod tracing requires cgroup resolution, which is disabled in mock data mode")
+	}
+	if sm.cgroupResolver.PIDBased() {
+		return fmt.Errorf("per-pod tracing filters by cgroup ID and requires a cgroup v2 hierarchy, which this node does not have")
+	}
+
+	sm.metricsMutex.RLock()
+	key, err := sm.resolvePodKey(id)
+	var pod tracedPod
+	if err == nil {
+		pod = tracedPod{podName: sm.metrics[key].PodName, namespace: sm.metrics[key].Namespace}
+	}
+	sm.metricsMutex.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	pod.cgroupIDs = sm.cgroupResolver.CgroupIDs(key)
+	if len(pod.cgroupIDs) == 0 {
+		return fmt.Errorf("no cgroups found for pod %s", id)
+	}
+
+	sm.tracingMutex.Lock()
+	defer sm.tracingMutex.Unlock()
+
+	for _, cgroupID := range pod.cgroupIDs {
+		if err := sm.bpfMonitor.EnablePod(cgroupID); err != nil {
+			return err
+		}
+	}
+	sm.tracedPods[key] = &pod
+	return nil
+}
+
+// DisablePodTracing 停止跟踪指定Pod的I/O，id为Pod UID或名称，按名称只匹配正在跟踪的Pod
+// 停止跟踪最后一个Pod后不再跟踪任何Pod，直到再次启用或调用TraceAllPods
+func (sm *StorageMonitor) DisablePodTracing(id string) error {
+	sm.tracingMutex.Lock()
+	defer sm.tracingMutex.Unlock()
+
+	key, err := sm.resolveTracedPod(id)
+	if err != nil {
+		return err
+	}
+
+	for _, cgroupID := range sm.tracedPods[key].cgroupIDs {
+		if err := sm.bpfMonitor.DisablePod(cgroupID); err != nil {
+			return err
+		}
+	}
+	delete(sm.tracedPods, key)
+	return nil
+}
+
+// resolveTracedPod 将Pod UID或名称解析为正在跟踪的Pod的key，调用方需持有tracingMutex
+// 被跟踪的Pod可能已被删除，因此不使用指标中的Pod列表
+func (sm *StorageMonitor) resolveTracedPod(id string) (string, error) {
+	if _, ok := sm.tracedPods[id]; ok {
+		return id, nil
+	}
+
+	var matches []string
+	for key, pod := range sm.tracedPods {
+		if pod.podName == id {
+			matches = append(matches, key)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("pod %s is not being traced", id)
+	case 1:
+		return matches[0], nil
+	default:
+		sort.Strings(matches)
+		return "", fmt.Errorf("pod name %s is ambiguous, use one of the pod UIDs %s", id, strings.Join(matches, ", "))
+	}
+}
+
+// TraceAllPods 取消按Pod跟踪，恢复跟踪所有Pod
+func (sm *StorageMonitor) TraceAllPods() error {
+	sm.tracingMutex.Lock()
+	defer sm.tracingMutex.Unlock()
+
+	if err := sm.bpfMonitor.TraceAllPods(); err != nil {
+		return err
+	}
+	sm.tracedPods = make(map[string]*tracedPod)
+	return nil
+}
+
+// GetTracingStatus 返回按Pod跟踪的状态，Pod按命名空间和名称排序
+func (sm *StorageMonitor) GetTracingStatus() *TracingStatus {
+	filtering, _ := sm.bpfMonitor.TracingFilter()
+
+	sm.tracingMutex.Lock()
+	defer sm.tracingMutex.Unlock()
+
+	status := &TracingStatus{
+		Filtering:  filtering,
+		SampleRate: sm.bpfMonitor.SampleRate(),
+		Pods:       make([]PodTracingStatus, 0, len(sm.tracedPods)),
+	}
+	for key, pod := range sm.tracedPods {
+		status.Pods = append(status.Pods, PodTracingStatus{
+			PodName:   pod.podName,
+			Namespace: pod.namespace,
+			PodUID:    key,
+			CgroupIDs: append([]uint64(nil), pod.cgroupIDs...),
+		})
+	}
+	sort.Slice(status.Pods, func(i, j int) bool {
+		a, b := status.Pods[i], status.Pods[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.PodName != b.PodName {
+			return a.PodName < b.PodName
+		}
+		return a.PodUID < b.PodUID
+	})
+
+	return status
+}
+
+// syncPodTracing 在cgroup映射刷新后更新被跟踪Pod的cgroup，跟上容器的创建和重启
+func (sm *StorageMonitor) syncPodTracing() {
+	sm.tracingMutex.Lock()
+	defer sm.tracingMutex.Unlock()
+
+	for key, pod := range sm.tracedPods {
+		newIDs := sm.cgroupResolver.CgroupIDs(key)
+
+		current := make(map[uint64]bool, len(newIDs))
+		for _, id := range newIDs {
+			current[id] = true
+			if err := sm.bpfMonitor.EnablePod(id); err != nil {
+				fmt.Printf("Error enabling tracing for pod %s: %v\n", pod.podName, err)
+			}
+		}
+		for _, id := range pod.cgroupIDs {
+			if current[id] {
+				continue
+			}
+			if err := sm.bpfMonitor.DisablePod(id); err != nil {
+				fmt.Printf("Error disabling tracing for pod %s: %v\n", pod.podName, err)
+			}
+		}
+
+		pod.cgroupIDs = newIDs
+	}
+}
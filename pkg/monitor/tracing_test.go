@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lizhongxuan/ioeye/pkg/ebpf"
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+)
+
+const (
+	tracingPodUID1 = "1b4e28ba-2fa1-11d2-883f-0016d3cca427"
+	tracingPodUID2 = "6fa459ea-ee8a-3ca4-894e-db77e160355e"
+)
+
+// newTracingMonitor 返回两个命名空间中各有一个db-0的监控器，cgroup目录为cgroupfs驱动的布局
+func newTracingMonitor(t *testing.T) *StorageMonitor {
+	t.Helper()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "cgroup.controllers"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, uid := range []string{tracingPodUID1, tracingPodUID2} {
+		if err := os.MkdirAll(filepath.Join(root, "kubepods", "besteffort", "pod"+uid), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pods := []k8s.PodRef{
+		{Name: "db-0", Namespace: "team-a", UID: tracingPodUID1},
+		{Name: "db-0", Namespace: "team-b", UID: tracingPodUID2},
+	}
+	resolver := k8s.NewCgroupResolver(root)
+	if err := resolver.Refresh(pods); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	bpfMonitor, err := ebpf.NewMonitor(ebpf.WithMockData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm := NewStorageMonitor(bpfMonitor, nil, WithCgroupResolver(resolver))
+	for _, pod := range pods {
+		sm.metrics[pod.UID] = &PodStorageMetrics{PodName: pod.Name, Namespace: pod.Namespace, PodUID: pod.UID}
+	}
+	return sm
+}
+
+func TestEnablePodTracingByUID(t *testing.T) {
+	sm := newTracingMonitor(t)
+
+	if err := sm.EnablePodTracing("db-0"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("EnablePodTracing(ambiguous name) error = %v, want ambiguous", err)
+	}
+	if err := sm.EnablePodTracing(tracingPodUID1); err != nil {
+		t.Fatalf("EnablePodTracing(uid) error = %v", err)
+	}
+
+	status := sm.GetTracingStatus()
+	if len(status.Pods) != 1 {
+		t.Fatalf("traced pods = %+v, want only team-a/db-0", status.Pods)
+	}
+	pod := status.Pods[0]
+	if pod.PodUID != tracingPodUID1 || pod.Namespace != "team-a" || pod.PodName != "db-0" {
+		t.Errorf("traced pod = %+v, want team-a/db-0", pod)
+	}
+	_, cgroupIDs := sm.bpfMonitor.TracingFilter()
+	if !reflect.DeepEqual(cgroupIDs, sm.cgroupResolver.CgroupIDs(tracingPodUID1)) {
+		t.Errorf("traced cgroups = %v, want only the cgroups of team-a/db-0", cgroupIDs)
+	}
+
+	// 只有一个被跟踪的db-0，按名称停止跟踪不会影响另一个命名空间的Pod
+	if err := sm.DisablePodTracing("db-0"); err != nil {
+		t.Fatalf("DisablePodTracing(name) error = %v", err)
+	}
+	if pods := sm.GetTracingStatus().Pods; len(pods) != 0 {
+		t.Errorf("traced pods after disable = %+v, want none", pods)
+	}
+	if err := sm.DisablePodTracing(tracingPodUID2); err == nil {
+		t.Error("DisablePodTracing(untraced pod) error = nil")
+	}
+}
@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"sort"
+
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+)
+
+// VolumeMetrics 挂载同一PVC的Pod的聚合存储性能指标，用于估计各PVC的负载
+// eBPF数据按Pod而非按卷采集：只挂载一个PVC的Pod，其I/O全部计入该PVC；
+// 挂载多个PVC的Pod无法区分I/O落在哪个卷上，会计入每个PVC，并将这些PVC标记为Ambiguous
+type VolumeMetrics struct {
+	Cluster       string // 单集群时为空
+	Namespace     string
+	PVCName       string
+	PVName        string
+	StorageClass  string
+	CSIDriver     string   // 非CSI卷为空
+	Ambiguous     bool     // 有Pod同时挂载了其他PVC，指标可能高估该PVC的负载
+	AmbiguousPods []string // 同时挂载了其他PVC的Pod，按名称排序
+	AggregateMetrics
+}
+
+// volumeKey 标识集群中的一个PVC
+type volumeKey struct {
+	cluster   string
+	namespace string
+	pvc       string
+}
+
+// GetVolumeMetrics 按PVC聚合挂载了已绑定PVC的Pod的指标，结果按集群、命名空间和PVC名称排序
+// 同一PVC被多个Pod挂载（如ReadWriteMany）时合并这些Pod的I/O；Pod写入根文件系统或emptyDir的I/O同样会计入
+func (sm *StorageMonitor) GetVolumeMetrics() []*VolumeMetrics {
+	sm.metricsMutex.RLock()
+	groups := make(map[volumeKey][]PodStorageMetrics)
+	infos := make(map[volumeKey]k8s.VolumeInfo)
+	ambiguous := make(map[volumeKey][]string)
+	for _, metrics := range sm.metrics {
+		for _, volume := range metrics.Volumes {
+			if !volume.Bound() {
+				continue
+			}
+			key := volumeKey{cluster: metrics.Cluster, namespace: metrics.Namespace, pvc: volume.PVCName}
+			infos[key] = volume
+			groups[key] = append(groups[key], *metrics)
+			if len(metrics.Volumes) > 1 {
+				ambiguous[key] = append(ambiguous[key], metrics.PodName)
+			}
+		}
+	}
+	sm.metricsMutex.RUnlock()
+
+	volumes := make([]*VolumeMetrics, 0, len(groups))
+	for key, pods := range groups {
+		info := infos[key]
+		volumeMetrics := &VolumeMetrics{
+			Cluster:          key.cluster,
+			Namespace:        key.namespace,
+			PVCName:          key.pvc,
+			PVName:           info.PVName,
+			StorageClass:     info.StorageClass,
+			CSIDriver:        info.CSIDriver,
+			Ambiguous:        len(ambiguous[key]) > 0,
+			AmbiguousPods:    ambiguous[key],
+			AggregateMetrics: aggregatePods(pods),
+		}
+		sort.Strings(volumeMetrics.AmbiguousPods)
+		volumes = append(volumes, volumeMetrics)
+	}
+
+	sort.Slice(volumes, func(i, j int) bool {
+		a, b := volumes[i], volumes[j]
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.PVCName < b.PVCName
+	})
+
+	return volumes
+}
@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"sort"
+
+	"github.com/lizhongxuan/ioeye/pkg/k8s"
+)
+
+// WorkloadMetrics 工作负载（Deployment/StatefulSet/DaemonSet等）的聚合存储性能指标
+type WorkloadMetrics struct {
+	Cluster   string // 单集群时为空
+	Kind      string // 裸Pod为standalone
+	Name      string // standalone时为空
+	Namespace string
+	AggregateMetrics
+}
+
+// workloadKey 标识集群和命名空间内的一个工作负载
+type workloadKey struct {
+	cluster   string
+	namespace string
+	kind      string
+	name      string
+}
+
+// GetWorkloadMetrics 按控制器聚合所有Pod的指标，同一命名空间内的裸Pod归入一个standalone工作负载
+// 结果按集群、命名空间、类型和名称排序
+func (sm *StorageMonitor) GetWorkloadMetrics() []*WorkloadMetrics {
+	sm.metricsMutex.RLock()
+	groups := make(map[workloadKey][]PodStorageMetrics)
+	for _, metrics := range sm.metrics {
+		key := workloadKey{cluster: metrics.Cluster, namespace: metrics.Namespace, kind: metrics.WorkloadKind, name: metrics.WorkloadName}
+		if key.kind == "" {
+			key.kind = k8s.WorkloadKindStandalone
+		}
+		if key.kind == k8s.WorkloadKindStandalone {
+			key.name = ""
+		}
+		groups[key] = append(groups[key], *metrics)
+	}
+	sm.metricsMutex.RUnlock()
+
+	workloads := make([]*WorkloadMetrics, 0, len(groups))
+	for key, pods := range groups {
+		workloads = append(workloads, &WorkloadMetrics{
+			Cluster:          key.cluster,
+			Kind:             key.kind,
+			Name:             key.name,
+			Namespace:        key.namespace,
+			AggregateMetrics: aggregatePods(pods),
+		})
+	}
+
+	sort.Slice(workloads, func(i, j int) bool {
+		a, b := workloads[i], workloads[j]
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+
+	return workloads
+}